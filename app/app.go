@@ -5,27 +5,43 @@ import (
 	"claude-squad/keys"
 	"claude-squad/log"
 	"claude-squad/session"
+	"claude-squad/session/git"
+	"claude-squad/session/tmux"
 	"claude-squad/ui"
 	"claude-squad/ui/overlay"
+	"claude-squad/util"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/mattn/go-runewidth"
 )
 
 const GlobalInstanceLimit = 10
 
-// Run is the main entrypoint into the application.
-func Run(ctx context.Context, program string, autoYes bool) error {
+// Run is the main entrypoint into the application. initialPrompt, if not empty, seeds the prompt for
+// the first new instance. The prompts of batch, if any, are dispatched to new instances on launch.
+func Run(ctx context.Context, program string, autoYes bool, initialPrompt string, batch PromptBatch) error {
 	home := newHome(ctx, program, autoYes)
+	home.initialPrompt = initialPrompt
+	if len(batch.Prompts) > 0 {
+		if cmd := home.dispatchPromptBatch(batch); cmd != nil {
+			home.startupCmds = append(home.startupCmds, cmd)
+		}
+	}
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -34,24 +50,26 @@ func Run(ctx context.Context, program string, autoYes bool) error {
 	// Cleanup function to stop all dev servers
 	cleanup := func() {
 		log.InfoLog.Printf("Cleaning up dev servers on shutdown...")
-		for _, instance := range home.list.GetInstances() {
-			if instance.DevServer != nil && instance.DevServer.Status() == session.DevServerRunning {
-				if err := instance.DevServer.Stop(); err != nil {
-					log.ErrorLog.Printf("failed to stop dev server for %s: %v", instance.Title, err)
-				}
-			}
-		}
+		home.suspendDevServers()
 		// Save instances state
 		if err := home.storage.SaveInstances(home.list.GetInstances()); err != nil {
 			log.ErrorLog.Printf("failed to save instances on shutdown: %v", err)
 		}
 	}
 
-	p := tea.NewProgram(
-		home,
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(), // Mouse scroll
-	)
+	opts := []tea.ProgramOption{tea.WithAltScreen()}
+	if home.appConfig.MouseEnabled {
+		// Mouse scroll. It is released to attached sessions, see attachCmd.
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	p := tea.NewProgram(home, opts...)
+
+	stopControlServer, err := startControlServer(home.appConfig, p.Send)
+	if err != nil {
+		// The control API is optional, so don't keep the TUI from starting.
+		log.ErrorLog.Printf("control API disabled: %v", err)
+	}
+	defer stopControlServer()
 
 	// Handle signals in a goroutine
 	go func() {
@@ -61,7 +79,7 @@ func Run(ctx context.Context, program string, autoYes bool) error {
 		p.Quit()
 	}()
 
-	_, err := p.Run()
+	_, err = p.Run()
 
 	// Stop signal handling
 	signal.Stop(sigChan)
@@ -86,6 +104,22 @@ const (
 	stateConfirm
 	// stateDevServerConfig is when user is configuring dev server settings.
 	stateDevServerConfig
+	// stateSendFile is the state when the user is entering the path of a file to send as a prompt.
+	stateSendFile
+	// stateSelectTemplate is the state when the user is picking a template for a new instance.
+	stateSelectTemplate
+	// stateBatchPrompt is the state when the user is entering a prompt for all marked instances.
+	stateBatchPrompt
+	// stateQuickSwitch is the state when the user is searching for an instance to select.
+	stateQuickSwitch
+	// stateEditSettings is the state when the user is editing the repo's settings file.
+	stateEditSettings
+	// stateSelectDevServerField is the state when the user is picking which dev server setting to edit.
+	stateSelectDevServerField
+	// stateSelectDiffFile is the state when the user is picking a changed file to narrow the diff to.
+	stateSelectDiffFile
+	// stateRunCommand is the state when the user is entering a command to run in the worktree.
+	stateRunCommand
 )
 
 type home struct {
@@ -110,9 +144,23 @@ type home struct {
 	// newInstanceFinalizer is called when the state is stateNew and then you press enter.
 	// It registers the new instance in the list after the instance has been started.
 	newInstanceFinalizer func()
+	// newInstance is the placeholder of the instance being named in stateNew. It's in the list
+	// but not started, and is removed again if the creation is abandoned.
+	newInstance *session.Instance
 
 	// promptAfterName tracks if we should enter prompt mode after naming
 	promptAfterName bool
+	// initialPrompt seeds the prompt for the first new instance. It is cleared once used.
+	initialPrompt string
+	// seededPrompt is the text the prompt overlay was seeded with, and seededValue the overlay's
+	// value for it, which has the newlines replaced. The seeded text is sent as a paste, keeping its
+	// newlines, unless the value was edited.
+	seededPrompt, seededValue string
+	// newInstanceTemplate is the template the instance being named was created from, if any
+	newInstanceTemplate *config.InstanceTemplate
+	// newInstanceDevServer is the dev server config copied from another instance for the instance
+	// being named, if any. It takes precedence over the template's.
+	newInstanceDevServer *session.DevServerConfig
 
 	// keySent is used to manage underlining menu items
 	keySent bool
@@ -133,8 +181,52 @@ type home struct {
 	textInputOverlay *overlay.TextInputOverlay
 	// textOverlay displays text information
 	textOverlay *overlay.TextOverlay
+	// onHelpDismiss is run when the help screen in the text overlay is closed
+	onHelpDismiss func() tea.Cmd
+	// devServerConfigCmd is set by the submit callbacks of the dev server config overlay and
+	// returned once the key press that submitted was handled
+	devServerConfigCmd tea.Cmd
 	// confirmationOverlay displays confirmation modals
 	confirmationOverlay *overlay.ConfirmationOverlay
+	// selectionOverlay lets the user pick from a list, e.g. a template
+	selectionOverlay *overlay.SelectionOverlay
+	// diffFiles are the files listed in the selection overlay while picking a file of the diff
+	diffFiles []string
+	// quickSwitchOverlay searches instances by title
+	quickSwitchOverlay *overlay.QuickSwitchOverlay
+	// textEditorOverlay edits multi-line text, e.g. the settings file
+	textEditorOverlay *overlay.TextEditorOverlay
+	// newInstanceOverlay is the form for naming the instance being created
+	newInstanceOverlay *overlay.NewInstanceOverlay
+	// shownReport is the report shown in the text overlay, if any. It is copied to the clipboard if
+	// the overlay is closed with c.
+	shownReport string
+	// lastCommandResult is the result of the last command run in a worktree, shown again with
+	// KeyCommandOutput
+	lastCommandResult *commandFinishedMsg
+	// confirmResult holds the message returned by the last confirmed action until it is dispatched
+	confirmResult tea.Msg
+
+	// pendingKill is the last killed instance, kept until its undo window passes
+	pendingKill *pendingKill
+	// killSeq identifies pending kills so a stale undo timer doesn't finalize a newer kill
+	killSeq int
+	// failedCreation is the last instance that failed to start, kept until its retry window passes
+	failedCreation *failedCreation
+	// creationSeq identifies failed creations so a stale retry timer doesn't drop a newer one
+	creationSeq int
+
+	// fetchingBase is true while base branches are fetched in the background
+	fetchingBase bool
+	// lastBaseFetch is when base branches were last fetched, used to throttle fetching
+	lastBaseFetch time.Time
+	// lastAutoSave is when the instances were last auto-saved
+	lastAutoSave time.Time
+
+	// startupCmds run once the program starts, so slow startup work doesn't delay the first frame
+	startupCmds []tea.Cmd
+	// startingInstances is how many instances are being started in the background, see startInstancesCmd
+	startingInstances int
 }
 
 func newHome(ctx context.Context, program string, autoYes bool) *home {
@@ -149,6 +241,11 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 	}
 
 	appConfig := config.LoadConfig()
+	session.SetAutoYesDenyPatterns(appConfig.AutoYesDenyPatterns)
+	session.SetDiffOptions(appConfig.DiffOptions)
+	session.SetDiffStaleAfter(appConfig.GetDiffStaleAfter())
+	session.SetDedupeServerOutput(appConfig.DedupeServerOutput)
+	session.SetDevServerSize(appConfig.GetDevServerSize())
 
 	appState := config.LoadStateForRepo(currentDir)
 
@@ -158,11 +255,15 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		os.Exit(1)
 	}
 
+	preview := ui.NewPreviewPane()
+	// An empty setting predates the option, so it gets the default snapshot behavior.
+	preview.SetPausedSnapshot(appConfig.PausedPreview != config.PausedPreviewMessage)
+
 	h := &home{
 		ctx:          ctx,
 		spinner:      spinner.New(spinner.WithSpinner(spinner.MiniDot)),
 		menu:         ui.NewMenu(),
-		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+		tabbedWindow: ui.NewTabbedWindow(preview, ui.NewDiffPane()),
 		errBox:       ui.NewErrBox(),
 		storage:      storage,
 		appConfig:    appConfig,
@@ -172,6 +273,7 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		appState:     appState,
 	}
 	h.list = ui.NewList(&h.spinner, autoYes)
+	h.list.SetCompact(appConfig.CompactList)
 
 	// Load saved instances
 	instances, err := storage.LoadInstances()
@@ -189,9 +291,148 @@ func newHome(ctx context.Context, program string, autoYes bool) *home {
 		}
 	}
 
+	h.startupCmds = append(h.startupCmds, h.restoreDevServers())
+	if cmd := h.createStartupInstances(currentDir); cmd != nil {
+		h.startupCmds = append(h.startupCmds, cmd)
+	}
+	if appConfig.QuitKey != "" {
+		if err := keys.SetQuitKey(appConfig.QuitKey); err != nil {
+			log.ErrorLog.Printf("failed to set quit key: %v", err)
+			h.errBox.SetError(fmt.Errorf("ignored quit_key: %w", err))
+		}
+	}
+	h.menu.SetAutoYes(h.autoYesActive())
+	h.menu.SetCheatSheet(appConfig.ShowCheatSheet)
+	h.showOnboarding()
+
 	return h
 }
 
+// autoYesActive returns true if auto-yes is on globally or for any instance.
+func (m *home) autoYesActive() bool {
+	if m.autoYes {
+		return true
+	}
+	for _, instance := range m.list.GetInstances() {
+		if instance.AutoYes {
+			return true
+		}
+	}
+	return false
+}
+
+// createStartupInstances returns a Cmd that creates and starts the instances in
+// config.Config.StartupInstances that don't exist yet, or nil if there are none. Instances are matched
+// by title, so this is a no-op on later launches.
+func (m *home) createStartupInstances(repoPath string) tea.Cmd {
+	existing := make(map[string]bool)
+	for _, instance := range m.list.GetInstances() {
+		existing[instance.Title] = true
+	}
+
+	var errs []error
+	var opts []session.InstanceOptions
+	free := GlobalInstanceLimit - m.numInstancesTowardLimit()
+	for _, spec := range m.appConfig.StartupInstances {
+		if existing[spec.Title] {
+			continue
+		}
+		if len(opts) >= free {
+			errs = append(errs, fmt.Errorf("skipped startup instance '%s': you can't create more than %d instances", spec.Title, GlobalInstanceLimit))
+			continue
+		}
+		program := m.program
+		if spec.Program != "" {
+			program = spec.Program
+		}
+		opts = append(opts, session.InstanceOptions{
+			Title:               spec.Title,
+			Path:                repoPath,
+			Program:             program,
+			BaseBranch:          spec.BaseBranch,
+			Prompt:              spec.Prompt,
+			Container:           m.appConfig.Container,
+			TmuxStartupCommands: m.appConfig.TmuxStartupCommands,
+		})
+		existing[spec.Title] = true
+	}
+	return m.startInstancesCmd("startup", opts, errors.Join(errs...), nil)
+}
+
+// instancesStartedMsg is sent when a background start of new instances finished.
+type instancesStartedMsg struct {
+	// requested is how many instances were being started, see home.startingInstances
+	requested int
+	instances []*session.Instance
+	info      string
+	err       error
+}
+
+// startInstancesCmd returns a Cmd that creates and starts an instance for each of opts in the
+// background, so setting up their worktrees and sessions doesn't delay the first frame. kind names
+// the instances in errors, skipped is reported along with the errors of the start and report, if
+// not nil, describes how many instances were created. The instancesStartedMsg handler adds the
+// instances to the list. Returns nil if there is nothing to start or report.
+func (m *home) startInstancesCmd(kind string, opts []session.InstanceOptions, skipped error, report func(created int) string) tea.Cmd {
+	if len(opts) == 0 && skipped == nil {
+		return nil
+	}
+	// The instances are started concurrently with Update, so they check for worktrees in use
+	// against a copy of the list.
+	existing := append([]*session.Instance(nil), m.list.GetInstances()...)
+	m.startingInstances += len(opts)
+	return func() tea.Msg {
+		errs := []error{skipped}
+		var instances []*session.Instance
+		for _, opt := range opts {
+			instance, err := session.NewInstance(opt)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to create %s instance '%s': %w", kind, opt.Title, err))
+				continue
+			}
+			instance.SetWorktreeCheck(func(path string) error {
+				return session.WorktreeInUse(path, existing)
+			})
+			if err := instance.Start(true); err != nil {
+				errs = append(errs, fmt.Errorf("failed to start %s instance '%s': %w", kind, opt.Title, err))
+				continue
+			}
+			instances = append(instances, instance)
+		}
+		msg := instancesStartedMsg{requested: len(opts), instances: instances, err: errors.Join(errs...)}
+		if report != nil {
+			msg.info = report(len(instances))
+		}
+		return msg
+	}
+}
+
+// handleInstancesStarted adds the instances started by startInstancesCmd to the list and saves them.
+// Errors include the report, so it isn't hidden behind them.
+func (m *home) handleInstancesStarted(msg instancesStartedMsg) tea.Cmd {
+	m.startingInstances -= msg.requested
+	for _, instance := range msg.instances {
+		instance.AutoYes = m.autoYes
+		m.list.AddInstance(instance)()
+	}
+	err := msg.err
+	if len(msg.instances) > 0 {
+		if saveErr := m.storage.SaveInstances(m.list.GetInstances()); saveErr != nil {
+			err = errors.Join(err, saveErr)
+		}
+	}
+	cmds := []tea.Cmd{m.instanceChanged()}
+	switch {
+	case err != nil && msg.info != "":
+		cmds = append(cmds, m.handleError(fmt.Errorf("%s: %w", msg.info, err)))
+	case err != nil:
+		cmds = append(cmds, m.handleError(err))
+	case msg.info != "":
+		cmds = append(cmds, m.showInfo(msg.info))
+	}
+	return tea.Batch(cmds...)
+}
+
 // updateHandleWindowSizeEvent sets the sizes of the components.
 // The components will try to render inside their bounds.
 func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
@@ -203,6 +444,10 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 	contentHeight := int(float32(msg.Height) * 0.9)
 	menuHeight := msg.Height - contentHeight - 1     // minus 1 for error box
 	m.errBox.SetSize(int(float32(msg.Width)*0.9), 1) // error box takes 1 row
+	// The cheat sheet above the menu takes its lines from the list and window.
+	cheatSheetHeight := min(m.menu.CheatSheetHeight(msg.Width), contentHeight/2)
+	contentHeight -= cheatSheetHeight
+	menuHeight += cheatSheetHeight
 
 	m.tabbedWindow.SetSize(tabsWidth, contentHeight)
 	m.list.SetSize(listWidth, contentHeight)
@@ -213,44 +458,84 @@ func (m *home) updateHandleWindowSizeEvent(msg tea.WindowSizeMsg) {
 	if m.textOverlay != nil {
 		m.textOverlay.SetWidth(int(float32(msg.Width) * 0.6))
 	}
+	if m.textEditorOverlay != nil {
+		m.textEditorOverlay.SetSize(int(float32(msg.Width)*0.6), int(float32(msg.Height)*0.8))
+	}
+	if m.newInstanceOverlay != nil {
+		m.newInstanceOverlay.SetSize(int(float32(msg.Width)*0.6), int(float32(msg.Height)*0.4))
+	}
+	if m.selectionOverlay != nil {
+		m.selectionOverlay.SetHeight(int(float32(msg.Height) * 0.8))
+	}
 
 	previewWidth, previewHeight := m.tabbedWindow.GetPreviewSize()
 	if err := m.list.SetSessionPreviewSize(previewWidth, previewHeight); err != nil {
 		log.ErrorLog.Print(err)
 	}
+	// The server pane shares the preview's size.
+	for _, instance := range m.list.GetInstances() {
+		m.resizeDevServer(instance)
+	}
 	m.menu.SetSize(msg.Width, menuHeight)
 }
 
 func (m *home) Init() tea.Cmd {
 	// Upon starting, we want to start the spinner. Whenever we get a spinner.TickMsg, we
 	// update the spinner, which sends a new spinner.TickMsg. I think this lasts forever lol.
-	return tea.Batch(
+	return tea.Batch(append([]tea.Cmd{
 		m.spinner.Tick,
-		func() tea.Msg {
-			time.Sleep(100 * time.Millisecond)
-			return previewTickMsg{}
-		},
+		m.previewTickCmd(),
 		tickUpdateMetadataCmd,
-	)
+	}, m.startupCmds...)...)
 }
 
 func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case hideErrMsg:
-		m.errBox.Clear()
+		// Keep the retry hint of a failed creation until its window passes, and pinned errors until
+		// a key is pressed.
+		if m.failedCreation == nil && !m.errBox.Pinned() {
+			m.errBox.Clear()
+		}
+	case ringBellMsg:
+		// Written from Update rather than the Cmd's goroutine, between the renderer's frames.
+		if _, err := io.WriteString(bellOutput, "\a"); err != nil {
+			log.WarningLog.Printf("failed to ring bell: %v", err)
+		}
+		return m, nil
+	case hideInfoMsg:
+		// Keep the undo toast of a pending kill until its window passes.
+		if m.pendingKill == nil {
+			m.errBox.ClearInfo()
+		}
+	case killPendingMsg:
+		return m, tea.Batch(m.instanceChanged(), func() tea.Msg {
+			select {
+			case <-m.ctx.Done():
+			case <-time.After(killUndoWindow):
+			}
+			return killUndoExpiredMsg{id: msg.id}
+		})
+	case killUndoExpiredMsg:
+		if m.pendingKill != nil && m.pendingKill.id == msg.id {
+			m.finalizePendingKill()
+		}
+		return m, nil
+	case creationRetryExpiredMsg:
+		if m.failedCreation != nil && m.failedCreation.id == msg.id {
+			m.failedCreation = nil
+			m.errBox.Clear()
+		}
+		return m, nil
 	case previewTickMsg:
+		// Only the selected instance is captured. The others are captured once they are selected.
 		cmd := m.instanceChanged()
-		return m, tea.Batch(
-			cmd,
-			func() tea.Msg {
-				time.Sleep(100 * time.Millisecond)
-				return previewTickMsg{}
-			},
-		)
+		return m, tea.Batch(cmd, m.previewTickCmd())
 	case keyupMsg:
 		m.menu.ClearKeydown()
 		return m, nil
 	case tickUpdateMetadataMessage:
+		cmds := []tea.Cmd{tickUpdateMetadataCmd}
 		for _, instance := range m.list.GetInstances() {
 			if !instance.Started() || instance.Paused() {
 				continue
@@ -260,8 +545,21 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				instance.SetStatus(session.Running)
 			} else {
 				if prompt {
+					wasBlocked := instance.AutoYesBlockedBy() != ""
 					instance.TapEnter()
+					if pattern := instance.AutoYesBlockedBy(); pattern != "" && !wasBlocked {
+						cmds = append(cmds, m.handleError(fmt.Errorf(
+							"auto-yes skipped '%s': prompt matches %q, review it manually", instance.Title, pattern)))
+					}
+				} else if instance.Prompt != "" {
+					// The program went idle for the first time, so it's ready for its initial prompt.
+					if err := instance.SendInitialPrompt(); err != nil {
+						cmds = append(cmds, m.handleError(err))
+					}
 				} else {
+					if instance.Status == session.Running {
+						cmds = append(cmds, m.agentFinished(instance))
+					}
 					instance.SetStatus(session.Ready)
 				}
 			}
@@ -272,8 +570,46 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if instance.DevServer != nil {
 				instance.DevServer.CheckHealth()
 			}
+			if err := instance.RecordFrame(); err != nil {
+				log.WarningLog.Printf("could not record frame: %v", err)
+			}
+		}
+		m.autoSaveIfDue()
+		cmds = append(cmds, m.fetchBaseIfDue())
+		return m, tea.Batch(cmds...)
+	case instanceResumedMsg:
+		if err := msg.instance.FinishResume(msg.err); err != nil {
+			return m, tea.Batch(m.handleError(fmt.Errorf("failed to resume '%s': %w", msg.instance.Title, err)), m.instanceChanged())
+		}
+		// The restored session takes the size of the preview.
+		return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
+	case instancesStartedMsg:
+		return m, m.handleInstancesStarted(msg)
+	case attachMsg:
+		msg.attach()
+		if m.appConfig.MouseEnabled {
+			return m, tea.EnableMouseCellMotion
 		}
-		return m, tickUpdateMetadataCmd
+		return m, nil
+	case worktreeRepairedMsg:
+		if msg.err != nil {
+			return m, tea.Batch(m.handleError(msg.err), m.instanceChanged())
+		}
+		if len(msg.repaired) == 0 {
+			return m, m.showInfo(fmt.Sprintf("The worktree of '%s' needs no repair", msg.instance.Title))
+		}
+		return m, tea.Batch(m.instanceChanged(),
+			m.showInfo(fmt.Sprintf("Repaired the worktree of '%s': %s", msg.instance.Title, strings.Join(msg.repaired, "; "))))
+	case baseFetchedMsg:
+		m.fetchingBase = false
+		for _, divergence := range msg.divergences {
+			divergence.instance.SetBaseDivergence(divergence.ahead, divergence.behind)
+		}
+		return m, nil
+	case controlRequestMsg:
+		resp, cmd := m.handleControlRequest(msg)
+		msg.reply <- resp
+		return m, cmd
 	case tea.MouseMsg:
 		// Handle mouse wheel events for scrolling the diff/preview pane
 		if msg.Action == tea.MouseActionPress {
@@ -283,6 +619,7 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 
+				m.focusWindow(true)
 				switch msg.Button {
 				case tea.MouseButtonWheelUp:
 					m.tabbedWindow.ScrollUp()
@@ -297,6 +634,20 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.updateHandleWindowSizeEvent(msg)
 		return m, nil
+	case tea.ResumeMsg:
+		return m, m.resumeFromSuspend()
+	case quitConfirmedMsg:
+		return m.handleQuit()
+	case helpScreensResetMsg:
+		return m, m.showInfo("Help screens will be shown again")
+	case commandFinishedMsg:
+		m.lastCommandResult = &msg
+		// Don't replace a form or overlay that is open in the meantime.
+		if m.state != stateDefault {
+			view := keys.GlobalkeyBindings[keys.KeyCommandOutput].Help().Key
+			return m, m.showInfo(fmt.Sprintf("`%s` in '%s' finished: press %s to see its output", msg.result.Command, msg.title, view))
+		}
+		return m, m.showCommandResult(msg)
 	case error:
 		// Handle errors from confirmation actions
 		return m, m.handleError(msg)
@@ -312,19 +663,95 @@ func (m *home) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m *home) handleQuit() (tea.Model, tea.Cmd) {
+	// Nothing can be undone after quitting, so finish any pending kill
+	if m.pendingKill != nil {
+		m.finalizePendingKill()
+	}
+
 	// Stop all running dev servers before quitting
+	m.suspendDevServers()
+
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m, m.handleError(err)
+	}
+	return m, tea.Quit
+}
+
+// focusWindow gives the focus to the tabbed window, or back to the list. The pane without the
+// focus is dimmed, so it's clear where the navigation and scroll keys go.
+func (m *home) focusWindow(focused bool) {
+	m.tabbedWindow.SetFocused(focused)
+	m.list.SetFocused(!focused)
+}
+
+// suspendDevServers stops the running dev servers when the app quits, remembering them so they can
+// be restored on the next launch. With config.Config.RestoreDevServers they are left running
+// instead, so the next launch can reattach to them.
+func (m *home) suspendDevServers() {
+	if m.appConfig.RestoreDevServers {
+		return
+	}
 	for _, instance := range m.list.GetInstances() {
 		if instance.DevServer != nil && instance.DevServer.Status() == session.DevServerRunning {
-			if err := instance.DevServer.Stop(); err != nil {
+			if err := instance.DevServer.Suspend(); err != nil {
 				log.ErrorLog.Printf("failed to stop dev server for %s: %v", instance.Title, err)
 			}
 		}
 	}
+}
 
-	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
-		return m, m.handleError(err)
+// restoreDevServers returns a command that runs the dev servers that were running when the app quit
+// again. The dev servers of paused instances are left alone, since they have no worktree. With
+// config.Config.RestoreDevServers the command reattaches or restarts them, otherwise the info box
+// offers to start them.
+func (m *home) restoreDevServers() tea.Cmd {
+	var offered []string
+	var restore []*session.Instance
+	for _, instance := range m.list.GetInstances() {
+		if instance.DevServer == nil || instance.Archived() || instance.Paused() || !instance.DevServer.ShouldRestore() {
+			continue
+		}
+		if !m.appConfig.RestoreDevServers {
+			offered = append(offered, instance.Title)
+			continue
+		}
+		restore = append(restore, instance)
 	}
-	return m, tea.Quit
+	if len(offered) > 0 {
+		start := keys.GlobalkeyBindings[keys.KeyDevServerStart].Help().Key
+		m.errBox.SetInfo(fmt.Sprintf("Dev servers were running for %s when you quit. Select an instance and press %s to start its dev server again.",
+			strings.Join(offered, ", "), start))
+	}
+	if len(restore) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		var errs []error
+		for _, instance := range restore {
+			reattached, err := instance.DevServer.Restore()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore the dev server of '%s': %w", instance.Title, err))
+				continue
+			}
+			log.InfoLog.Printf("restored the dev server of %s (reattached: %v)", instance.Title, reattached)
+		}
+		if err := errors.Join(errs...); err != nil {
+			log.ErrorLog.Printf("failed to restore dev servers: %v", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// workingInstances returns the number of instances whose agent is working.
+func (m *home) workingInstances() int {
+	working := 0
+	for _, instance := range m.list.GetInstances() {
+		if instance.Started() && instance.Status == session.Running {
+			working++
+		}
+	}
+	return working
 }
 
 func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly bool) {
@@ -334,7 +761,10 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 		m.keySent = false
 		return nil, false
 	}
-	if m.state == statePrompt || m.state == stateHelp || m.state == stateConfirm || m.state == stateDevServerConfig {
+	if m.state == statePrompt || m.state == stateHelp || m.state == stateConfirm || m.state == stateDevServerConfig ||
+		m.state == stateSendFile || m.state == stateSelectTemplate || m.state == stateBatchPrompt || m.state == stateQuickSwitch ||
+		m.state == stateEditSettings || m.state == stateSelectDevServerField || m.state == stateSelectDiffFile ||
+		m.state == stateRunCommand {
 		return nil, false
 	}
 	// If it's in the global keymap, we should try to highlight it.
@@ -346,7 +776,9 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 	if m.list.GetSelectedInstance() != nil && m.list.GetSelectedInstance().Paused() && name == keys.KeyEnter {
 		return nil, false
 	}
-	if name == keys.KeyShiftDown || name == keys.KeyShiftUp {
+	switch name {
+	case keys.KeyShiftDown, keys.KeyShiftUp, keys.KeyScrollTop, keys.KeyScrollBottom,
+		keys.KeyPageUp, keys.KeyPageDown, keys.KeyHalfPageUp, keys.KeyHalfPageDown:
 		return nil, false
 	}
 
@@ -362,6 +794,12 @@ func (m *home) handleMenuHighlighting(msg tea.KeyMsg) (cmd tea.Cmd, returnEarly
 }
 
 func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
+	// Suspend to the shell in every state, like other terminal apps. Agents and dev servers run in
+	// their own tmux sessions, so they keep running until the app is resumed with fg.
+	if msg.String() == "ctrl+z" {
+		return m, tea.Suspend
+	}
+
 	cmd, returnEarly := m.handleMenuHighlighting(msg)
 	if returnEarly {
 		return m, cmd
@@ -372,91 +810,35 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 	}
 
 	if m.state == stateNew {
-		// Handle quit commands first. Don't handle q because the user might want to type that.
-		if msg.String() == "ctrl+c" {
-			m.state = stateDefault
-			m.promptAfterName = false
-			m.list.Kill()
-			return m, tea.Sequence(
-				tea.WindowSize(),
-				func() tea.Msg {
-					m.menu.SetState(ui.StateDefault)
-					return nil
-				},
-			)
+		instance := m.newInstance
+		// Don't handle q because the user might want to type that.
+		closed := msg.String() == "ctrl+c" || m.newInstanceOverlay.HandleKeyPress(msg)
+		// Show the title in the list while it's typed.
+		if err := instance.SetTitle(m.newInstanceOverlay.Values().Title); err != nil {
+			return m, m.handleError(err)
+		}
+		if !closed {
+			return m, nil
+		}
+		form := m.newInstanceOverlay
+		m.newInstanceOverlay = nil
+		if form.Submitted {
+			return m.startNewInstance(instance)
 		}
 
-		instance := m.list.GetInstances()[m.list.NumInstances()-1]
-		switch msg.Type {
-		// Start the instance (enable previews etc) and go back to the main menu state.
-		case tea.KeyEnter:
-			if len(instance.Title) == 0 {
-				return m, m.handleError(fmt.Errorf("title cannot be empty"))
-			}
-
-			if err := instance.Start(true); err != nil {
-				m.list.Kill()
-				m.state = stateDefault
-				return m, m.handleError(err)
-			}
-			// Save after adding new instance
-			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
-				return m, m.handleError(err)
-			}
-			// Instance added successfully, call the finalizer.
-			m.newInstanceFinalizer()
-			if m.autoYes {
-				instance.AutoYes = true
-			}
-
-			m.newInstanceFinalizer()
-			m.state = stateDefault
-			if m.promptAfterName {
-				m.state = statePrompt
-				m.menu.SetState(ui.StatePrompt)
-				// Initialize the text input overlay
-				m.textInputOverlay = overlay.NewTextInputOverlay("Enter prompt", "")
-				m.promptAfterName = false
-			} else {
+		m.discardNewInstance()
+		m.state = stateDefault
+		m.promptAfterName = false
+		m.newInstanceTemplate = nil
+		m.newInstanceDevServer = nil
+		return m, tea.Sequence(
+			tea.WindowSize(),
+			func() tea.Msg {
 				m.menu.SetState(ui.StateDefault)
-				m.showHelpScreen(helpStart(instance), nil)
-			}
-
-			return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
-		case tea.KeyRunes:
-			if runewidth.StringWidth(instance.Title) >= 32 {
-				return m, m.handleError(fmt.Errorf("title cannot be longer than 32 characters"))
-			}
-			if err := instance.SetTitle(instance.Title + string(msg.Runes)); err != nil {
-				return m, m.handleError(err)
-			}
-		case tea.KeyBackspace:
-			runes := []rune(instance.Title)
-			if len(runes) == 0 {
-				return m, nil
-			}
-			if err := instance.SetTitle(string(runes[:len(runes)-1])); err != nil {
-				return m, m.handleError(err)
-			}
-		case tea.KeySpace:
-			if err := instance.SetTitle(instance.Title + " "); err != nil {
-				return m, m.handleError(err)
-			}
-		case tea.KeyEsc:
-			m.list.Kill()
-			m.state = stateDefault
-			m.instanceChanged()
-
-			return m, tea.Sequence(
-				tea.WindowSize(),
-				func() tea.Msg {
-					m.menu.SetState(ui.StateDefault)
-					return nil
-				},
-			)
-		default:
-		}
-		return m, nil
+				return nil
+			},
+			m.instanceChanged(),
+		)
 	} else if m.state == statePrompt {
 		// Use the new TextInputOverlay component to handle all key events
 		shouldClose := m.textInputOverlay.HandleKeyPress(msg)
@@ -468,8 +850,17 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			if selected == nil {
 				return m, nil
 			}
+			seededPrompt, seededValue := m.seededPrompt, m.seededValue
+			m.seededPrompt, m.seededValue = "", ""
 			if m.textInputOverlay.IsSubmitted() {
-				if err := selected.SendPrompt(m.textInputOverlay.GetValue()); err != nil {
+				value := m.textInputOverlay.GetValue()
+				var err error
+				if seededPrompt != "" && value == seededValue {
+					err = selected.SendPastedPrompt(seededPrompt)
+				} else {
+					err = selected.SendPrompt(value)
+				}
+				if err != nil {
 					// TODO: we probably end up in a bad state here.
 					return m, m.handleError(err)
 				}
@@ -495,7 +886,126 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			return m, nil
 		}
 		m.textInputOverlay.HandleKeyPress(msg)
-		return m, nil
+		cmd := m.devServerConfigCmd
+		m.devServerConfigCmd = nil
+		// Canceling leaves the dev server and its settings as they were.
+		if m.textInputOverlay != nil && m.textInputOverlay.IsCanceled() {
+			m.textInputOverlay = nil
+			m.state = stateDefault
+			return m, tea.WindowSize()
+		}
+		return m, cmd
+	} else if m.state == stateSelectTemplate {
+		if !m.selectionOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		selection := m.selectionOverlay
+		m.selectionOverlay = nil
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		if !selection.Submitted {
+			return m, tea.WindowSize()
+		}
+		template := m.appConfig.Templates[selection.Selected()]
+		return m, tea.Batch(tea.WindowSize(), m.newInstanceFromTemplate(&template))
+	} else if m.state == stateSelectDevServerField {
+		if !m.selectionOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		selection := m.selectionOverlay
+		m.selectionOverlay = nil
+		m.state = stateDefault
+		selected := m.list.GetSelectedInstance()
+		if !selection.Submitted || selected == nil {
+			return m, tea.WindowSize()
+		}
+		field := devServerField(selection.Selected())
+		if field == devServerFieldAll {
+			return m, tea.Batch(tea.WindowSize(), m.handleDevServerEdit(selected))
+		}
+		return m, m.editDevServerField(selected, field)
+	} else if m.state == stateSelectDiffFile {
+		if !m.selectionOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		selection := m.selectionOverlay
+		m.selectionOverlay = nil
+		m.state = stateDefault
+		files := m.diffFiles
+		m.diffFiles = nil
+		selected := m.list.GetSelectedInstance()
+		if !selection.Submitted || selected == nil {
+			return m, tea.WindowSize()
+		}
+		// The first item shows all files. The files are the ones listed, since the diff stats may
+		// have been recomputed while the list was open.
+		path := ""
+		if index := selection.Selected(); index > 0 && index <= len(files) {
+			path = files[index-1]
+		}
+		m.tabbedWindow.ShowDiffFile(selected, path)
+		return m, tea.WindowSize()
+	} else if m.state == stateQuickSwitch {
+		if !m.quickSwitchOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		quickSwitch := m.quickSwitchOverlay
+		m.quickSwitchOverlay = nil
+		m.state = stateDefault
+		if !quickSwitch.Submitted {
+			return m, nil
+		}
+		m.selectInstance(quickSwitch.Selected())
+		return m, m.instanceChanged()
+	} else if m.state == stateEditSettings {
+		// The submit callback saves the settings and keeps the editor open on errors.
+		if !m.textEditorOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		editor := m.textEditorOverlay
+		m.textEditorOverlay = nil
+		m.state = stateDefault
+		if !editor.Submitted {
+			return m, tea.WindowSize()
+		}
+		return m, tea.Batch(tea.WindowSize(), m.instanceChanged(), m.showInfo("Saved "+config.SettingsFileName))
+	} else if m.state == stateSendFile {
+		if !m.textInputOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		if selected := m.list.GetSelectedInstance(); selected != nil && m.textInputOverlay.IsSubmitted() {
+			cmd = m.sendFileAsPrompt(selected, m.textInputOverlay.GetValue())
+		}
+		m.textInputOverlay = nil
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		return m, tea.Batch(tea.WindowSize(), cmd)
+	} else if m.state == stateRunCommand {
+		if !m.textInputOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		command := strings.TrimSpace(m.textInputOverlay.GetValue())
+		if selected := m.list.GetSelectedInstance(); selected != nil && m.textInputOverlay.IsSubmitted() && command != "" {
+			cmd = m.runWorktreeCommand(selected, command)
+		}
+		m.textInputOverlay = nil
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		return m, tea.Batch(tea.WindowSize(), cmd)
+	} else if m.state == stateBatchPrompt {
+		if !m.textInputOverlay.HandleKeyPress(msg) {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		if m.textInputOverlay.IsSubmitted() {
+			cmd = m.sendBatchPrompt(m.list.MarkedInstances(), m.textInputOverlay.GetValue())
+		}
+		m.textInputOverlay = nil
+		m.state = stateDefault
+		m.menu.SetState(ui.StateDefault)
+		return m, tea.Batch(tea.WindowSize(), cmd)
 	}
 
 	// Handle confirmation state
@@ -504,11 +1014,21 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if shouldClose {
 			m.state = stateDefault
 			m.confirmationOverlay = nil
+			// Feed the confirmed action's result (e.g. an error) back into Update.
+			if result := m.confirmResult; result != nil {
+				m.confirmResult = nil
+				return m, func() tea.Msg { return result }
+			}
 			return m, nil
 		}
 		return m, nil
 	}
 
+	// A pinned error was seen once a key is pressed in the main view. The key is still handled.
+	if m.errBox.Pinned() {
+		m.errBox.Clear()
+	}
+
 	// Exit scrolling mode when ESC is pressed and preview pane is in scrolling mode
 	// Check if Escape key was pressed and we're not in the diff tab (meaning we're in preview tab)
 	// Always check for escape key first to ensure it doesn't get intercepted elsewhere
@@ -527,10 +1047,15 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 			m.tabbedWindow.ResetServerToNormalMode()
 			return m, m.instanceChanged()
 		}
+		// Otherwise, unmark all instances
+		if len(m.list.MarkedInstances()) > 0 {
+			m.list.ClearMarked()
+			return m, nil
+		}
 	}
 
 	// Handle quit commands first
-	if msg.String() == "ctrl+c" || msg.String() == "q" {
+	if msg.String() == "ctrl+c" {
 		return m.handleQuit()
 	}
 
@@ -540,74 +1065,332 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 	}
 
 	switch name {
+	case keys.KeyQuit:
+		if working := m.workingInstances(); m.appConfig.ConfirmQuit && working > 0 {
+			message := fmt.Sprintf("%d agents are still working. Quit anyway? They keep running in the background.", working)
+			return m, m.confirmAction(message, func() tea.Msg { return quitConfirmedMsg{} })
+		}
+		return m.handleQuit()
 	case keys.KeyHelp:
 		return m.showHelpScreen(helpTypeGeneral{}, nil)
 	case keys.KeyPrompt:
-		if m.list.NumInstances() >= GlobalInstanceLimit {
+		if m.instanceLimitReached() {
 			return m, m.handleError(
 				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
 		}
 		instance, err := session.NewInstance(session.InstanceOptions{
-			Title:   "",
-			Path:    ".",
-			Program: m.program,
+			Title:               "",
+			Path:                ".",
+			Program:             m.program,
+			Container:           m.appConfig.Container,
+			TmuxStartupCommands: m.appConfig.TmuxStartupCommands,
 		})
 		if err != nil {
 			return m, m.handleError(err)
 		}
 
-		m.newInstanceFinalizer = m.list.AddInstance(instance)
-		m.list.SetSelectedInstance(m.list.NumInstances() - 1)
-		m.state = stateNew
-		m.menu.SetState(ui.StateNewInstance)
 		m.promptAfterName = true
-
-		return m, nil
-	case keys.KeyNew:
-		if m.list.NumInstances() >= GlobalInstanceLimit {
+		return m, m.openNewInstanceForm(instance)
+	case keys.KeyNew, keys.KeyNewInPlace:
+		if m.instanceLimitReached() {
 			return m, m.handleError(
 				fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
 		}
 		instance, err := session.NewInstance(session.InstanceOptions{
-			Title:   "",
-			Path:    ".",
-			Program: m.program,
+			Title:               "",
+			Path:                ".",
+			Program:             m.program,
+			Container:           m.appConfig.Container,
+			NoWorktree:          name == keys.KeyNewInPlace,
+			TmuxStartupCommands: m.appConfig.TmuxStartupCommands,
 		})
 		if err != nil {
 			return m, m.handleError(err)
 		}
 
-		m.newInstanceFinalizer = m.list.AddInstance(instance)
-		m.list.SetSelectedInstance(m.list.NumInstances() - 1)
-		m.state = stateNew
-		m.menu.SetState(ui.StateNewInstance)
-
-		return m, nil
-	case keys.KeyUp:
-		m.list.Up()
-		return m, m.instanceChanged()
-	case keys.KeyDown:
-		m.list.Down()
+		return m, m.openNewInstanceForm(instance)
+	case keys.KeyPausedOnly:
+		m.list.TogglePausedOnly()
 		return m, m.instanceChanged()
-	case keys.KeyShiftUp:
-		m.tabbedWindow.ScrollUp()
-		return m, m.instanceChanged()
-	case keys.KeyShiftDown:
-		m.tabbedWindow.ScrollDown()
+	case keys.KeyCompactList:
+		return m, m.toggleCompactList()
+	case keys.KeyCheatSheet:
+		m.menu.SetCheatSheet(!m.menu.CheatSheet())
+		return m, tea.WindowSize()
+	case keys.KeyRecord:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() || selected.Paused() {
+			return m, nil
+		}
+		return m.toggleRecording(selected)
+	case keys.KeyUndo:
+		return m.undoKill()
+	case keys.KeyToggleGroup:
+		m.list.ToggleGroupCollapsed()
 		return m, m.instanceChanged()
-	case keys.KeyTab:
-		m.tabbedWindow.Toggle()
-		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
+	case keys.KeyExpandGroups:
+		m.list.ExpandAllGroups()
+		return m, m.instanceChanged()
+	case keys.KeyCopyPaths:
+		return m, m.copyWorktreePaths()
+	case keys.KeyReport:
+		return m, m.showReport()
+	case keys.KeyPrevRun, keys.KeyNextRun:
+		if !m.tabbedWindow.IsInServerTab() {
+			return m, nil
+		}
+		if name == keys.KeyPrevRun {
+			m.tabbedWindow.JumpToPrevServerRun()
+		} else {
+			m.tabbedWindow.JumpToNextServerRun()
+		}
+		return m, nil
+	case keys.KeyToggleFollow:
+		if !m.tabbedWindow.IsInServerTab() {
+			return m, nil
+		}
+		if m.tabbedWindow.ToggleServerFollow() {
+			return m, m.showInfo("Following dev server output")
+		}
+		return m, m.showInfo("Stopped following dev server output")
+	case keys.KeyCopyTab:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		if m.tabbedWindow.IsInDiffTab() {
+			return m, m.copyDiff(selected)
+		}
+		if m.tabbedWindow.IsInServerTab() {
+			return m, m.copyDevServerLog(selected)
+		}
+		return m, nil
+	case keys.KeySaveServerLog:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !m.tabbedWindow.IsInServerTab() {
+			return m, nil
+		}
+		return m, m.saveDevServerLog(selected)
+	case keys.KeyIgnoreSpace:
+		opts := session.GetDiffOptions()
+		opts.IgnoreWhitespace = !opts.IgnoreWhitespace
+		session.SetDiffOptions(opts)
+		// Other instances pick up the change on the next metadata tick.
+		if selected := m.list.GetSelectedInstance(); selected != nil {
+			if err := selected.UpdateDiffStats(); err != nil {
+				return m, m.handleError(err)
+			}
+		}
+		m.tabbedWindow.UpdateDiff(m.list.GetSelectedInstance())
+		if opts.IgnoreWhitespace {
+			return m, m.showInfo("Ignoring whitespace changes in the diff")
+		}
+		return m, m.showInfo("Showing whitespace changes in the diff")
+	case keys.KeyRefreshDiff:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() {
+			return m, nil
+		}
+		if err := selected.RefreshDiffStats(); err != nil {
+			return m, m.handleError(err)
+		}
+		m.tabbedWindow.UpdateDiff(selected)
+		return m, m.showInfo("Refreshed the diff")
+	case keys.KeyTemplate:
+		if len(m.appConfig.Templates) == 0 {
+			return m, m.handleError(fmt.Errorf("no templates configured: add them under \"templates\" in %s", config.ConfigFileName))
+		}
+		items := make([]overlay.SelectionItem, 0, len(m.appConfig.Templates))
+		for _, template := range m.appConfig.Templates {
+			items = append(items, overlay.SelectionItem{Label: template.Name, Description: templateSummary(template)})
+		}
+		m.selectionOverlay = overlay.NewSelectionOverlay("New instance from template", items)
+		m.state = stateSelectTemplate
+		return m, nil
+	case keys.KeyQuickSwitch:
+		m.focusWindow(false)
+		instances := m.list.GetInstances()
+		if len(instances) == 0 {
+			return m, nil
+		}
+		items := make([]overlay.SelectionItem, 0, len(instances))
+		for _, instance := range instances {
+			description := instanceStatusText(instance.Status)
+			if instance.Branch != "" {
+				description += " · " + instance.Branch
+			}
+			items = append(items, overlay.SelectionItem{Label: instance.Title, Description: description})
+		}
+		m.quickSwitchOverlay = overlay.NewQuickSwitchOverlay("Switch to instance", items)
+		m.state = stateQuickSwitch
+		return m, nil
+	case keys.KeySendFile:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() || selected.Paused() {
+			return m, nil
+		}
+		m.state = stateSendFile
+		m.menu.SetState(ui.StatePrompt)
+		m.textInputOverlay = overlay.NewTextInputOverlay("File to send as prompt (relative to the worktree):", "")
+		return m, tea.WindowSize()
+	case keys.KeyRunCommand:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() || selected.Paused() {
+			return m, nil
+		}
+		m.state = stateRunCommand
+		m.menu.SetState(ui.StatePrompt)
+		m.textInputOverlay = overlay.NewTextInputOverlay(fmt.Sprintf("Command to run in the worktree of '%s':", selected.Title), "")
+		return m, tea.WindowSize()
+	case keys.KeyCommandOutput:
+		if m.lastCommandResult == nil {
+			return m, nil
+		}
+		return m, m.showCommandResult(*m.lastCommandResult)
+	case keys.KeyMark:
+		m.list.ToggleMarked()
+		return m, nil
+	case keys.KeyBatchPrompt:
+		marked := m.list.MarkedInstances()
+		if len(marked) == 0 {
+			return m, m.handleError(fmt.Errorf("no instances marked: mark them with space first"))
+		}
+		m.state = stateBatchPrompt
+		m.menu.SetState(ui.StatePrompt)
+		m.textInputOverlay = overlay.NewTextInputOverlay(fmt.Sprintf("Prompt for %d marked instances", len(marked)), "")
+		return m, tea.WindowSize()
+	case keys.KeyDetails:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		m.textOverlay = overlay.NewTextOverlay(instanceDetails(selected))
+		m.state = stateHelp
+		return m, nil
+	case keys.KeyToggleAutoYes:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		selected.AutoYes = !selected.AutoYes
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m, m.handleError(err)
+		}
+		return m, m.instanceChanged()
+	case keys.KeyCycleColor:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		selected.CycleColor()
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m, m.handleError(err)
+		}
+		return m, m.instanceChanged()
+	case keys.KeyPin:
+		if m.list.GetSelectedInstance() == nil {
+			return m, nil
+		}
+		m.list.TogglePinned()
+		if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+			return m, m.handleError(err)
+		}
+		return m, m.instanceChanged()
+	case keys.KeyArchive:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m, m.toggleArchived(selected)
+	case keys.KeyResetHelp:
+		resetAction := func() tea.Msg {
+			if err := m.appState.ResetHelpScreensSeen(); err != nil {
+				return fmt.Errorf("failed to reset help screens: %w", err)
+			}
+			return helpScreensResetMsg{}
+		}
+		return m, m.confirmAction("Show all first-time help screens again?", resetAction)
+	case keys.KeyRepairWorktree:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || !selected.Started() {
+			return m, nil
+		}
+		return m, repairWorktreeCmd(selected)
+	case keys.KeyDiffFiles:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m, m.showDiffFiles(selected)
+	case keys.KeyArchiveView:
+		m.list.SetArchiveView(!m.list.ArchiveView())
+		return m, m.instanceChanged()
+	case keys.KeyUp:
+		m.focusWindow(false)
+		m.list.Up()
+		return m, m.instanceChanged()
+	case keys.KeyDown:
+		m.focusWindow(false)
+		m.list.Down()
+		return m, m.instanceChanged()
+	case keys.KeyJumpToInstance:
+		m.focusWindow(false)
+		// Digits map to the numbers shown in the list, with 0 selecting the 10th visible instance.
+		n := int(msg.String()[0] - '0')
+		if n == 0 {
+			n = 10
+		}
+		m.list.SelectVisible(n)
+		return m, m.instanceChanged()
+	case keys.KeyShiftUp:
+		m.focusWindow(true)
+		m.tabbedWindow.ScrollUp()
+		return m, m.instanceChanged()
+	case keys.KeyShiftDown:
+		m.focusWindow(true)
+		m.tabbedWindow.ScrollDown()
+		return m, m.instanceChanged()
+	case keys.KeyScrollTop:
+		m.focusWindow(true)
+		m.tabbedWindow.ScrollToTop()
+		return m, m.instanceChanged()
+	case keys.KeyScrollBottom:
+		m.focusWindow(true)
+		m.tabbedWindow.ScrollToBottom()
+		return m, m.instanceChanged()
+	case keys.KeyPageUp, keys.KeyHalfPageUp:
+		m.focusWindow(true)
+		m.tabbedWindow.ScrollPageUp(name == keys.KeyHalfPageUp)
+		return m, m.instanceChanged()
+	case keys.KeyPageDown, keys.KeyHalfPageDown:
+		m.focusWindow(true)
+		m.tabbedWindow.ScrollPageDown(name == keys.KeyHalfPageDown)
+		return m, m.instanceChanged()
+	case keys.KeyTab:
+		m.focusWindow(true)
+		m.tabbedWindow.Toggle()
+		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
 		return m, m.instanceChanged()
 	case keys.KeyShiftTab:
+		m.focusWindow(true)
 		m.tabbedWindow.ToggleBackward()
 		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
 		return m, m.instanceChanged()
+	case keys.KeySelectTab:
+		m.focusWindow(true)
+		// alt+1 selects the first tab shown, the preview.
+		m.tabbedWindow.SetTab(int(msg.String()[len("alt+")] - '1'))
+		m.menu.SetInDiffTab(m.tabbedWindow.IsInDiffTab())
+		return m, m.instanceChanged()
 	case keys.KeyKill:
 		selected := m.list.GetSelectedInstance()
 		if selected == nil {
 			return m, nil
 		}
+		if selected.Resuming() {
+			// The resume would recreate the session and worktree after they are killed.
+			return m, m.handleError(fmt.Errorf("cannot kill '%s' while it is resuming", selected.Title))
+		}
 
 		// Create the kill action as a tea.Cmd
 		killAction := func() tea.Msg {
@@ -634,29 +1417,13 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 				return err
 			}
 
-			// Then kill the instance (cleans up tmux and worktree)
-			m.list.Kill()
-
-			// Check if any instances remain for this repo, if not cleanup project folder
-			remainingInstances := m.list.GetInstances()
-			hasInstancesForRepo := false
-			for _, inst := range remainingInstances {
-				wt, err := inst.GetGitWorktree()
-				if err != nil {
-					continue
-				}
-				if wt.GetRepoPath() == repoPath {
-					hasInstancesForRepo = true
-					break
-				}
-			}
-			if !hasInstancesForRepo {
-				if err := session.CleanupProjectFolder(repoPath); err != nil {
-					log.ErrorLog.Printf("failed to cleanup project folder: %v", err)
-				}
+			// Then close the tmux session. The worktree is removed once the undo window passes.
+			if err := selected.KillSession(); err != nil {
+				log.ErrorLog.Printf("could not kill instance: %v", err)
 			}
+			m.list.RemoveSelected()
 
-			return instanceChangedMsg{}
+			return m.startPendingKill(selected, repoPath)
 		}
 
 		// Show confirmation modal
@@ -667,6 +1434,10 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if selected == nil {
 			return m, nil
 		}
+		// Committing everything in the repository's checkout and pushing it is not what submit is for.
+		if selected.NoWorktree() {
+			return m, m.handleError(fmt.Errorf("cannot push '%s': it runs in the repository's checkout", selected.Title))
+		}
 
 		// Create the push action as a tea.Cmd
 		pushAction := func() tea.Msg {
@@ -692,22 +1463,80 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		}
 
 		// Show help screen before pausing
-		m.showHelpScreen(helpTypeInstanceCheckout{}, func() {
+		return m.showHelpScreen(helpTypeInstanceCheckout{}, func() tea.Cmd {
 			if err := selected.Pause(); err != nil {
-				m.handleError(err)
+				return tea.Batch(m.handleError(err), m.instanceChanged())
 			}
-			m.instanceChanged()
+			return m.instanceChanged()
 		})
-		return m, nil
 	case keys.KeyResume:
 		selected := m.list.GetSelectedInstance()
 		if selected == nil {
 			return m, nil
 		}
-		if err := selected.Resume(); err != nil {
+		if err := selected.BeginResume(); err != nil {
 			return m, m.handleError(err)
 		}
-		return m, tea.WindowSize()
+		return m, resumeInstanceCmd(selected)
+	case keys.KeyRetryCreation:
+		if m.failedCreation == nil {
+			return m, nil
+		}
+		return m.retryFailedCreation()
+	case keys.KeyRebase:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || selected.Paused() {
+			return m, nil
+		}
+
+		rebaseAction := func() tea.Msg {
+			if err := selected.RebaseOntoBase(); err != nil {
+				return err
+			}
+			if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+				return err
+			}
+			return instanceChangedMsg{}
+		}
+
+		message := fmt.Sprintf("[!] Commit changes and rebase session '%s' onto base?", selected.Title)
+		return m, m.confirmAction(message, rebaseAction)
+	case keys.KeyResetToBase:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || selected.Paused() {
+			return m, nil
+		}
+
+		resetAction := func() tea.Msg {
+			if err := selected.ResetToBase(); err != nil {
+				return err
+			}
+			return instanceChangedMsg{}
+		}
+
+		message := fmt.Sprintf("[!] DISCARD ALL changes and commits in session '%s'? This cannot be undone.", selected.Title)
+		return m, m.confirmAction(message, resetAction)
+	case keys.KeyRestartSession:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || selected.Paused() {
+			return m, nil
+		}
+
+		restartAction := func() tea.Msg {
+			if err := selected.RestartSession(); err != nil {
+				return err
+			}
+			return instanceChangedMsg{}
+		}
+
+		message := fmt.Sprintf("[!] Restart the agent in session '%s'? Files are kept, the conversation is lost.", selected.Title)
+		return m, m.confirmAction(message, restartAction)
+	case keys.KeyWatchDevServer:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil || selected.Paused() {
+			return m, nil
+		}
+		return m, m.handleDevServerAttach(selected, true)
 	case keys.KeyDevServerStart:
 		if m.list.NumInstances() == 0 {
 			return m, nil
@@ -734,7 +1563,19 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		if selected == nil {
 			return m, nil
 		}
-		return m, m.handleDevServerEdit(selected)
+		return m, m.showDevServerEditMenu(selected)
+	case keys.KeyEditSettings:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m, m.handleEditSettings(selected)
+	case keys.KeyOpenURL:
+		selected := m.list.GetSelectedInstance()
+		if selected == nil {
+			return m, nil
+		}
+		return m, m.handleDevServerOpenURL(selected)
 	case keys.KeyEnter:
 		if m.list.NumInstances() == 0 {
 			return m, nil
@@ -747,95 +1588,1009 @@ func (m *home) handleKeyPress(msg tea.KeyMsg) (mod tea.Model, cmd tea.Cmd) {
 		// Check which tab is active to determine what to attach to
 		if m.tabbedWindow.IsInServerTab() {
 			// Server tab - attach to dev server session
-			return m, m.handleDevServerAttach(selected)
+			return m, m.handleDevServerAttach(selected, false)
 		} else {
 			// Preview/Diff tab - attach to instance session (existing behavior)
 			if !selected.TmuxAlive() {
 				return m, nil
 			}
-			m.showHelpScreen(helpTypeInstanceAttach{}, func() {
-				ch, err := m.list.Attach()
-				if err != nil {
-					m.handleError(err)
-					return
+			if m.useTmuxWindowAttach() {
+				if err := selected.SwitchClient(); err != nil {
+					return m, m.handleError(err)
 				}
-				<-ch
-				m.state = stateDefault
+				return m, nil
+			}
+			return m.showHelpScreen(helpTypeInstanceAttach{}, func() tea.Cmd {
+				return m.attachCmd(func() {
+					ch, err := m.list.Attach()
+					if err != nil {
+						m.handleError(err)
+						return
+					}
+					<-ch
+					m.state = stateDefault
+
+					// The terminal may have been resized while attached, which resized the session to
+					// the full terminal. Shrink it back to the preview.
+					previewWidth, previewHeight := m.tabbedWindow.GetPreviewSize()
+					if err := selected.Resize(previewWidth, previewHeight); err != nil {
+						log.WarningLog.Printf("could not resize session after detach: %v", err)
+					}
+				})
 			})
-			return m, nil
 		}
 	default:
 		return m, nil
 	}
 }
 
-// instanceChanged updates the preview pane, menu, and diff pane based on the selected instance. It returns an error
-// Cmd if there was any error.
-func (m *home) instanceChanged() tea.Cmd {
-	// selected may be nil
-	selected := m.list.GetSelectedInstance()
+// instanceChanged updates the preview pane, menu, and diff pane based on the selected instance. It returns an error
+// Cmd if there was any error.
+func (m *home) instanceChanged() tea.Cmd {
+	// selected may be nil
+	selected := m.list.GetSelectedInstance()
+
+	m.tabbedWindow.UpdateDiff(selected)
+	m.tabbedWindow.SetInstance(selected)
+	// Update menu with current instance
+	m.menu.SetInstance(selected)
+	m.menu.SetAutoYes(m.autoYesActive())
+
+	// If there's no selected instance, we don't need to update the preview.
+	if err := m.tabbedWindow.UpdatePreview(selected); err != nil {
+		return m.handleError(err)
+	}
+
+	// Update the server pane even without a dev server, so it doesn't keep showing the output of the
+	// previously selected instance.
+	if err := m.tabbedWindow.UpdateServer(selected); err != nil {
+		return m.handleError(err)
+	}
+
+	return nil
+}
+
+type keyupMsg struct{}
+
+// keydownCallback clears the menu option highlighting after 500ms.
+func (m *home) keydownCallback(name keys.KeyName) tea.Cmd {
+	m.menu.Keydown(name)
+	return func() tea.Msg {
+		select {
+		case <-m.ctx.Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+
+		return keyupMsg{}
+	}
+}
+
+// hideErrMsg implements tea.Msg and clears the error text from the screen.
+type hideErrMsg struct{}
+
+// hideInfoMsg implements tea.Msg and clears the info text from the screen.
+type hideInfoMsg struct{}
+
+// ringBellMsg rings the terminal bell, see config.Config.BellOnReady.
+type ringBellMsg struct{}
+
+// bellOutput is where the terminal bell is written to.
+var bellOutput io.Writer = os.Stdout
+
+// helpScreensResetMsg is sent when the seen help screens were reset.
+type helpScreensResetMsg struct{}
+
+// quitConfirmedMsg is sent when quitting while agents are working was confirmed.
+type quitConfirmedMsg struct{}
+
+// commandFinishedMsg is sent when a command run in the worktree of an instance exits.
+type commandFinishedMsg struct {
+	title  string
+	result session.CommandResult
+}
+
+// previewTickMsg implements tea.Msg and triggers a preview update
+type previewTickMsg struct{}
+
+// previewTickCmd sends the next previewTickMsg after the configured preview interval.
+func (m *home) previewTickCmd() tea.Cmd {
+	interval := m.appConfig.GetPreviewInterval()
+	return func() tea.Msg {
+		time.Sleep(interval)
+		return previewTickMsg{}
+	}
+}
+
+type tickUpdateMetadataMessage struct{}
+
+// instanceResumedMsg is sent when a background resume of instance finished.
+type instanceResumedMsg struct {
+	instance *session.Instance
+	err      error
+}
+
+// worktreeRepairedMsg is sent when a background repair of instance's worktree finished.
+type worktreeRepairedMsg struct {
+	instance *session.Instance
+	repaired []string
+	err      error
+}
+
+// repairWorktreeCmd repairs the worktree of instance in the background, since it may be recreated.
+func repairWorktreeCmd(instance *session.Instance) tea.Cmd {
+	return func() tea.Msg {
+		repaired, err := instance.RepairWorktree()
+		return worktreeRepairedMsg{instance: instance, repaired: repaired, err: err}
+	}
+}
+
+// resumeInstanceCmd resumes instance in the background, since recreating its worktree and session
+// can take a while. BeginResume must have been called; the instanceResumedMsg handler finishes the
+// resume, so the instance's state only changes in Update.
+func resumeInstanceCmd(instance *session.Instance) tea.Cmd {
+	return func() tea.Msg {
+		return instanceResumedMsg{instance: instance, err: instance.ResumeSession()}
+	}
+}
+
+type instanceChangedMsg struct{}
+
+// tickUpdateMetadataCmd is the callback to update the metadata of the instances every 500ms. Note that we iterate
+// overall the instances and capture their output. It's a pretty expensive operation. Let's do it 2x a second only.
+var tickUpdateMetadataCmd = func() tea.Msg {
+	time.Sleep(500 * time.Millisecond)
+	return tickUpdateMetadataMessage{}
+}
+
+// baseDivergence is the divergence of an instance from its base branch's upstream.
+type baseDivergence struct {
+	instance      *session.Instance
+	ahead, behind int
+}
+
+// autoSaveIfDue saves the instances at most once per config.Config.AutoSaveIntervalSeconds, so
+// metadata that changes while running, e.g. diff stats, survives a crash. Unchanged instances
+// aren't written again.
+func (m *home) autoSaveIfDue() {
+	interval := m.appConfig.GetAutoSaveInterval()
+	if interval <= 0 || time.Since(m.lastAutoSave) < interval {
+		return
+	}
+	m.lastAutoSave = time.Now()
+	if _, err := m.storage.SaveInstancesIfChanged(m.list.GetInstances()); err != nil {
+		log.WarningLog.Printf("failed to auto-save instances: %v", err)
+	}
+}
+
+// baseFetchedMsg is sent when a background fetch of the base branches finished.
+type baseFetchedMsg struct {
+	divergences []baseDivergence
+}
+
+// fetchBaseIfDue fetches the base branches of the running instances in the background, at most once
+// per config.Config.FetchIntervalMinutes, and computes their divergence. Returns nil if it isn't due.
+func (m *home) fetchBaseIfDue() tea.Cmd {
+	interval := time.Duration(m.appConfig.FetchIntervalMinutes) * time.Minute
+	if interval <= 0 || m.fetchingBase || time.Since(m.lastBaseFetch) < interval {
+		return nil
+	}
+	var worktrees []*git.GitWorktree
+	var instances []*session.Instance
+	for _, instance := range m.list.GetInstances() {
+		if !instance.Started() || instance.Paused() {
+			continue
+		}
+		worktree, err := instance.GetGitWorktree()
+		if err != nil || worktree == nil {
+			continue
+		}
+		worktrees = append(worktrees, worktree)
+		instances = append(instances, instance)
+	}
+	m.fetchingBase = true
+	m.lastBaseFetch = time.Now()
+
+	return func() tea.Msg {
+		var msg baseFetchedMsg
+		// Instances of the same repo usually share the upstream, so fetch each one only once.
+		fetched := make(map[string]bool)
+		for idx, worktree := range worktrees {
+			upstream, err := worktree.BaseUpstream()
+			if err != nil {
+				log.WarningLog.Printf("not fetching base of %s: %v", instances[idx].Title, err)
+				continue
+			}
+			key := worktree.GetRepoPath() + "\x00" + upstream
+			if !fetched[key] {
+				fetched[key] = true
+				if err := worktree.FetchBase(); err != nil {
+					log.WarningLog.Printf("could not fetch base of %s: %v", instances[idx].Title, err)
+				}
+			}
+			ahead, behind, err := worktree.DivergenceFromBase()
+			if err != nil {
+				log.WarningLog.Printf("could not compute divergence of %s: %v", instances[idx].Title, err)
+				continue
+			}
+			msg.divergences = append(msg.divergences, baseDivergence{instance: instances[idx], ahead: ahead, behind: behind})
+		}
+		return msg
+	}
+}
+
+// killUndoWindow is how long a killed instance's worktree is kept so the kill can be undone.
+const killUndoWindow = 5 * time.Second
+
+// pendingKill is a killed instance whose worktree removal is deferred until the undo window passes.
+type pendingKill struct {
+	id       int
+	instance *session.Instance
+	repoPath string
+}
+
+// killPendingMsg is sent when an instance was killed and its worktree cleanup is pending.
+type killPendingMsg struct {
+	id int
+}
+
+// killUndoExpiredMsg is sent when the undo window of a pending kill has passed.
+type killUndoExpiredMsg struct {
+	id int
+}
+
+// startPendingKill records a killed instance so the kill can be undone. Only the most recent kill can
+// be undone, so an earlier pending kill is finalized right away.
+func (m *home) startPendingKill(instance *session.Instance, repoPath string) tea.Msg {
+	if m.pendingKill != nil {
+		m.finalizePendingKill()
+	}
+	m.killSeq++
+	m.pendingKill = &pendingKill{id: m.killSeq, instance: instance, repoPath: repoPath}
+	m.errBox.SetInfo(fmt.Sprintf("Killed '%s'. Undo (u)", instance.Title))
+	return killPendingMsg{id: m.killSeq}
+}
+
+// finalizePendingKill removes the worktree of the pending kill and, if no instances remain for its
+// repo, the project folder.
+func (m *home) finalizePendingKill() {
+	pending := m.pendingKill
+	m.pendingKill = nil
+	m.errBox.ClearInfo()
+
+	if err := pending.instance.CleanupWorktree(); err != nil {
+		log.ErrorLog.Printf("could not cleanup worktree of killed instance: %v", err)
+	}
+
+	// Check if any instances remain for this repo, if not cleanup project folder
+	for _, inst := range m.list.GetInstances() {
+		wt, err := inst.GetGitWorktree()
+		if err != nil {
+			continue
+		}
+		if wt.GetRepoPath() == pending.repoPath {
+			return
+		}
+	}
+	if err := session.CleanupProjectFolder(pending.repoPath); err != nil {
+		log.ErrorLog.Printf("failed to cleanup project folder: %v", err)
+	}
+}
+
+// undoKill restores the pending killed instance as paused. Its tmux session is gone, so resuming it
+// starts a new session in the preserved worktree.
+func (m *home) undoKill() (tea.Model, tea.Cmd) {
+	if m.pendingKill == nil {
+		return m, nil
+	}
+	instance := m.pendingKill.instance
+	m.pendingKill = nil
+	m.errBox.ClearInfo()
+
+	instance.SetStatus(session.Paused)
+	m.clearListFilters()
+	m.list.AddInstance(instance)()
+	m.list.SelectInstance(instance)
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m, m.handleError(err)
+	}
+	return m, m.instanceChanged()
+}
+
+// startNewInstance starts the named instance being created and goes back to the main menu, or to
+// the prompt if one was requested. If the start fails, the creation can be retried.
+func (m *home) startNewInstance(instance *session.Instance) (tea.Model, tea.Cmd) {
+	instance.SetWorktreeCheck(m.worktreeInUse)
+	if err := instance.Start(true); err != nil {
+		options := instance.Options()
+		promptAfterName := m.promptAfterName
+		template, devServer := m.newInstanceTemplate, m.newInstanceDevServer
+		m.discardNewInstance()
+		m.state = stateDefault
+		m.promptAfterName = false
+		m.newInstanceTemplate = nil
+		m.newInstanceDevServer = nil
+		return m, m.recordFailedCreation(options, promptAfterName, template, devServer, err)
+	}
+	// Save after adding new instance
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m, m.handleError(err)
+	}
+	// Instance added successfully, call the finalizer.
+	m.newInstanceFinalizer()
+	m.newInstance = nil
+	if m.autoYes {
+		instance.AutoYes = true
+	}
+	m.applyNewInstanceTemplate(instance)
+	m.applyNewInstanceDevServer(instance)
+
+	m.state = stateDefault
+	if m.promptAfterName || m.initialPrompt != "" {
+		m.state = statePrompt
+		m.menu.SetState(ui.StatePrompt)
+		m.showPromptOverlay(m.initialPrompt)
+		m.promptAfterName = false
+		m.initialPrompt = ""
+	} else {
+		m.menu.SetState(ui.StateDefault)
+		m.showHelpScreen(helpStart(instance), nil)
+	}
+
+	return m, tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
+
+// showPromptOverlay opens the overlay for the prompt of a new instance, seeded with prompt, e.g. the
+// contents of the prompt file.
+func (m *home) showPromptOverlay(prompt string) {
+	m.textInputOverlay = overlay.NewTextInputOverlay("Enter prompt", prompt)
+	m.seededPrompt = prompt
+	m.seededValue = m.textInputOverlay.GetValue()
+}
+
+// toggleCompactList switches the list between compact and expanded rows and saves the choice in
+// the config file, leaving its other options as they are.
+func (m *home) toggleCompactList() tea.Cmd {
+	m.list.SetCompact(!m.list.Compact())
+	m.appConfig.CompactList = m.list.Compact()
+	if err := config.SaveConfigField("compact_list", m.appConfig.CompactList); err != nil {
+		return m.handleError(fmt.Errorf("failed to save the list layout: %w", err))
+	}
+	return m.instanceChanged()
+}
+
+// discardNewInstance kills the placeholder of the instance being named and removes it from the
+// list, whichever instance is selected.
+func (m *home) discardNewInstance() {
+	if m.newInstance == nil {
+		return
+	}
+	if err := m.newInstance.Kill(); err != nil {
+		log.ErrorLog.Printf("could not kill instance: %v", err)
+	}
+	m.list.RemoveInstance(m.newInstance)
+	m.newInstance = nil
+}
+
+// numInstancesTowardLimit returns how many instances count against GlobalInstanceLimit: the started
+// active instances, those being started in the background and at most one that is being created. Placeholders that weren't cleaned up
+// don't take up the room of real instances.
+func (m *home) numInstancesTowardLimit() int {
+	n, placeholder := 0, false
+	for _, instance := range m.list.GetInstances() {
+		switch {
+		case instance.Archived():
+		case instance.Started():
+			n++
+		case !placeholder:
+			placeholder = true
+			n++
+		}
+	}
+	return n + m.startingInstances
+}
+
+// instanceLimitReached returns true if no more instances can be created.
+func (m *home) instanceLimitReached() bool {
+	return m.numInstancesTowardLimit() >= GlobalInstanceLimit
+}
+
+// worktreeInUse returns an error if an instance in the list already uses the worktree at path.
+func (m *home) worktreeInUse(path string) error {
+	return session.WorktreeInUse(path, m.list.GetInstances())
+}
+
+// creationRetryWindow is how long a failed instance creation can be retried.
+const creationRetryWindow = 10 * time.Second
+
+// failedCreation is an instance that failed to start, kept so its creation can be retried without
+// entering the title and options again.
+type failedCreation struct {
+	id              int
+	options         session.InstanceOptions
+	promptAfterName bool
+	template        *config.InstanceTemplate
+	devServer       *session.DevServerConfig
+}
+
+// creationRetryExpiredMsg is sent when the retry window of a failed creation has passed.
+type creationRetryExpiredMsg struct {
+	id int
+}
+
+// recordFailedCreation shows the start error with a retry hint and keeps the options of the failed
+// instance until the retry window passes. Only the most recent failure can be retried.
+func (m *home) recordFailedCreation(options session.InstanceOptions, promptAfterName bool,
+	template *config.InstanceTemplate, devServer *session.DevServerConfig, err error) tea.Cmd {
+	log.ErrorLog.Printf("failed to start instance '%s': %v", options.Title, err)
+	m.creationSeq++
+	id := m.creationSeq
+	m.failedCreation = &failedCreation{id: id, options: options, promptAfterName: promptAfterName,
+		template: template, devServer: devServer}
+	retry := keys.GlobalkeyBindings[keys.KeyRetryCreation].Help().Key
+	m.errBox.SetError(fmt.Errorf("%w. Press %s to retry creation", err, retry))
+	return func() tea.Msg {
+		select {
+		case <-m.ctx.Done():
+		case <-time.After(creationRetryWindow):
+		}
+		return creationRetryExpiredMsg{id: id}
+	}
+}
+
+// retryFailedCreation creates the failed instance again with the same options and starts it.
+func (m *home) retryFailedCreation() (tea.Model, tea.Cmd) {
+	failed := m.failedCreation
+	m.failedCreation = nil
+	m.errBox.Clear()
+
+	if m.instanceLimitReached() {
+		return m, m.handleError(
+			fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	instance, err := session.NewInstance(failed.options)
+	if err != nil {
+		return m, m.handleError(err)
+	}
+	m.clearListFilters()
+	m.newInstanceFinalizer = m.list.AddInstance(instance)
+	m.newInstance = instance
+	m.list.SelectInstance(instance)
+	m.promptAfterName = failed.promptAfterName
+	m.newInstanceTemplate = failed.template
+	m.newInstanceDevServer = failed.devServer
+	m.state = stateNew
+	return m.startNewInstance(instance)
+}
+
+// copyWorktreePaths copies the worktree paths of all instances to the clipboard, one per line.
+// Instances without an initialized worktree are skipped.
+func (m *home) copyWorktreePaths() tea.Cmd {
+	var paths []string
+	for _, instance := range m.list.GetInstances() {
+		worktree, err := instance.GetGitWorktree()
+		if err != nil || worktree == nil || worktree.GetWorktreePath() == "" {
+			continue
+		}
+		paths = append(paths, worktree.GetWorktreePath())
+	}
+	if len(paths) == 0 {
+		return m.handleError(fmt.Errorf("no worktree paths to copy"))
+	}
+	if err := clipboard.WriteAll(strings.Join(paths, "\n")); err != nil {
+		return m.handleError(fmt.Errorf("failed to copy worktree paths: %w", err))
+	}
+	return m.showInfo(fmt.Sprintf("Copied %d worktree path(s) to the clipboard", len(paths)))
+}
+
+// newInstanceFromTemplate adds an instance configured from template and lets the user name it. The
+// rest of the template is applied once the instance is started.
+func (m *home) newInstanceFromTemplate(template *config.InstanceTemplate) tea.Cmd {
+	if m.instanceLimitReached() {
+		return m.handleError(fmt.Errorf("you can't create more than %d instances", GlobalInstanceLimit))
+	}
+	program := m.program
+	if template.Program != "" {
+		program = template.Program
+	}
+	container := m.appConfig.Container
+	if template.Container != nil {
+		container = template.Container
+	}
+	tmuxStartupCommands := m.appConfig.TmuxStartupCommands
+	if template.TmuxStartupCommands != nil {
+		tmuxStartupCommands = template.TmuxStartupCommands
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title:               "",
+		Path:                ".",
+		Program:             program,
+		ExtraArgs:           template.ExtraArgs,
+		BaseBranch:          template.BaseBranch,
+		Labels:              template.Labels,
+		Container:           container,
+		TmuxStartupCommands: tmuxStartupCommands,
+	})
+	if err != nil {
+		return m.handleError(err)
+	}
+
+	m.newInstanceTemplate = template
+	return m.openNewInstanceForm(instance)
+}
+
+// openNewInstanceForm adds the instance being created to the list and opens the form to name it.
+// Submitting the form applies its values to the instance and starts it.
+func (m *home) openNewInstanceForm(instance *session.Instance) tea.Cmd {
+	m.clearListFilters()
+	m.newInstanceFinalizer = m.list.AddInstance(instance)
+	m.newInstance = instance
+	m.list.SelectInstance(instance)
+	m.state = stateNew
+	m.menu.SetState(ui.StateNewInstance)
+
+	m.newInstanceOverlay = overlay.NewNewInstanceOverlay(overlay.NewInstanceValues{
+		Title:      instance.Title,
+		Program:    instance.Program,
+		BaseBranch: instance.BaseBranch(),
+		Branch:     instance.BranchName(),
+	})
+	var devServerSources []string
+	for _, other := range m.list.GetInstances() {
+		if other != instance && other.DevServer != nil && other.DevServer.Config().DevCommand != "" {
+			devServerSources = append(devServerSources, other.Title)
+		}
+	}
+	m.newInstanceOverlay.SetDevServerSources(devServerSources)
+	m.newInstanceOverlay.OnSubmit = func(values overlay.NewInstanceValues) error {
+		if strings.TrimSpace(values.Title) == "" {
+			return fmt.Errorf("title cannot be empty")
+		}
+		for _, other := range m.list.GetInstances() {
+			if other != instance && other.Title == values.Title {
+				return fmt.Errorf("a session named '%s' already exists", values.Title)
+			}
+		}
+		if values.Program == "" {
+			return fmt.Errorf("program cannot be empty")
+		}
+		if instance.NoWorktree() && (values.BaseBranch != "" || values.Branch != "") {
+			return fmt.Errorf("sessions without a worktree run on the current branch; leave the branches empty")
+		}
+		devServer, err := m.devServerConfigOf(values.DevServerFrom)
+		if err != nil {
+			return err
+		}
+		if err := instance.SetBranchName(values.Branch); err != nil {
+			return err
+		}
+		if err := instance.SetTitle(values.Title); err != nil {
+			return err
+		}
+		if err := instance.SetProgramAndBase(values.Program, values.BaseBranch); err != nil {
+			return err
+		}
+		m.newInstanceDevServer = devServer
+		return nil
+	}
+	return tea.WindowSize()
+}
+
+// applyNewInstanceTemplate sets up the dev server and seeds the prompt of a started instance from
+// the template it was created from.
+func (m *home) applyNewInstanceTemplate(instance *session.Instance) {
+	template := m.newInstanceTemplate
+	m.newInstanceTemplate = nil
+	if template == nil {
+		return
+	}
+	if template.DevServer != nil {
+		worktreePath := instance.Path
+		if worktree, err := instance.GetGitWorktree(); err == nil && worktree != nil {
+			worktreePath = worktree.GetWorktreePath()
+		}
+		instance.DevServer = session.NewDevServer(
+			session.DevServerConfig{
+				BuildCommand:   template.DevServer.BuildCommand,
+				DevCommand:     template.DevServer.DevCommand,
+				Env:            template.DevServer.Env,
+				WorkingDir:     template.DevServer.WorkingDir,
+				MaxOutputLines: template.DevServer.MaxOutputLines,
+			},
+			worktreePath,
+			instance.Title,
+		)
+	}
+	if template.Prompt != "" {
+		m.initialPrompt = template.Prompt
+	}
+}
+
+// devServerConfigOf returns a copy of the dev server config of the instance titled title, or nil if
+// title is empty.
+func (m *home) devServerConfigOf(title string) (*session.DevServerConfig, error) {
+	if title == "" {
+		return nil, nil
+	}
+	for _, other := range m.list.GetInstances() {
+		if other.Title != title {
+			continue
+		}
+		if other.DevServer == nil || other.DevServer.Config().DevCommand == "" {
+			return nil, fmt.Errorf("'%s' has no dev server config to copy", title)
+		}
+		copied := other.DevServer.Config()
+		// The copy can be edited without changing the other instance's env.
+		copied.Env = maps.Clone(copied.Env)
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("no session named '%s' to copy the dev server config from", title)
+}
+
+// applyNewInstanceDevServer sets up the dev server of a started instance with the config copied
+// from another instance, if one was chosen when it was named.
+func (m *home) applyNewInstanceDevServer(instance *session.Instance) {
+	devServer := m.newInstanceDevServer
+	m.newInstanceDevServer = nil
+	if devServer == nil {
+		return
+	}
+	worktreePath := instance.Path
+	if worktree, err := instance.GetGitWorktree(); err == nil && worktree != nil {
+		worktreePath = worktree.GetWorktreePath()
+	}
+	instance.DevServer = session.NewDevServer(*devServer, worktreePath, instance.Title)
+}
+
+// templateSummary describes what a template configures for the template picker.
+func templateSummary(template config.InstanceTemplate) string {
+	var parts []string
+	if template.Program != "" {
+		parts = append(parts, template.Program)
+	}
+	if template.ExtraArgs != "" {
+		parts = append(parts, template.ExtraArgs)
+	}
+	if template.BaseBranch != "" {
+		parts = append(parts, "from "+template.BaseBranch)
+	}
+	if len(template.Labels) > 0 {
+		parts = append(parts, "["+strings.Join(template.Labels, ", ")+"]")
+	}
+	if template.DevServer != nil {
+		parts = append(parts, "dev server")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// sendFileAsPrompt reads the file at path and sends its contents to the instance as a prompt.
+// Relative paths are resolved against the instance's worktree.
+func (m *home) sendFileAsPrompt(instance *session.Instance, path string) tea.Cmd {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	if !filepath.IsAbs(path) {
+		dir := instance.Path
+		if worktree, err := instance.GetGitWorktree(); err == nil && worktree != nil && worktree.GetWorktreePath() != "" {
+			dir = worktree.GetWorktreePath()
+		}
+		path = filepath.Join(dir, path)
+	}
+
+	maxBytes := m.appConfig.GetPromptFileMaxBytes()
+	content, truncated, err := session.ReadPromptFile(path, maxBytes)
+	if err != nil {
+		return m.handleError(err)
+	}
+	if err := instance.SendPastedPrompt(content); err != nil {
+		return m.handleError(fmt.Errorf("failed to send %s: %w", filepath.Base(path), err))
+	}
+	if truncated {
+		return m.handleError(fmt.Errorf("%s is larger than %d bytes, only the beginning was sent", filepath.Base(path), maxBytes))
+	}
+	return m.showInfo(fmt.Sprintf("Sent %s to '%s'", filepath.Base(path), instance.Title))
+}
+
+// sendBatchPrompt sends the prompt to each of the instances. Instances that aren't running are skipped.
+// A failure doesn't stop the prompt from reaching the remaining instances; all errors are reported together.
+func (m *home) sendBatchPrompt(instances []*session.Instance, prompt string) tea.Cmd {
+	if strings.TrimSpace(prompt) == "" {
+		return nil
+	}
+	var errs []error
+	sent := 0
+	for _, instance := range instances {
+		if !instance.Started() || instance.Paused() {
+			errs = append(errs, fmt.Errorf("skipped '%s': not running", instance.Title))
+			continue
+		}
+		if err := instance.SendPrompt(prompt); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send prompt to '%s': %w", instance.Title, err))
+			continue
+		}
+		sent++
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.WarningLog.Printf("batch prompt sent to %d of %d instances: %v", sent, len(instances), err)
+		return m.handleError(err)
+	}
+	return m.showInfo(fmt.Sprintf("Sent prompt to %d instances", sent))
+}
+
+// onReadyCommandTimeout bounds how long config.Config.OnReadyCommand may run.
+const onReadyCommandTimeout = 30 * time.Second
+
+// agentFinished notifies the user that instance's agent went from running to ready, by ringing the
+// bell and running config.Config.OnReadyCommand when configured.
+func (m *home) agentFinished(instance *session.Instance) tea.Cmd {
+	var cmds []tea.Cmd
+	if m.appConfig.BellOnReady {
+		cmds = append(cmds, func() tea.Msg { return ringBellMsg{} })
+	}
+	if command := m.appConfig.OnReadyCommand; command != "" {
+		title := instance.Title
+		cmds = append(cmds, func() tea.Msg {
+			ctx, cancel := context.WithTimeout(m.ctx, onReadyCommandTimeout)
+			defer cancel()
+			if output, err := onReadyCmd(ctx, command, title).CombinedOutput(); err != nil {
+				log.WarningLog.Printf("on_ready_command for %s failed: %v: %s", title, err, output)
+			}
+			return nil
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// onReadyCmd builds the shell command for config.Config.OnReadyCommand. The command is run as it
+// is configured; the title is passed as $1 and in CS_TITLE rather than spliced into the command, so
+// it can't be interpreted by the shell.
+func onReadyCmd(ctx context.Context, command, title string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command, "sh", title)
+	cmd.Env = append(os.Environ(), "CS_TITLE="+title)
+	return cmd
+}
+
+// showInfo shows a non-error message in the error box and clears it after 3 seconds.
+func (m *home) showInfo(info string) tea.Cmd {
+	m.errBox.SetInfo(info)
+	return func() tea.Msg {
+		select {
+		case <-m.ctx.Done():
+		case <-time.After(3 * time.Second):
+		}
+		return hideInfoMsg{}
+	}
+}
+
+// toggleRecording starts recording the instance to a new cast file in the recordings directory, or
+// stops the active recording. The cast file path is shown so the user can replay it.
+func (m *home) toggleRecording(instance *session.Instance) (tea.Model, tea.Cmd) {
+	var message string
+	if instance.IsRecording() {
+		path := instance.RecordingPath()
+		if err := instance.StopRecording(); err != nil {
+			return m, m.handleError(err)
+		}
+		message = fmt.Sprintf("Recording saved to:\n\n%s\n\nReplay it with `asciinema play %s`", path, path)
+	} else {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return m, m.handleError(err)
+		}
+		path := filepath.Join(configDir, "recordings", fmt.Sprintf("%s-%s.cast", fileSafeName(instance.Title), time.Now().Format("20060102-150405")))
+		if err := instance.StartRecording(path); err != nil {
+			return m, m.handleError(err)
+		}
+		message = fmt.Sprintf("Recording session '%s' to:\n\n%s\n\nPress ctrl+r again to stop.", instance.Title, path)
+	}
+
+	m.textOverlay = overlay.NewTextOverlay(message)
+	m.state = stateHelp
+	return m, nil
+}
+
+// fileSafeName replaces the characters of title that aren't safe in a file name, e.g. spaces and
+// path separators, with underscores.
+func fileSafeName(title string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, title)
+}
 
-	m.tabbedWindow.UpdateDiff(selected)
-	m.tabbedWindow.SetInstance(selected)
-	// Update menu with current instance
-	m.menu.SetInstance(selected)
+// maxClipboardDiffBytes caps the size of a diff copied to the clipboard. Larger diffs are rejected
+// rather than handed to the clipboard tool, which may hang or truncate them.
+const maxClipboardDiffBytes = 8 << 20
 
-	// If there's no selected instance, we don't need to update the preview.
-	if err := m.tabbedWindow.UpdatePreview(selected); err != nil {
-		return m.handleError(err)
+// copyDiff copies the diff of instance, as shown in the diff tab, to the clipboard.
+func (m *home) copyDiff(instance *session.Instance) tea.Cmd {
+	stats := instance.GetDiffStats()
+	if stats == nil {
+		return m.showInfo("The diff isn't ready yet")
 	}
-
-	// Update server pane if dev server is running
-	if selected != nil && selected.DevServer != nil {
-		if err := m.tabbedWindow.UpdateServer(selected); err != nil {
-			return m.handleError(err)
+	if stats.Error != nil {
+		return m.handleError(fmt.Errorf("failed to copy diff: %w", stats.Error))
+	}
+	content := stats.Content
+	if file := m.tabbedWindow.DiffFile(); file != "" {
+		var err error
+		if content, err = instance.FileDiff(file); err != nil {
+			return m.handleError(fmt.Errorf("failed to copy diff: %w", err))
 		}
 	}
+	if content == "" {
+		return m.showInfo("No changes to copy")
+	}
+	if len(content) > maxClipboardDiffBytes {
+		return m.handleError(fmt.Errorf("diff is too large to copy (%d MB, limit %d MB)",
+			len(content)>>20, maxClipboardDiffBytes>>20))
+	}
+	if err := clipboard.WriteAll(content); err != nil {
+		return m.handleError(fmt.Errorf("failed to copy diff: %w", err))
+	}
+	lines := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	return m.showInfo(fmt.Sprintf("Copied %d diff lines to the clipboard", lines))
+}
+
+// showDiffFiles opens the list of the files changed by instance, to narrow the diff tab to one of
+// them or show all files again.
+func (m *home) showDiffFiles(instance *session.Instance) tea.Cmd {
+	stats := instance.GetDiffStats()
+	if stats == nil {
+		return m.showInfo("The diff isn't ready yet")
+	}
+	if len(stats.Files) == 0 {
+		return m.showInfo("No changed files")
+	}
+	items := make([]overlay.SelectionItem, 0, len(stats.Files)+1)
+	items = append(items, overlay.SelectionItem{
+		Label:       "All files",
+		Description: fmt.Sprintf("+%d -%d", stats.Added, stats.Removed),
+	})
+	for _, file := range stats.Files {
+		added, removed := stats.FileStats(file)
+		items = append(items, overlay.SelectionItem{Label: file, Description: fmt.Sprintf("+%d -%d", added, removed)})
+	}
+	m.selectionOverlay = overlay.NewSelectionOverlay("Changed files", items)
+	m.selectionOverlay.SetWidth(70)
+	m.diffFiles = slices.Clone(stats.Files)
+	m.state = stateSelectDiffFile
+	return tea.WindowSize()
+}
 
+// showReport shows the report of all instances in the text overlay, see session.GenerateReport.
+func (m *home) showReport() tea.Cmd {
+	report, err := session.GenerateReport(m.list.GetInstances())
+	if report == "" {
+		return m.handleError(err)
+	}
+	if err != nil {
+		log.WarningLog.Printf("incomplete report: %v", err)
+	}
+	m.shownReport = report
+	m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Report"),
+		"",
+		report,
+		"",
+		descStyle.Render("Press ")+keyStyle.Render("c")+descStyle.Render(" to copy the report, any other key to close"),
+	))
+	m.state = stateHelp
 	return nil
 }
 
-type keyupMsg struct{}
+// worktreeCommandTimeout is how long a command run in a worktree may take before it is killed.
+const worktreeCommandTimeout = 5 * time.Minute
 
-// keydownCallback clears the menu option highlighting after 500ms.
-func (m *home) keydownCallback(name keys.KeyName) tea.Cmd {
-	m.menu.Keydown(name)
-	return func() tea.Msg {
-		select {
-		case <-m.ctx.Done():
-		case <-time.After(500 * time.Millisecond):
-		}
+// worktreeCommandMaxLines is the number of output lines shown for a command run in a worktree.
+const worktreeCommandMaxLines = 40
 
-		return keyupMsg{}
-	}
+// runWorktreeCommand runs command in the worktree of instance in the background. The output is
+// shown once it exits.
+func (m *home) runWorktreeCommand(instance *session.Instance, command string) tea.Cmd {
+	title := instance.Title
+	return tea.Batch(
+		m.showInfo(fmt.Sprintf("Running `%s` in '%s'...", command, title)),
+		func() tea.Msg {
+			result := instance.RunCommand(m.ctx, command, worktreeCommandTimeout, worktreeCommandMaxLines)
+			return commandFinishedMsg{title: title, result: result}
+		},
+	)
 }
 
-// hideErrMsg implements tea.Msg and clears the error text from the screen.
-type hideErrMsg struct{}
-
-// previewTickMsg implements tea.Msg and triggers a preview update
-type previewTickMsg struct{}
+// showCommandResult shows the output of a command run in a worktree.
+func (m *home) showCommandResult(msg commandFinishedMsg) tea.Cmd {
+	result := msg.result
+	status := fmt.Sprintf("Finished in %s", result.Duration.Round(100*time.Millisecond))
+	if result.Err != nil {
+		status = fmt.Sprintf("Failed after %s: %v", result.Duration.Round(100*time.Millisecond), result.Err)
+	}
+	output := result.Output
+	if result.Truncated {
+		output = fmt.Sprintf("... (showing the last %d lines)\n%s", worktreeCommandMaxLines, output)
+	}
+	m.errBox.ClearInfo()
+	m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render(fmt.Sprintf("$ %s (%s)", result.Command, msg.title)),
+		"",
+		output,
+		"",
+		descStyle.Render(status),
+		"",
+		descStyle.Render("Press any key to close"),
+	))
+	m.state = stateHelp
+	return tea.WindowSize()
+}
 
-type tickUpdateMetadataMessage struct{}
+// copyReport copies report to the clipboard.
+func (m *home) copyReport(report string) tea.Cmd {
+	if err := clipboard.WriteAll(report); err != nil {
+		return m.handleError(fmt.Errorf("failed to copy report: %w", err))
+	}
+	return m.showInfo("Copied the report to the clipboard")
+}
 
-type instanceChangedMsg struct{}
+// copyDevServerLog copies the dev server output of instance to the clipboard, headed by the
+// command and crash count.
+func (m *home) copyDevServerLog(instance *session.Instance) tea.Cmd {
+	text, err := instance.DevServerLog()
+	if err != nil {
+		return m.handleError(err)
+	}
+	if err := clipboard.WriteAll(text); err != nil {
+		return m.handleError(fmt.Errorf("failed to copy dev server log: %w", err))
+	}
+	return m.showInfo(fmt.Sprintf("Copied the dev server log of '%s' to the clipboard", instance.Title))
+}
 
-// tickUpdateMetadataCmd is the callback to update the metadata of the instances every 500ms. Note that we iterate
-// overall the instances and capture their output. It's a pretty expensive operation. Let's do it 2x a second only.
-var tickUpdateMetadataCmd = func() tea.Msg {
-	time.Sleep(500 * time.Millisecond)
-	return tickUpdateMetadataMessage{}
+// saveDevServerLog writes the dev server output of instance to a new file in the server-logs
+// directory and shows its path.
+func (m *home) saveDevServerLog(instance *session.Instance) tea.Cmd {
+	text, err := instance.DevServerLog()
+	if err != nil {
+		return m.handleError(err)
+	}
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return m.handleError(err)
+	}
+	dir := filepath.Join(configDir, "server-logs")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return m.handleError(fmt.Errorf("failed to create server log directory: %w", err))
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.log", fileSafeName(instance.Title), time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		return m.handleError(fmt.Errorf("failed to write dev server log: %w", err))
+	}
+	return m.showInfo(fmt.Sprintf("Saved the dev server log to %s", path))
 }
 
 // handleError handles all errors which get bubbled up to the app. sets the error message. We return a callback tea.Cmd that returns a hideErrMsg message
-// which clears the error message after 3 seconds.
+// which clears the error message after config.Config.ErrorDisplaySeconds. Long errors may be pinned instead, see
+// config.Config.PinErrorsLongerThan, and stay until a key is pressed.
 func (m *home) handleError(err error) tea.Cmd {
 	log.ErrorLog.Printf("%v", err)
+	if m.appConfig.PinsError(err.Error()) {
+		m.errBox.SetPinnedError(err)
+		return nil
+	}
 	m.errBox.SetError(err)
+	duration := m.appConfig.GetErrorDisplayDuration()
 	return func() tea.Msg {
 		select {
 		case <-m.ctx.Done():
-		case <-time.After(3 * time.Second):
+		case <-time.After(duration):
 		}
 
 		return hideErrMsg{}
@@ -856,7 +2611,7 @@ func (m *home) confirmAction(message string, action tea.Cmd) tea.Cmd {
 		m.state = stateDefault
 		// Execute the action if it exists
 		if action != nil {
-			_ = action()
+			m.confirmResult = action()
 		}
 	}
 
@@ -898,7 +2653,7 @@ func (m *home) handleDevServerStart(instance *session.Instance) tea.Cmd {
 		repoPath = worktreePath
 	}
 
-	log.InfoLog.Printf("handleDevServerStart: worktreePath=%s, repoPath=%s", worktreePath, repoPath)
+	log.DebugLog.Printf("handleDevServerStart: worktreePath=%s, repoPath=%s", worktreePath, repoPath)
 
 	if instance.DevServer == nil {
 		// Load settings from main repo (project-wide settings)
@@ -913,15 +2668,26 @@ func (m *home) handleDevServerStart(instance *session.Instance) tea.Cmd {
 
 		instance.DevServer = session.NewDevServer(
 			session.DevServerConfig{
-				BuildCommand: settings.BuildCommand,
-				DevCommand:   settings.DevCommand,
-				Env:          settings.Env,
+				BuildCommand:   settings.BuildCommand,
+				DevCommand:     settings.DevCommand,
+				Env:            settings.Env,
+				WorkingDir:     settings.WorkingDir,
+				MaxOutputLines: settings.MaxOutputLines,
 			},
 			worktreePath,
 			instance.Title,
 		)
 	}
 
+	// Start the session at the server pane's size rather than the configured default.
+	m.resizeDevServer(instance)
+	if instance.DevServer.ShouldRestore() {
+		// It was running when the app quit, so reattach to its session if it's still alive.
+		if _, err := instance.DevServer.Restore(); err != nil {
+			return m.handleError(err)
+		}
+		return m.instanceChanged()
+	}
 	if err := instance.DevServer.Start(); err != nil {
 		return m.handleError(err)
 	}
@@ -929,6 +2695,36 @@ func (m *home) handleDevServerStart(instance *session.Instance) tea.Cmd {
 	return m.instanceChanged()
 }
 
+// resizeDevServer sizes the instance's dev server session to the server pane.
+func (m *home) resizeDevServer(instance *session.Instance) {
+	if instance.DevServer == nil {
+		return
+	}
+	width, height := m.tabbedWindow.GetPreviewSize()
+	if width <= 0 || height <= 0 {
+		// The window size isn't known yet.
+		return
+	}
+	if err := instance.DevServer.Resize(width, height); err != nil {
+		log.WarningLog.Printf("could not resize dev server for %s: %v", instance.Title, err)
+	}
+}
+
+// handleDevServerOpenURL opens the URL the instance's dev server printed in the default browser.
+func (m *home) handleDevServerOpenURL(instance *session.Instance) tea.Cmd {
+	if instance.DevServer == nil || instance.DevServer.Status() != session.DevServerRunning {
+		return m.handleError(fmt.Errorf("dev server for '%s' is not running", instance.Title))
+	}
+	url := instance.DevServer.URL()
+	if url == "" {
+		return m.handleError(fmt.Errorf("no URL detected in the dev server output yet"))
+	}
+	if err := util.OpenURL(url); err != nil {
+		return m.handleError(err)
+	}
+	return m.showInfo(fmt.Sprintf("Opened %s", url))
+}
+
 func (m *home) handleDevServerStop(instance *session.Instance) tea.Cmd {
 	if instance.DevServer == nil {
 		return nil
@@ -941,19 +2737,104 @@ func (m *home) handleDevServerStop(instance *session.Instance) tea.Cmd {
 	return m.instanceChanged()
 }
 
-func (m *home) handleDevServerAttach(instance *session.Instance) tea.Cmd {
+// clearListFilters turns off the paused-only list filter and the archive view so that new instances
+// are visible.
+func (m *home) clearListFilters() {
+	if m.list.PausedOnly() {
+		m.list.TogglePausedOnly()
+	}
+	m.list.SetArchiveView(false)
+}
+
+// toggleArchived archives the instance, or unarchives it in the archive view. Unarchived instances
+// stay paused and count against the instance limit again.
+func (m *home) toggleArchived(instance *session.Instance) tea.Cmd {
+	var info string
+	if instance.Archived() {
+		if m.instanceLimitReached() {
+			return m.handleError(fmt.Errorf("you can't have more than %d active instances", GlobalInstanceLimit))
+		}
+		if err := instance.Unarchive(); err != nil {
+			return m.handleError(err)
+		}
+		info = fmt.Sprintf("Unarchived '%s'. Press r in the active instances to resume it", instance.Title)
+	} else {
+		if err := instance.Archive(); err != nil {
+			return m.handleError(err)
+		}
+		info = fmt.Sprintf("Archived '%s'. Press A to show the archived instances", instance.Title)
+	}
+	if err := m.storage.SaveInstances(m.list.GetInstances()); err != nil {
+		return m.handleError(err)
+	}
+	return tea.Batch(m.instanceChanged(), m.showInfo(info))
+}
+
+// selectInstance selects the instance at idx, clearing the paused-only filter, switching to or from
+// the archive view and expanding the repo groups if they hide it.
+func (m *home) selectInstance(idx int) {
+	instances := m.list.GetInstances()
+	if idx < 0 || idx >= len(instances) {
+		return
+	}
+	m.list.SetSelectedInstance(idx)
+	if m.list.GetSelectedInstance() == instances[idx] {
+		return
+	}
+	m.clearListFilters()
+	m.list.SetArchiveView(instances[idx].Archived())
+	m.list.ExpandAllGroups()
+	m.list.SetSelectedInstance(idx)
+}
+
+// useTmuxWindowAttach returns true if sessions should be opened by switching the surrounding tmux
+// client rather than taking over the TUI.
+func (m *home) useTmuxWindowAttach() bool {
+	return m.appConfig.AttachMode == config.AttachModeTmuxWindow && tmux.InsideTmux()
+}
+
+// resumeFromSuspend redraws the TUI after it was resumed from ctrl+z. Bubble Tea restores the alt
+// screen but not mouse reporting, and the terminal may have been resized in the meantime.
+func (m *home) resumeFromSuspend() tea.Cmd {
+	log.InfoLog.Printf("resumed from suspend")
+	cmds := []tea.Cmd{tea.WindowSize(), m.instanceChanged()}
+	if m.appConfig.MouseEnabled {
+		cmds = append(cmds, tea.EnableMouseCellMotion)
+	}
+	return tea.Batch(cmds...)
+}
+
+// attachMsg runs attach once the TUI's mouse reporting was turned off, see attachCmd.
+type attachMsg struct {
+	attach func()
+}
+
+// attachCmd runs attach, which blocks while a session is attached. The TUI's mouse reporting is
+// turned off meanwhile, so the mouse events go to the session as it expects, e.g. for tmux's
+// scrolling and selection, and turned back on after detaching, since the session may have changed it.
+func (m *home) attachCmd(attach func()) tea.Cmd {
+	runAttach := func() tea.Msg { return attachMsg{attach: attach} }
+	if !m.appConfig.MouseEnabled {
+		return runAttach
+	}
+	return tea.Sequence(tea.DisableMouse, runAttach)
+}
+
+// handleDevServerAttach attaches to the dev server session of instance. If readOnly is true, the
+// session is only watched: input doesn't reach it, so a stray ctrl+c can't stop the server.
+func (m *home) handleDevServerAttach(instance *session.Instance, readOnly bool) tea.Cmd {
 	// Check if dev server exists and is running
 	if instance.DevServer == nil {
 		return m.handleError(fmt.Errorf("no dev server configured"))
 	}
 
+	// Attach whenever the session is alive, even if the health check thinks otherwise, so a
+	// server wrongly marked as crashed can still be investigated.
 	status := instance.DevServer.Status()
-	if status != session.DevServerRunning {
-		return m.handleError(fmt.Errorf("dev server is not running (status: %v)", status))
-	}
-
-	// Check if dev server session exists
 	if !instance.DevServer.SessionExists() {
+		if status != session.DevServerRunning {
+			return m.handleError(fmt.Errorf("dev server is not running (status: %s)", devServerStatusText(status)))
+		}
 		return m.handleError(fmt.Errorf("dev server session does not exist"))
 	}
 
@@ -963,45 +2844,159 @@ func (m *home) handleDevServerAttach(instance *session.Instance) tea.Cmd {
 		return m.handleError(fmt.Errorf("dev server session is nil"))
 	}
 
-	// Show help screen before attaching
-	m.showHelpScreen(helpTypeServerAttach{}, func() {
-		ch, err := devServerSession.Attach()
-		if err != nil {
-			m.handleError(err)
-			return
+	var warning string
+	if status != session.DevServerRunning {
+		warning = fmt.Sprintf("dev server is marked %s but its session is still alive", devServerStatusText(status))
+		log.WarningLog.Printf("%s: %s", instance.Title, warning)
+	}
+
+	// Switching the surrounding tmux client can't make it read-only, so watching always takes over
+	// the TUI.
+	if m.useTmuxWindowAttach() && !readOnly {
+		if err := devServerSession.SwitchClient(); err != nil {
+			return m.handleError(err)
 		}
-		<-ch
-		m.state = stateDefault
-	})
+		if warning != "" {
+			return m.handleError(fmt.Errorf("%s", warning))
+		}
+		return nil
+	}
+
+	attach := func() tea.Cmd {
+		return m.attachCmd(func() {
+			attachSession := devServerSession.Attach
+			if readOnly {
+				attachSession = devServerSession.AttachReadOnly
+			}
+			ch, err := attachSession()
+			if err != nil {
+				m.handleError(err)
+				return
+			}
+			<-ch
+			m.state = stateDefault
+		})
+	}
+
+	if warning != "" {
+		// Always show the warning before attaching, the help screen may already have been seen.
+		title := "Attaching to Dev Server"
+		if readOnly {
+			title = "Watching Dev Server"
+		}
+		m.textOverlay = overlay.NewTextOverlay(lipgloss.JoinVertical(lipgloss.Left,
+			titleStyle.Render(title),
+			"",
+			warningStyle.Render("Warning: "+warning+"."),
+			descStyle.Render("The crash detection may have been wrong, or the server is shutting down."),
+			"",
+			descStyle.Render("To detach from the dev server, press ")+keyStyle.Render("ctrl-q"),
+		))
+		m.onHelpDismiss = attach
+		m.state = stateHelp
+		return nil
+	}
+
+	// Show help screen before attaching
+	var cmd tea.Cmd
+	if readOnly {
+		_, cmd = m.showHelpScreen(helpTypeServerWatch{}, attach)
+	} else {
+		_, cmd = m.showHelpScreen(helpTypeServerAttach{}, attach)
+	}
+	return cmd
+}
+
+// devServerField is a dev server setting that can be edited on its own from the dev server edit
+// menu, in the order of the menu.
+type devServerField int
+
+const (
+	// devServerFieldAll walks through the build command, dev command and working directory.
+	devServerFieldAll devServerField = iota
+	devServerFieldBuild
+	devServerFieldDev
+	devServerFieldEnv
+	devServerFieldWorkingDir
+)
 
+// showDevServerEditMenu lets the user pick which dev server setting of the instance's repo to edit,
+// or all of them.
+func (m *home) showDevServerEditMenu(instance *session.Instance) tea.Cmd {
+	_, repoPath := devServerPaths(instance)
+	settings := loadDevServerSettingsOrDefault(repoPath)
+	items := []overlay.SelectionItem{
+		{Label: "All settings"},
+		{Label: "Build command", Description: settings.BuildCommand},
+		{Label: "Dev command", Description: settings.DevCommand},
+		{Label: "Env", Description: config.FormatEnv(settings.Env)},
+		{Label: "Working directory", Description: settings.WorkingDir},
+	}
+	m.selectionOverlay = overlay.NewSelectionOverlay("Edit dev server", items)
+	m.state = stateSelectDevServerField
 	return nil
 }
 
-func (m *home) handleDevServerEdit(instance *session.Instance) tea.Cmd {
-	worktreePath := ""
-	repoPath := ""
+// editDevServerField edits a single dev server setting of the instance's repo, keeping the others.
+func (m *home) editDevServerField(instance *session.Instance, field devServerField) tea.Cmd {
+	worktreePath, repoPath := devServerPaths(instance)
+	settings := loadDevServerSettingsOrDefault(repoPath)
+
+	var title, value string
+	switch field {
+	case devServerFieldBuild:
+		title, value = "Build command (empty to skip):", settings.BuildCommand
+	case devServerFieldDev:
+		title, value = "Dev server command:", settings.DevCommand
+	case devServerFieldEnv:
+		title, value = "Env, as NAME=value pairs separated by spaces (quote values with spaces):", config.FormatEnv(settings.Env)
+	case devServerFieldWorkingDir:
+		title, value = devServerWorkingDirTitle, settings.WorkingDir
+	}
 
-	// Try to get worktree path from gitWorktree
-	worktree, err := instance.GetGitWorktree()
-	if err == nil && worktree != nil {
+	m.state = stateDevServerConfig
+	m.textInputOverlay = overlay.NewTextInputOverlay(title, value)
+	m.textInputOverlay.SetOnSubmit(func() {
+		value := m.textInputOverlay.GetValue()
+		switch field {
+		case devServerFieldBuild:
+			settings.BuildCommand = value
+		case devServerFieldDev:
+			settings.DevCommand = value
+		case devServerFieldEnv:
+			env, err := config.ParseEnv(value)
+			if err != nil {
+				m.devServerConfigCmd = m.handleError(err)
+				return
+			}
+			settings.Env = env
+		case devServerFieldWorkingDir:
+			settings.WorkingDir = strings.TrimSpace(value)
+		}
+		m.devServerConfigCmd = m.applyDevServerSettings(instance, settings, repoPath, worktreePath)
+	})
+	return tea.WindowSize()
+}
+
+// devServerPaths returns the worktree the instance's dev server runs in and the repo its settings
+// are saved to, falling back to the instance path.
+func devServerPaths(instance *session.Instance) (worktreePath, repoPath string) {
+	if worktree, err := instance.GetGitWorktree(); err == nil && worktree != nil {
 		worktreePath = worktree.GetWorktreePath()
 		repoPath = worktree.GetRepoPath()
 	}
-
-	// Fallback to instance path
 	if worktreePath == "" {
 		worktreePath = instance.Path
 	}
 	if repoPath == "" {
 		repoPath = instance.Path
 	}
+	return worktreePath, repoPath
+}
 
-	// Stop the dev server if running
-	if instance.DevServer != nil && instance.DevServer.IsRunning() {
-		instance.DevServer.Stop()
-	}
-
-	// Load existing settings (or defaults)
+// loadDevServerSettingsOrDefault loads the repo's dev server settings, or returns empty settings if
+// there are none or they fail to load.
+func loadDevServerSettingsOrDefault(repoPath string) *config.DevServerSettings {
 	settings, _ := config.LoadDevServerSettings(repoPath)
 	if settings == nil {
 		settings = &config.DevServerSettings{
@@ -1010,6 +3005,51 @@ func (m *home) handleDevServerEdit(instance *session.Instance) tea.Cmd {
 			Env:          make(map[string]string),
 		}
 	}
+	return settings
+}
+
+// applyDevServerSettings saves settings to the repo and gives the instance a dev server using them,
+// closing the dev server config overlay. The running server is only replaced once the new settings
+// are submitted, and is started again with them. The returned Cmd starts the server or updates the
+// panes.
+func (m *home) applyDevServerSettings(instance *session.Instance, settings *config.DevServerSettings, repoPath, worktreePath string) tea.Cmd {
+	// Save settings to main repo (project-wide)
+	if err := config.SaveDevServerSettings(settings, repoPath); err != nil {
+		return m.handleError(err)
+	}
+
+	wasRunning := instance.DevServer != nil && instance.DevServer.IsRunning()
+	if wasRunning {
+		if err := instance.DevServer.Stop(); err != nil {
+			log.WarningLog.Printf("failed to stop dev server before applying new settings: %v", err)
+		}
+	}
+
+	instance.DevServer = session.NewDevServer(
+		session.DevServerConfig{
+			BuildCommand:   settings.BuildCommand,
+			DevCommand:     settings.DevCommand,
+			Env:            settings.Env,
+			WorkingDir:     settings.WorkingDir,
+			MaxOutputLines: settings.MaxOutputLines,
+		},
+		worktreePath,
+		instance.Title,
+	)
+
+	m.state = stateDefault
+	m.textInputOverlay = nil
+	if wasRunning {
+		return tea.Batch(tea.WindowSize(), m.handleDevServerStart(instance))
+	}
+	return tea.Batch(tea.WindowSize(), m.instanceChanged())
+}
+
+func (m *home) handleDevServerEdit(instance *session.Instance) tea.Cmd {
+	worktreePath, repoPath := devServerPaths(instance)
+
+	// Load existing settings (or defaults)
+	settings := loadDevServerSettingsOrDefault(repoPath)
 
 	m.state = stateDevServerConfig
 	m.textInputOverlay = overlay.NewTextInputOverlay("Build command (empty to skip):", settings.BuildCommand)
@@ -1020,37 +3060,75 @@ func (m *home) handleDevServerEdit(instance *session.Instance) tea.Cmd {
 		m.textInputOverlay.SetOnSubmit(func() {
 			devCmd := m.textInputOverlay.GetValue()
 
-			newSettings := &config.DevServerSettings{
-				BuildCommand: buildCmd,
-				DevCommand:   devCmd,
-				Env:          make(map[string]string),
-			}
+			m.textInputOverlay = overlay.NewTextInputOverlay(devServerWorkingDirTitle, settings.WorkingDir)
+			m.textInputOverlay.SetOnSubmit(func() {
+				workingDir := strings.TrimSpace(m.textInputOverlay.GetValue())
 
-			// Save settings to main repo (project-wide)
-			if err := config.SaveDevServerSettings(newSettings, repoPath); err != nil {
-				m.handleError(err)
-				return
-			}
+				// Settings the walk doesn't ask for, like the env and copy_files, are kept.
+				newSettings := *settings
+				newSettings.BuildCommand = buildCmd
+				newSettings.DevCommand = devCmd
+				newSettings.WorkingDir = workingDir
+				m.devServerConfigCmd = m.applyDevServerSettings(instance, &newSettings, repoPath, worktreePath)
+			})
+		})
+	})
+
+	return nil
+}
+
+// handleEditSettings opens the settings file of the instance's repo in a text editor. The edited
+// JSON is validated and saved on submit; errors keep the editor open. Settings the dev server edit
+// menu doesn't cover, like max_output_lines, can be edited this way.
+func (m *home) handleEditSettings(instance *session.Instance) tea.Cmd {
+	repoPath := instance.Path
+	worktreePath := instance.Path
+	if worktree, err := instance.GetGitWorktree(); err == nil && worktree != nil {
+		repoPath = worktree.GetRepoPath()
+		worktreePath = worktree.GetWorktreePath()
+	}
 
+	content, err := config.DevServerSettingsJSON(repoPath)
+	if err != nil {
+		return m.handleError(err)
+	}
+
+	m.textEditorOverlay = overlay.NewTextEditorOverlay(config.SettingsFileName, content)
+	m.textEditorOverlay.OnSubmit = func(value string) error {
+		settings, unknown, err := config.ParseDevServerSettings([]byte(value))
+		if err != nil {
+			return err
+		}
+		if len(unknown) > 0 {
+			// They would be dropped on save, and are usually typos.
+			return fmt.Errorf("unknown keys: %s", strings.Join(unknown, ", "))
+		}
+		if err := config.SaveDevServerSettings(settings, repoPath); err != nil {
+			return err
+		}
+		// A running dev server keeps its settings until it is stopped.
+		if instance.DevServer == nil || !instance.DevServer.IsRunning() {
 			instance.DevServer = session.NewDevServer(
 				session.DevServerConfig{
-					BuildCommand: buildCmd,
-					DevCommand:   devCmd,
-					Env:          newSettings.Env,
+					BuildCommand:   settings.BuildCommand,
+					DevCommand:     settings.DevCommand,
+					Env:            settings.Env,
+					WorkingDir:     settings.WorkingDir,
+					MaxOutputLines: settings.MaxOutputLines,
 				},
 				worktreePath,
 				instance.Title,
 			)
-
-			m.state = stateDefault
-			m.textInputOverlay = nil
-			m.instanceChanged()
-		})
-	})
-
-	return nil
+		}
+		return nil
+	}
+	m.state = stateEditSettings
+	return tea.WindowSize()
 }
 
+// devServerWorkingDirTitle is the prompt for the dev server working directory in the config flows.
+const devServerWorkingDirTitle = "Working directory, relative to the worktree (empty for the root):"
+
 type devServerConfigState int
 
 const (
@@ -1088,31 +3166,38 @@ func (m *home) showDevServerConfigOverlay(instance *session.Instance, repoPath s
 		m.textInputOverlay.SetOnSubmit(func() {
 			devCmd := m.textInputOverlay.GetValue()
 
-			settings := &config.DevServerSettings{
-				BuildCommand: buildCmd,
-				DevCommand:   devCmd,
-				Env:          make(map[string]string),
-			}
+			m.textInputOverlay = overlay.NewTextInputOverlay(devServerWorkingDirTitle, "")
+			m.textInputOverlay.SetOnSubmit(func() {
+				workingDir := strings.TrimSpace(m.textInputOverlay.GetValue())
 
-			// Save settings to main repo (project-wide)
-			if err := config.SaveDevServerSettings(settings, repoPath); err != nil {
-				m.handleError(err)
-				return
-			}
+				// Settings without a dev command can still hold others, like copy_files.
+				settings := loadDevServerSettingsOrDefault(repoPath)
+				settings.BuildCommand = buildCmd
+				settings.DevCommand = devCmd
+				settings.WorkingDir = workingDir
 
-			instance.DevServer = session.NewDevServer(
-				session.DevServerConfig{
-					BuildCommand: buildCmd,
-					DevCommand:   devCmd,
-					Env:          settings.Env,
-				},
-				worktreePath,
-				instance.Title,
-			)
+				// Save settings to main repo (project-wide)
+				if err := config.SaveDevServerSettings(settings, repoPath); err != nil {
+					m.devServerConfigCmd = m.handleError(err)
+					return
+				}
 
-			m.state = stateDefault
-			m.textInputOverlay = nil
-			m.handleDevServerStart(instance)
+				instance.DevServer = session.NewDevServer(
+					session.DevServerConfig{
+						BuildCommand:   buildCmd,
+						DevCommand:     devCmd,
+						Env:            settings.Env,
+						WorkingDir:     workingDir,
+						MaxOutputLines: settings.MaxOutputLines,
+					},
+					worktreePath,
+					instance.Title,
+				)
+
+				m.state = stateDefault
+				m.textInputOverlay = nil
+				m.devServerConfigCmd = tea.Batch(tea.WindowSize(), m.handleDevServerStart(instance))
+			})
 		})
 	})
 
@@ -1131,7 +3216,8 @@ func (m *home) View() string {
 		m.errBox.String(),
 	)
 
-	if m.state == statePrompt || m.state == stateDevServerConfig {
+	if m.state == statePrompt || m.state == stateDevServerConfig || m.state == stateSendFile || m.state == stateBatchPrompt ||
+		m.state == stateRunCommand {
 		if m.textInputOverlay == nil {
 			log.ErrorLog.Printf("text input overlay is nil")
 		}
@@ -1141,6 +3227,26 @@ func (m *home) View() string {
 			log.ErrorLog.Printf("text overlay is nil")
 		}
 		return overlay.PlaceOverlay(0, 0, m.textOverlay.Render(), mainView, true, true)
+	} else if m.state == stateSelectTemplate || m.state == stateSelectDevServerField || m.state == stateSelectDiffFile {
+		if m.selectionOverlay == nil {
+			log.ErrorLog.Printf("selection overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.selectionOverlay.Render(), mainView, true, true)
+	} else if m.state == stateQuickSwitch {
+		if m.quickSwitchOverlay == nil {
+			log.ErrorLog.Printf("quick switch overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.quickSwitchOverlay.Render(), mainView, true, true)
+	} else if m.state == stateNew {
+		if m.newInstanceOverlay == nil {
+			log.ErrorLog.Printf("new instance overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.newInstanceOverlay.Render(), mainView, true, true)
+	} else if m.state == stateEditSettings {
+		if m.textEditorOverlay == nil {
+			log.ErrorLog.Printf("text editor overlay is nil")
+		}
+		return overlay.PlaceOverlay(0, 0, m.textEditorOverlay.Render(), mainView, true, true)
 	} else if m.state == stateConfirm {
 		if m.confirmationOverlay == nil {
 			log.ErrorLog.Printf("confirmation overlay is nil")
@@ -2,14 +2,20 @@ package app
 
 import (
 	"claude-squad/config"
+	"claude-squad/keys"
 	"claude-squad/log"
 	"claude-squad/session"
 	"claude-squad/ui"
 	"claude-squad/ui/overlay"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
@@ -464,3 +470,1256 @@ func TestConfirmationModalVisualAppearance(t *testing.T) {
 	// Test that the danger indicator is preserved
 	assert.Contains(t, rendered, "[!")
 }
+
+func TestCreateStartupInstances_SkipsExisting(t *testing.T) {
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "backend", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+
+	s := spinner.New()
+	h := &home{
+		ctx:       context.Background(),
+		appConfig: &config.Config{StartupInstances: []config.StartupInstanceSpec{{Title: "backend", Prompt: "hi"}}},
+		list:      ui.NewList(&s, false),
+	}
+	h.list.AddInstance(instance)
+
+	// Nothing is started or saved when every startup instance already exists.
+	assert.Nil(t, h.createStartupInstances(t.TempDir()))
+	assert.Equal(t, 1, h.list.NumInstances())
+	assert.Empty(t, instance.Prompt)
+}
+
+func TestQuickSwitch(t *testing.T) {
+	titles := []string{"backend", "frontend", "fe-login"}
+
+	tests := []struct {
+		name          string
+		query         string
+		keys          []tea.KeyMsg
+		expectedTitle string
+		expectClosed  bool
+	}{
+		{name: "empty query keeps list order", query: "", expectedTitle: "backend"},
+		{name: "selects the best match", query: "fe", expectedTitle: "fe-login"},
+		{name: "moves through the matches", query: "fe", keys: []tea.KeyMsg{{Type: tea.KeyDown}}, expectedTitle: "frontend"},
+		{name: "no matches", query: "xyz", expectedTitle: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := spinner.New()
+			h := &home{ctx: context.Background(), list: ui.NewList(&s, false)}
+			items := make([]overlay.SelectionItem, 0, len(titles))
+			for _, title := range titles {
+				instance, err := session.NewInstance(session.InstanceOptions{Title: title, Path: t.TempDir(), Program: "claude"})
+				require.NoError(t, err)
+				h.list.AddInstance(instance)
+				items = append(items, overlay.SelectionItem{Label: title})
+			}
+
+			quickSwitch := overlay.NewQuickSwitchOverlay("Switch to instance", items)
+			for _, r := range tt.query {
+				require.False(t, quickSwitch.HandleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}))
+			}
+			for _, key := range tt.keys {
+				require.False(t, quickSwitch.HandleKeyPress(key))
+			}
+			require.True(t, quickSwitch.HandleKeyPress(tea.KeyMsg{Type: tea.KeyEnter}))
+
+			if tt.expectedTitle == "" {
+				assert.True(t, quickSwitch.Canceled)
+				assert.Equal(t, -1, quickSwitch.Selected())
+				return
+			}
+			assert.True(t, quickSwitch.Submitted)
+			h.selectInstance(quickSwitch.Selected())
+			assert.Equal(t, tt.expectedTitle, h.list.GetSelectedInstance().Title)
+		})
+	}
+
+	t.Run("selecting a hidden instance clears the paused-only filter", func(t *testing.T) {
+		s := spinner.New()
+		h := &home{ctx: context.Background(), list: ui.NewList(&s, false)}
+		for _, status := range []session.Status{session.Paused, session.Ready} {
+			instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+			require.NoError(t, err)
+			instance.Status = status
+			h.list.AddInstance(instance)
+		}
+		h.list.TogglePausedOnly()
+
+		h.selectInstance(1)
+		assert.False(t, h.list.PausedOnly())
+		assert.Equal(t, h.list.GetInstances()[1], h.list.GetSelectedInstance())
+	})
+}
+
+func TestOnReadyCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		title    string
+		expected string
+	}{
+		{name: "passes the title as $1", command: `echo done: "$1"`, title: "feature", expected: "done: feature\n"},
+		{name: "passes the title in CS_TITLE", command: `printf '%s|' "$CS_TITLE"`, title: "my feature", expected: "my feature|"},
+		{name: "leaves compound commands as they are", command: `echo first; echo "second $1" # done`, title: "feature",
+			expected: "first\nsecond feature\n"},
+		{name: "does not evaluate the title", command: `printf '%s' "$1"`, title: "$(echo pwned)", expected: "$(echo pwned)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := onReadyCmd(context.Background(), tt.command, tt.title).Output()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(output))
+		})
+	}
+}
+
+func TestAgentFinishedRingsBellFromUpdate(t *testing.T) {
+	var out strings.Builder
+	bellOutput = &out
+	defer func() { bellOutput = os.Stdout }()
+
+	h := &home{ctx: context.Background(), appConfig: config.DefaultConfig()}
+	h.appConfig.BellOnReady = true
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+
+	msg := h.agentFinished(instance)()
+	assert.Empty(t, out.String(), "the Cmd doesn't write to the terminal itself")
+	h.Update(msg)
+	assert.Equal(t, "\a", out.String())
+}
+
+func TestUndoKillSelectsPinnedInstance(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	t.Setenv("HOME", t.TempDir())
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	storage, err := session.NewStorage(config.DefaultState())
+	require.NoError(t, err)
+	h.storage = storage
+	for _, title := range []string{"a", "b"} {
+		instance, err := session.FromInstanceData(session.InstanceData{Title: title, Path: t.TempDir(), Status: session.Paused, Program: "claude"})
+		require.NoError(t, err)
+		h.list.AddInstance(instance)
+	}
+	killed, err := session.FromInstanceData(session.InstanceData{Title: "pinned", Path: t.TempDir(), Status: session.Paused, Program: "claude", Pinned: true})
+	require.NoError(t, err)
+	h.pendingKill = &pendingKill{id: 1, instance: killed}
+
+	// The pinned instance goes back to the top of the list and is the one selected.
+	h.undoKill()
+	assert.Equal(t, killed, h.list.GetInstances()[0])
+	assert.Equal(t, killed, h.list.GetSelectedInstance())
+}
+
+func TestAssignRoundRobin(t *testing.T) {
+	prompts := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		name     string
+		n        int
+		expected [][]string
+	}{
+		{name: "one prompt per instance", n: 5, expected: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{name: "fewer instances than prompts", n: 2, expected: [][]string{{"a", "c", "e"}, {"b", "d"}}},
+		{name: "more instances than prompts", n: 8, expected: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{name: "no instances", n: 0, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, assignRoundRobin(prompts, tt.n))
+		})
+	}
+}
+
+func TestBatchTitles(t *testing.T) {
+	taken := map[string]bool{"tasks-2": true}
+	assert.Equal(t, []string{"tasks-1", "tasks-3", "tasks-4"}, batchTitles("tasks", 3, taken))
+}
+
+func TestCreateBatchInstances_RespectsLimit(t *testing.T) {
+	s := spinner.New()
+	h := &home{ctx: context.Background(), list: ui.NewList(&s, false)}
+	for i := 0; i < GlobalInstanceLimit; i++ {
+		instance, err := session.FromInstanceData(session.InstanceData{Title: fmt.Sprintf("test-%d", i), Path: t.TempDir(), Status: session.Paused, Program: "claude"})
+		require.NoError(t, err)
+		h.list.AddInstance(instance)
+	}
+
+	cmd := h.createBatchInstances(t.TempDir(), PromptBatch{Name: "tasks", Prompts: []string{"a", "b"}})
+	require.NotNil(t, cmd)
+	msg, ok := cmd().(instancesStartedMsg)
+	require.True(t, ok)
+	require.Error(t, msg.err)
+	assert.Empty(t, msg.instances)
+	assert.Zero(t, h.startingInstances)
+	assert.Equal(t, GlobalInstanceLimit, h.list.NumInstances())
+}
+
+func TestHandleInstancesStarted_ReportsWithErrors(t *testing.T) {
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	h.errBox.SetSize(200, 1)
+	h.startingInstances = 2
+
+	h.handleInstancesStarted(instancesStartedMsg{
+		requested: 2,
+		info:      "Created 0 instances for 2 prompts",
+		err:       fmt.Errorf("failed to start batch instance 'tasks-1'"),
+	})
+	assert.Zero(t, h.startingInstances)
+	assert.Contains(t, h.errBox.String(), "Created 0 instances for 2 prompts")
+	assert.Contains(t, h.errBox.String(), "tasks-1")
+}
+
+func TestEditSettings(t *testing.T) {
+	repoPath := t.TempDir()
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: repoPath, Program: "claude"})
+	require.NoError(t, err)
+
+	s := spinner.New()
+	h := &home{ctx: context.Background(), list: ui.NewList(&s, false), errBox: ui.NewErrBox()}
+	h.handleEditSettings(instance)
+	require.Equal(t, stateEditSettings, h.state)
+	editor := h.textEditorOverlay
+	assert.Contains(t, editor.GetValue(), `"dev_command": ""`)
+
+	t.Run("keeps the editor open on invalid JSON", func(t *testing.T) {
+		require.Error(t, editor.OnSubmit(`{"dev_command": `))
+		require.Error(t, editor.OnSubmit(`{"dev_comand": "npm run dev"}`))
+		assert.False(t, config.SettingsExist(repoPath))
+	})
+
+	t.Run("saves valid settings", func(t *testing.T) {
+		require.NoError(t, editor.OnSubmit(`{"dev_command": "npm run dev", "env": {"PORT": "3000"}}`))
+		settings, err := config.LoadDevServerSettings(repoPath)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"PORT": "3000"}, settings.Env)
+		require.NotNil(t, instance.DevServer)
+		assert.Equal(t, "npm run dev", instance.DevServer.Config().DevCommand)
+	})
+}
+
+func TestRetryFailedCreation(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	// Instances fail to start outside of a git repository.
+	repoPath := t.TempDir()
+	s := spinner.New()
+	h := &home{
+		ctx:       context.Background(),
+		state:     stateNew,
+		appConfig: config.DefaultConfig(),
+		list:      ui.NewList(&s, false),
+		menu:      ui.NewMenu(),
+		errBox:    ui.NewErrBox(),
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{
+		Title: "retry-me", Path: repoPath, Program: "claude", ExtraArgs: "--model opus", Labels: []string{"api"}})
+	require.NoError(t, err)
+	h.newInstanceFinalizer = h.list.AddInstance(instance)
+	h.newInstance = instance
+	h.promptAfterName = true
+
+	h.startNewInstance(instance)
+	require.NotNil(t, h.failedCreation)
+	assert.Equal(t, stateDefault, h.state)
+	assert.False(t, h.promptAfterName)
+	assert.Zero(t, h.list.NumInstances())
+	assert.Contains(t, h.errBox.String(), "Press ctrl+n to retry creation")
+
+	// Rebase keeps its meaning while a retry is offered.
+	firstID := h.failedCreation.id
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("R")})
+	require.NotNil(t, h.failedCreation)
+	assert.Equal(t, firstID, h.failedCreation.id)
+	assert.Zero(t, h.list.NumInstances())
+
+	// The retry uses the same options and fails again, offering another retry.
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyCtrlN})
+	require.NotNil(t, h.failedCreation)
+	assert.NotEqual(t, firstID, h.failedCreation.id)
+	assert.Equal(t, "retry-me", h.failedCreation.options.Title)
+	assert.Equal(t, "--model opus", h.failedCreation.options.ExtraArgs)
+	assert.Equal(t, []string{"api"}, h.failedCreation.options.Labels)
+	assert.True(t, h.failedCreation.promptAfterName)
+	assert.Zero(t, h.list.NumInstances())
+
+	// Only the timer of the latest failure drops it.
+	h.Update(creationRetryExpiredMsg{id: firstID})
+	require.NotNil(t, h.failedCreation)
+	h.Update(creationRetryExpiredMsg{id: h.failedCreation.id})
+	assert.Nil(t, h.failedCreation)
+}
+
+func TestDevServerEdit_CancelKeepsServerRunning(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	repoPath := t.TempDir()
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: repoPath, Program: "claude"})
+	require.NoError(t, err)
+	devServer := session.NewDevServer(session.DevServerConfig{DevCommand: "npm run dev"}, repoPath, instance.Title)
+	devServer.SetStatus(session.DevServerRunning)
+	instance.DevServer = devServer
+
+	s := spinner.New()
+	h := &home{ctx: context.Background(), list: ui.NewList(&s, false), menu: ui.NewMenu(), errBox: ui.NewErrBox()}
+	h.handleDevServerEdit(instance)
+	require.Equal(t, stateDevServerConfig, h.state)
+	assert.Equal(t, session.DevServerRunning, devServer.Status(), "opening the editor doesn't stop the server")
+
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Nil(t, h.textInputOverlay)
+	assert.Same(t, devServer, instance.DevServer)
+	assert.Equal(t, session.DevServerRunning, devServer.Status())
+}
+
+func TestDevServerEdit_KeepsOtherSettings(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	repoPath := t.TempDir()
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, config.SaveDevServerSettings(&config.DevServerSettings{
+		DevCommand: "npm run dev",
+		Env:        map[string]string{"PORT": "3001"},
+		CopyFiles:  []string{".envrc"},
+		CreatedAt:  createdAt,
+	}, repoPath))
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: repoPath, Program: "claude"})
+	require.NoError(t, err)
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	h.handleDevServerEdit(instance)
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("make")})
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	_, cmd := h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	require.Equal(t, stateDefault, h.state)
+	// The Cmd that resizes and updates the panes isn't dropped.
+	assert.NotNil(t, cmd)
+	assert.Nil(t, h.devServerConfigCmd)
+
+	settings, err := config.LoadDevServerSettings(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, "make", settings.BuildCommand)
+	assert.Equal(t, "npm run dev", settings.DevCommand)
+	assert.Equal(t, map[string]string{"PORT": "3001"}, settings.Env)
+	assert.Equal(t, []string{".envrc"}, settings.CopyFiles)
+	assert.True(t, createdAt.Equal(settings.CreatedAt))
+}
+
+func TestCopyDiff_NotReady(t *testing.T) {
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+
+	h := &home{ctx: context.Background(), errBox: ui.NewErrBox()}
+	h.copyDiff(instance)
+	assert.Contains(t, h.errBox.String(), "The diff isn't ready yet")
+}
+
+func TestNewInstanceForm(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	press := func(msg tea.KeyMsg) {
+		h.keySent = true
+		h.handleKeyPress(msg)
+	}
+	newForm := func() *session.Instance {
+		instance, err := session.NewInstance(session.InstanceOptions{Title: "", Path: t.TempDir(), Program: "claude"})
+		require.NoError(t, err)
+		h.openNewInstanceForm(instance)
+		require.Equal(t, stateNew, h.state)
+		return instance
+	}
+
+	t.Run("esc cancels", func(t *testing.T) {
+		newForm()
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("draft"), Paste: true})
+		assert.Equal(t, "draft", h.list.GetSelectedInstance().Title)
+		press(tea.KeyMsg{Type: tea.KeyEsc})
+		assert.Equal(t, stateDefault, h.state)
+		assert.Nil(t, h.newInstanceOverlay)
+		assert.Zero(t, h.list.NumInstances())
+	})
+
+	t.Run("validates and applies the values", func(t *testing.T) {
+		instance := newForm()
+		press(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Error(t, h.newInstanceOverlay.Error())
+		assert.Equal(t, stateNew, h.state)
+
+		// The title is capped at the maximum length.
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(strings.Repeat("a", 40)), Paste: true})
+		assert.Len(t, instance.Title, overlay.MaxTitleLength)
+		for range 40 {
+			press(tea.KeyMsg{Type: tea.KeyBackspace})
+		}
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("my task")})
+		press(tea.KeyMsg{Type: tea.KeyTab})
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" --model opus")})
+		press(tea.KeyMsg{Type: tea.KeyTab})
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("develop")})
+		assert.Equal(t, "my task", instance.Title)
+
+		// The branch must be a legal git branch name.
+		press(tea.KeyMsg{Type: tea.KeyTab})
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("my..branch"), Paste: true})
+		press(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Error(t, h.newInstanceOverlay.Error())
+		for range len("my..branch") {
+			press(tea.KeyMsg{Type: tea.KeyBackspace})
+		}
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("fix/login"), Paste: true})
+
+		// The values are applied before the start, which fails outside of a git repository.
+		press(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Nil(t, h.newInstanceOverlay)
+		require.NotNil(t, h.failedCreation)
+		assert.Equal(t, "my task", h.failedCreation.options.Title)
+		assert.Equal(t, "claude --model opus", h.failedCreation.options.Program)
+		assert.Equal(t, "develop", h.failedCreation.options.BaseBranch)
+		assert.Equal(t, "fix/login", h.failedCreation.options.BranchName)
+	})
+
+	t.Run("copies the dev server config of another instance", func(t *testing.T) {
+		devConfig := session.DevServerConfig{DevCommand: "npm run dev", Env: map[string]string{"PORT": "3001"}}
+		source, err := session.FromInstanceData(session.InstanceData{Title: "server", Path: t.TempDir(),
+			Status: session.Paused, Program: "claude", DevServer: &session.DevServerData{Config: devConfig}})
+		require.NoError(t, err)
+		h.list.AddInstance(source)()
+
+		newForm()
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("copy"), Paste: true})
+		for range 4 {
+			press(tea.KeyMsg{Type: tea.KeyTab})
+		}
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("nope"), Paste: true})
+		press(tea.KeyMsg{Type: tea.KeyEnter})
+		require.ErrorContains(t, h.newInstanceOverlay.Error(), "no session named 'nope'")
+		for range len("nope") {
+			press(tea.KeyMsg{Type: tea.KeyBackspace})
+		}
+
+		// Titles of instances with a dev server are completed with right.
+		press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("se")})
+		press(tea.KeyMsg{Type: tea.KeyRight})
+		press(tea.KeyMsg{Type: tea.KeyEnter})
+		require.NotNil(t, h.failedCreation)
+		require.NotNil(t, h.failedCreation.devServer)
+		assert.Equal(t, devConfig, *h.failedCreation.devServer)
+
+		// The copied env is independent of the source's.
+		h.failedCreation.devServer.Env["PORT"] = "3002"
+		assert.Equal(t, "3001", source.DevServer.Config().Env["PORT"])
+	})
+}
+
+func TestInstanceLimitCountsOnePlaceholder(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	for i := 0; i < GlobalInstanceLimit-1; i++ {
+		instance, err := session.FromInstanceData(session.InstanceData{Title: fmt.Sprintf("test-%d", i), Path: t.TempDir(), Status: session.Paused, Program: "claude"})
+		require.NoError(t, err)
+		h.list.AddInstance(instance)()
+	}
+	// Two placeholders left behind by abandoned creations only take up one slot.
+	for range 2 {
+		placeholder, err := session.NewInstance(session.InstanceOptions{Title: "", Path: t.TempDir(), Program: "claude"})
+		require.NoError(t, err)
+		h.list.AddInstance(placeholder)
+	}
+	assert.Equal(t, GlobalInstanceLimit+1, h.list.NumActiveInstances())
+	assert.Equal(t, GlobalInstanceLimit, h.numInstancesTowardLimit())
+	assert.True(t, h.instanceLimitReached())
+
+	h.list.SetSelectedInstance(GlobalInstanceLimit)
+	h.list.RemoveSelected()
+	h.list.RemoveSelected()
+	require.False(t, h.instanceLimitReached())
+
+	// The last slot can be used, and canceling the creation removes the placeholder even if
+	// another instance got selected meanwhile.
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	require.Equal(t, stateNew, h.state)
+	assert.True(t, h.instanceLimitReached())
+	h.list.SetSelectedInstance(0)
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Nil(t, h.newInstance)
+	assert.Equal(t, GlobalInstanceLimit-1, h.list.NumInstances())
+	assert.Equal(t, "test-0", h.list.GetSelectedInstance().Title)
+	assert.False(t, h.instanceLimitReached())
+}
+
+func TestToggleCompactList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	assert.True(t, h.list.Compact())
+	assert.True(t, config.LoadConfig().CompactList, "the layout is saved in the config")
+
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	assert.False(t, h.list.Compact())
+	assert.False(t, config.LoadConfig().CompactList)
+}
+
+func TestToggleCompactList_KeepsIgnoredConfig(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	configDir, err := config.GetConfigDir()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	configPath := filepath.Join(configDir, config.ConfigFileName)
+	// The template without a name is ignored by LoadConfig, but mustn't be lost.
+	require.NoError(t, os.WriteFile(configPath,
+		[]byte(`{"default_program": "aider", "templates": [{"name": " ", "program": "claude"}], "future_option": 1}`), 0644))
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.LoadConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	require.Empty(t, h.appConfig.Templates)
+
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	assert.True(t, config.LoadConfig().CompactList)
+
+	var saved map[string]any
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, []any{map[string]any{"name": " ", "program": "claude"}}, saved["templates"])
+	assert.Equal(t, float64(1), saved["future_option"])
+	assert.Equal(t, "aider", saved["default_program"])
+}
+
+func TestRestoreDevServers(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{ctx: context.Background(), appConfig: config.DefaultConfig(), list: ui.NewList(&s, false), errBox: ui.NewErrBox()}
+	for _, title := range []string{"web", "idle", "paused"} {
+		status := session.DevServerRunning
+		if title == "idle" {
+			status = session.DevServerStopped
+		}
+		instance, err := session.FromInstanceData(session.InstanceData{
+			Title: title, Path: t.TempDir(), Status: session.Paused, Program: "claude",
+			DevServer: &session.DevServerData{Config: session.DevServerConfig{DevCommand: "npm run dev", WorkingDir: "missing"}, Status: status},
+		})
+		require.NoError(t, err)
+		if title != "paused" {
+			instance.SetStatus(session.Ready)
+		}
+		h.list.AddInstance(instance)()
+	}
+
+	// Without the setting, the dev servers that were running are offered.
+	assert.Nil(t, h.restoreDevServers())
+	assert.Contains(t, h.errBox.String(), "Dev servers were running for web when you quit")
+	assert.Contains(t, h.errBox.String(), "press s to start its dev server")
+
+	// With it, they are started by the returned command, which fails here because the working
+	// directory is missing.
+	h.errBox.Clear()
+	h.appConfig.RestoreDevServers = true
+	cmd := h.restoreDevServers()
+	require.NotNil(t, cmd)
+	err, ok := cmd().(error)
+	require.True(t, ok)
+	assert.Contains(t, err.Error(), "failed to restore the dev server of 'web'")
+	assert.NotContains(t, err.Error(), "idle")
+	assert.NotContains(t, err.Error(), "paused")
+}
+
+func TestFocusFollowsNavigationAndScrolling(t *testing.T) {
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	press := func(msg tea.KeyMsg) {
+		h.keySent = true
+		h.handleKeyPress(msg)
+	}
+	assert.True(t, h.list.Focused(), "the list has the focus at first")
+	assert.False(t, h.tabbedWindow.Focused())
+
+	press(tea.KeyMsg{Type: tea.KeyPgDown})
+	assert.True(t, h.tabbedWindow.Focused())
+	assert.False(t, h.list.Focused())
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	assert.True(t, h.list.Focused())
+	assert.False(t, h.tabbedWindow.Focused())
+
+	press(tea.KeyMsg{Type: tea.KeyTab})
+	assert.True(t, h.tabbedWindow.Focused())
+	assert.False(t, h.list.Focused())
+}
+
+func TestDiffFilePickerUsesListedFiles(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	instance, err := session.FromInstanceData(session.InstanceData{Title: "diff", Path: t.TempDir(), Status: session.Paused,
+		Program: "claude", DiffStats: session.DiffStatsData{Added: 2, Files: []string{"a.go", "b.go"}}})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)()
+	h.list.SetSelectedInstance(0)
+
+	h.showDiffFiles(instance)
+	require.Equal(t, stateSelectDiffFile, h.state)
+	// The metadata tick replaces the stats while the list is open.
+	instance.GetDiffStats().Files = nil
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyDown})
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyDown})
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Nil(t, h.diffFiles)
+}
+
+func TestKillWhileResuming(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	instance, err := session.FromInstanceData(session.InstanceData{Title: "resuming", Path: t.TempDir(),
+		Status: session.Paused, Program: "claude"})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)()
+	h.list.SetSelectedInstance(0)
+	require.NoError(t, instance.BeginResume())
+
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	assert.Equal(t, stateDefault, h.state, "no kill confirmation is shown")
+	assert.Contains(t, h.errBox.String(), "while it is resuming")
+
+	// The result of the background work is applied in Update.
+	h.Update(instanceResumedMsg{instance: instance, err: fmt.Errorf("no worktree")})
+	assert.False(t, instance.Resuming())
+	assert.Equal(t, session.Paused, instance.Status)
+}
+
+func TestSelectTab(t *testing.T) {
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	press := func(key string) {
+		h.keySent = true
+		h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key), Alt: true})
+	}
+
+	press("3")
+	assert.True(t, h.tabbedWindow.IsInDiffTab())
+	assert.True(t, h.tabbedWindow.Focused())
+	press("2")
+	assert.True(t, h.tabbedWindow.IsInServerTab())
+	press("1")
+	assert.False(t, h.tabbedWindow.IsInDiffTab())
+	assert.False(t, h.tabbedWindow.IsInServerTab())
+}
+
+func TestSuspend(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	suspend := tea.KeyMsg{Type: tea.KeyCtrlZ}
+
+	_, cmd := h.handleKeyPress(suspend)
+	require.NotNil(t, cmd)
+	assert.Equal(t, tea.SuspendMsg{}, cmd())
+
+	// ctrl+z suspends even while typing, and the input is kept for after the resume.
+	h.state = stateRunCommand
+	h.textInputOverlay = overlay.NewTextInputOverlay("Command", "make")
+	_, cmd = h.handleKeyPress(suspend)
+	require.NotNil(t, cmd)
+	assert.Equal(t, tea.SuspendMsg{}, cmd())
+	assert.Equal(t, stateRunCommand, h.state)
+	assert.Equal(t, "make", h.textInputOverlay.GetValue())
+
+	_, cmd = h.Update(tea.ResumeMsg{})
+	assert.NotNil(t, cmd, "the TUI is redrawn after a resume")
+}
+
+func TestCommandResult(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	h.errBox.SetSize(200, 1)
+	result := commandFinishedMsg{title: "task", result: session.CommandResult{Command: "make test", Output: "ok"}}
+
+	// A result that arrives while typing doesn't replace the input.
+	h.state = stateRunCommand
+	h.textInputOverlay = overlay.NewTextInputOverlay("Command", "make")
+	h.Update(result)
+	assert.Equal(t, stateRunCommand, h.state)
+	assert.Equal(t, "make", h.textInputOverlay.GetValue())
+	assert.Contains(t, h.errBox.String(), "press = to see its output")
+
+	h.state = stateDefault
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("=")})
+	assert.Equal(t, stateHelp, h.state)
+	assert.Contains(t, h.textOverlay.Render(), "$ make test (task)")
+}
+
+func TestPinnedErrors(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    &config.Config{PinErrorsLongerThan: 20},
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	h.errBox.SetSize(100, 1)
+
+	// Short errors are hidden by their timer.
+	require.NotNil(t, h.handleError(fmt.Errorf("short")))
+	h.Update(hideErrMsg{})
+	assert.NotContains(t, h.errBox.String(), "short")
+
+	// Long errors stay until a key is pressed, which is still handled.
+	assert.Nil(t, h.handleError(fmt.Errorf("git failed: fatal: a long explanation")))
+	h.Update(hideErrMsg{})
+	assert.Contains(t, h.errBox.String(), "git failed")
+	assert.Contains(t, h.errBox.String(), "press any key")
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyTab})
+	assert.NotContains(t, h.errBox.String(), "git failed")
+	assert.True(t, h.tabbedWindow.Focused())
+}
+
+func TestAutoYesIndicator(t *testing.T) {
+	tests := []struct {
+		name            string
+		globalAutoYes   bool
+		instanceAutoYes bool
+		expected        bool
+	}{
+		{name: "off", expected: false},
+		{name: "on globally", globalAutoYes: true, expected: true},
+		{name: "on for an instance", instanceAutoYes: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+			require.NoError(t, err)
+			instance.AutoYes = tt.instanceAutoYes
+
+			s := spinner.New()
+			h := &home{ctx: context.Background(), list: ui.NewList(&s, tt.globalAutoYes), menu: ui.NewMenu(), autoYes: tt.globalAutoYes}
+			h.list.AddInstance(instance)
+			assert.Equal(t, tt.expected, h.autoYesActive())
+
+			h.menu.SetAutoYes(h.autoYesActive())
+			assert.Equal(t, tt.expected, strings.Contains(h.menu.String(), "AUTO-YES"))
+		})
+	}
+}
+
+func TestDevServerEditField(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	repoPath := t.TempDir()
+	require.NoError(t, config.SaveDevServerSettings(&config.DevServerSettings{
+		BuildCommand: "npm ci",
+		DevCommand:   "npm run dev",
+		Env:          map[string]string{"PORT": "3000"},
+	}, repoPath))
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: repoPath, Program: "claude"})
+	require.NoError(t, err)
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	h.list.AddInstance(instance)
+	press := func(msg tea.KeyMsg) {
+		h.keySent = true
+		h.handleKeyPress(msg)
+	}
+
+	// Pick the env from the menu.
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	require.Equal(t, stateSelectDevServerField, h.state)
+	for range devServerFieldEnv {
+		press(tea.KeyMsg{Type: tea.KeyDown})
+	}
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	require.Equal(t, stateDevServerConfig, h.state)
+	assert.Equal(t, "PORT=3000", h.textInputOverlay.GetValue())
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(" DEBUG=1")})
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, stateDefault, h.state)
+
+	settings, err := config.LoadDevServerSettings(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PORT": "3000", "DEBUG": "1"}, settings.Env)
+	assert.Equal(t, "npm ci", settings.BuildCommand, "the other settings are kept")
+	assert.Equal(t, "npm run dev", settings.DevCommand)
+	require.NotNil(t, instance.DevServer)
+	assert.Equal(t, settings.Env, instance.DevServer.Config().Env)
+}
+
+// memoryAppState is an AppState that keeps the seen help screens in memory.
+type memoryAppState map[string]bool
+
+func (s memoryAppState) IsHelpScreenSeen(name string) bool { return s[name] }
+
+func (s memoryAppState) SetHelpScreenSeen(name string) error {
+	s[name] = true
+	return nil
+}
+
+func (s memoryAppState) ResetHelpScreensSeen() error {
+	clear(s)
+	return nil
+}
+
+func TestShowOnboarding(t *testing.T) {
+	newTestHome := func(appState memoryAppState) *home {
+		s := spinner.New()
+		return &home{ctx: context.Background(), list: ui.NewList(&s, false), appState: appState}
+	}
+
+	appState := memoryAppState{}
+	h := newTestHome(appState)
+	h.showOnboarding()
+	assert.Equal(t, stateHelp, h.state)
+	assert.Contains(t, h.textOverlay.Render(), "Welcome")
+	assert.True(t, appState["onboarding"])
+
+	// It is only shown once.
+	h = newTestHome(appState)
+	h.showOnboarding()
+	assert.Equal(t, stateDefault, h.state)
+
+	// Users who created instances before don't need it.
+	h = newTestHome(memoryAppState{"instance_start": true})
+	h.showOnboarding()
+	assert.Equal(t, stateDefault, h.state)
+
+	h = newTestHome(memoryAppState{})
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)
+	h.showOnboarding()
+	assert.Equal(t, stateDefault, h.state)
+}
+
+func TestResetHelpScreens(t *testing.T) {
+	appState := memoryAppState{"general": true, "onboarding": true, "instance_start": true}
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+		appState:     appState,
+	}
+	press := func(msg tea.KeyMsg) tea.Cmd {
+		h.keySent = true
+		_, cmd := h.handleKeyPress(msg)
+		return cmd
+	}
+
+	// Declining keeps the seen help screens.
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	assert.Equal(t, stateConfirm, h.state)
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Len(t, appState, 3)
+
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("H")})
+	cmd := press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Empty(t, appState)
+	require.NotNil(t, cmd)
+	assert.Equal(t, helpScreensResetMsg{}, cmd())
+
+	h.showOnboarding()
+	assert.Equal(t, stateHelp, h.state, "the first-time help is shown again")
+}
+
+func TestAttachCmd(t *testing.T) {
+	attached := false
+	attach := func() { attached = true }
+
+	// The mouse reporting is turned off before attaching and back on afterwards.
+	s := spinner.New()
+	h := &home{ctx: context.Background(), appConfig: config.DefaultConfig(), list: ui.NewList(&s, false)}
+	h.appConfig.MouseEnabled = true
+	// tea.Sequence's message is unexported, it is a slice of the Cmds in order.
+	cmds := reflect.ValueOf(h.attachCmd(attach)())
+	require.Equal(t, reflect.Slice, cmds.Kind())
+	require.Equal(t, 2, cmds.Len())
+	assert.IsType(t, tea.DisableMouse(), cmds.Index(0).Interface().(tea.Cmd)())
+	attachMessage, ok := cmds.Index(1).Interface().(tea.Cmd)().(attachMsg)
+	require.True(t, ok)
+	_, cmd := h.Update(attachMessage)
+	assert.True(t, attached)
+	require.NotNil(t, cmd)
+	assert.IsType(t, tea.EnableMouseCellMotion(), cmd())
+
+	// Without mouse support there is nothing to release.
+	attached = false
+	h.appConfig.MouseEnabled = false
+	attachMessage, ok = h.attachCmd(attach)().(attachMsg)
+	require.True(t, ok)
+	_, cmd = h.Update(attachMessage)
+	assert.True(t, attached)
+	assert.Nil(t, cmd)
+}
+
+func TestShowReport(t *testing.T) {
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)
+
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	assert.Equal(t, stateHelp, h.state)
+	assert.Contains(t, h.textOverlay.Render(), "COMMITS")
+	assert.Contains(t, h.shownReport, "PUSHED")
+	assert.NotContains(t, h.shownReport, "test", "instances that haven't started are left out")
+
+	// Closing with a key other than c doesn't copy it.
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Empty(t, h.shownReport)
+}
+
+// keysRecordingBackend is a session backend that records the keys sent to it.
+type keysRecordingBackend struct {
+	session.Backend
+	keys *[]string
+}
+
+func (b keysRecordingBackend) DoesSessionExist() bool { return true }
+
+func (b keysRecordingBackend) Restore() error { return nil }
+
+func (b keysRecordingBackend) SendKeys(keys string) error {
+	*b.keys = append(*b.keys, keys)
+	return nil
+}
+
+func (b keysRecordingBackend) TapEnter() error { return nil }
+
+func TestSeededPromptIsPasted(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	t.Setenv("HOME", t.TempDir())
+
+	var sent []string
+	defer func(newBackend func(name, program string) session.Backend) { session.NewBackend = newBackend }(session.NewBackend)
+	session.NewBackend = func(name, program string) session.Backend {
+		return keysRecordingBackend{keys: &sent}
+	}
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+		appState:     config.LoadStateForRepo(t.TempDir()),
+	}
+	instance, err := session.FromInstanceData(session.InstanceData{Title: "test", Path: t.TempDir(), Status: session.Ready, Program: "claude"})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)
+
+	// An unedited prompt file keeps its lines in one paste.
+	h.state = statePrompt
+	h.showPromptOverlay("fix the tests\nthen run them")
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, []string{"\x1b[200~fix the tests\nthen run them\x1b[201~"}, sent)
+
+	// An edited prompt is sent as typed.
+	sent = nil
+	h.state = statePrompt
+	h.showPromptOverlay("fix the tests\nthen run them")
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("!")})
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, []string{"fix the tests then run them!"}, sent)
+}
+
+// captureCountingBackend is a session backend that counts how often its pane is captured.
+type captureCountingBackend struct {
+	session.Backend
+	captures *int
+}
+
+func (b captureCountingBackend) DoesSessionExist() bool { return true }
+
+func (b captureCountingBackend) Restore() error { return nil }
+
+func (b captureCountingBackend) CapturePaneContent() (string, error) {
+	*b.captures++
+	return "output", nil
+}
+
+func TestPreviewTickCapturesSelectedInstanceOnly(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	captures := make(map[string]*int)
+	defer func(newBackend func(name, program string) session.Backend) { session.NewBackend = newBackend }(session.NewBackend)
+	session.NewBackend = func(name, program string) session.Backend {
+		captures[name] = new(int)
+		return captureCountingBackend{captures: captures[name]}
+	}
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	for _, title := range []string{"a", "b", "c"} {
+		instance, err := session.FromInstanceData(session.InstanceData{Title: title, Path: t.TempDir(), Status: session.Ready, Program: "claude"})
+		require.NoError(t, err)
+		h.list.AddInstance(instance)
+	}
+	h.list.SetSelectedInstance(1)
+
+	for range 3 {
+		h.Update(previewTickMsg{})
+	}
+	assert.Equal(t, 0, *captures["a"])
+	assert.Equal(t, 3, *captures["b"])
+	assert.Equal(t, 0, *captures["c"])
+
+	// Another instance is captured once it is selected.
+	h.list.SetSelectedInstance(2)
+	h.Update(previewTickMsg{})
+	assert.Equal(t, 1, *captures["c"])
+	assert.Equal(t, 3, *captures["b"])
+}
+
+func TestQuitKey(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	t.Setenv("HOME", t.TempDir())
+
+	defer func(newBackend func(name, program string) session.Backend) { session.NewBackend = newBackend }(session.NewBackend)
+	session.NewBackend = func(name, program string) session.Backend {
+		return captureCountingBackend{captures: new(int)}
+	}
+
+	storage, err := session.NewStorage(config.DefaultState())
+	require.NoError(t, err)
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+		storage:      storage,
+	}
+	instance, err := session.FromInstanceData(session.InstanceData{Title: "busy", Path: t.TempDir(), Status: session.Running, Program: "claude"})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)
+	press := func(key string) tea.Cmd {
+		h.keySent = true
+		_, cmd := h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		return cmd
+	}
+	quits := func(cmd tea.Cmd) bool {
+		if cmd == nil {
+			return false
+		}
+		_, ok := cmd().(tea.QuitMsg)
+		return ok
+	}
+
+	assert.ErrorContains(t, keys.SetQuitKey("n"), "already bound")
+	require.NoError(t, keys.SetQuitKey("Q"))
+	defer func() { require.NoError(t, keys.SetQuitKey("q")) }()
+	assert.Equal(t, "Q", quitKey())
+	assert.False(t, quits(press("q")))
+	assert.True(t, quits(press("Q")))
+
+	// Quitting while an agent works asks first.
+	h.appConfig.ConfirmQuit = true
+	assert.Nil(t, press("Q"))
+	assert.Equal(t, stateConfirm, h.state)
+	assert.Contains(t, h.confirmationOverlay.Render(), "1 agents are still working")
+	cmd := press("y")
+	require.NotNil(t, cmd)
+	assert.Equal(t, quitConfirmedMsg{}, cmd())
+}
+
+func TestWatchDevServerNeedsDevServer(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New()
+	h := &home{
+		ctx:          context.Background(),
+		appConfig:    config.DefaultConfig(),
+		list:         ui.NewList(&s, false),
+		menu:         ui.NewMenu(),
+		errBox:       ui.NewErrBox(),
+		tabbedWindow: ui.NewTabbedWindow(ui.NewPreviewPane(), ui.NewDiffPane()),
+	}
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+	h.list.AddInstance(instance)
+
+	h.keySent = true
+	h.handleKeyPress(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("V")})
+	assert.Equal(t, stateDefault, h.state)
+	assert.Contains(t, h.errBox.String(), "no dev server configured")
+}
@@ -0,0 +1,103 @@
+package app
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PromptBatch is a list of prompts dispatched round-robin to new instances on launch.
+type PromptBatch struct {
+	// Name prefixes the titles of the created instances, which are numbered from 1.
+	Name string
+	// Prompts are the prompts to dispatch, usually one per line of a file.
+	Prompts []string
+	// Instances is the number of instances to create. Zero creates one instance per prompt.
+	Instances int
+}
+
+// assignRoundRobin distributes prompts over n instances: prompt i goes to instance i mod n. Each
+// instance gets at least one prompt, so n is capped at the number of prompts.
+func assignRoundRobin(prompts []string, n int) [][]string {
+	n = min(n, len(prompts))
+	if n <= 0 {
+		return nil
+	}
+	assigned := make([][]string, n)
+	for i, prompt := range prompts {
+		assigned[i%n] = append(assigned[i%n], prompt)
+	}
+	return assigned
+}
+
+// batchTitles returns n titles "<name>-<k>" that aren't taken yet, numbering from 1.
+func batchTitles(name string, n int, taken map[string]bool) []string {
+	titles := make([]string, 0, n)
+	for k := 1; len(titles) < n; k++ {
+		title := fmt.Sprintf("%s-%d", name, k)
+		if !taken[title] {
+			titles = append(titles, title)
+		}
+	}
+	return titles
+}
+
+// createBatchInstances returns a Cmd that creates and starts the instances of batch in repoPath.
+// Prompts are spread over fewer instances when GlobalInstanceLimit doesn't leave room for all of
+// them, so no prompt is dropped. Instances with several prompts get them joined into one, separated
+// by blank lines, and send it once the agent is ready like a startup instance.
+func (m *home) createBatchInstances(repoPath string, batch PromptBatch) tea.Cmd {
+	requested := batch.Instances
+	if requested <= 0 {
+		requested = len(batch.Prompts)
+	}
+	free := GlobalInstanceLimit - m.numInstancesTowardLimit()
+	if free <= 0 {
+		return m.startInstancesCmd("batch", nil,
+			fmt.Errorf("skipped prompts file: you can't create more than %d instances", GlobalInstanceLimit), nil)
+	}
+
+	var skipped error
+	if requested > free {
+		skipped = fmt.Errorf("created %d instances instead of %d: you can't create more than %d instances",
+			free, requested, GlobalInstanceLimit)
+		requested = free
+	}
+
+	taken := make(map[string]bool)
+	for _, instance := range m.list.GetInstances() {
+		taken[instance.Title] = true
+	}
+	assigned := assignRoundRobin(batch.Prompts, requested)
+	titles := batchTitles(batch.Name, len(assigned), taken)
+
+	opts := make([]session.InstanceOptions, 0, len(assigned))
+	for i, prompts := range assigned {
+		opts = append(opts, session.InstanceOptions{
+			Title:               titles[i],
+			Path:                repoPath,
+			Program:             m.program,
+			Prompt:              strings.Join(prompts, "\n\n"),
+			Container:           m.appConfig.Container,
+			TmuxStartupCommands: m.appConfig.TmuxStartupCommands,
+		})
+	}
+	return m.startInstancesCmd("batch", opts, skipped, func(created int) string {
+		return fmt.Sprintf("Created %d instances for %d prompts", created, len(batch.Prompts))
+	})
+}
+
+// dispatchPromptBatch returns a Cmd that creates the instances of batch in the current directory
+// and reports how many were created.
+func (m *home) dispatchPromptBatch(batch PromptBatch) tea.Cmd {
+	repoPath, err := filepath.Abs(".")
+	if err != nil {
+		return func() tea.Msg {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+	}
+	return m.createBatchInstances(repoPath, batch)
+}
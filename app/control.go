@@ -0,0 +1,278 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// controlRequestTimeout bounds how long a control request waits for the UI to handle it.
+const controlRequestTimeout = 30 * time.Second
+
+// controlRequestMsg asks the UI loop to run a control API request, so instances are only ever
+// touched from Update. The response is sent on reply, which must be buffered.
+type controlRequestMsg struct {
+	action string
+	title  string
+	prompt string
+	reply  chan controlResponse
+}
+
+type controlResponse struct {
+	status int
+	body   any
+}
+
+// controlInstance is the JSON representation of an instance in the control API.
+type controlInstance struct {
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	Branch    string `json:"branch"`
+	Path      string `json:"path"`
+	Program   string `json:"program"`
+	AutoYes   bool   `json:"auto_yes"`
+	Added     int    `json:"added"`
+	Removed   int    `json:"removed"`
+	DevServer string `json:"dev_server,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// startControlServer serves the control API configured by config.Config.ControlSocket. send
+// delivers requests to the UI loop. The returned function shuts the server down; it is a no-op
+// when the API is disabled.
+func startControlServer(cfg *config.Config, send func(tea.Msg)) (stop func(), err error) {
+	network, address, err := cfg.GetControlListener()
+	if err != nil {
+		return func() {}, err
+	}
+	if network == "" {
+		return func() {}, nil
+	}
+
+	token, err := config.LoadControlToken()
+	if err != nil {
+		return func() {}, err
+	}
+
+	if network == "unix" {
+		// A socket left behind by a crashed run would make Listen fail. Anything else at the path
+		// isn't ours to delete.
+		if info, err := os.Lstat(address); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return func() {}, fmt.Errorf("control socket path %s exists and is not a socket", address)
+			}
+			if err := os.Remove(address); err != nil {
+				return func() {}, fmt.Errorf("failed to remove stale control socket: %w", err)
+			}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return func() {}, fmt.Errorf("failed to check control socket path: %w", err)
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return func() {}, fmt.Errorf("failed to listen on control socket %s: %w", address, err)
+	}
+	if network == "unix" {
+		if err := os.Chmod(address, 0600); err != nil {
+			listener.Close()
+			return func() {}, fmt.Errorf("failed to restrict control socket permissions: %w", err)
+		}
+	}
+
+	server := &http.Server{Handler: newControlHandler(send, token)}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.ErrorLog.Printf("control server stopped: %v", err)
+		}
+	}()
+	log.InfoLog.Printf("control API listening on %s %s", network, address)
+
+	return func() {
+		if err := server.Close(); err != nil {
+			log.WarningLog.Printf("failed to close control server: %v", err)
+		}
+	}, nil
+}
+
+// newControlHandler returns the control API routes:
+//
+//	GET  /instances                 status of all instances
+//	POST /instances/{title}/pause   pause an instance
+//	POST /instances/{title}/resume  resume a paused instance
+//	POST /instances/{title}/prompt  send {"prompt": "..."} to an instance
+//
+// Requests must carry token as a bearer token, and POSTs must be JSON, see requireControlAuth.
+func newControlHandler(send func(tea.Msg), token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /instances", func(w http.ResponseWriter, r *http.Request) {
+		dispatchControlRequest(w, r, send, controlRequestMsg{action: "list"})
+	})
+	mux.HandleFunc("POST /instances/{title}/{action}", func(w http.ResponseWriter, r *http.Request) {
+		req := controlRequestMsg{action: r.PathValue("action"), title: r.PathValue("title")}
+		switch req.action {
+		case "pause", "resume":
+		case "prompt":
+			var body struct {
+				Prompt string `json:"prompt"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeControlJSON(w, http.StatusBadRequest, controlError(fmt.Errorf("invalid request body: %w", err)))
+				return
+			}
+			req.prompt = body.Prompt
+		default:
+			writeControlJSON(w, http.StatusNotFound, controlError(fmt.Errorf("unknown action '%s'", req.action)))
+			return
+		}
+		dispatchControlRequest(w, r, send, req)
+	})
+	return requireControlAuth(token, mux)
+}
+
+// requireControlAuth guards the control API against other local users and web pages. The Host must
+// be a loopback name, which defeats DNS rebinding, and POSTs must be JSON, which browsers can't send
+// cross-origin without a preflight. The bearer token keeps out everyone who can't read the config
+// directory.
+func requireControlAuth(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !config.IsLoopbackHost(strings.Trim(host, "[]")) {
+			writeControlJSON(w, http.StatusForbidden, controlError(fmt.Errorf("host %q is not allowed", r.Host)))
+			return
+		}
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			writeControlJSON(w, http.StatusUnauthorized, controlError(fmt.Errorf("missing or invalid token")))
+			return
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+				writeControlJSON(w, http.StatusUnsupportedMediaType, controlError(fmt.Errorf("requests must be application/json")))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dispatchControlRequest hands req to the UI loop and writes its response.
+func dispatchControlRequest(w http.ResponseWriter, r *http.Request, send func(tea.Msg), req controlRequestMsg) {
+	req.reply = make(chan controlResponse, 1)
+	ctx, cancel := context.WithTimeout(r.Context(), controlRequestTimeout)
+	defer cancel()
+
+	go send(req)
+	select {
+	case resp := <-req.reply:
+		writeControlJSON(w, resp.status, resp.body)
+	case <-ctx.Done():
+		writeControlJSON(w, http.StatusServiceUnavailable, controlError(fmt.Errorf("timed out waiting for the UI")))
+	}
+}
+
+func writeControlJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.WarningLog.Printf("failed to write control response: %v", err)
+	}
+}
+
+func controlError(err error) map[string]string {
+	return map[string]string{"error": err.Error()}
+}
+
+// handleControlRequest runs a control API request against the instance list. It is called from
+// Update.
+func (m *home) handleControlRequest(req controlRequestMsg) (controlResponse, tea.Cmd) {
+	if req.action == "list" {
+		instances := make([]controlInstance, 0, m.list.NumInstances())
+		for _, instance := range m.list.GetInstances() {
+			instances = append(instances, newControlInstance(instance))
+		}
+		return controlResponse{status: http.StatusOK, body: instances}, nil
+	}
+
+	var instance *session.Instance
+	for _, candidate := range m.list.GetInstances() {
+		if candidate.Title == req.title {
+			instance = candidate
+			break
+		}
+	}
+	if instance == nil {
+		return controlResponse{status: http.StatusNotFound, body: controlError(fmt.Errorf("no instance named '%s'", req.title))}, nil
+	}
+
+	var cmd tea.Cmd
+	var err error
+	switch req.action {
+	case "pause":
+		if instance.Paused() {
+			err = fmt.Errorf("instance '%s' is already paused", instance.Title)
+			break
+		}
+		if err = instance.Pause(); err == nil {
+			cmd = m.instanceChanged()
+		}
+	case "resume":
+		if !instance.Paused() {
+			err = fmt.Errorf("instance '%s' is not paused", instance.Title)
+			break
+		}
+		if err = instance.Resume(); err == nil {
+			cmd = tea.WindowSize()
+		}
+	case "prompt":
+		switch {
+		case req.prompt == "":
+			return controlResponse{status: http.StatusBadRequest, body: controlError(fmt.Errorf("prompt is empty"))}, nil
+		case !instance.Started() || instance.Paused():
+			err = fmt.Errorf("instance '%s' is not running", instance.Title)
+		default:
+			err = instance.SendPrompt(req.prompt)
+		}
+	default:
+		return controlResponse{status: http.StatusNotFound, body: controlError(fmt.Errorf("unknown action '%s'", req.action))}, nil
+	}
+	if err != nil {
+		log.WarningLog.Printf("control request %s for %s failed: %v", req.action, req.title, err)
+		return controlResponse{status: http.StatusConflict, body: controlError(err)}, nil
+	}
+	return controlResponse{status: http.StatusOK, body: newControlInstance(instance)}, cmd
+}
+
+func newControlInstance(instance *session.Instance) controlInstance {
+	result := controlInstance{
+		Title:   instance.Title,
+		Status:  instanceStatusText(instance.Status),
+		Branch:  instance.Branch,
+		Path:    instance.Path,
+		Program: instance.Command(),
+		AutoYes: instance.AutoYes,
+	}
+	if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+		result.Added, result.Removed = stats.Added, stats.Removed
+	}
+	if instance.DevServer != nil {
+		result.DevServer = devServerStatusText(instance.DevServer.Status())
+		result.URL = instance.DevServer.URL()
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"claude-squad/config"
+	"claude-squad/session"
+	"claude-squad/ui"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newControlTestHome returns a home with a single, unstarted instance named "test" and a send
+// function that handles control requests the way Update does.
+func newControlTestHome(t *testing.T) (*home, func(tea.Msg)) {
+	instance, err := session.NewInstance(session.InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+
+	s := spinner.New()
+	h := &home{
+		ctx:   context.Background(),
+		state: stateDefault,
+		list:  ui.NewList(&s, false),
+	}
+	h.list.AddInstance(instance)
+
+	send := func(msg tea.Msg) {
+		req := msg.(controlRequestMsg)
+		resp, _ := h.handleControlRequest(req)
+		req.reply <- resp
+	}
+	return h, send
+}
+
+func TestControlHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		// host, token and contentType override the valid defaults.
+		host           string
+		token          string
+		contentType    string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "lists instances", method: http.MethodGet, path: "/instances", expectedStatus: http.StatusOK, expectedBody: `"title":"test"`},
+		{name: "unknown instance", method: http.MethodPost, path: "/instances/missing/pause", expectedStatus: http.StatusNotFound, expectedBody: "no instance named 'missing'"},
+		{name: "unknown action", method: http.MethodPost, path: "/instances/test/list", expectedStatus: http.StatusNotFound, expectedBody: "unknown action"},
+		{name: "resume requires a paused instance", method: http.MethodPost, path: "/instances/test/resume", expectedStatus: http.StatusConflict, expectedBody: "not paused"},
+		{name: "prompt requires a running instance", method: http.MethodPost, path: "/instances/test/prompt", body: `{"prompt": "hi"}`, expectedStatus: http.StatusConflict, expectedBody: "not running"},
+		{name: "prompt must not be empty", method: http.MethodPost, path: "/instances/test/prompt", body: `{}`, expectedStatus: http.StatusBadRequest, expectedBody: "prompt is empty"},
+		{name: "prompt body must be JSON", method: http.MethodPost, path: "/instances/test/prompt", body: `hi`, expectedStatus: http.StatusBadRequest, expectedBody: "invalid request body"},
+		{name: "only GET lists instances", method: http.MethodPost, path: "/instances", expectedStatus: http.StatusMethodNotAllowed},
+		{name: "requires the token", method: http.MethodGet, path: "/instances", token: "wrong", expectedStatus: http.StatusUnauthorized, expectedBody: "invalid token"},
+		{name: "rejects other hosts", method: http.MethodGet, path: "/instances", host: "attacker.example:7777", expectedStatus: http.StatusForbidden, expectedBody: "not allowed"},
+		{name: "POSTs must be JSON", method: http.MethodPost, path: "/instances/test/pause", contentType: "text/plain", expectedStatus: http.StatusUnsupportedMediaType, expectedBody: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, send := newControlTestHome(t)
+			handler := newControlHandler(send, "secret")
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Host = "127.0.0.1:7777"
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			token := "secret"
+			if tt.token != "" {
+				token = tt.token
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			contentType := "application/json"
+			if tt.contentType != "" {
+				contentType = tt.contentType
+			}
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestStartControlServer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	t.Run("disabled by default", func(t *testing.T) {
+		stop, err := startControlServer(&config.Config{}, func(tea.Msg) {})
+		require.NoError(t, err)
+		stop()
+	})
+
+	t.Run("rejects non-loopback addresses", func(t *testing.T) {
+		stop, err := startControlServer(&config.Config{ControlSocket: "0.0.0.0:0"}, func(tea.Msg) {})
+		require.Error(t, err)
+		stop()
+	})
+
+	t.Run("serves on a unix socket", func(t *testing.T) {
+		_, send := newControlTestHome(t)
+		socket := filepath.Join(t.TempDir(), "cs.sock")
+		stop, err := startControlServer(&config.Config{ControlSocket: socket}, send)
+		require.NoError(t, err)
+		defer stop()
+
+		client := http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+		token, err := config.LoadControlToken()
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodGet, "http://localhost/instances", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var instances []controlInstance
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&instances))
+		require.Len(t, instances, 1)
+		assert.Equal(t, "test", instances[0].Title)
+		assert.Equal(t, "claude", instances[0].Program)
+	})
+
+	t.Run("leaves other files at the socket path alone", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notes.txt")
+		require.NoError(t, os.WriteFile(path, []byte("keep"), 0644))
+		stop, err := startControlServer(&config.Config{ControlSocket: path}, func(tea.Msg) {})
+		require.ErrorContains(t, err, "not a socket")
+		stop()
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "keep", string(data))
+	})
+}
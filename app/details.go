@@ -0,0 +1,121 @@
+package app
+
+import (
+	"claude-squad/session"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// instanceDetails renders a read-only summary of everything we know about an instance.
+func instanceDetails(instance *session.Instance) string {
+	lines := []string{
+		titleStyle.Render(instance.Title),
+		"",
+		headerStyle.Render("Session:"),
+		detailLine("Status", instanceStatusText(instance.Status)),
+		detailLine("Program", instance.Command()),
+		detailLine("Container", containerDetail(instance)),
+		detailLine("Auto-yes", fmt.Sprintf("%t", instance.AutoYes)),
+		detailLine("Labels", strings.Join(instance.Labels, ", ")),
+		detailLine("Created", formatDetailTime(instance.CreatedAt)),
+		detailLine("Updated", formatDetailTime(instance.UpdatedAt)),
+		"",
+		headerStyle.Render("Git:"),
+		detailLine("Branch", instance.Branch),
+	}
+
+	if worktree, err := instance.GetGitWorktree(); err == nil && worktree != nil {
+		lines = append(lines,
+			detailLine("Base commit", worktree.GetBaseCommitSHA()),
+			detailLine("Worktree", worktree.GetWorktreePath()),
+			detailLine("Repository", worktree.GetRepoPath()),
+		)
+	} else {
+		lines = append(lines, detailLine("Repository", instance.Path))
+	}
+
+	diff := "-"
+	if stats := instance.GetDiffStats(); stats != nil && stats.Error == nil {
+		diff = fmt.Sprintf("+%d, -%d", stats.Added, stats.Removed)
+	}
+	lines = append(lines, detailLine("Diff", diff))
+	if ahead, behind := instance.BaseDivergence(); ahead > 0 || behind > 0 {
+		lines = append(lines, detailLine("Vs. base", fmt.Sprintf("%d ahead, %d behind", ahead, behind)))
+	}
+
+	lines = append(lines, "", headerStyle.Render("Dev server:"))
+	if instance.DevServer == nil {
+		lines = append(lines, detailLine("Status", "not configured"))
+	} else {
+		cfg := instance.DevServer.Config()
+		lines = append(lines,
+			detailLine("Status", devServerStatusText(instance.DevServer.Status())),
+			detailLine("Build command", cfg.BuildCommand),
+			detailLine("Dev command", cfg.DevCommand),
+			detailLine("Working dir", cfg.WorkingDir),
+			detailLine("URL", instance.DevServer.URL()),
+			detailLine("Crash count", fmt.Sprintf("%d", instance.DevServer.CrashCount())),
+		)
+	}
+
+	lines = append(lines, "", descStyle.Render("Press any key to close"))
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// detailLine renders a single label/value pair, showing a dash for empty values.
+func detailLine(label, value string) string {
+	if value == "" {
+		value = "-"
+	}
+	return keyStyle.Render(fmt.Sprintf("%-14s", label)) + descStyle.Render(value)
+}
+
+func formatDetailTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC822)
+}
+
+func instanceStatusText(status session.Status) string {
+	switch status {
+	case session.Running:
+		return "running"
+	case session.Ready:
+		return "ready"
+	case session.Loading:
+		return "loading"
+	case session.Paused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+func devServerStatusText(status session.DevServerStatus) string {
+	switch status {
+	case session.DevServerStopped:
+		return "stopped"
+	case session.DevServerBuilding:
+		return "building"
+	case session.DevServerStarting:
+		return "starting"
+	case session.DevServerRunning:
+		return "running"
+	case session.DevServerCrashed:
+		return "crashed"
+	default:
+		return "unknown"
+	}
+}
+
+// containerDetail returns the image of the container the instance's program runs in, if any.
+func containerDetail(instance *session.Instance) string {
+	if instance.Container == nil {
+		return ""
+	}
+	return instance.Container.Image
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"claude-squad/keys"
 	"claude-squad/log"
 	"claude-squad/session"
 	"claude-squad/ui"
@@ -11,12 +12,17 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// quitKey returns the key that quits, which can be changed with config.Config.QuitKey.
+func quitKey() string {
+	return keys.GlobalkeyBindings[keys.KeyQuit].Help().Key
+}
+
 type helpText interface {
 	// toContent returns the help UI content.
 	toContent() string
-	// mask returns the bit mask for this help text. These are used to track which help screens
-	// have been seen in the config and app state.
-	mask() uint32
+	// name returns the unique name of this help text. It is used to track which help screens
+	// have been seen in the app state, so it must not change once released.
+	name() string
 }
 
 type helpTypeGeneral struct{}
@@ -29,8 +35,14 @@ type helpTypeInstanceAttach struct{}
 
 type helpTypeServerAttach struct{}
 
+// helpTypeServerWatch is shown before attaching to a dev server read-only.
+type helpTypeServerWatch struct{}
+
 type helpTypeInstanceCheckout struct{}
 
+// helpTypeOnboarding introduces the app on the first run, when there are no instances yet.
+type helpTypeOnboarding struct{}
+
 func helpStart(instance *session.Instance) helpText {
 	return helpTypeInstanceStart{instance: instance}
 }
@@ -44,20 +56,61 @@ func (h helpTypeGeneral) toContent() string {
 		headerStyle.Render("Managing:"),
 		keyStyle.Render("n")+descStyle.Render("         - Create a new session"),
 		keyStyle.Render("N")+descStyle.Render("         - Create a new session with a prompt"),
+		keyStyle.Render("ctrl-n")+descStyle.Render("    - Retry creating a session that just failed to start"),
+		keyStyle.Render("I")+descStyle.Render("         - Create a new session in the current checkout, without a worktree or branch"),
+		keyStyle.Render("T")+descStyle.Render("         - Create a new session from a template"),
 		keyStyle.Render("D")+descStyle.Render("         - Kill (delete) the selected session"),
+		keyStyle.Render("u")+descStyle.Render("         - Undo the last kill (within a few seconds)"),
 		keyStyle.Render("↑/j, ↓/k")+descStyle.Render("  - Navigate between sessions"),
+		keyStyle.Render("1-9, 0")+descStyle.Render("    - Jump to session by number"),
+		keyStyle.Render("ctrl-p")+descStyle.Render("    - Search sessions by name and jump to one"),
+		keyStyle.Render("P")+descStyle.Render("         - Toggle showing only paused sessions"),
+		keyStyle.Render("l")+descStyle.Render("         - Toggle compact list rows with only the title and status"),
+		keyStyle.Render("h")+descStyle.Render("         - Toggle the strip of common keys above the menu"),
+		keyStyle.Render("a, A")+descStyle.Render("      - Archive/unarchive the selected session, toggle showing archived sessions"),
+		keyStyle.Render("z, Z")+descStyle.Render("      - Collapse/expand the selected repo group, expand all groups"),
 		keyStyle.Render("↵/o")+descStyle.Render("       - Attach to the selected session"),
 		keyStyle.Render("ctrl-q")+descStyle.Render("    - Detach from session"),
+		keyStyle.Render("ctrl-r")+descStyle.Render("    - Start/stop recording the session to an asciinema cast"),
+		keyStyle.Render("y")+descStyle.Render("         - Toggle auto-yes for the selected session"),
+		keyStyle.Render("C")+descStyle.Render("         - Cycle the color of the selected session in the list"),
+		keyStyle.Render("t")+descStyle.Render("         - Pin/unpin the selected session to the top of the list"),
+		keyStyle.Render("K")+descStyle.Render("         - Restart the agent in the selected session, keeping its files"),
+		keyStyle.Render("i")+descStyle.Render("         - Show details for the selected session"),
+		keyStyle.Render("W")+descStyle.Render("         - Copy all worktree paths to the clipboard"),
+		keyStyle.Render("b")+descStyle.Render("         - Report the commits, diff and push state of all sessions"),
+		keyStyle.Render("F")+descStyle.Render("         - Send the contents of a file as a prompt"),
+		keyStyle.Render("!")+descStyle.Render("         - Run a command in the worktree and show its output"),
+		keyStyle.Render("=")+descStyle.Render("         - Show the output of the last command again"),
+		keyStyle.Render("U")+descStyle.Render("         - Recompute the diff, e.g. after running git outside the app"),
+		keyStyle.Render("space, B")+descStyle.Render("  - Mark sessions, send a prompt to all marked sessions (esc unmarks)"),
 		"",
 		headerStyle.Render("Handoff:"),
 		keyStyle.Render("p")+descStyle.Render("         - Commit and push branch to github"),
 		keyStyle.Render("c")+descStyle.Render("         - Checkout: commit changes and pause session"),
 		keyStyle.Render("r")+descStyle.Render("         - Resume a paused session"),
+		keyStyle.Render("R")+descStyle.Render("         - Rebase session onto base (aborts on conflicts)"),
+		keyStyle.Render("X")+descStyle.Render("         - Reset session to base, discarding all changes"),
+		keyStyle.Render("M")+descStyle.Render("         - Repair the session's worktree, e.g. recreate it if it was deleted"),
 		"",
 		headerStyle.Render("Other:"),
 		keyStyle.Render("tab/shift+tab")+descStyle.Render(" - Switch between tabs (forward/backward)"),
+		keyStyle.Render("alt+1-3")+descStyle.Render("   - Go to the preview, server or diff tab"),
 		keyStyle.Render("shift-↓/↑")+descStyle.Render(" - Scroll in diff view"),
-		keyStyle.Render("q")+descStyle.Render("         - Quit the application"),
+		keyStyle.Render("w")+descStyle.Render("         - Toggle ignoring whitespace changes in the diff"),
+		keyStyle.Render("v")+descStyle.Render("         - Pick a changed file to show in the diff tab, or all files"),
+		keyStyle.Render("pgup/pgdn")+descStyle.Render(" - Scroll the active pane by a page (ctrl-u/ctrl-d for half a page)"),
+		keyStyle.Render("g, G")+descStyle.Render("      - Jump to the top/bottom of the active pane (bottom follows output)"),
+		keyStyle.Render("[, ]")+descStyle.Render("      - Jump between dev server runs in the server tab"),
+		keyStyle.Render("Y")+descStyle.Render("         - Copy the diff or the dev server log of the active tab to the clipboard"),
+		keyStyle.Render("L")+descStyle.Render("         - Save the dev server log to a file"),
+		keyStyle.Render("f")+descStyle.Render("         - Toggle following the latest output in the server tab"),
+		keyStyle.Render("O")+descStyle.Render("         - Open the running dev server's URL in the browser"),
+		keyStyle.Render("V")+descStyle.Render("         - Watch the dev server read-only, so keys like ctrl-c don't reach it"),
+		keyStyle.Render("E")+descStyle.Render("         - Edit the repo's settings.json, e.g. dev server env"),
+		keyStyle.Render("H")+descStyle.Render("         - Show the first-time help screens again"),
+		keyStyle.Render("ctrl-z")+descStyle.Render("    - Suspend to the shell; sessions keep running, fg resumes"),
+		keyStyle.Render(quitKey())+descStyle.Render(fmt.Sprintf("%*s- Quit the application", max(10-len(quitKey()), 1), "")),
 	)
 	return content
 }
@@ -102,6 +155,16 @@ func (h helpTypeServerAttach) toContent() string {
 	return content
 }
 
+func (h helpTypeServerWatch) toContent() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Watching Dev Server"),
+		"",
+		descStyle.Render("The dev server is attached read-only: keys, including ctrl-c, don't reach it."),
+		descStyle.Render("To stop watching, press ")+keyStyle.Render("ctrl-q"),
+	)
+	return content
+}
+
 func (h helpTypeInstanceCheckout) toContent() string {
 	content := lipgloss.JoinVertical(lipgloss.Left,
 		titleStyle.Render("Checkout Instance"),
@@ -116,32 +179,72 @@ func (h helpTypeInstanceCheckout) toContent() string {
 	)
 	return content
 }
-func (h helpTypeGeneral) mask() uint32 {
-	return 1
+func (h helpTypeOnboarding) toContent() string {
+	content := lipgloss.JoinVertical(lipgloss.Left,
+		titleStyle.Render("Welcome to Claude Squad"),
+		"",
+		"Each session runs an agent in its own git worktree and branch, so several can work on this repo at once.",
+		"",
+		headerStyle.Render("Getting started:"),
+		keyStyle.Render("n")+descStyle.Render("      - Create a session: enter a title, then press enter"),
+		keyStyle.Render("N")+descStyle.Render("      - Create a session and send it a prompt right away"),
+		keyStyle.Render("↵/o")+descStyle.Render("    - Attach to the selected session to work with the agent"),
+		keyStyle.Render("ctrl-q")+descStyle.Render(" - Detach from the session; the agent keeps working"),
+		keyStyle.Render("tab")+descStyle.Render("    - Switch between the preview, the diff and the dev server"),
+		"",
+		headerStyle.Render("Dev server:"),
+		keyStyle.Render("e")+descStyle.Render("      - Configure the build and dev commands of this repo"),
+		keyStyle.Render("E")+descStyle.Render("      - Edit all settings, e.g. env, in .claude-squad/settings.json"),
+		"",
+		headerStyle.Render("More:"),
+		keyStyle.Render("?")+descStyle.Render("      - Show all key shortcuts"),
+		keyStyle.Render(quitKey())+descStyle.Render(fmt.Sprintf("%*s- Quit; sessions are saved and restored on the next start", max(7-len(quitKey()), 1), "")),
+	)
+	return content
+}
+
+func (h helpTypeGeneral) name() string {
+	return "general"
 }
 
-func (h helpTypeInstanceStart) mask() uint32 {
-	return 1 << 1
+func (h helpTypeInstanceStart) name() string {
+	return "instance_start"
+}
+func (h helpTypeInstanceAttach) name() string {
+	return "instance_attach"
 }
-func (h helpTypeInstanceAttach) mask() uint32 {
-	return 1 << 2
+func (h helpTypeServerAttach) name() string {
+	return "server_attach"
 }
-func (h helpTypeServerAttach) mask() uint32 {
-	return 1 << 4
+func (h helpTypeServerWatch) name() string {
+	return "server_watch"
 }
-func (h helpTypeInstanceCheckout) mask() uint32 {
-	return 1 << 3
+func (h helpTypeInstanceCheckout) name() string {
+	return "instance_checkout"
+}
+func (h helpTypeOnboarding) name() string {
+	return "onboarding"
+}
+
+// showOnboarding shows the onboarding screen on the first run: when there are no instances and none
+// has been created before.
+func (m *home) showOnboarding() {
+	if m.list.NumInstances() > 0 || m.appState.IsHelpScreenSeen(helpStart(nil).name()) {
+		return
+	}
+	m.showHelpScreen(helpTypeOnboarding{}, nil)
 }
 
 var (
-	titleStyle  = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("#7D56F4"))
-	headerStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#36CFC9"))
-	keyStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFCC00"))
-	descStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+	titleStyle   = lipgloss.NewStyle().Bold(true).Underline(true).Foreground(lipgloss.Color("#7D56F4"))
+	headerStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#36CFC9"))
+	keyStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFCC00"))
+	descStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
+	warningStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#de613e"))
 )
 
 // showHelpScreen displays the help screen overlay if it hasn't been shown before
-func (m *home) showHelpScreen(helpType helpText, onDismiss func()) (tea.Model, tea.Cmd) {
+func (m *home) showHelpScreen(helpType helpText, onDismiss func() tea.Cmd) (tea.Model, tea.Cmd) {
 	// Get the flag for this help type
 	var alwaysShow bool
 	switch helpType.(type) {
@@ -149,28 +252,27 @@ func (m *home) showHelpScreen(helpType helpText, onDismiss func()) (tea.Model, t
 		alwaysShow = true
 	}
 
-	flag := helpType.mask()
+	name := helpType.name()
 
 	// Check if this help screen has been seen before
-	// Only show if we're showing the general help screen or the corresponding flag is not set
-	// in the seen bitmask.
-	if alwaysShow || (m.appState.GetHelpScreensSeen()&flag) == 0 {
+	// Only show if we're showing the general help screen or it hasn't been seen yet.
+	if alwaysShow || !m.appState.IsHelpScreenSeen(name) {
 		// Mark this help screen as seen and save state
-		if err := m.appState.SetHelpScreensSeen(m.appState.GetHelpScreensSeen() | flag); err != nil {
+		if err := m.appState.SetHelpScreenSeen(name); err != nil {
 			log.WarningLog.Printf("Failed to save help screen state: %v", err)
 		}
 
 		content := helpType.toContent()
 
 		m.textOverlay = overlay.NewTextOverlay(content)
-		m.textOverlay.OnDismiss = onDismiss
+		m.onHelpDismiss = onDismiss
 		m.state = stateHelp
 		return m, nil
 	}
 
 	// Skip displaying the help screen
 	if onDismiss != nil {
-		onDismiss()
+		return m, onDismiss()
 	}
 	return m, nil
 }
@@ -181,13 +283,25 @@ func (m *home) handleHelpState(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	shouldClose := m.textOverlay.HandleKeyPress(msg)
 	if shouldClose {
 		m.state = stateDefault
-		return m, tea.Sequence(
+		var dismissCmd tea.Cmd
+		if onDismiss := m.onHelpDismiss; onDismiss != nil {
+			m.onHelpDismiss = nil
+			dismissCmd = onDismiss()
+		}
+		var copyCmd tea.Cmd
+		if report := m.shownReport; report != "" {
+			m.shownReport = ""
+			if msg.String() == "c" {
+				copyCmd = m.copyReport(report)
+			}
+		}
+		return m, tea.Batch(dismissCmd, copyCmd, tea.Sequence(
 			tea.WindowSize(),
 			func() tea.Msg {
 				m.menu.SetState(ui.StateDefault)
 				return nil
 			},
-		)
+		))
 	}
 
 	return m, nil
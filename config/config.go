@@ -2,14 +2,20 @@ package config
 
 import (
 	"claude-squad/log"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -36,8 +42,324 @@ type Config struct {
 	DaemonPollInterval int `json:"daemon_poll_interval"`
 	// BranchPrefix is the prefix used for git branches created by the application.
 	BranchPrefix string `json:"branch_prefix"`
+	// TmuxPrefix is the prefix used for tmux session names. Sessions and dev servers created under a
+	// previous prefix are renamed when they are restored after changing it.
+	TmuxPrefix string `json:"tmux_prefix"`
+	// AttachMode controls how sessions are attached to. See AttachModeTakeover and AttachModeTmuxWindow.
+	AttachMode string `json:"attach_mode"`
+	// DedupeServerOutput collapses consecutive identical dev server output lines into "line ×N".
+	DedupeServerOutput bool `json:"dedupe_server_output"`
+	// AutoYesDenyPatterns stop auto-yes from confirming a prompt when the pane contains any of them
+	// (case-insensitive), e.g. "rm -rf" or "force push". The prompt is left for manual review.
+	AutoYesDenyPatterns []string `json:"auto_yes_deny_patterns,omitempty"`
+	// PausedPreview controls what the preview pane shows for paused instances. See
+	// PausedPreviewSnapshot and PausedPreviewMessage.
+	PausedPreview string `json:"paused_preview"`
+	// MouseEnabled enables mouse support (scroll wheel in the preview and diff panes). Disable it to
+	// select and copy text with the mouse in the terminal. Defaults to true.
+	MouseEnabled bool `json:"mouse_enabled"`
+	// PromptFileMaxBytes limits how much of a file is sent when sending a file as a prompt. Larger
+	// files are truncated. Defaults to DefaultPromptFileMaxBytes when unset.
+	PromptFileMaxBytes int `json:"prompt_file_max_bytes,omitempty"`
+	// Templates are named presets for creating instances. Invalid templates are ignored at load.
+	Templates []InstanceTemplate `json:"templates,omitempty"`
+	// StartupInstances are created and started on launch in the current repo, skipping those whose
+	// title matches an existing instance. Invalid specs are ignored at load.
+	StartupInstances []StartupInstanceSpec `json:"startup_instances,omitempty"`
+	// DevServerWidth and DevServerHeight size a dev server's tmux session when it starts. The session
+	// is resized to the server pane once the UI knows its size. Default to DefaultDevServerWidth and
+	// DefaultDevServerHeight when unset.
+	DevServerWidth  int `json:"dev_server_width,omitempty"`
+	DevServerHeight int `json:"dev_server_height,omitempty"`
+	// FetchIntervalMinutes enables fetching each instance's base branch in the background, at most
+	// once per interval, to show how far instances have fallen behind it. Zero disables fetching.
+	FetchIntervalMinutes int `json:"fetch_interval_minutes,omitempty"`
+	// ControlSocket enables a small HTTP API for other tools to list instances and pause, resume or
+	// prompt them. Either an absolute Unix socket path or a loopback host:port such as
+	// "127.0.0.1:7777". Empty disables it. Requests must send the token in ControlTokenFileName as
+	// "Authorization: Bearer <token>".
+	ControlSocket string `json:"control_socket,omitempty"`
+	// OnReadyCommand is run through the shell whenever an agent finishes working (its status goes
+	// from running to ready), e.g. to send a notification. The instance title is passed as $1 and in
+	// the CS_TITLE environment variable, e.g. `notify-send "$CS_TITLE is ready"`.
+	OnReadyCommand string `json:"on_ready_command,omitempty"`
+	// BellOnReady rings the terminal bell whenever an agent finishes working.
+	BellOnReady bool `json:"bell_on_ready,omitempty"`
+	// DiffOptions controls how the diff pane and the diff stats are computed.
+	DiffOptions DiffOptions `json:"diff_options"`
+	// ProgramPatterns override how the state of a program is detected from its screen, keyed by
+	// the base name of the program's executable, e.g. "aider". Invalid patterns are ignored.
+	ProgramPatterns map[string]ProgramPatterns `json:"program_patterns,omitempty"`
+	// AutoSaveIntervalSeconds is how often the instances are saved while running, so a crash doesn't
+	// lose recent metadata such as diff stats. Zero uses DefaultAutoSaveIntervalSeconds and a
+	// negative value disables auto-save.
+	AutoSaveIntervalSeconds int `json:"auto_save_interval_seconds,omitempty"`
+	// Container runs the program of new instances in a container with the worktree mounted,
+	// e.g. {"image": "my-agent:latest"}. Nil runs programs on the host.
+	Container *ContainerConfig `json:"container,omitempty"`
+	// LogLevel is the least severe kind of message written to the log file: error, warn, info or
+	// debug. Empty means info.
+	LogLevel string `json:"log_level,omitempty"`
+	// LogFile is the path of the log file. Empty means claudesquad.log in the temp directory.
+	LogFile string `json:"log_file,omitempty"`
+	// PreviewIntervalMs is how often the preview of the selected instance is captured. Other
+	// instances are only captured when they are selected. Zero uses DefaultPreviewIntervalMs.
+	PreviewIntervalMs int `json:"preview_interval_ms,omitempty"`
+	// QuitKey is the key that quits, e.g. "Q" to not quit on a stray q. It can't be a key that is
+	// bound to another action. ctrl+c always quits. Empty means q.
+	QuitKey string `json:"quit_key,omitempty"`
+	// ConfirmQuit asks before quitting with the quit key while any agent is working.
+	ConfirmQuit bool `json:"confirm_quit,omitempty"`
+	// TmuxStartupCommands are tmux commands run against the session of a new instance once it is
+	// created, e.g. ["split-window -h", "send-keys 'git status' Enter"] to open a shell next to the
+	// agent. Commands target the session unless they have a -t option. Templates can override them.
+	TmuxStartupCommands []string `json:"tmux_startup_commands,omitempty"`
+	// CompactList renders each instance in the list on a single line with only its title and
+	// status. It is toggled from the app, which saves it here.
+	CompactList bool `json:"compact_list,omitempty"`
+	// RestoreDevServers leaves dev servers running when the app quits and restores them on the next
+	// launch, reattaching to their sessions or restarting them. Otherwise they are stopped on quit
+	// and the app offers to start them again.
+	RestoreDevServers bool `json:"restore_dev_servers,omitempty"`
+	// ShowCheatSheet shows the strip of common key bindings above the menu on launch. It can be
+	// toggled with h either way.
+	ShowCheatSheet bool `json:"show_cheat_sheet,omitempty"`
+	// ErrorDisplaySeconds is how long errors are shown. Zero uses DefaultErrorDisplaySeconds.
+	ErrorDisplaySeconds int `json:"error_display_seconds,omitempty"`
+	// PinErrorsLongerThan keeps errors with more characters shown until a key is pressed, so long
+	// failures such as git output can be read. Zero never pins errors.
+	PinErrorsLongerThan int `json:"pin_errors_longer_than,omitempty"`
+	// StaleDiffSeconds is the age after which the diff pane marks diff stats as stale. Zero uses
+	// DefaultStaleDiffSeconds; negative values only mark the diff of paused instances stale.
+	StaleDiffSeconds int `json:"stale_diff_seconds,omitempty"`
 }
 
+// ProgramPatterns are regular expressions matched against the screen of a program.
+type ProgramPatterns struct {
+	// Prompt matches while the program waits for the user to confirm something, which is what
+	// auto-yes accepts.
+	Prompt string `json:"prompt,omitempty"`
+	// Working matches while the program is working. When set, the instance shows as running only
+	// while it matches, and ready otherwise, instead of depending on whether the screen changed.
+	Working string `json:"working,omitempty"`
+}
+
+// DiffOptions are the options passed to git diff when computing an instance's changes.
+type DiffOptions struct {
+	// ContextLines is the number of unchanged lines shown around each change (git diff -U). Unset
+	// uses git's default of 3.
+	ContextLines *int `json:"context_lines,omitempty"`
+	// IgnoreWhitespace ignores whitespace when comparing lines (git diff -w), so reformatted code
+	// doesn't drown out the real changes.
+	IgnoreWhitespace bool `json:"ignore_whitespace,omitempty"`
+}
+
+// GitArgs returns the git diff flags for the options. Negative context lines are ignored.
+func (o DiffOptions) GitArgs() []string {
+	var args []string
+	if o.ContextLines != nil && *o.ContextLines >= 0 {
+		args = append(args, fmt.Sprintf("-U%d", *o.ContextLines))
+	}
+	if o.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	return args
+}
+
+const (
+	// DefaultDevServerWidth is the default width of a dev server's tmux session.
+	DefaultDevServerWidth = 200
+	// DefaultDevServerHeight is the default height of a dev server's tmux session.
+	DefaultDevServerHeight = 50
+)
+
+// GetDevServerSize returns the configured dev server session size, using the defaults for unset values.
+func (c *Config) GetDevServerSize() (width, height int) {
+	width, height = c.DevServerWidth, c.DevServerHeight
+	if width <= 0 {
+		width = DefaultDevServerWidth
+	}
+	if height <= 0 {
+		height = DefaultDevServerHeight
+	}
+	return width, height
+}
+
+// DefaultAutoSaveIntervalSeconds is the default interval between automatic saves of the instances.
+const DefaultAutoSaveIntervalSeconds = 30
+
+// DefaultErrorDisplaySeconds is how long errors are shown by default.
+const DefaultErrorDisplaySeconds = 3
+
+// GetErrorDisplayDuration returns how long errors are shown before they are hidden. A nil config
+// uses the default.
+func (c *Config) GetErrorDisplayDuration() time.Duration {
+	if c == nil || c.ErrorDisplaySeconds <= 0 {
+		return DefaultErrorDisplaySeconds * time.Second
+	}
+	return time.Duration(c.ErrorDisplaySeconds) * time.Second
+}
+
+// PinsError returns true if message is long enough to stay shown until a key is pressed. A nil
+// config never pins errors.
+func (c *Config) PinsError(message string) bool {
+	return c != nil && c.PinErrorsLongerThan > 0 && utf8.RuneCountInString(message) > c.PinErrorsLongerThan
+}
+
+// DefaultStaleDiffSeconds is the default age after which diff stats are marked as stale.
+const DefaultStaleDiffSeconds = 10
+
+// GetDiffStaleAfter returns the age after which diff stats are marked as stale, or zero if only
+// the diff of paused instances is. A nil config uses the default.
+func (c *Config) GetDiffStaleAfter() time.Duration {
+	if c == nil || c.StaleDiffSeconds == 0 {
+		return DefaultStaleDiffSeconds * time.Second
+	}
+	if c.StaleDiffSeconds < 0 {
+		return 0
+	}
+	return time.Duration(c.StaleDiffSeconds) * time.Second
+}
+
+// GetAutoSaveInterval returns the interval between automatic saves of the instances, or zero when
+// auto-save is disabled.
+func (c *Config) GetAutoSaveInterval() time.Duration {
+	switch {
+	case c.AutoSaveIntervalSeconds < 0:
+		return 0
+	case c.AutoSaveIntervalSeconds == 0:
+		return DefaultAutoSaveIntervalSeconds * time.Second
+	default:
+		return time.Duration(c.AutoSaveIntervalSeconds) * time.Second
+	}
+}
+
+// GetControlListener returns the network and address to serve the control API on, or empty strings
+// when it is disabled. Only Unix sockets and loopback addresses are accepted so the API is never
+// reachable from other machines.
+func (c *Config) GetControlListener() (network, address string, err error) {
+	if c.ControlSocket == "" {
+		return "", "", nil
+	}
+	if filepath.IsAbs(c.ControlSocket) {
+		return "unix", c.ControlSocket, nil
+	}
+	host, _, err := net.SplitHostPort(c.ControlSocket)
+	if err != nil {
+		return "", "", fmt.Errorf("control_socket: %q must be an absolute socket path or host:port: %w", c.ControlSocket, err)
+	}
+	if !IsLoopbackHost(host) {
+		return "", "", fmt.Errorf("control_socket: %q must listen on a loopback address", c.ControlSocket)
+	}
+	return "tcp", c.ControlSocket, nil
+}
+
+// IsLoopbackHost returns true if host, without a port, is localhost or a loopback IP address.
+func IsLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ControlTokenFileName is the file in the config directory holding the control API token.
+const ControlTokenFileName = "control_token"
+
+// LoadControlToken returns the token clients of the control API authenticate with, creating a
+// random one on first use. The file is only readable by the user.
+func LoadControlToken() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(configDir, ControlTokenFileName)
+	data, err := os.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		// Tighten the permissions of a token copied or created by hand.
+		if err := os.Chmod(path, 0600); err != nil {
+			return "", fmt.Errorf("failed to restrict control token permissions: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read control token: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate control token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write control token: %w", err)
+	}
+	// WriteFile keeps the permissions of an existing, empty file.
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", fmt.Errorf("failed to restrict control token permissions: %w", err)
+	}
+	return token, nil
+}
+
+// DefaultPreviewIntervalMs is the default interval between captures of the selected preview.
+const DefaultPreviewIntervalMs = 100
+
+// MinPreviewIntervalMs bounds PreviewIntervalMs so a typo can't flood tmux with captures.
+const MinPreviewIntervalMs = 20
+
+// GetPreviewInterval returns the interval between captures of the selected preview.
+func (c *Config) GetPreviewInterval() time.Duration {
+	switch {
+	case c.PreviewIntervalMs <= 0:
+		return DefaultPreviewIntervalMs * time.Millisecond
+	case c.PreviewIntervalMs < MinPreviewIntervalMs:
+		return MinPreviewIntervalMs * time.Millisecond
+	default:
+		return time.Duration(c.PreviewIntervalMs) * time.Millisecond
+	}
+}
+
+// GetLogLevel returns the configured log level. An invalid level is logged and the default is used.
+func (c *Config) GetLogLevel() log.Level {
+	level, err := log.ParseLevel(c.LogLevel)
+	if err != nil {
+		log.WarningLog.Printf("log_level: %v", err)
+	}
+	return level
+}
+
+// DefaultPromptFileMaxBytes is the default limit for files sent as a prompt.
+const DefaultPromptFileMaxBytes = 32 * 1024
+
+// GetPromptFileMaxBytes returns the configured prompt file limit, or the default when unset.
+func (c *Config) GetPromptFileMaxBytes() int {
+	if c.PromptFileMaxBytes <= 0 {
+		return DefaultPromptFileMaxBytes
+	}
+	return c.PromptFileMaxBytes
+}
+
+const (
+	// PausedPreviewSnapshot shows the screen captured when the instance was paused, under a banner.
+	PausedPreviewSnapshot = "snapshot"
+	// PausedPreviewMessage shows only a message explaining how to resume the instance.
+	PausedPreviewMessage = "message"
+)
+
+const (
+	// AttachModeTakeover attaches to sessions inside the TUI until detached.
+	AttachModeTakeover = "takeover"
+	// AttachModeTmuxWindow switches the surrounding tmux client to the session when running inside
+	// tmux, leaving the TUI running. Falls back to AttachModeTakeover outside of tmux.
+	AttachModeTmuxWindow = "tmux-window"
+)
+
+// DefaultTmuxPrefix is the prefix used for tmux session names unless TmuxPrefix overrides it.
+const DefaultTmuxPrefix = "claudesquad_"
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() *Config {
 	program, err := GetClaudeCommand()
@@ -58,6 +380,10 @@ func DefaultConfig() *Config {
 			}
 			return fmt.Sprintf("%s/", strings.ToLower(user.Username))
 		}(),
+		TmuxPrefix:    DefaultTmuxPrefix,
+		AttachMode:    AttachModeTakeover,
+		PausedPreview: PausedPreviewSnapshot,
+		MouseEnabled:  true,
 	}
 }
 
@@ -132,11 +458,14 @@ func LoadConfig() *Config {
 		return DefaultConfig()
 	}
 
-	var config Config
+	// Options that default to true keep their default when missing from older config files.
+	config := Config{MouseEnabled: true}
 	if err := json.Unmarshal(data, &config); err != nil {
 		log.ErrorLog.Printf("failed to parse config file: %v", err)
 		return DefaultConfig()
 	}
+	config.Templates = validTemplates(config.Templates)
+	config.StartupInstances = validStartupInstances(config.StartupInstances)
 
 	return &config
 }
@@ -165,3 +494,45 @@ func saveConfig(config *Config) error {
 func SaveConfig(config *Config) error {
 	return saveConfig(config)
 }
+
+// SaveConfigField sets the option key of the config file to value and leaves the rest of the file
+// as it is. Unlike SaveConfig, it keeps the entries LoadConfig ignores, like invalid templates, and
+// keys it doesn't know.
+func SaveConfigField(key string, value any) error {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	configPath := filepath.Join(configDir, ConfigFileName)
+
+	fields := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(configPath)
+	switch {
+	case os.IsNotExist(err):
+		// Start from the defaults, as LoadConfig does without a config file.
+		data, err = json.Marshal(DefaultConfig())
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	fields[key] = raw
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
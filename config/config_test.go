@@ -2,11 +2,13 @@ package config
 
 import (
 	"claude-squad/log"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -108,6 +110,7 @@ func TestDefaultConfig(t *testing.T) {
 		assert.Equal(t, 1000, config.DaemonPollInterval)
 		assert.NotEmpty(t, config.BranchPrefix)
 		assert.True(t, strings.HasSuffix(config.BranchPrefix, "/"))
+		assert.True(t, config.MouseEnabled)
 	})
 
 }
@@ -172,6 +175,23 @@ func TestLoadConfig(t *testing.T) {
 		assert.True(t, config.AutoYes)
 		assert.Equal(t, 2000, config.DaemonPollInterval)
 		assert.Equal(t, "test/", config.BranchPrefix)
+		assert.True(t, config.MouseEnabled) // Missing options keep their default
+	})
+
+	t.Run("loads disabled mouse support", func(t *testing.T) {
+		tempHome := t.TempDir()
+		configDir := filepath.Join(tempHome, ".claude-squad")
+		require.NoError(t, os.MkdirAll(configDir, 0755))
+		configPath := filepath.Join(configDir, ConfigFileName)
+		require.NoError(t, os.WriteFile(configPath, []byte(`{"mouse_enabled": false}`), 0644))
+
+		originalHome := os.Getenv("HOME")
+		os.Setenv("HOME", tempHome)
+		defer os.Setenv("HOME", originalHome)
+
+		config := LoadConfig()
+
+		assert.False(t, config.MouseEnabled)
 	})
 
 	t.Run("returns default config on invalid JSON", func(t *testing.T) {
@@ -237,3 +257,505 @@ func TestSaveConfig(t *testing.T) {
 		assert.Equal(t, testConfig.BranchPrefix, loadedConfig.BranchPrefix)
 	})
 }
+
+func TestLoadStateMigratesHelpScreensSeen(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]bool
+	}{
+		{
+			name:     "converts the legacy bitmask",
+			content:  `{"help_screens_seen": 19, "instances": []}`,
+			expected: map[string]bool{"general": true, "instance_start": true, "server_attach": true},
+		},
+		{
+			name:     "keeps seen screens",
+			content:  `{"seen_help_screens": {"general": true}, "instances": []}`,
+			expected: map[string]bool{"general": true},
+		},
+		{
+			name:     "empty state",
+			content:  `{"instances": []}`,
+			expected: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			repoPath := "/tmp/repo"
+			statePath, err := getRepoStatePath(repoPath)
+			require.NoError(t, err)
+			require.NoError(t, os.MkdirAll(filepath.Dir(statePath), 0755))
+			require.NoError(t, os.WriteFile(statePath, []byte(tt.content), 0644))
+
+			state := LoadStateForRepo(repoPath)
+
+			assert.Equal(t, tt.expected, state.SeenHelpScreens)
+			assert.Zero(t, state.HelpScreensSeen)
+			for name := range tt.expected {
+				assert.True(t, state.IsHelpScreenSeen(name))
+			}
+			assert.False(t, state.IsHelpScreenSeen("instance_attach"))
+		})
+	}
+}
+
+func TestValidTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []InstanceTemplate
+		expected  []string
+	}{
+		{
+			name: "keeps valid templates",
+			templates: []InstanceTemplate{
+				{Name: "api", Program: "claude", BaseBranch: "main", Labels: []string{"backend"}},
+				{Name: "web", DevServer: &DevServerSettings{DevCommand: "npm run dev"}},
+			},
+			expected: []string{"api", "web"},
+		},
+		{
+			name:      "drops templates without a name",
+			templates: []InstanceTemplate{{Name: " "}, {Name: "api"}},
+			expected:  []string{"api"},
+		},
+		{
+			name:      "drops duplicate names",
+			templates: []InstanceTemplate{{Name: "api"}, {Name: "api", Program: "aider"}},
+			expected:  []string{"api"},
+		},
+		{
+			name:      "drops empty labels",
+			templates: []InstanceTemplate{{Name: "api", Labels: []string{""}}},
+		},
+		{
+			name:      "drops dev server settings without a dev command",
+			templates: []InstanceTemplate{{Name: "web", DevServer: &DevServerSettings{BuildCommand: "make"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for _, template := range validTemplates(tt.templates) {
+				names = append(names, template.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestValidStartupInstances(t *testing.T) {
+	tests := []struct {
+		name     string
+		specs    []StartupInstanceSpec
+		expected []string
+	}{
+		{
+			name:     "keeps valid specs",
+			specs:    []StartupInstanceSpec{{Title: "backend", Prompt: "run the tests"}, {Title: "frontend", Program: "aider"}},
+			expected: []string{"backend", "frontend"},
+		},
+		{
+			name:     "drops specs without a title",
+			specs:    []StartupInstanceSpec{{Title: " "}, {Title: "backend"}},
+			expected: []string{"backend"},
+		},
+		{
+			name:     "drops duplicate titles",
+			specs:    []StartupInstanceSpec{{Title: "backend"}, {Title: "backend", BaseBranch: "dev"}},
+			expected: []string{"backend"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var titles []string
+			for _, spec := range validStartupInstances(tt.specs) {
+				titles = append(titles, spec.Title)
+			}
+			assert.Equal(t, tt.expected, titles)
+		})
+	}
+}
+
+func TestGetDevServerSize(t *testing.T) {
+	tests := []struct {
+		name           string
+		config         Config
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{name: "defaults when unset", config: Config{}, expectedWidth: DefaultDevServerWidth, expectedHeight: DefaultDevServerHeight},
+		{name: "uses configured size", config: Config{DevServerWidth: 120, DevServerHeight: 40}, expectedWidth: 120, expectedHeight: 40},
+		{name: "defaults invalid values", config: Config{DevServerWidth: -1, DevServerHeight: 30}, expectedWidth: DefaultDevServerWidth, expectedHeight: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height := tt.config.GetDevServerSize()
+			assert.Equal(t, tt.expectedWidth, width)
+			assert.Equal(t, tt.expectedHeight, height)
+		})
+	}
+}
+
+func TestGetControlListener(t *testing.T) {
+	tests := []struct {
+		name            string
+		controlSocket   string
+		expectedNetwork string
+		expectErr       bool
+	}{
+		{name: "disabled when unset", controlSocket: "", expectedNetwork: ""},
+		{name: "unix socket", controlSocket: "/tmp/cs.sock", expectedNetwork: "unix"},
+		{name: "loopback address", controlSocket: "127.0.0.1:7777", expectedNetwork: "tcp"},
+		{name: "localhost", controlSocket: "localhost:7777", expectedNetwork: "tcp"},
+		{name: "ipv6 loopback", controlSocket: "[::1]:7777", expectedNetwork: "tcp"},
+		{name: "rejects all interfaces", controlSocket: ":7777", expectErr: true},
+		{name: "rejects public addresses", controlSocket: "0.0.0.0:7777", expectErr: true},
+		{name: "rejects relative socket paths", controlSocket: "cs.sock", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{ControlSocket: tt.controlSocket}
+			network, address, err := cfg.GetControlListener()
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedNetwork, network)
+			assert.Equal(t, tt.controlSocket, address)
+		})
+	}
+}
+
+func TestLoadDevServerSettings(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+		expectedDev string
+	}{
+		{
+			name:        "valid settings",
+			content:     `{"dev_command": "npm run dev", "env": {"PORT": "3000"}, "working_dir": "packages/web"}`,
+			expectedDev: "npm run dev",
+		},
+		{
+			name:        "unknown keys are ignored",
+			content:     `{"dev_comand": "npm run dev", "dev_command": "make dev"}`,
+			expectedDev: "make dev",
+		},
+		{
+			name:        "reports the position of syntax errors",
+			content:     "{\n  \"dev_command\": \"npm run dev\"\n  \"build_command\": \"\"\n}",
+			expectedErr: "line 3, column 3",
+		},
+		{
+			name:        "reports fields with the wrong type",
+			content:     `{"env": {"PORT": 3000}}`,
+			expectedErr: "env.PORT must be a string, not a number",
+		},
+		{
+			name:        "rejects invalid env names",
+			content:     `{"env": {"MY-PORT": "3000"}}`,
+			expectedErr: `env: "MY-PORT" is not a valid environment variable name`,
+		},
+		{
+			name:        "rejects working dirs outside the worktree",
+			content:     `{"working_dir": "../web"}`,
+			expectedErr: "working_dir",
+		},
+		{
+			name:        "rejects output buffers above the limit",
+			content:     `{"max_output_lines": 1000000}`,
+			expectedErr: "max_output_lines",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoPath := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(repoPath, ".claude-squad"), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(repoPath, SettingsFileName), []byte(tt.content), 0644))
+
+			settings, err := LoadDevServerSettings(repoPath)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedDev, settings.DevCommand)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		settings, err := LoadDevServerSettings(t.TempDir())
+		require.NoError(t, err)
+		assert.Nil(t, settings)
+	})
+}
+
+func TestWriteFileInDir(t *testing.T) {
+	tests := []struct {
+		name        string
+		readOnly    bool
+		bestEffort  bool
+		expectWrite bool
+		expectErr   bool
+	}{
+		{name: "creates the directory and writes the file", expectWrite: true},
+		{name: "returns permission errors", readOnly: true, expectErr: true},
+		{name: "best effort writes ignore permission errors", readOnly: true, bestEffort: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.readOnly && os.Geteuid() == 0 {
+				t.Skip("permissions aren't enforced for root")
+			}
+			dir := t.TempDir()
+			if tt.readOnly {
+				require.NoError(t, os.Chmod(dir, 0555))
+				t.Cleanup(func() { os.Chmod(dir, 0755) })
+			}
+			path := filepath.Join(dir, "nested", "state.json")
+
+			write := writeFileInDir
+			if tt.bestEffort {
+				write = writeFileInDirBestEffort
+			}
+			err := write(path, []byte("{}"))
+			if tt.expectErr {
+				assert.ErrorIs(t, err, fs.ErrPermission)
+			} else {
+				require.NoError(t, err)
+			}
+			_, err = os.Stat(path)
+			assert.Equal(t, tt.expectWrite, err == nil)
+		})
+	}
+}
+
+func TestCheckConfigDirWritable(t *testing.T) {
+	t.Run("creates a missing config directory", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		require.NoError(t, CheckConfigDirWritable())
+
+		entries, err := os.ReadDir(filepath.Join(home, ".claude-squad"))
+		require.NoError(t, err)
+		assert.Empty(t, entries, "the probe file should be removed")
+	})
+
+	t.Run("reports a config path that isn't a directory", func(t *testing.T) {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		require.NoError(t, os.WriteFile(filepath.Join(home, ".claude-squad"), []byte(""), 0644))
+
+		err := CheckConfigDirWritable()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not writable")
+	})
+}
+
+func TestUnknownSettingsKeys(t *testing.T) {
+	keys := unknownSettingsKeys([]byte(`{"dev_comand": "", "dev_command": "", "Env": {}, "working_dir": ""}`))
+	assert.Equal(t, []string{"Env", "dev_comand"}, keys)
+}
+
+func TestSaveStateKeepsOtherProcessesInstances(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoPath := "/tmp/repo"
+	statePath, err := getRepoStatePath(repoPath)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(statePath), 0755))
+	require.NoError(t, os.WriteFile(statePath, []byte(`{"instances": [{"title": "a"}]}`), 0644))
+
+	storedTitles := func() []string {
+		_, titles, err := instanceTitles(LoadStateForRepo(repoPath).InstancesData)
+		require.NoError(t, err)
+		return titles
+	}
+
+	// Two processes load the same state and each add an instance.
+	first := LoadStateForRepo(repoPath)
+	second := LoadStateForRepo(repoPath)
+	first.InstancesData = []byte(`[{"title": "a"}, {"title": "b"}]`)
+	require.NoError(t, SaveStateForRepo(first, repoPath))
+	second.InstancesData = []byte(`[{"title": "a"}, {"title": "c"}]`)
+	second.SeenHelpScreens["general"] = true
+	require.NoError(t, SaveStateForRepo(second, repoPath))
+	assert.Equal(t, []string{"a", "c", "b"}, storedTitles())
+
+	// Removing an instance the process knows about still works.
+	first.InstancesData = []byte(`[{"title": "a"}]`)
+	require.NoError(t, SaveStateForRepo(first, repoPath))
+	assert.Equal(t, []string{"a", "c"}, storedTitles())
+	assert.True(t, LoadStateForRepo(repoPath).IsHelpScreenSeen("general"))
+}
+
+func TestResetHelpScreensSeen(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	state := LoadState()
+	require.NoError(t, state.SetHelpScreenSeen("general"))
+	require.NoError(t, state.SetHelpScreenSeen("onboarding"))
+
+	require.NoError(t, state.ResetHelpScreensSeen())
+	assert.False(t, state.IsHelpScreenSeen("general"))
+	assert.False(t, LoadState().IsHelpScreenSeen("general"), "the stored help screens aren't merged back")
+
+	// Help screens seen after the reset are stored again.
+	require.NoError(t, state.SetHelpScreenSeen("general"))
+	reloaded := LoadState()
+	assert.True(t, reloaded.IsHelpScreenSeen("general"))
+	assert.False(t, reloaded.IsHelpScreenSeen("onboarding"))
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	unlock := lockFile(path)
+
+	f, err := os.OpenFile(path+".lock", os.O_RDWR, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	locked, err := tryLockFile(f)
+	require.NoError(t, err)
+	assert.False(t, locked, "the lock should be held")
+
+	unlock()
+	locked, err = tryLockFile(f)
+	require.NoError(t, err)
+	require.True(t, locked, "the lock should be released")
+
+	t.Run("ignores a stale lock after the timeout", func(t *testing.T) {
+		original := stateLockTimeout
+		stateLockTimeout = 50 * time.Millisecond
+		defer func() { stateLockTimeout = original }()
+
+		start := time.Now()
+		lockFile(path)()
+		assert.GreaterOrEqual(t, time.Since(start), stateLockTimeout)
+	})
+	require.NoError(t, unlockFile(f))
+}
+
+func TestContainerConfigCommand(t *testing.T) {
+	assert.Error(t, ContainerConfig{}.Validate())
+
+	container := ContainerConfig{Image: "agent:latest", Flags: "--network host"}
+	require.NoError(t, container.Validate())
+	assert.Equal(t,
+		"docker run --rm -it -v '/wt/it'\\''s:/wt/it'\\''s' -w '/wt/it'\\''s' -v '/repo/.git:/repo/.git' --network host 'agent:latest' claude --model opus",
+		container.Command("claude --model opus", "/wt/it's", "/repo"))
+
+	container = ContainerConfig{Runtime: "podman", Image: "agent"}
+	assert.Equal(t, "podman run --rm -it -v '/wt:/wt' -w '/wt' 'agent' aider",
+		container.Command("aider", "/wt", ""))
+}
+
+func TestGetAutoSaveInterval(t *testing.T) {
+	assert.Equal(t, DefaultAutoSaveIntervalSeconds*time.Second, (&Config{}).GetAutoSaveInterval())
+	assert.Equal(t, 5*time.Second, (&Config{AutoSaveIntervalSeconds: 5}).GetAutoSaveInterval())
+	assert.Zero(t, (&Config{AutoSaveIntervalSeconds: -1}).GetAutoSaveInterval())
+}
+
+func TestErrorDisplay(t *testing.T) {
+	assert.Equal(t, DefaultErrorDisplaySeconds*time.Second, (&Config{}).GetErrorDisplayDuration())
+	assert.Equal(t, 10*time.Second, (&Config{ErrorDisplaySeconds: 10}).GetErrorDisplayDuration())
+	assert.Equal(t, DefaultErrorDisplaySeconds*time.Second, (*Config)(nil).GetErrorDisplayDuration())
+
+	assert.False(t, (&Config{}).PinsError(strings.Repeat("x", 1000)), "errors aren't pinned by default")
+	pinning := &Config{PinErrorsLongerThan: 5}
+	assert.False(t, pinning.PinsError("short"))
+	assert.True(t, pinning.PinsError("longer"))
+	assert.False(t, (*Config)(nil).PinsError("longer"))
+}
+
+func TestParseEnv(t *testing.T) {
+	env, err := ParseEnv("  PORT=3000 NODE_ENV=development EMPTY= ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PORT": "3000", "NODE_ENV": "development", "EMPTY": ""}, env)
+	assert.Equal(t, "EMPTY= NODE_ENV=development PORT=3000", FormatEnv(env))
+
+	env, err = ParseEnv("")
+	require.NoError(t, err)
+	assert.Empty(t, env)
+
+	_, err = ParseEnv("PORT")
+	assert.Error(t, err)
+	_, err = ParseEnv("1PORT=3000")
+	assert.Error(t, err)
+
+	// Values with spaces or quotes are quoted, and survive a round trip.
+	env = map[string]string{"FLAGS": "--host 0.0.0.0", "GREETING": `say "hi"`, "PORT": "3000"}
+	formatted := FormatEnv(env)
+	assert.Equal(t, `FLAGS="--host 0.0.0.0" GREETING="say \"hi\"" PORT=3000`, formatted)
+	parsed, err := ParseEnv(formatted)
+	require.NoError(t, err)
+	assert.Equal(t, env, parsed)
+
+	_, err = ParseEnv(`FLAGS="--host 0.0.0.0`)
+	assert.ErrorContains(t, err, "no closing quote")
+}
+
+func TestGetLogLevel(t *testing.T) {
+	assert.Equal(t, log.LevelInfo, (&Config{}).GetLogLevel())
+	assert.Equal(t, log.LevelDebug, (&Config{LogLevel: "debug"}).GetLogLevel())
+	assert.Equal(t, log.LevelWarn, (&Config{LogLevel: "WARN"}).GetLogLevel())
+	assert.Equal(t, log.LevelInfo, (&Config{LogLevel: "verbose"}).GetLogLevel(), "invalid levels use the default")
+}
+
+func TestGetPreviewInterval(t *testing.T) {
+	assert.Equal(t, DefaultPreviewIntervalMs*time.Millisecond, (&Config{}).GetPreviewInterval())
+	assert.Equal(t, 250*time.Millisecond, (&Config{PreviewIntervalMs: 250}).GetPreviewInterval())
+	assert.Equal(t, MinPreviewIntervalMs*time.Millisecond, (&Config{PreviewIntervalMs: 1}).GetPreviewInterval())
+}
+
+func TestCopyFiles(t *testing.T) {
+	repo := t.TempDir()
+	worktree := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "config", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".envrc"), []byte("export A=1\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "config", "app.local.yaml"), []byte("secret: 1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "config", "app.yaml"), []byte("public: 1\n"), 0644))
+
+	require.NoError(t, CopyFiles(repo, worktree, []string{".envrc", "config/*.local.yaml", "config/*", "missing.txt"}))
+
+	data, err := os.ReadFile(filepath.Join(worktree, ".envrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "export A=1\n", string(data))
+	info, err := os.Stat(filepath.Join(worktree, ".envrc"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "permissions are kept, e.g. of secrets")
+	assert.FileExists(t, filepath.Join(worktree, "config", "app.local.yaml"))
+	assert.FileExists(t, filepath.Join(worktree, "config", "app.yaml"))
+	assert.NoDirExists(t, filepath.Join(worktree, "config", "nested"), "directories are skipped")
+
+	assert.Error(t, CopyFiles(repo, worktree, []string{"../outside"}))
+	assert.Error(t, ValidateDevServerSettings(&DevServerSettings{CopyFiles: []string{"/etc/passwd"}}))
+	assert.Error(t, ValidateDevServerSettings(&DevServerSettings{CopyFiles: []string{"config/["}}))
+	assert.NoError(t, ValidateDevServerSettings(&DevServerSettings{CopyFiles: []string{"config/*.local.yaml"}}))
+}
+
+func TestLoadControlToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	token, err := LoadControlToken()
+	require.NoError(t, err)
+	assert.Len(t, token, 64)
+	info, err := os.Stat(filepath.Join(home, ".claude-squad", ControlTokenFileName))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	again, err := LoadControlToken()
+	require.NoError(t, err)
+	assert.Equal(t, token, again, "the token is kept across runs")
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultContainerRuntime is the container CLI used when ContainerConfig.Runtime is empty.
+const DefaultContainerRuntime = "docker"
+
+// ContainerConfig runs an instance's program inside a container instead of directly on the host.
+type ContainerConfig struct {
+	// Runtime is the container CLI, e.g. "docker" or "podman". Empty means DefaultContainerRuntime.
+	Runtime string `json:"runtime,omitempty"`
+	// Image is the image the program runs in. It must contain the program.
+	Image string `json:"image"`
+	// Flags are extra flags passed to `<runtime> run`, e.g. "--network host -e ANTHROPIC_API_KEY".
+	Flags string `json:"flags,omitempty"`
+}
+
+// Validate returns an error if the container can't be started.
+func (c ContainerConfig) Validate() error {
+	if strings.TrimSpace(c.Image) == "" {
+		return fmt.Errorf("container image cannot be empty")
+	}
+	return nil
+}
+
+// Command returns the shell command that runs program in the container. The worktree is mounted
+// at the same path and used as the working directory, so paths in the agent's output match the
+// host. The repository's git directory is mounted too, since the worktree's .git file points into
+// it.
+func (c ContainerConfig) Command(program, worktree, repoPath string) string {
+	runtime := c.Runtime
+	if runtime == "" {
+		runtime = DefaultContainerRuntime
+	}
+	args := []string{runtime, "run", "--rm", "-it",
+		"-v", shellQuote(worktree + ":" + worktree),
+		"-w", shellQuote(worktree),
+	}
+	if repoPath != "" {
+		gitDir := filepath.Join(repoPath, ".git")
+		args = append(args, "-v", shellQuote(gitDir+":"+gitDir))
+	}
+	if flags := strings.TrimSpace(c.Flags); flags != "" {
+		args = append(args, flags)
+	}
+	args = append(args, shellQuote(c.Image), program)
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
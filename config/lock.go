@@ -0,0 +1,64 @@
+package config
+
+import (
+	"claude-squad/log"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateLockTimeout is how long to wait for another process to release a state file lock. A lock
+// held for longer is assumed to be stale, e.g. held by a hung process, and is ignored.
+var stateLockTimeout = 5 * time.Second
+
+// stateLockPollInterval is how often a held lock is retried while waiting for it.
+const stateLockPollInterval = 20 * time.Millisecond
+
+// lockFile takes an exclusive advisory lock on path by locking path+".lock", so that several
+// processes reading and writing the same state file don't clobber each other. The returned function
+// releases the lock. Locks of crashed processes are released by the OS; if the lock can't be taken
+// within stateLockTimeout, or the lock file can't be created, a warning is logged and the caller
+// continues without the lock instead of blocking forever.
+func lockFile(path string) func() {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		log.WarningLog.Printf("failed to create directory for lock %s, continuing without it: %v", lockPath, err)
+		return func() {}
+	}
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		log.WarningLog.Printf("failed to open lock %s, continuing without it: %v", lockPath, err)
+		return func() {}
+	}
+
+	if err := acquireLock(f, stateLockTimeout); err != nil {
+		log.WarningLog.Printf("%v, continuing without it", err)
+		f.Close()
+		return func() {}
+	}
+	return func() {
+		if err := unlockFile(f); err != nil {
+			log.WarningLog.Printf("failed to release lock %s: %v", lockPath, err)
+		}
+		f.Close()
+	}
+}
+
+// acquireLock retries tryLockFile until it succeeds or timeout passes.
+func acquireLock(f *os.File, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to lock %s: %w", f.Name(), err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("lock %s is still held after %s", f.Name(), timeout)
+		}
+		time.Sleep(stateLockPollInterval)
+	}
+}
@@ -1,23 +1,50 @@
 package config
 
 import (
+	"bytes"
+	"claude-squad/log"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const SettingsFileName = ".claude-squad/settings.json"
 
 type DevServerSettings struct {
-	BuildCommand string            `json:"build_command"`
-	DevCommand   string            `json:"dev_command"`
-	Env          map[string]string `json:"env,omitempty"`
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
+	BuildCommand string `json:"build_command"`
+	DevCommand   string `json:"dev_command"`
+	// Env is exported before the build and dev commands run, so they can refer to it, e.g.
+	// `next dev -p $PORT`. Values are used literally.
+	Env map[string]string `json:"env,omitempty"`
+	// WorkingDir is the directory, relative to the worktree, the dev server runs in (e.g.
+	// packages/web in a monorepo). Empty means the worktree root.
+	WorkingDir string `json:"working_dir,omitempty"`
+	// MaxOutputLines is how many lines of dev server output are kept in memory. Defaults to
+	// DefaultDevServerOutputLines when unset; at most MaxDevServerOutputLines.
+	MaxOutputLines int `json:"max_output_lines,omitempty"`
+	// CopyFiles are glob patterns, relative to the repo root, of files copied into new worktrees
+	// along with the .env files, e.g. config.local.yaml or .envrc. These are usually untracked files
+	// the agent needs, like local config and secrets.
+	CopyFiles []string  `json:"copy_files,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
+const (
+	// DefaultDevServerOutputLines is the default number of dev server output lines kept in memory.
+	DefaultDevServerOutputLines = 100
+	// MaxDevServerOutputLines bounds max_output_lines so a typo can't exhaust memory.
+	MaxDevServerOutputLines = 10000
+)
+
 func DefaultDevServerSettings() *DevServerSettings {
 	return &DevServerSettings{
 		BuildCommand: "",
@@ -38,21 +65,184 @@ func LoadDevServerSettings(repoPath string) (*DevServerSettings, error) {
 		return nil, fmt.Errorf("failed to read settings file: %w", err)
 	}
 
+	settings, unknown, err := ParseDevServerSettings(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", settingsPath, err)
+	}
+	for _, key := range unknown {
+		log.WarningLog.Printf("%s: ignoring unknown key %q", settingsPath, key)
+	}
+
+	return settings, nil
+}
+
+// ParseDevServerSettings parses and validates the contents of a settings file. It also returns the
+// unknown top-level keys, which are ignored.
+func ParseDevServerSettings(data []byte) (*DevServerSettings, []string, error) {
 	var settings DevServerSettings
 	if err := json.Unmarshal(data, &settings); err != nil {
-		return nil, fmt.Errorf("failed to parse settings file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse settings: %w", describeJSONError(data, err))
 	}
+	if err := ValidateDevServerSettings(&settings); err != nil {
+		return nil, nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	return &settings, unknownSettingsKeys(data), nil
+}
 
-	return &settings, nil
+// DevServerSettingsJSON returns the repo's settings file pretty-printed for editing, or the default
+// settings if there is none. A file that fails to load is returned as it is so it can be fixed.
+func DevServerSettingsJSON(repoPath string) (string, error) {
+	settings, err := LoadDevServerSettings(repoPath)
+	if err != nil {
+		data, readErr := os.ReadFile(filepath.Join(repoPath, SettingsFileName))
+		if readErr != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	if settings == nil {
+		settings = DefaultDevServerSettings()
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	return string(data), nil
 }
 
-func SaveDevServerSettings(settings *DevServerSettings, repoPath string) error {
-	settings.UpdatedAt = time.Now()
+// envNameRegexp matches the environment variable names a shell accepts in a NAME=value prefix.
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateDevServerSettings returns an error naming the first invalid field of the settings. Empty
+// commands are valid; they mean the dev server isn't configured yet.
+func ValidateDevServerSettings(settings *DevServerSettings) error {
+	if settings == nil {
+		return fmt.Errorf("dev server settings are missing")
+	}
+	for name := range settings.Env {
+		if !envNameRegexp.MatchString(name) {
+			return fmt.Errorf("env: %q is not a valid environment variable name", name)
+		}
+	}
+	if settings.WorkingDir != "" && !filepath.IsLocal(settings.WorkingDir) {
+		return fmt.Errorf("working_dir: %q must be a path inside the worktree, relative to its root", settings.WorkingDir)
+	}
+	for _, pattern := range settings.CopyFiles {
+		if _, err := filepath.Match(pattern, ""); err != nil || !filepath.IsLocal(pattern) {
+			return fmt.Errorf("copy_files: %q must be a glob pattern inside the repo, relative to its root", pattern)
+		}
+	}
+	if settings.MaxOutputLines < 0 || settings.MaxOutputLines > MaxDevServerOutputLines {
+		return fmt.Errorf("max_output_lines: %d must be between 0 and %d", settings.MaxOutputLines, MaxDevServerOutputLines)
+	}
+	return nil
+}
+
+// FormatEnv formats env as space-separated NAME=value pairs sorted by name, the format ParseEnv
+// reads. Values with spaces, quotes or backslashes are quoted.
+func FormatEnv(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		value := env[name]
+		if strings.ContainsAny(value, " \t\n\"\\") {
+			value = strconv.Quote(value)
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// ParseEnv parses space-separated NAME=value pairs. Values containing spaces are written in double
+// quotes with Go escapes, e.g. FLAGS="--host 0.0.0.0", as FormatEnv does.
+func ParseEnv(s string) (map[string]string, error) {
+	env := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		end := strings.IndexAny(s, " \t\n")
+		if end < 0 {
+			end = len(s)
+		}
+		name, value, ok := strings.Cut(s[:end], "=")
+		if !ok {
+			return nil, fmt.Errorf("env: %q is not a NAME=value pair", s[:end])
+		}
+		if !envNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("env: %q is not a valid environment variable name", name)
+		}
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(s[len(name)+1:])
+			if err != nil {
+				return nil, fmt.Errorf("env: the value of %s has no closing quote", name)
+			}
+			if value, err = strconv.Unquote(quoted); err != nil {
+				return nil, fmt.Errorf("env: the value of %s is not a valid quoted string: %w", name, err)
+			}
+			end = len(name) + 1 + len(quoted)
+		}
+		env[name] = value
+		s = s[end:]
+	}
+	return env, nil
+}
+
+// describeJSONError rewrites JSON decoding errors to point at the offending field or position.
+func describeJSONError(data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset is just past the character that made decoding fail.
+		line, column := lineAndColumn(data, max(syntaxErr.Offset-1, 0))
+		return fmt.Errorf("line %d, column %d: %w", line, column, err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("%s must be a %s, not a %s: %w", typeErr.Field, typeErr.Type, typeErr.Value, err)
+	}
+	return err
+}
+
+// lineAndColumn converts a byte offset in data into a 1-based line and column.
+func lineAndColumn(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// unknownSettingsKeys returns the top-level keys in data that don't match a DevServerSettings field,
+// sorted. These are usually typos, e.g. dev_comand.
+func unknownSettingsKeys(data []byte) []string {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	known := make(map[string]bool)
+	settingsType := reflect.TypeOf(DevServerSettings{})
+	for i := 0; i < settingsType.NumField(); i++ {
+		name, _, _ := strings.Cut(settingsType.Field(i).Tag.Get("json"), ",")
+		known[name] = true
+	}
+	var unknown []string
+	for key := range raw {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
 
-	settingsDir := filepath.Join(repoPath, ".claude-squad")
-	if err := os.MkdirAll(settingsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create settings directory: %w", err)
+func SaveDevServerSettings(settings *DevServerSettings, repoPath string) error {
+	if err := ValidateDevServerSettings(settings); err != nil {
+		return fmt.Errorf("invalid dev server settings: %w", err)
 	}
+	settings.UpdatedAt = time.Now()
 
 	settingsPath := filepath.Join(repoPath, SettingsFileName)
 	data, err := json.MarshalIndent(settings, "", "  ")
@@ -60,7 +250,7 @@ func SaveDevServerSettings(settings *DevServerSettings, repoPath string) error {
 		return fmt.Errorf("failed to marshal settings: %w", err)
 	}
 
-	return os.WriteFile(settingsPath, data, 0644)
+	return writeFileInDir(settingsPath, data)
 }
 
 func CopySettingsToWorktree(mainRepoPath, worktreePath string) error {
@@ -109,6 +299,45 @@ func CopyEnvFiles(mainRepoPath, worktreePath string) error {
 	return nil
 }
 
+// CopyFiles copies the files in mainRepoPath matching the glob patterns, relative to the repo root,
+// to the same place in worktreePath, keeping their permissions. Directories are skipped.
+func CopyFiles(mainRepoPath, worktreePath string, patterns []string) error {
+	for _, pattern := range patterns {
+		if !filepath.IsLocal(pattern) {
+			return fmt.Errorf("copy pattern %q is outside the repo", pattern)
+		}
+		matches, err := filepath.Glob(filepath.Join(mainRepoPath, pattern))
+		if err != nil {
+			return fmt.Errorf("invalid copy pattern %q: %w", pattern, err)
+		}
+		for _, srcPath := range matches {
+			info, err := os.Stat(srcPath)
+			if err != nil {
+				return fmt.Errorf("failed to stat %s: %w", srcPath, err)
+			}
+			if !info.Mode().IsRegular() {
+				continue
+			}
+			rel, err := filepath.Rel(mainRepoPath, srcPath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve %s: %w", srcPath, err)
+			}
+			data, err := os.ReadFile(srcPath)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", rel, err)
+			}
+			dstPath := filepath.Join(worktreePath, rel)
+			if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", rel, err)
+			}
+			if err := os.WriteFile(dstPath, data, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to write %s: %w", rel, err)
+			}
+		}
+	}
+	return nil
+}
+
 func SettingsExist(repoPath string) bool {
 	settingsPath := filepath.Join(repoPath, SettingsFileName)
 	_, err := os.Stat(settingsPath)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const (
@@ -55,10 +56,12 @@ type InstanceStorage interface {
 
 // AppState handles application-level state
 type AppState interface {
-	// GetHelpScreensSeen returns the bitmask of seen help screens
-	GetHelpScreensSeen() uint32
-	// SetHelpScreensSeen updates the bitmask of seen help screens
-	SetHelpScreensSeen(seen uint32) error
+	// IsHelpScreenSeen returns true if the help screen with the given name has been shown
+	IsHelpScreenSeen(name string) bool
+	// SetHelpScreenSeen marks the help screen with the given name as shown
+	SetHelpScreenSeen(name string) error
+	// ResetHelpScreensSeen marks all help screens as not shown, so they are shown again
+	ResetHelpScreensSeen() error
 }
 
 // StateManager combines instance storage and app state management
@@ -69,20 +72,52 @@ type StateManager interface {
 
 // State represents the application state that persists between sessions
 type State struct {
-	// HelpScreensSeen is a bitmask tracking which help screens have been shown
-	HelpScreensSeen uint32 `json:"help_screens_seen"`
+	// HelpScreensSeen is the legacy bitmask of seen help screens. It is converted to SeenHelpScreens
+	// when the state is loaded and no longer written.
+	HelpScreensSeen uint32 `json:"help_screens_seen,omitempty"`
+	// SeenHelpScreens tracks which help screens have been shown, keyed by help screen name
+	SeenHelpScreens map[string]bool `json:"seen_help_screens,omitempty"`
 	// Instances stores the serialized instance data as raw JSON
 	InstancesData json.RawMessage `json:"instances"`
+
+	// knownTitles are the titles of the instances this process loaded or saved. Stored instances
+	// with other titles were added by another process and are kept when the state is saved.
+	knownTitles map[string]bool
+	// helpScreensReset is true if the seen help screens were reset since the state was last
+	// written, so the ones in the stored state are dropped instead of merged.
+	helpScreensReset bool
+}
+
+// legacyHelpScreenBits maps the bits of the legacy HelpScreensSeen bitmask to help screen names.
+var legacyHelpScreenBits = map[uint32]string{
+	1 << 0: "general",
+	1 << 1: "instance_start",
+	1 << 2: "instance_attach",
+	1 << 3: "instance_checkout",
+	1 << 4: "server_attach",
 }
 
 // DefaultState returns the default state
 func DefaultState() *State {
 	return &State{
-		HelpScreensSeen: 0,
+		SeenHelpScreens: make(map[string]bool),
 		InstancesData:   json.RawMessage("[]"),
 	}
 }
 
+// migrateHelpScreensSeen converts the legacy HelpScreensSeen bitmask into SeenHelpScreens.
+func (s *State) migrateHelpScreensSeen() {
+	if s.SeenHelpScreens == nil {
+		s.SeenHelpScreens = make(map[string]bool)
+	}
+	for bit, name := range legacyHelpScreenBits {
+		if s.HelpScreensSeen&bit != 0 {
+			s.SeenHelpScreens[name] = true
+		}
+	}
+	s.HelpScreensSeen = 0
+}
+
 // LoadState loads the state from disk. If it cannot be done, we return the default state.
 func LoadState() *State {
 	configDir, err := GetConfigDir()
@@ -92,7 +127,7 @@ func LoadState() *State {
 	}
 
 	statePath := filepath.Join(configDir, StateFileName)
-	data, err := os.ReadFile(statePath)
+	data, err := readStateFile(statePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Create and save default state if file doesn't exist
@@ -112,6 +147,8 @@ func LoadState() *State {
 		log.ErrorLog.Printf("failed to parse state file: %v", err)
 		return DefaultState()
 	}
+	state.migrateHelpScreensSeen()
+	state.rememberInstances()
 
 	return &state
 }
@@ -123,17 +160,7 @@ func SaveState(state *State) error {
 		return fmt.Errorf("failed to get config directory: %w", err)
 	}
 
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	statePath := filepath.Join(configDir, StateFileName)
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
-	}
-
-	return os.WriteFile(statePath, data, 0644)
+	return writeStateFile(filepath.Join(configDir, StateFileName), state)
 }
 
 func LoadStateForRepo(repoPath string) *State {
@@ -143,7 +170,7 @@ func LoadStateForRepo(repoPath string) *State {
 		return DefaultState()
 	}
 
-	data, err := os.ReadFile(statePath)
+	data, err := readStateFile(statePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			defaultState := DefaultState()
@@ -162,6 +189,8 @@ func LoadStateForRepo(repoPath string) *State {
 		log.ErrorLog.Printf("failed to parse repo state file: %v", err)
 		return DefaultState()
 	}
+	state.migrateHelpScreensSeen()
+	state.rememberInstances()
 
 	return &state
 }
@@ -173,18 +202,145 @@ func SaveStateForRepo(state *State, repoPath string) error {
 	}
 
 	identity := repoIdentity(repoPath)
-	repoDir := filepath.Join(configDir, identity)
-	if err := os.MkdirAll(repoDir, 0755); err != nil {
-		return fmt.Errorf("failed to create repo directory: %w", err)
+	return writeStateFile(filepath.Join(configDir, identity, StateFileName), state)
+}
+
+// readStateFile reads the state file at path while holding its lock.
+func readStateFile(path string) ([]byte, error) {
+	unlock := lockFile(path)
+	defer unlock()
+	return os.ReadFile(path)
+}
+
+// writeStateFile writes state to path while holding its lock. The stored state is read first so
+// that instances another process added since state was loaded, and help screens it marked as seen,
+// aren't lost.
+func writeStateFile(path string, state *State) error {
+	unlock := lockFile(path)
+	defer unlock()
+
+	stored := &State{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, stored); err != nil {
+			log.WarningLog.Printf("failed to parse stored state %s, overwriting it: %v", path, err)
+			stored = &State{}
+		}
 	}
+	stored.migrateHelpScreensSeen()
 
-	statePath := filepath.Join(repoDir, StateFileName)
-	data, err := json.MarshalIndent(state, "", "  ")
+	data, err := json.MarshalIndent(state.mergeStored(stored), "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
+	if err := writeFileInDirBestEffort(path, data); err != nil {
+		return err
+	}
+	state.rememberInstances()
+	state.helpScreensReset = false
+	return nil
+}
+
+// mergeStored returns the state to write over stored: state's instances followed by the stored
+// instances that belong to another process, and the help screens seen by either unless state's were
+// reset.
+func (s *State) mergeStored(stored *State) *State {
+	if s.SeenHelpScreens == nil {
+		s.SeenHelpScreens = make(map[string]bool)
+	}
+	if !s.helpScreensReset {
+		for name := range stored.SeenHelpScreens {
+			s.SeenHelpScreens[name] = true
+		}
+	}
+
+	merged := *s
+	instances, titles, err := instanceTitles(s.InstancesData)
+	if err != nil {
+		// Leave data we can't make sense of as it is.
+		return &merged
+	}
+	storedInstances, storedTitles, err := instanceTitles(stored.InstancesData)
+	if err != nil {
+		return &merged
+	}
+	ours := make(map[string]bool, len(titles))
+	for _, title := range titles {
+		ours[title] = true
+	}
+	added := false
+	for i, raw := range storedInstances {
+		title := storedTitles[i]
+		if title == "" || ours[title] || s.knownTitles[title] {
+			continue
+		}
+		instances = append(instances, raw)
+		added = true
+	}
+	if !added {
+		return &merged
+	}
+	data, err := json.Marshal(instances)
+	if err != nil {
+		return &merged
+	}
+	merged.InstancesData = data
+	return &merged
+}
 
-	return os.WriteFile(statePath, data, 0644)
+// rememberInstances records the titles of the state's instances as known to this process.
+func (s *State) rememberInstances() {
+	_, titles, err := instanceTitles(s.InstancesData)
+	if err != nil {
+		return
+	}
+	s.knownTitles = make(map[string]bool, len(titles))
+	for _, title := range titles {
+		s.knownTitles[title] = true
+	}
+}
+
+// instanceTitles splits raw instance data into its entries and their titles. Entries without a
+// readable title get an empty one.
+func instanceTitles(data json.RawMessage) ([]json.RawMessage, []string, error) {
+	if len(data) == 0 {
+		return nil, nil, nil
+	}
+	var instances []json.RawMessage
+	if err := json.Unmarshal(data, &instances); err != nil {
+		return nil, nil, err
+	}
+	titles := make([]string, len(instances))
+	for i, raw := range instances {
+		var entry struct {
+			Title string `json:"title"`
+		}
+		if json.Unmarshal(raw, &entry) == nil {
+			titles[i] = entry.Title
+		}
+	}
+	return instances, titles, nil
+}
+
+// BackupInstancesData writes the raw instance data to a timestamped backup file in the config
+// directory. It is used before corrupt instance data gets overwritten so that it can be recovered
+// by hand. Returns the path of the backup file.
+func BackupInstancesData(data json.RawMessage) (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	backupName := fmt.Sprintf("%s.%s.bak", InstancesFileName, time.Now().Format("20060102-150405"))
+	backupPath := filepath.Join(configDir, backupName)
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write instances backup: %w", err)
+	}
+
+	return backupPath, nil
 }
 
 func MigrateLegacyState() error {
@@ -206,6 +362,7 @@ func MigrateLegacyState() error {
 	if err := json.Unmarshal(data, &legacyState); err != nil {
 		return fmt.Errorf("failed to parse legacy state file: %w", err)
 	}
+	legacyState.migrateHelpScreensSeen()
 
 	var instancesData []map[string]interface{}
 	if err := json.Unmarshal(legacyState.InstancesData, &instancesData); err != nil {
@@ -241,7 +398,7 @@ func MigrateLegacyState() error {
 		}
 
 		state := &State{
-			HelpScreensSeen: legacyState.HelpScreensSeen,
+			SeenHelpScreens: legacyState.SeenHelpScreens,
 			InstancesData:   instancesJSON,
 		}
 
@@ -300,13 +457,23 @@ func (s *State) DeleteAllInstances() error {
 
 // AppState interface implementation
 
-// GetHelpScreensSeen returns the bitmask of seen help screens
-func (s *State) GetHelpScreensSeen() uint32 {
-	return s.HelpScreensSeen
+// IsHelpScreenSeen returns true if the help screen with the given name has been shown
+func (s *State) IsHelpScreenSeen(name string) bool {
+	return s.SeenHelpScreens[name]
+}
+
+// SetHelpScreenSeen marks the help screen with the given name as shown
+func (s *State) SetHelpScreenSeen(name string) error {
+	if s.SeenHelpScreens == nil {
+		s.SeenHelpScreens = make(map[string]bool)
+	}
+	s.SeenHelpScreens[name] = true
+	return SaveState(s)
 }
 
-// SetHelpScreensSeen updates the bitmask of seen help screens
-func (s *State) SetHelpScreensSeen(seen uint32) error {
-	s.HelpScreensSeen = seen
+// ResetHelpScreensSeen marks all help screens as not shown, so they are shown again
+func (s *State) ResetHelpScreensSeen() error {
+	s.SeenHelpScreens = make(map[string]bool)
+	s.helpScreensReset = true
 	return SaveState(s)
 }
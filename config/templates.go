@@ -0,0 +1,104 @@
+package config
+
+import (
+	"claude-squad/log"
+	"fmt"
+	"strings"
+)
+
+// InstanceTemplate pre-configures new instances. Templates are edited in the config file and picked
+// when creating an instance.
+type InstanceTemplate struct {
+	// Name identifies the template in the picker. Names must be unique.
+	Name string `json:"name"`
+	// Program overrides the default program.
+	Program string `json:"program,omitempty"`
+	// ExtraArgs are appended to the program, e.g. "--model opus".
+	ExtraArgs string `json:"extra_args,omitempty"`
+	// BaseBranch is the branch the instance's worktree is created from instead of HEAD.
+	BaseBranch string `json:"base_branch,omitempty"`
+	// Prompt seeds the prompt entered after naming the instance.
+	Prompt string `json:"prompt,omitempty"`
+	// Labels are attached to the instance.
+	Labels []string `json:"labels,omitempty"`
+	// DevServer configures the instance's dev server.
+	DevServer *DevServerSettings `json:"dev_server,omitempty"`
+	// Container overrides the container the program runs in (Config.Container).
+	Container *ContainerConfig `json:"container,omitempty"`
+	// TmuxStartupCommands override the tmux commands run against the new session
+	// (Config.TmuxStartupCommands).
+	TmuxStartupCommands []string `json:"tmux_startup_commands,omitempty"`
+}
+
+// Validate returns an error if the template can't be used to create an instance.
+func (t InstanceTemplate) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("template name cannot be empty")
+	}
+	for _, label := range t.Labels {
+		if strings.TrimSpace(label) == "" {
+			return fmt.Errorf("template %q has an empty label", t.Name)
+		}
+	}
+	if t.DevServer != nil {
+		if strings.TrimSpace(t.DevServer.DevCommand) == "" {
+			return fmt.Errorf("template %q has dev server settings without a dev command", t.Name)
+		}
+		if err := ValidateDevServerSettings(t.DevServer); err != nil {
+			return fmt.Errorf("template %q has invalid dev server settings: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// StartupInstanceSpec describes an instance that is created on launch unless an instance with the
+// same title already exists in the repo.
+type StartupInstanceSpec struct {
+	// Title is the instance's title. It identifies the instance across restarts.
+	Title string `json:"title"`
+	// Program overrides the default program.
+	Program string `json:"program,omitempty"`
+	// BaseBranch is the branch the instance's worktree is created from instead of HEAD.
+	BaseBranch string `json:"base_branch,omitempty"`
+	// Prompt is sent to the instance once its program is ready.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// validStartupInstances returns the specs that have a title, dropping duplicate titles and logging
+// the others.
+func validStartupInstances(specs []StartupInstanceSpec) []StartupInstanceSpec {
+	var valid []StartupInstanceSpec
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		if strings.TrimSpace(spec.Title) == "" {
+			log.ErrorLog.Printf("ignoring startup instance without a title")
+			continue
+		}
+		if seen[spec.Title] {
+			log.ErrorLog.Printf("ignoring duplicate startup instance %q", spec.Title)
+			continue
+		}
+		seen[spec.Title] = true
+		valid = append(valid, spec)
+	}
+	return valid
+}
+
+// validTemplates returns the templates that are valid and have a unique name, logging the others.
+func validTemplates(templates []InstanceTemplate) []InstanceTemplate {
+	var valid []InstanceTemplate
+	seen := make(map[string]bool)
+	for _, template := range templates {
+		if err := template.Validate(); err != nil {
+			log.ErrorLog.Printf("ignoring invalid template: %v", err)
+			continue
+		}
+		if seen[template.Name] {
+			log.ErrorLog.Printf("ignoring duplicate template %q", template.Name)
+			continue
+		}
+		seen[template.Name] = true
+		valid = append(valid, template)
+	}
+	return valid
+}
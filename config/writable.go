@@ -0,0 +1,73 @@
+package config
+
+import (
+	"claude-squad/log"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CheckConfigDirWritable returns an error explaining how to fix it if the config directory can't be
+// created or written to. Instances, worktrees and state all live there, so it is checked once at startup.
+func CheckConfigDirWritable() error {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	if err := checkDirWritable(configDir); err != nil {
+		return fmt.Errorf("config directory %s is not writable, fix its permissions or ownership (e.g. chown -R $USER %s): %w",
+			configDir, configDir, err)
+	}
+	return nil
+}
+
+// checkDirWritable creates dir if needed and writes and removes a probe file in it.
+func checkDirWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	name := probe.Name()
+	closeErr := probe.Close()
+	if err := os.Remove(name); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// permissionWarnings holds the directories a permission error was already logged for.
+var permissionWarnings sync.Map
+
+// writeFileInDir writes data to path, creating its directory if needed.
+func writeFileInDir(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeFileInDirBestEffort is writeFileInDir for state saved in the background. Permission errors are
+// logged once per directory and otherwise ignored, so the caller keeps running with its in-memory
+// copy instead of failing the same save on every action. Saves the user asked for should use
+// writeFileInDir so they learn the file wasn't written.
+func writeFileInDirBestEffort(path string, data []byte) error {
+	err := writeFileInDir(path, data)
+	if err == nil || !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if _, warned := permissionWarnings.LoadOrStore(dir, true); !warned {
+		log.WarningLog.Printf("%v; changes are only kept in memory until the permissions are fixed", err)
+	}
+	return nil
+}
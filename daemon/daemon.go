@@ -18,6 +18,8 @@ import (
 // It's expected that the main process kills the daemon when the main process starts.
 func RunDaemon(cfg *config.Config) error {
 	log.InfoLog.Printf("starting daemon")
+	session.SetAutoYesDenyPatterns(cfg.AutoYesDenyPatterns)
+	session.SetDiffOptions(cfg.DiffOptions)
 	state := config.LoadState()
 	storage, err := session.NewStorage(state)
 	if err != nil {
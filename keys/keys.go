@@ -1,6 +1,8 @@
 package keys
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/bubbles/key"
 )
 
@@ -34,9 +36,58 @@ const (
 	KeyDevServerStart
 	KeyDevServerStop
 	KeyDevServerEdit
+
+	KeyJumpToInstance // Digit keys select the instance with that number in the list
+	KeyRebase         // Rebase the instance branch onto the base
+	KeyResetToBase    // Discard all changes in the instance worktree
+	KeyPausedOnly     // Toggle showing only paused instances
+	KeyRecord         // Start or stop recording the instance to an asciinema cast
+	KeyToggleAutoYes  // Toggle AutoYes for the selected instance
+	KeyDetails        // Show details for the selected instance
+	KeyUndo           // Undo the last kill
+	KeyToggleGroup    // Collapse or expand the repo group of the selected instance
+	KeyExpandGroups   // Expand all repo groups
+	KeyCopyPaths      // Copy all worktree paths to the clipboard
+	KeySendFile       // Send the contents of a file as a prompt
+	KeyTemplate       // Create a new instance from a template
+	KeyPrevRun        // Jump to the previous dev server run in the server tab
+	KeyNextRun        // Jump to the next dev server run in the server tab
+	KeyMark           // Mark or unmark the selected instance for batch actions
+	KeyBatchPrompt    // Send a prompt to all marked instances
+	KeyOpenURL        // Open the dev server URL in the browser
+	KeyRestartSession // Restart the agent session, keeping the worktree
+	KeyScrollTop      // Jump to the top of the active pane
+	KeyScrollBottom   // Jump to the bottom of the active pane
+	KeyPageUp         // Scroll the active pane up by a page
+	KeyPageDown       // Scroll the active pane down by a page
+	KeyHalfPageUp     // Scroll the active pane up by half a page
+	KeyHalfPageDown   // Scroll the active pane down by half a page
+	KeyQuickSwitch    // Search for an instance by title and select it
+	KeyToggleFollow   // Toggle pinning the server tab to the latest output
+	KeyCycleColor     // Assign the next palette color to the selected instance
+	KeyIgnoreSpace    // Toggle ignoring whitespace changes in the diff
+	KeyEditSettings   // Edit the repo's settings file as JSON
+	KeyPin            // Pin or unpin the selected instance to the top of the list
+	KeyCopyTab        // Copy the diff or the dev server output of the active tab to the clipboard
+	KeySaveServerLog  // Write the dev server output to a file in the server tab
+	KeyArchive        // Archive or unarchive the selected instance
+	KeyArchiveView    // Toggle showing the archived instances instead of the active ones
+	KeyDiffFiles      // Pick a changed file to narrow the diff to
+	KeyRepairWorktree // Repair the selected instance's worktree and git's metadata about it
+	KeyNewInPlace     // Create a new instance in the repository's checkout, without a worktree
+	KeyResetHelp      // Mark all help screens as not seen so they are shown again
+	KeyReport         // Show a report of what the instances accomplished
+	KeyWatchDevServer // Attach to the dev server read-only
+	KeyCompactList    // Toggle rendering instances on a single line
+	KeyCheatSheet     // Toggle the cheat sheet of common key bindings above the menu
+	KeyRunCommand     // Run a one-off command in the worktree and show its output
+	KeyRefreshDiff    // Recompute the diff stats of the selected instance
+	KeySelectTab      // Alt+digit keys select the tab with that position
+	KeyCommandOutput  // Show the output of the last command run in a worktree again
+	KeyRetryCreation  // Create the instance that just failed to start again
 )
 
-// GlobalKeyStringsMap is a global, immutable map string to keybinding.
+// GlobalKeyStringsMap is a global map string to keybinding. Only SetQuitKey changes it.
 var GlobalKeyStringsMap = map[string]KeyName{
 	"up":         KeyUp,
 	"k":          KeyUp,
@@ -59,9 +110,70 @@ var GlobalKeyStringsMap = map[string]KeyName{
 	"s":          KeyDevServerStart,
 	"S":          KeyDevServerStop,
 	"e":          KeyDevServerEdit,
+	"1":          KeyJumpToInstance,
+	"2":          KeyJumpToInstance,
+	"3":          KeyJumpToInstance,
+	"4":          KeyJumpToInstance,
+	"5":          KeyJumpToInstance,
+	"6":          KeyJumpToInstance,
+	"7":          KeyJumpToInstance,
+	"8":          KeyJumpToInstance,
+	"9":          KeyJumpToInstance,
+	"0":          KeyJumpToInstance,
+	"R":          KeyRebase,
+	"X":          KeyResetToBase,
+	"P":          KeyPausedOnly,
+	"ctrl+r":     KeyRecord,
+	"y":          KeyToggleAutoYes,
+	"i":          KeyDetails,
+	"u":          KeyUndo,
+	"z":          KeyToggleGroup,
+	"Z":          KeyExpandGroups,
+	"W":          KeyCopyPaths,
+	"F":          KeySendFile,
+	"T":          KeyTemplate,
+	"[":          KeyPrevRun,
+	"]":          KeyNextRun,
+	" ":          KeyMark,
+	"B":          KeyBatchPrompt,
+	"O":          KeyOpenURL,
+	"K":          KeyRestartSession,
+	"g":          KeyScrollTop,
+	"home":       KeyScrollTop,
+	"G":          KeyScrollBottom,
+	"end":        KeyScrollBottom,
+	"pgup":       KeyPageUp,
+	"pgdown":     KeyPageDown,
+	"ctrl+u":     KeyHalfPageUp,
+	"ctrl+d":     KeyHalfPageDown,
+	"ctrl+p":     KeyQuickSwitch,
+	"f":          KeyToggleFollow,
+	"C":          KeyCycleColor,
+	"w":          KeyIgnoreSpace,
+	"E":          KeyEditSettings,
+	"t":          KeyPin,
+	"Y":          KeyCopyTab,
+	"L":          KeySaveServerLog,
+	"a":          KeyArchive,
+	"A":          KeyArchiveView,
+	"v":          KeyDiffFiles,
+	"M":          KeyRepairWorktree,
+	"I":          KeyNewInPlace,
+	"H":          KeyResetHelp,
+	"b":          KeyReport,
+	"V":          KeyWatchDevServer,
+	"l":          KeyCompactList,
+	"h":          KeyCheatSheet,
+	"!":          KeyRunCommand,
+	"U":          KeyRefreshDiff,
+	"alt+1":      KeySelectTab,
+	"alt+2":      KeySelectTab,
+	"alt+3":      KeySelectTab,
+	"=":          KeyCommandOutput,
+	"ctrl+n":     KeyRetryCreation,
 }
 
-// GlobalkeyBindings is a global, immutable map of KeyName tot keybinding.
+// GlobalkeyBindings is a global map of KeyName tot keybinding. Only SetQuitKey changes it.
 var GlobalkeyBindings = map[KeyName]key.Binding{
 	KeyUp: key.NewBinding(
 		key.WithKeys("up", "k"),
@@ -123,6 +235,198 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithKeys("r"),
 		key.WithHelp("r", "resume"),
 	),
+	KeyJumpToInstance: key.NewBinding(
+		key.WithKeys("1", "2", "3", "4", "5", "6", "7", "8", "9", "0"),
+		key.WithHelp("1-9/0", "jump to instance"),
+	),
+	KeyRebase: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "rebase onto base"),
+	),
+	KeyResetToBase: key.NewBinding(
+		key.WithKeys("X"),
+		key.WithHelp("X", "reset to base"),
+	),
+	KeyPausedOnly: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "paused only"),
+	),
+	KeyRecord: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "record"),
+	),
+	KeyToggleAutoYes: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "toggle auto-yes"),
+	),
+	KeyDetails: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "details"),
+	),
+	KeyUndo: key.NewBinding(
+		key.WithKeys("u"),
+		key.WithHelp("u", "undo kill"),
+	),
+	KeyToggleGroup: key.NewBinding(
+		key.WithKeys("z"),
+		key.WithHelp("z", "collapse group"),
+	),
+	KeyExpandGroups: key.NewBinding(
+		key.WithKeys("Z"),
+		key.WithHelp("Z", "expand groups"),
+	),
+	KeyCopyPaths: key.NewBinding(
+		key.WithKeys("W"),
+		key.WithHelp("W", "copy worktree paths"),
+	),
+	KeySendFile: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "send file as prompt"),
+	),
+	KeyTemplate: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "new from template"),
+	),
+	KeyPrevRun: key.NewBinding(
+		key.WithKeys("["),
+		key.WithHelp("[", "previous run"),
+	),
+	KeyNextRun: key.NewBinding(
+		key.WithKeys("]"),
+		key.WithHelp("]", "next run"),
+	),
+	KeyMark: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "mark"),
+	),
+	KeyBatchPrompt: key.NewBinding(
+		key.WithKeys("B"),
+		key.WithHelp("B", "prompt marked"),
+	),
+	KeyOpenURL: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "open dev server url"),
+	),
+	KeyRestartSession: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "restart session"),
+	),
+	KeyScrollTop: key.NewBinding(
+		key.WithKeys("g", "home"),
+		key.WithHelp("g/home", "scroll to top"),
+	),
+	KeyScrollBottom: key.NewBinding(
+		key.WithKeys("G", "end"),
+		key.WithHelp("G/end", "scroll to bottom"),
+	),
+	KeyPageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page up"),
+	),
+	KeyPageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdown", "page down"),
+	),
+	KeyHalfPageUp: key.NewBinding(
+		key.WithKeys("ctrl+u"),
+		key.WithHelp("ctrl+u", "half page up"),
+	),
+	KeyHalfPageDown: key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("ctrl+d", "half page down"),
+	),
+	KeyQuickSwitch: key.NewBinding(
+		key.WithKeys("ctrl+p"),
+		key.WithHelp("ctrl+p", "switch instance"),
+	),
+	KeyToggleFollow: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "follow output"),
+	),
+	KeyCycleColor: key.NewBinding(
+		key.WithKeys("C"),
+		key.WithHelp("C", "cycle color"),
+	),
+	KeyIgnoreSpace: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "ignore whitespace"),
+	),
+	KeyEditSettings: key.NewBinding(
+		key.WithKeys("E"),
+		key.WithHelp("E", "edit settings.json"),
+	),
+	KeyPin: key.NewBinding(
+		key.WithKeys("t"),
+		key.WithHelp("t", "pin"),
+	),
+	KeyCopyTab: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "copy"),
+	),
+	KeySaveServerLog: key.NewBinding(
+		key.WithKeys("L"),
+		key.WithHelp("L", "save server log"),
+	),
+	KeyArchive: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "archive"),
+	),
+	KeyArchiveView: key.NewBinding(
+		key.WithKeys("A"),
+		key.WithHelp("A", "archived"),
+	),
+	KeyDiffFiles: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "changed files"),
+	),
+	KeyRepairWorktree: key.NewBinding(
+		key.WithKeys("M"),
+		key.WithHelp("M", "repair worktree"),
+	),
+	KeyNewInPlace: key.NewBinding(
+		key.WithKeys("I"),
+		key.WithHelp("I", "new in place"),
+	),
+	KeyResetHelp: key.NewBinding(
+		key.WithKeys("H"),
+		key.WithHelp("H", "reset help"),
+	),
+	KeyReport: key.NewBinding(
+		key.WithKeys("b"),
+		key.WithHelp("b", "report"),
+	),
+	KeyWatchDevServer: key.NewBinding(
+		key.WithKeys("V"),
+		key.WithHelp("V", "watch dev server"),
+	),
+	KeyCompactList: key.NewBinding(
+		key.WithKeys("l"),
+		key.WithHelp("l", "compact list"),
+	),
+	KeyCheatSheet: key.NewBinding(
+		key.WithKeys("h"),
+		key.WithHelp("h", "key hints"),
+	),
+	KeyRunCommand: key.NewBinding(
+		key.WithKeys("!"),
+		key.WithHelp("!", "run command"),
+	),
+	KeyRefreshDiff: key.NewBinding(
+		key.WithKeys("U"),
+		key.WithHelp("U", "refresh diff"),
+	),
+	KeySelectTab: key.NewBinding(
+		key.WithKeys("alt+1", "alt+2", "alt+3"),
+		key.WithHelp("alt+1-3", "select tab"),
+	),
+	KeyCommandOutput: key.NewBinding(
+		key.WithKeys("="),
+		key.WithHelp("=", "command output"),
+	),
+	KeyRetryCreation: key.NewBinding(
+		key.WithKeys("ctrl+n"),
+		key.WithHelp("ctrl+n", "retry creation"),
+	),
 
 	// -- Special keybindings --
 
@@ -146,3 +450,26 @@ var GlobalkeyBindings = map[KeyName]key.Binding{
 		key.WithHelp("e", "edit dev server config"),
 	),
 }
+
+// SetQuitKey makes k quit instead of the current quit key, e.g. to avoid quitting with a stray q. It
+// is meant to be called once at startup, and fails if k is already bound to another action. ctrl+c
+// always quits, and ctrl+z always suspends.
+func SetQuitKey(k string) error {
+	if k == "ctrl+z" {
+		return fmt.Errorf("quit key %q is reserved for suspending to the shell", k)
+	}
+	if name, ok := GlobalKeyStringsMap[k]; ok && name != KeyQuit {
+		return fmt.Errorf("quit key %q is already bound to %q", k, GlobalkeyBindings[name].Help().Desc)
+	}
+	for old, name := range GlobalKeyStringsMap {
+		if name == KeyQuit {
+			delete(GlobalKeyStringsMap, old)
+		}
+	}
+	GlobalKeyStringsMap[k] = KeyQuit
+	GlobalkeyBindings[KeyQuit] = key.NewBinding(
+		key.WithKeys(k),
+		key.WithHelp(k, "quit"),
+	)
+	return nil
+}
@@ -2,9 +2,11 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,8 +14,42 @@ var (
 	WarningLog *log.Logger
 	InfoLog    *log.Logger
 	ErrorLog   *log.Logger
+	// DebugLog is for frequent, low-level messages, e.g. periodic checks. It is only written at
+	// LevelDebug.
+	DebugLog *log.Logger
 )
 
+// Level is the least severe kind of message written to the log file.
+type Level int
+
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+)
+
+// DefaultLevel is the level used when none is configured.
+const DefaultLevel = LevelInfo
+
+// ParseLevel parses a level name: error, warn, info or debug. An empty name is DefaultLevel.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "":
+		return DefaultLevel, nil
+	case "error":
+		return LevelError, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "info":
+		return LevelInfo, nil
+	case "debug":
+		return LevelDebug, nil
+	default:
+		return DefaultLevel, fmt.Errorf("unknown log level %q: use error, warn, info or debug", name)
+	}
+}
+
 var logFileName = filepath.Join(os.TempDir(), "claudesquad.log")
 
 var globalLogFile *os.File
@@ -38,8 +74,46 @@ func Initialize(daemon bool) {
 	InfoLog = log.New(f, fmt.Sprintf(fmtS, "INFO:"), log.Ldate|log.Ltime|log.Lshortfile)
 	WarningLog = log.New(f, fmt.Sprintf(fmtS, "WARNING:"), log.Ldate|log.Ltime|log.Lshortfile)
 	ErrorLog = log.New(f, fmt.Sprintf(fmtS, "ERROR:"), log.Ldate|log.Ltime|log.Lshortfile)
+	DebugLog = log.New(f, fmt.Sprintf(fmtS, "DEBUG:"), log.Ldate|log.Ltime|log.Lshortfile)
 
 	globalLogFile = f
+	globalLevel = DefaultLevel
+	applyLevel()
+}
+
+var globalLevel = DefaultLevel
+
+// Configure sets the level and, if path isn't empty, moves logging to the file at path. It is
+// called once the config is loaded, after Initialize. Messages logged before go to the default file.
+func Configure(level Level, path string) error {
+	if path != "" && path != logFileName {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return fmt.Errorf("could not open log file: %w", err)
+		}
+		_ = globalLogFile.Close()
+		globalLogFile = f
+		logFileName = path
+	}
+	globalLevel = level
+	applyLevel()
+	return nil
+}
+
+// applyLevel points the loggers at or above globalLevel to the log file and discards the others.
+func applyLevel() {
+	for level, logger := range map[Level]*log.Logger{
+		LevelError: ErrorLog,
+		LevelWarn:  WarningLog,
+		LevelInfo:  InfoLog,
+		LevelDebug: DebugLog,
+	} {
+		if level <= globalLevel {
+			logger.SetOutput(globalLogFile)
+		} else {
+			logger.SetOutput(io.Discard)
+		}
+	}
 }
 
 func Close() {
@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -23,7 +24,13 @@ var (
 	programFlag string
 	autoYesFlag bool
 	daemonFlag  bool
-	rootCmd     = &cobra.Command{
+	// promptFileFlag is a path to a file whose contents seed the prompt for the first new instance
+	promptFileFlag string
+	// promptsFileFlag is a path to a file with one prompt per line, dispatched round-robin to new instances
+	promptsFileFlag string
+	// batchInstancesFlag is the number of instances to spread the prompts of promptsFileFlag over
+	batchInstancesFlag int
+	rootCmd            = &cobra.Command{
 		Use:   "claude-squad",
 		Short: "Claude Squad - Manage multiple AI agents like Claude Code, Aider, Codex, and Amp.",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -35,6 +42,9 @@ var (
 
 			if daemonFlag {
 				cfg := config.LoadConfig()
+				configureLogging(cfg)
+				tmux.SetTmuxPrefix(cfg.TmuxPrefix)
+				tmux.SetProgramPatterns(tmux.CompileProgramPatterns(cfg.ProgramPatterns))
 				err := daemon.RunDaemon(cfg)
 				log.ErrorLog.Printf("failed to start daemon %v", err)
 				return err
@@ -50,7 +60,32 @@ var (
 				return fmt.Errorf("error: claude-squad must be run from within a git repository")
 			}
 
+			if err := config.CheckConfigDirWritable(); err != nil {
+				return fmt.Errorf("error: %w", err)
+			}
+
 			cfg := config.LoadConfig()
+			configureLogging(cfg)
+			tmux.SetTmuxPrefix(cfg.TmuxPrefix)
+			tmux.SetProgramPatterns(tmux.CompileProgramPatterns(cfg.ProgramPatterns))
+
+			var initialPrompt string
+			if promptFileFlag != "" {
+				initialPrompt, err = readPromptFile(promptFileFlag, cfg.GetPromptFileMaxBytes())
+				if err != nil {
+					return err
+				}
+			}
+
+			var batch app.PromptBatch
+			if promptsFileFlag != "" {
+				batch.Prompts, err = readPromptsFile(promptsFileFlag, cfg.GetPromptFileMaxBytes())
+				if err != nil {
+					return err
+				}
+				batch.Name = strings.TrimSuffix(filepath.Base(promptsFileFlag), filepath.Ext(promptsFileFlag))
+				batch.Instances = batchInstancesFlag
+			}
 
 			// Program flag overrides config
 			program := cfg.DefaultProgram
@@ -74,7 +109,7 @@ var (
 				log.ErrorLog.Printf("failed to stop daemon: %v", err)
 			}
 
-			return app.Run(ctx, program, autoYes)
+			return app.Run(ctx, program, autoYes, initialPrompt, batch)
 		},
 	}
 
@@ -91,6 +126,7 @@ var (
 			}
 
 			allFlag, _ := cmd.Flags().GetBool("all")
+			tmux.SetTmuxPrefix(config.LoadConfig().TmuxPrefix)
 
 			if allFlag {
 				if err := resetAllRepos(currentDir); err != nil {
@@ -152,6 +188,48 @@ var (
 	}
 )
 
+// configureLogging applies the log level and log file of cfg. Errors are logged to the default log
+// file, which stays in use.
+func configureLogging(cfg *config.Config) {
+	if err := log.Configure(cfg.GetLogLevel(), cfg.LogFile); err != nil {
+		log.ErrorLog.Printf("failed to configure logging: %v", err)
+	}
+}
+
+// readPromptFile reads a prompt from path with session.ReadPromptFile, stripping the trailing
+// newline. Files larger than maxBytes are rejected rather than truncated, since the prompt is sent
+// before the user could notice that its end is missing.
+func readPromptFile(path string, maxBytes int) (string, error) {
+	content, truncated, err := session.ReadPromptFile(path, maxBytes)
+	if err != nil {
+		return "", err
+	}
+	if truncated {
+		return "", fmt.Errorf("prompt file %s is larger than %d bytes (prompt_file_max_bytes)", path, maxBytes)
+	}
+
+	prompt := strings.TrimRight(content, "\r\n")
+	if strings.TrimSpace(prompt) == "" {
+		return "", fmt.Errorf("prompt file %s is empty", path)
+	}
+	return prompt, nil
+}
+
+// readPromptsFile reads one prompt per line from path, skipping blank lines.
+func readPromptsFile(path string, maxBytes int) ([]string, error) {
+	content, err := readPromptFile(path, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	var prompts []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			prompts = append(prompts, line)
+		}
+	}
+	return prompts, nil
+}
+
 func resetCurrentRepo(currentDir string) error {
 	state := config.LoadStateForRepo(currentDir)
 	storage, err := session.NewStorage(state)
@@ -214,6 +292,12 @@ func init() {
 		"Program to run in new instances (e.g. 'aider --model ollama_chat/gemma3:1b')")
 	rootCmd.Flags().BoolVarP(&autoYesFlag, "autoyes", "y", false,
 		"[experimental] If enabled, all instances will automatically accept prompts")
+	rootCmd.Flags().StringVar(&promptFileFlag, "prompt-file", "",
+		"Path to a file whose contents seed the prompt for the first new instance")
+	rootCmd.Flags().StringVar(&promptsFileFlag, "prompts-file", "",
+		"Path to a file with one prompt per line to dispatch round-robin to new instances on launch")
+	rootCmd.Flags().IntVar(&batchInstancesFlag, "batch-instances", 0,
+		"Number of instances to spread the prompts of --prompts-file over (default one per prompt)")
 	rootCmd.Flags().BoolVar(&daemonFlag, "daemon", false, "Run a program that loads all sessions"+
 		" and runs autoyes mode on them.")
 
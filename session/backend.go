@@ -0,0 +1,111 @@
+package session
+
+import (
+	"claude-squad/log"
+	"claude-squad/session/tmux"
+)
+
+// Backend is the terminal session that runs an instance's program. Instance only talks to its
+// session through this interface, so environments without tmux can provide a different
+// implementation (screen, a plain PTY, ...). tmux is the default.
+type Backend interface {
+	// Start creates a new session running the program in workDir.
+	Start(workDir string) error
+	// Restore reconnects to a session that already exists.
+	Restore() error
+	// DoesSessionExist returns true if the underlying session is alive.
+	DoesSessionExist() bool
+	// Close terminates the session.
+	Close() error
+
+	// Attach connects the current terminal to the session. The returned channel is closed on detach.
+	Attach() (chan struct{}, error)
+	// SwitchClient attaches without taking over the terminal, when supported by the backend.
+	SwitchClient() error
+	// DetachSafely disconnects from the session while leaving it running.
+	DetachSafely() error
+
+	// CapturePaneContent returns the visible content of the session.
+	CapturePaneContent() (string, error)
+	// CapturePaneContentWithOptions returns the content between the start and end lines.
+	CapturePaneContentWithOptions(start, end string) (string, error)
+	// HasUpdated reports whether the content changed since the last call and whether the program
+	// is waiting on a prompt.
+	HasUpdated() (updated bool, hasPrompt bool)
+
+	// SendKeys sends keys to the program.
+	SendKeys(keys string) error
+	// TapEnter sends an enter key press to the program.
+	TapEnter() error
+
+	// SetDetachedSize sets the size of the session while no terminal is attached.
+	SetDetachedSize(width, height int) error
+	// Resize resizes the live session and reflows its content to the new size.
+	Resize(width, height int) error
+	// PaneSize returns the width and height of the session.
+	PaneSize() (int, int, error)
+}
+
+// nameMigrator is implemented by backends whose session names changed over time, through a
+// configurable prefix or a new sanitization of titles.
+type nameMigrator interface {
+	MigrateFromPrefix(oldPrefix string) error
+	MigrateLegacyName() error
+}
+
+// startupCommandRunner is implemented by backends that can customize a new session with
+// commands, e.g. split it into panes.
+type startupCommandRunner interface {
+	RunStartupCommands(commands []string) error
+}
+
+// sessionRenamer is implemented by backends whose sessions can be renamed, so a replacement
+// session can start next to the one it replaces.
+type sessionRenamer interface {
+	Rename(name string) error
+}
+
+var _ Backend = (*tmux.TmuxSession)(nil)
+
+// NewBackend creates the backend for a new instance. It defaults to tmux and can be replaced to
+// run instances on a different backend.
+var NewBackend = func(name, program string) Backend {
+	return tmux.NewTmuxSession(name, program)
+}
+
+// migrateSessionName picks up sessions created under the tmux prefix the instance recorded, if the
+// configured prefix changed since, or with the legacy session naming before the backend is restored.
+func (i *Instance) migrateSessionName() {
+	migrator, ok := i.backend.(nameMigrator)
+	if !ok {
+		return
+	}
+	if err := migrator.MigrateLegacyName(); err != nil {
+		log.WarningLog.Printf("failed to migrate legacy tmux session name for %s: %v", i.Title, err)
+	}
+	if i.sessionPrefix == "" || i.sessionPrefix == tmux.TmuxPrefix {
+		return
+	}
+	if err := migrator.MigrateFromPrefix(i.sessionPrefix); err != nil {
+		// The prefix stays recorded, so the migration is tried again on the next start.
+		log.WarningLog.Printf("failed to migrate tmux session for %s from prefix %s: %v", i.Title, i.sessionPrefix, err)
+		return
+	}
+	i.sessionPrefix = tmux.TmuxPrefix
+}
+
+// runTmuxStartupCommands customizes a newly created session with the instance's tmux startup
+// commands. Failures are only logged, since the program runs fine without them.
+func (i *Instance) runTmuxStartupCommands() {
+	if len(i.tmuxStartupCommands) == 0 {
+		return
+	}
+	runner, ok := i.backend.(startupCommandRunner)
+	if !ok {
+		log.WarningLog.Printf("the session backend of %s can't run tmux startup commands", i.Title)
+		return
+	}
+	if err := runner.RunStartupCommands(i.tmuxStartupCommands); err != nil {
+		log.WarningLog.Printf("failed to run tmux startup commands for %s: %v", i.Title, err)
+	}
+}
@@ -0,0 +1,36 @@
+package session
+
+import (
+	"hash/fnv"
+	"slices"
+)
+
+// InstancePalette holds the colors instances can be tinted with, as hex strings.
+var InstancePalette = []string{
+	"#5fafd7", // blue
+	"#51bd73", // green
+	"#ffb000", // amber
+	"#d787d7", // pink
+	"#5fd7d7", // cyan
+	"#ff8700", // orange
+	"#af87ff", // purple
+	"#d7d75f", // yellow
+}
+
+// Color returns the instance's color: the one assigned with CycleColor, or else one derived from
+// the title so it stays the same across restarts.
+func (i *Instance) Color() string {
+	if i.color != "" {
+		return i.color
+	}
+	h := fnv.New32a()
+	h.Write([]byte(i.Title))
+	return InstancePalette[h.Sum32()%uint32(len(InstancePalette))]
+}
+
+// CycleColor assigns the palette color after the current one and returns it.
+func (i *Instance) CycleColor() string {
+	idx := slices.Index(InstancePalette, i.Color())
+	i.color = InstancePalette[(idx+1)%len(InstancePalette)]
+	return i.color
+}
@@ -0,0 +1,62 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// CommandResult is the outcome of a command run with RunCommand.
+type CommandResult struct {
+	Command string
+	// Output is the combined stdout and stderr, cut to the last maxLines lines. Escape sequences are
+	// removed, and of a line overwritten with carriage returns only the last version is kept.
+	Output string
+	// Truncated is true if earlier lines of the output were dropped.
+	Truncated bool
+	Duration  time.Duration
+	// Err is set if the command couldn't run, failed or timed out.
+	Err error
+}
+
+// RunCommand runs command with sh in the instance's worktree and returns its output once it exits
+// or timeout passes. It doesn't touch the agent's session.
+func (i *Instance) RunCommand(ctx context.Context, command string, timeout time.Duration, maxLines int) CommandResult {
+	result := CommandResult{Command: command}
+	if !i.started || i.gitWorktree == nil {
+		result.Err = fmt.Errorf("cannot run a command in instance that has not been started")
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = i.gitWorktree.GetWorktreePath()
+	// Don't wait for processes the command left running once it was killed.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	result.Duration = time.Since(start)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	result.Err = err
+
+	lines := strings.Split(strings.TrimRight(ansi.Strip(string(output)), "\n"), "\n")
+	for n, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		lines[n] = line[strings.LastIndex(line, "\r")+1:]
+	}
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+		result.Truncated = true
+	}
+	result.Output = strings.Join(lines, "\n")
+	return result
+}
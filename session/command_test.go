@@ -0,0 +1,44 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	worktreePath := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "a.txt"), []byte("a\n"), 0644))
+	instance := &Instance{
+		Title:       "cmd",
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage(worktreePath, worktreePath, "cmd", "cmd", ""),
+	}
+
+	result := instance.RunCommand(context.Background(), "ls; echo oops >&2", time.Minute, 10)
+	require.NoError(t, result.Err)
+	assert.Equal(t, "a.txt\noops", result.Output, "the command runs in the worktree and captures stderr")
+	assert.False(t, result.Truncated)
+
+	result = instance.RunCommand(context.Background(), "seq 1 5; exit 3", time.Minute, 2)
+	assert.ErrorContains(t, result.Err, "exit status 3")
+	assert.Equal(t, "4\n5", result.Output)
+	assert.True(t, result.Truncated)
+
+	result = instance.RunCommand(context.Background(), `printf '\033[31mred\033[0m\r\n10%%\r50%%\rdone\n'`, time.Minute, 10)
+	require.NoError(t, result.Err)
+	assert.Equal(t, "red\ndone", result.Output, "escape sequences and overwritten progress are dropped")
+
+	result = instance.RunCommand(context.Background(), "sleep 10", 100*time.Millisecond, 10)
+	assert.ErrorContains(t, result.Err, "timed out")
+	assert.Less(t, result.Duration, 5*time.Second)
+
+	result = createTestInstance().RunCommand(context.Background(), "true", time.Minute, 10)
+	assert.ErrorContains(t, result.Err, "has not been started")
+}
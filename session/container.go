@@ -0,0 +1,27 @@
+package session
+
+import "fmt"
+
+// launchCommandSetter is implemented by backends that can start a different command than the
+// program they were created with, e.g. the program wrapped in a container. The program is still
+// used to recognize the agent, e.g. its prompts.
+type launchCommandSetter interface {
+	SetLaunchCommand(command string)
+}
+
+// setContainerCommand makes backend start the program in the instance's container, if it has one.
+// It must be called once the worktree is known and before the backend is started.
+func (i *Instance) setContainerCommand(backend Backend) error {
+	if i.Container == nil {
+		return nil
+	}
+	if err := i.Container.Validate(); err != nil {
+		return err
+	}
+	setter, ok := backend.(launchCommandSetter)
+	if !ok {
+		return fmt.Errorf("the session backend can't run programs in a container")
+	}
+	setter.SetLaunchCommand(i.Container.Command(i.Command(), i.gitWorktree.GetWorktreePath(), i.gitWorktree.GetRepoPath()))
+	return nil
+}
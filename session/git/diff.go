@@ -1,7 +1,13 @@
 package git
 
 import (
+	"claude-squad/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // DiffStats holds statistics about the changes in a diff
@@ -12,6 +18,12 @@ type DiffStats struct {
 	Added int
 	// Removed is the number of removed lines
 	Removed int
+	// Files are the paths of the changed files, relative to the worktree root
+	Files []string
+	// ComputedAt is when these stats were computed
+	ComputedAt time.Time
+	// fileLines are the lines added and removed per file in Files, see FileStats
+	fileLines map[string][2]int
 	// Error holds any error that occurred during diff computation
 	// This allows propagating setup errors (like missing base commit) without breaking the flow
 	Error error
@@ -21,18 +33,27 @@ func (d *DiffStats) IsEmpty() bool {
 	return d.Added == 0 && d.Removed == 0 && d.Content == ""
 }
 
-// Diff returns the git diff between the worktree and the base branch along with statistics
-func (g *GitWorktree) Diff() *DiffStats {
-	stats := &DiffStats{}
+// Diff returns the git diff between the worktree and the base branch along with statistics. The
+// statistics count the lines of the diff produced with opts, so ignored whitespace changes aren't
+// counted either.
+func (g *GitWorktree) Diff(opts config.DiffOptions) *DiffStats {
+	stats := &DiffStats{ComputedAt: time.Now()}
 
-	// -N stages untracked files (intent to add), including them in the diff
-	_, err := g.runGitCommand(g.worktreePath, "add", "-N", ".")
-	if err != nil {
-		stats.Error = err
-		return stats
+	// The diff of an in-place session is against HEAD. Its untracked files aren't staged, since
+	// the index belongs to the user's checkout.
+	base := "HEAD"
+	if !g.inPlace {
+		// -N stages untracked files (intent to add), including them in the diff
+		_, err := g.runGitCommand(g.worktreePath, "add", "-N", ".")
+		if err != nil {
+			stats.Error = err
+			return stats
+		}
+		base = g.GetBaseCommitSHA()
 	}
 
-	content, err := g.runGitCommand(g.worktreePath, "--no-pager", "diff", g.GetBaseCommitSHA())
+	args := append([]string{"--no-pager", "diff"}, opts.GitArgs()...)
+	content, err := g.runGitCommand(g.worktreePath, append(args, base)...)
 	if err != nil {
 		stats.Error = err
 		return stats
@@ -47,5 +68,144 @@ func (g *GitWorktree) Diff() *DiffStats {
 	}
 	stats.Content = content
 
+	numstat, err := g.runGitCommand(g.worktreePath, append(append(args, "--numstat", "-z"), base)...)
+	if err != nil {
+		stats.Error = err
+		return stats
+	}
+	stats.Files, stats.fileLines = parseNumstat(numstat)
+
 	return stats
 }
+
+// parseNumstat parses the output of `git diff --numstat -z` into the changed paths and the lines
+// added and removed in each. With -z paths are never quoted, and a rename is followed by its old
+// and new path instead of one path. Binary files count no lines.
+func parseNumstat(output string) (files []string, lines map[string][2]int) {
+	lines = make(map[string][2]int)
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		path := parts[2]
+		if path == "" && i+2 < len(fields) {
+			// Renamed: the old and new path follow.
+			path = fields[i+2]
+			i += 2
+		}
+		added, _ := strconv.Atoi(parts[0])
+		removed, _ := strconv.Atoi(parts[1])
+		files = append(files, path)
+		lines[path] = [2]int{added, removed}
+	}
+	return files, lines
+}
+
+// FileDiff returns the diff of path between the worktree and the base branch, computed like Diff.
+// Renamed files are found by their new path.
+func (g *GitWorktree) FileDiff(opts config.DiffOptions, path string) (string, error) {
+	base := "HEAD"
+	if !g.inPlace {
+		base = g.GetBaseCommitSHA()
+	}
+	args := append([]string{"--no-pager", "diff"}, opts.GitArgs()...)
+	return g.runGitCommand(g.worktreePath, append(args, base, "--", path)...)
+}
+
+// FileStats returns the number of lines added and removed in path. It is zero for stats loaded
+// from storage until they are computed again.
+func (d *DiffStats) FileStats(path string) (added, removed int) {
+	lines := d.fileLines[path]
+	return lines[0], lines[1]
+}
+
+// HasChangedSince reports whether the worktree changed since token was taken and returns the
+// token for its current state. The token covers HEAD, its reflog and the status of the worktree
+// with the modification times of the changed files. It takes a single git status, so it's cheap
+// enough to check on every tick before calling Diff. An empty token is always considered changed.
+func (g *GitWorktree) HasChangedSince(token string) (bool, string, error) {
+	current, err := g.changeToken()
+	if err != nil {
+		return true, "", err
+	}
+	return token != current, current, nil
+}
+
+// changeToken builds the token used by HasChangedSince.
+func (g *GitWorktree) changeToken() (string, error) {
+	gitDir, err := g.gitDir()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(g.baseCommitSHA)
+
+	// HEAD holds the checked out ref and logs/HEAD grows on every commit, reset and rebase. The
+	// index is left out on purpose: Diff rewrites it with `git add -N` every time it runs, and
+	// the diff against the base commit doesn't depend on what's staged.
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	b.Write(head)
+	if info, err := os.Stat(filepath.Join(gitDir, "logs", "HEAD")); err == nil {
+		fmt.Fprintf(&b, "|reflog:%d:%d", info.ModTime().UnixNano(), info.Size())
+	}
+
+	// Edits don't touch git's metadata until they're staged. git status lists the changed and
+	// untracked files without walking ignored directories like node_modules, and the modification
+	// time and size of the listed files catch further edits to files that were already changed.
+	// Optional locks are skipped so the check doesn't write the index the agent may be using.
+	status, err := g.runGitCommand(g.worktreePath, "--no-optional-locks", "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	entries := strings.Split(status, "\x00")
+	for n := 0; n < len(entries); n++ {
+		entry := entries[n]
+		if len(entry) < 4 {
+			continue
+		}
+		if entry[0] == 'R' || entry[0] == 'C' {
+			// The source path of a rename or copy follows as an entry of its own.
+			n++
+		}
+		// Only the path counts, not the status: Diff turns untracked files into intent-to-add ones.
+		fmt.Fprintf(&b, "|%s", entry[3:])
+		if info, err := os.Stat(filepath.Join(g.worktreePath, entry[3:])); err == nil {
+			fmt.Fprintf(&b, ":%d:%d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+
+	return b.String(), nil
+}
+
+// gitDir returns the git directory of the worktree. Linked worktrees have a .git file pointing
+// to their directory inside the main repository.
+func (g *GitWorktree) gitDir() (string, error) {
+	dotGit := filepath.Join(g.worktreePath, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat .git: %w", err)
+	}
+	if info.IsDir() {
+		return dotGit, nil
+	}
+
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return "", fmt.Errorf("failed to read .git: %w", err)
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("unexpected .git file content in %s", g.worktreePath)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(g.worktreePath, gitDir)
+	}
+	return gitDir, nil
+}
@@ -0,0 +1,40 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// checkWorktreeDirectory returns an actionable error if new worktrees can't be created in dir, so
+// setup fails before git does with a less helpful message.
+func checkWorktreeDirectory(dir string) error {
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		if errors.Is(err, syscall.EROFS) {
+			return fmt.Errorf("worktree directory %s is on a read-only filesystem: %w", dir, err)
+		}
+		return fmt.Errorf("worktree directory %s is not writable, fix its permissions or ownership: %w", dir, err)
+	}
+	name := probe.Name()
+	closeErr := probe.Close()
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("failed to clean up write check in worktree directory %s: %w", dir, err)
+	}
+	return closeErr
+}
+
+// describeWorktreeAddError explains a failed `git worktree add` when the worktree is on a different
+// filesystem than the repository, which git handles poorly on some setups (e.g. network or FUSE
+// mounts). Other errors are returned unchanged.
+func (g *GitWorktree) describeWorktreeAddError(err error) error {
+	dir := filepath.Dir(g.worktreePath)
+	same, statErr := sameFilesystem(g.repoPath, dir)
+	if statErr != nil || same {
+		return err
+	}
+	return fmt.Errorf("%w: the worktree directory %s is on a different filesystem than the repository %s, "+
+		"check that both are local, writable filesystems", err, dir, g.repoPath)
+}
@@ -0,0 +1,59 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWorktreeDirectory(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name: "writable directory",
+			dir:  func(t *testing.T) string { return t.TempDir() },
+		},
+		{
+			name:    "missing directory",
+			dir:     func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing") },
+			wantErr: "is not writable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tt.dir(t)
+			err := checkWorktreeDirectory(dir)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// The write probe must not be left behind.
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestSameFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0755))
+
+	same, err := sameFilesystem(dir, sub)
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	_, err = sameFilesystem(dir, filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
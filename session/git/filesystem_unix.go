@@ -0,0 +1,34 @@
+//go:build !windows
+
+package git
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// sameFilesystem returns true if paths a and b are on the same device.
+func sameFilesystem(a, b string) (bool, error) {
+	devA, err := deviceOf(a)
+	if err != nil {
+		return false, err
+	}
+	devB, err := deviceOf(b)
+	if err != nil {
+		return false, err
+	}
+	return devA == devB, nil
+}
+
+func deviceOf(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no device information for %s", path)
+	}
+	return uint64(stat.Dev), nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package git
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// sameFilesystem returns true if paths a and b are on the same volume.
+func sameFilesystem(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(filepath.VolumeName(absA), filepath.VolumeName(absB)), nil
+}
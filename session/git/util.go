@@ -2,6 +2,7 @@ package git
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 )
 
 // sanitizeBranchName transforms an arbitrary string into a Git branch name friendly string.
@@ -36,6 +38,40 @@ func sanitizeBranchName(s string) string {
 	return s
 }
 
+// ValidateBranchName returns an error if name isn't a legal name for a new git branch.
+func ValidateBranchName(name string) error {
+	if name == "" {
+		return fmt.Errorf("branch name cannot be empty")
+	}
+	// check-ref-format accepts both, but git refuses to create branches named like this.
+	if strings.HasPrefix(name, "-") || name == "HEAD" {
+		return fmt.Errorf("'%s' is not a valid branch name", name)
+	}
+	if err := exec.Command("git", "check-ref-format", "refs/heads/"+name).Run(); err != nil {
+		return fmt.Errorf("'%s' is not a valid branch name", name)
+	}
+	return nil
+}
+
+// BranchExists returns true if the repository containing path has a local branch named name.
+func BranchExists(path string, name string) (bool, error) {
+	repoRoot, err := findGitRepoRoot(path)
+	if err != nil {
+		return false, err
+	}
+	repo, err := git.PlainOpen(repoRoot)
+	if err != nil {
+		return false, fmt.Errorf("failed to open repository: %w", err)
+	}
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName(name), false); err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up branch %s: %w", name, err)
+	}
+	return true, nil
+}
+
 // checkGHCLI checks if GitHub CLI is installed and configured
 func checkGHCLI() error {
 	// Check if gh is installed
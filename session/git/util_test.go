@@ -71,3 +71,35 @@ func TestSanitizeBranchName(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateBranchName(t *testing.T) {
+	for _, name := range []string{"feature", "user/fix-login", "release-1.2"} {
+		if err := ValidateBranchName(name); err != nil {
+			t.Errorf("ValidateBranchName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "HEAD", "-feature", "has space", "a..b", "ends.lock", "trailing/", "colon:name", "what?"} {
+		if err := ValidateBranchName(name); err == nil {
+			t.Errorf("ValidateBranchName(%q) = nil, want an error", name)
+		}
+	}
+}
+
+func TestBranchExists(t *testing.T) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.email", "test@example.com")
+	runGit(t, repo, "config", "user.name", "test")
+	commitFile(t, repo, "a.txt", "a")
+	runGit(t, repo, "branch", "user/feature")
+
+	for name, want := range map[string]bool{"main": true, "user/feature": true, "user/other": false} {
+		got, err := BranchExists(repo, name)
+		if err != nil {
+			t.Fatalf("BranchExists(%q) error = %v", name, err)
+		}
+		if got != want {
+			t.Errorf("BranchExists(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
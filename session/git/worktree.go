@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -36,6 +37,23 @@ type GitWorktree struct {
 	branchName string
 	// Base commit hash for the worktree
 	baseCommitSHA string
+	// baseBranch is the branch the worktree is created from and rebased onto. Empty means HEAD; Setup
+	// then records the branch HEAD is on.
+	baseBranch string
+	// inPlace is true if the session runs in the repository's own checkout instead of a worktree.
+	inPlace bool
+}
+
+// SetBaseBranch sets the branch a new worktree is created from instead of HEAD. It must be called
+// before Setup, or after loading the worktree from storage.
+func (g *GitWorktree) SetBaseBranch(branch string) {
+	g.baseBranch = branch
+}
+
+// BaseBranch returns the branch the worktree was created from. It is empty for worktrees created
+// from a detached HEAD, and for ones saved before it was recorded.
+func (g *GitWorktree) BaseBranch() string {
+	return g.baseBranch
 }
 
 func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName string, branchName string, baseCommitSHA string) *GitWorktree {
@@ -48,6 +66,46 @@ func NewGitWorktreeFromStorage(repoPath string, worktreePath string, sessionName
 	}
 }
 
+// NewInPlaceWorktree creates a GitWorktree for a session that runs in the checkout of the
+// repository at repoPath instead of a worktree of its own. It stays on the checked out branch and
+// its diff is against HEAD. Nothing is created or removed on disk: Setup only records the base
+// commit, and Cleanup, Remove and Repair do nothing.
+func NewInPlaceWorktree(repoPath string, sessionName string) (tree *GitWorktree, branchname string, err error) {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get absolute path of %s: %w", repoPath, err)
+	}
+	repoPath, err = findGitRepoRoot(absPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tree = &GitWorktree{
+		repoPath:     repoPath,
+		worktreePath: repoPath,
+		sessionName:  sessionName,
+		inPlace:      true,
+	}
+	// symbolic-ref also works before the first commit. A detached HEAD has no branch.
+	branch, err := tree.runGitCommand(repoPath, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		branch = "HEAD"
+	}
+	tree.branchName = strings.TrimSpace(branch)
+	return tree, tree.branchName, nil
+}
+
+// SetInPlace marks a worktree loaded from storage as one that runs in the repository's checkout,
+// see NewInPlaceWorktree.
+func (g *GitWorktree) SetInPlace() {
+	g.inPlace = true
+}
+
+// InPlace returns true if the session runs in the repository's checkout instead of a worktree.
+func (g *GitWorktree) InPlace() bool {
+	return g.inPlace
+}
+
 // NewGitWorktree creates a new GitWorktree instance
 func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, branchname string, err error) {
 	cfg := config.LoadConfig()
@@ -55,7 +113,13 @@ func NewGitWorktree(repoPath string, sessionName string) (tree *GitWorktree, bra
 	// Sanitize the final branch name to handle invalid characters from any source
 	// (e.g., backslashes from Windows domain usernames like DOMAIN\user)
 	branchName = sanitizeBranchName(branchName)
+	return NewGitWorktreeWithBranch(repoPath, sessionName, branchName)
+}
 
+// NewGitWorktreeWithBranch creates a new GitWorktree instance on branchName instead of a branch
+// derived from the session name. The name is used as is, so it should be checked with
+// ValidateBranchName first. Setup reuses the branch if it already exists.
+func NewGitWorktreeWithBranch(repoPath string, sessionName string, branchName string) (tree *GitWorktree, branchname string, err error) {
 	// Convert repoPath to absolute path
 	absPath, err := filepath.Abs(repoPath)
 	if err != nil {
@@ -111,7 +175,8 @@ func (g *GitWorktree) GetBaseCommitSHA() string {
 	return g.baseCommitSHA
 }
 
-// copySettingsAndEnvFiles copies .claude-squad/settings.json and .env files from main repo to worktree
+// copySettingsAndEnvFiles copies .claude-squad/settings.json, .env files and the files matching the
+// settings' copy_files patterns from main repo to worktree
 func (g *GitWorktree) copySettingsAndEnvFiles() error {
 	if err := config.CopySettingsToWorktree(g.repoPath, g.worktreePath); err != nil {
 		return fmt.Errorf("failed to copy settings: %w", err)
@@ -119,5 +184,14 @@ func (g *GitWorktree) copySettingsAndEnvFiles() error {
 	if err := config.CopyEnvFiles(g.repoPath, g.worktreePath); err != nil {
 		return fmt.Errorf("failed to copy env files: %w", err)
 	}
+	settings, err := config.LoadDevServerSettings(g.repoPath)
+	if err != nil {
+		return err
+	}
+	if settings != nil {
+		if err := config.CopyFiles(g.repoPath, g.worktreePath, settings.CopyFiles); err != nil {
+			return fmt.Errorf("failed to copy files: %w", err)
+		}
+	}
 	return nil
 }
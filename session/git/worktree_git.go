@@ -3,7 +3,9 @@ package git
 import (
 	"claude-squad/log"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -22,6 +24,9 @@ func (g *GitWorktree) runGitCommand(path string, args ...string) (string, error)
 
 // PushChanges commits and pushes changes in the worktree to the remote branch
 func (g *GitWorktree) PushChanges(commitMessage string, open bool) error {
+	if g.inPlace {
+		return fmt.Errorf("can't push a session that runs in the repository's checkout")
+	}
 	if err := checkGHCLI(); err != nil {
 		return err
 	}
@@ -112,8 +117,198 @@ func (g *GitWorktree) IsDirty() (bool, error) {
 	return len(output) > 0, nil
 }
 
-// IsBranchCheckedOut checks if the instance branch is currently checked out
+// HasConflicts checks if the worktree has unmerged paths, e.g. from a merge or rebase that stopped
+// on conflicts. Unmerged paths are only looked for while such an operation is in progress, so the
+// check usually doesn't run git.
+func (g *GitWorktree) HasConflicts() (bool, error) {
+	gitDir, err := g.gitDir()
+	if err != nil {
+		return false, err
+	}
+	inProgress := false
+	for _, name := range []string{"MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD", "rebase-merge", "rebase-apply"} {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			inProgress = true
+			break
+		}
+	}
+	if !inProgress {
+		return false, nil
+	}
+	files, err := g.conflictedFiles()
+	if err != nil {
+		return false, err
+	}
+	return len(files) > 0, nil
+}
+
+// conflictedFiles returns the unmerged paths in the worktree
+func (g *GitWorktree) conflictedFiles() ([]string, error) {
+	output, err := g.runGitCommand(g.worktreePath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for conflicts: %w", err)
+	}
+	return strings.Fields(output), nil
+}
+
+// RebaseOntoBase rebases the worktree branch onto the current tip of its base branch and moves the
+// base commit there. Worktrees saved before the base branch was recorded use the repository's HEAD. The worktree must be clean. If the rebase fails it is aborted so the
+// worktree is never left half-rebased.
+func (g *GitWorktree) RebaseOntoBase() error {
+	if g.inPlace {
+		return fmt.Errorf("can't rebase a session that runs in the repository's checkout")
+	}
+	if isDirty, err := g.IsDirty(); err != nil {
+		return fmt.Errorf("failed to check for changes: %w", err)
+	} else if isDirty {
+		return fmt.Errorf("worktree has uncommitted changes, commit them before rebasing")
+	}
+
+	baseRef := g.baseBranch
+	if baseRef == "" {
+		baseRef = "HEAD"
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", baseRef+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("failed to resolve base branch %s: %w", baseRef, err)
+	}
+	base := strings.TrimSpace(output)
+
+	if _, err := g.runGitCommand(g.worktreePath, "rebase", base); err != nil {
+		log.ErrorLog.Print(err)
+		conflicts, _ := g.conflictedFiles()
+		if _, abortErr := g.runGitCommand(g.worktreePath, "rebase", "--abort"); abortErr != nil {
+			return fmt.Errorf("rebase failed: %v (abort error: %v)", err, abortErr)
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("rebase aborted due to conflicts in: %s", strings.Join(conflicts, ", "))
+		}
+		return fmt.Errorf("rebase failed: %w", err)
+	}
+
+	g.baseCommitSHA = base
+	return nil
+}
+
+// BaseUpstream returns the remote-tracking branch the base branch follows, e.g. origin/main. The base
+// branch is the one the worktree was created from, which Setup records, so switching branches in the
+// repository's checkout doesn't change it. Only worktrees saved before it was recorded use the
+// repo's current branch.
+func (g *GitWorktree) BaseUpstream() (string, error) {
+	branch := g.baseBranch
+	if branch == "" {
+		output, err := g.runGitCommand(g.repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return "", fmt.Errorf("failed to get current branch: %w", err)
+		}
+		branch = strings.TrimSpace(output)
+		if branch == "HEAD" {
+			return "", fmt.Errorf("repository is in detached HEAD state")
+		}
+	}
+
+	if output, err := g.runGitCommand(g.repoPath, "rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}"); err == nil {
+		return strings.TrimSpace(output), nil
+	}
+	// The base may already be a remote-tracking branch, e.g. a template with base_branch origin/main.
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "--symbolic-full-name", branch)
+	if err == nil && strings.HasPrefix(strings.TrimSpace(output), "refs/remotes/") {
+		return strings.TrimPrefix(strings.TrimSpace(output), "refs/remotes/"), nil
+	}
+	return "", fmt.Errorf("base branch %s has no upstream", branch)
+}
+
+// FetchBase fetches the base branch's upstream from its remote. It never prompts for credentials.
+func (g *GitWorktree) FetchBase() error {
+	upstream, err := g.BaseUpstream()
+	if err != nil {
+		return err
+	}
+	remote, branch, ok := strings.Cut(upstream, "/")
+	if !ok {
+		return fmt.Errorf("unexpected upstream %s", upstream)
+	}
+	cmd := exec.Command("git", "-C", g.repoPath, "fetch", "--quiet", remote, branch)
+	cmd.Env = append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to fetch %s: %s (%w)", upstream, strings.TrimSpace(string(output)), err)
+	}
+	return nil
+}
+
+// DivergenceFromBase returns how many commits the instance branch has that the base branch's
+// upstream doesn't (ahead), and how many the upstream has that the instance branch doesn't (behind).
+// It uses the last fetched state of the upstream; see FetchBase.
+func (g *GitWorktree) DivergenceFromBase() (ahead, behind int, err error) {
+	upstream, err := g.BaseUpstream()
+	if err != nil {
+		return 0, 0, err
+	}
+	output, err := g.runGitCommand(g.worktreePath, "rev-list", "--left-right", "--count", "HEAD..."+upstream)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compare with %s: %w", upstream, err)
+	}
+	if _, err := fmt.Sscanf(strings.TrimSpace(output), "%d\t%d", &ahead, &behind); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse divergence from %s: %w", upstream, err)
+	}
+	return ahead, behind, nil
+}
+
+// CommitsSinceBase returns the number of commits on the instance branch since the base commit. It
+// works on the branch in the repository, so also while the worktree is removed, e.g. when paused.
+func (g *GitWorktree) CommitsSinceBase() (int, error) {
+	if g.baseCommitSHA == "" {
+		return 0, fmt.Errorf("base commit SHA not set")
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-list", "--count", g.baseCommitSHA+".."+g.branchName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits since base: %w", err)
+	}
+	var count int
+	if _, err := fmt.Sscanf(strings.TrimSpace(output), "%d", &count); err != nil {
+		return 0, fmt.Errorf("failed to parse commit count: %w", err)
+	}
+	return count, nil
+}
+
+// Pushed returns true if the instance branch has an upstream that contains all of its commits.
+// Uncommitted changes aren't taken into account.
+func (g *GitWorktree) Pushed() (bool, error) {
+	if _, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", "--quiet", g.branchName+"@{upstream}"); err != nil {
+		return false, nil
+	}
+	output, err := g.runGitCommand(g.repoPath, "rev-list", "--count", g.branchName+"@{upstream}.."+g.branchName)
+	if err != nil {
+		return false, fmt.Errorf("failed to compare with upstream: %w", err)
+	}
+	return strings.TrimSpace(output) == "0", nil
+}
+
+// ResetToBase discards all changes in the worktree, including commits made since the base commit
+// and untracked files. Ignored files are kept.
+func (g *GitWorktree) ResetToBase() error {
+	if g.inPlace {
+		return fmt.Errorf("can't reset a session that runs in the repository's checkout")
+	}
+	if g.baseCommitSHA == "" {
+		return fmt.Errorf("base commit SHA not set")
+	}
+
+	if _, err := g.runGitCommand(g.worktreePath, "reset", "--hard", g.baseCommitSHA); err != nil {
+		return fmt.Errorf("failed to reset worktree to base: %w", err)
+	}
+	if _, err := g.runGitCommand(g.worktreePath, "clean", "-fd"); err != nil {
+		return fmt.Errorf("failed to remove untracked files: %w", err)
+	}
+	return nil
+}
+
+// IsBranchCheckedOut checks if the instance branch is currently checked out. The branch of an
+// in-place session is the checkout itself, so it never counts.
 func (g *GitWorktree) IsBranchCheckedOut() (bool, error) {
+	if g.inPlace {
+		return false, nil
+	}
 	output, err := g.runGitCommand(g.repoPath, "branch", "--show-current")
 	if err != nil {
 		return false, fmt.Errorf("failed to get current branch: %w", err)
@@ -0,0 +1,501 @@
+package git
+
+import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runGit runs a git command in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+	return strings.TrimSpace(string(output))
+}
+
+// commitFile writes content to name in dir and commits it
+func commitFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-m", "update "+name)
+}
+
+func TestHasCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		commit   bool
+		expected bool
+	}{
+		{name: "empty repository", commit: false, expected: false},
+		{name: "repository with a commit", commit: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			runGit(t, repo, "init", "-b", "main")
+			runGit(t, repo, "config", "user.email", "test@example.com")
+			runGit(t, repo, "config", "user.name", "test")
+			if tt.commit {
+				commitFile(t, repo, "file.txt", "base\n")
+			}
+
+			hasCommits, err := NewGitWorktreeFromStorage(repo, "", "test", "test", "").HasCommits()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hasCommits)
+		})
+	}
+}
+
+// setupRebaseTest creates a repo with a worktree on its own branch, both sharing file.txt
+func setupRebaseTest(t *testing.T) (string, *GitWorktree) {
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.email", "test@example.com")
+	runGit(t, repo, "config", "user.name", "test")
+	commitFile(t, repo, "file.txt", "base\n")
+	base := runGit(t, repo, "rev-parse", "HEAD")
+
+	worktreePath := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repo, "worktree", "add", "-b", "feature", worktreePath)
+
+	return repo, NewGitWorktreeFromStorage(repo, worktreePath, "feature", "feature", base)
+}
+
+func TestRebaseOntoBase(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	t.Run("rebases onto the advanced base", func(t *testing.T) {
+		repo, worktree := setupRebaseTest(t)
+		commitFile(t, worktree.GetWorktreePath(), "feature.txt", "feature\n")
+		commitFile(t, repo, "main.txt", "main\n")
+
+		require.NoError(t, worktree.RebaseOntoBase())
+
+		newBase := runGit(t, repo, "rev-parse", "HEAD")
+		assert.Equal(t, newBase, worktree.GetBaseCommitSHA())
+		assert.Equal(t, newBase, runGit(t, worktree.GetWorktreePath(), "rev-parse", "HEAD~1"))
+
+		hasConflicts, err := worktree.HasConflicts()
+		require.NoError(t, err)
+		assert.False(t, hasConflicts)
+	})
+
+	t.Run("aborts on conflicts", func(t *testing.T) {
+		repo, worktree := setupRebaseTest(t)
+		commitFile(t, worktree.GetWorktreePath(), "file.txt", "feature\n")
+		commitFile(t, repo, "file.txt", "main\n")
+		oldBase := worktree.GetBaseCommitSHA()
+		oldHead := runGit(t, worktree.GetWorktreePath(), "rev-parse", "HEAD")
+
+		err := worktree.RebaseOntoBase()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file.txt")
+
+		// The worktree is left exactly as it was before the rebase.
+		assert.Equal(t, oldBase, worktree.GetBaseCommitSHA())
+		assert.Equal(t, oldHead, runGit(t, worktree.GetWorktreePath(), "rev-parse", "HEAD"))
+		hasConflicts, err := worktree.HasConflicts()
+		require.NoError(t, err)
+		assert.False(t, hasConflicts)
+	})
+
+	t.Run("rebases onto the base branch, not the repository's checkout", func(t *testing.T) {
+		repo, worktree := setupRebaseTest(t)
+		worktree.SetBaseBranch("main")
+		commitFile(t, repo, "main.txt", "main\n")
+		mainHead := runGit(t, repo, "rev-parse", "HEAD")
+		runGit(t, repo, "checkout", "-q", "-b", "other")
+		commitFile(t, repo, "other.txt", "other\n")
+
+		require.NoError(t, worktree.RebaseOntoBase())
+		assert.Equal(t, mainHead, worktree.GetBaseCommitSHA())
+		assert.NoFileExists(t, filepath.Join(worktree.GetWorktreePath(), "other.txt"))
+	})
+
+	t.Run("reports the conflicts of a merge in progress", func(t *testing.T) {
+		repo, worktree := setupRebaseTest(t)
+		commitFile(t, worktree.GetWorktreePath(), "file.txt", "feature\n")
+		commitFile(t, repo, "file.txt", "main\n")
+		cmd := exec.Command("git", "-C", worktree.GetWorktreePath(), "merge", "main")
+		require.Error(t, cmd.Run(), "the merge stops on the conflict")
+
+		hasConflicts, err := worktree.HasConflicts()
+		require.NoError(t, err)
+		assert.True(t, hasConflicts)
+	})
+
+	t.Run("refuses to rebase a dirty worktree", func(t *testing.T) {
+		_, worktree := setupRebaseTest(t)
+		require.NoError(t, os.WriteFile(filepath.Join(worktree.GetWorktreePath(), "file.txt"), []byte("dirty\n"), 0644))
+
+		err := worktree.RebaseOntoBase()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "uncommitted changes")
+	})
+}
+
+func TestResetToBase(t *testing.T) {
+	t.Run("discards commits, changes and untracked files", func(t *testing.T) {
+		_, worktree := setupRebaseTest(t)
+		worktreePath := worktree.GetWorktreePath()
+		commitFile(t, worktreePath, "feature.txt", "feature\n")
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "file.txt"), []byte("changed\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "untracked.txt"), []byte("new\n"), 0644))
+
+		require.NoError(t, worktree.ResetToBase())
+
+		assert.Equal(t, worktree.GetBaseCommitSHA(), runGit(t, worktreePath, "rev-parse", "HEAD"))
+		isDirty, err := worktree.IsDirty()
+		require.NoError(t, err)
+		assert.False(t, isDirty)
+		assert.True(t, worktree.Diff(config.DiffOptions{}).IsEmpty())
+	})
+
+	t.Run("fails without a base commit", func(t *testing.T) {
+		worktree := NewGitWorktreeFromStorage(t.TempDir(), t.TempDir(), "feature", "feature", "")
+		err := worktree.ResetToBase()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "base commit SHA not set")
+	})
+}
+
+func TestSetupFromBaseBranch(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	t.Setenv("HOME", t.TempDir())
+
+	repo := t.TempDir()
+	runGit(t, repo, "init", "-b", "main")
+	runGit(t, repo, "config", "user.email", "test@example.com")
+	runGit(t, repo, "config", "user.name", "test")
+	commitFile(t, repo, "file.txt", "base\n")
+	runGit(t, repo, "branch", "release")
+	releaseCommit := runGit(t, repo, "rev-parse", "release")
+	commitFile(t, repo, "file.txt", "main\n")
+
+	tests := []struct {
+		name           string
+		baseBranch     string
+		expected       string
+		expectedBranch string
+		expectErr      bool
+	}{
+		{name: "defaults to HEAD", expected: runGit(t, repo, "rev-parse", "HEAD"), expectedBranch: "main"},
+		{name: "uses the base branch", baseBranch: "release", expected: releaseCommit, expectedBranch: "release"},
+		{name: "fails on an unknown branch", baseBranch: "missing", expectErr: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			branch := fmt.Sprintf("feature-%d", i)
+			worktreePath := filepath.Join(t.TempDir(), "wt")
+			worktree := NewGitWorktreeFromStorage(repo, worktreePath, branch, branch, "")
+			worktree.SetBaseBranch(tt.baseBranch)
+
+			err := worktree.Setup()
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, worktree.GetBaseCommitSHA())
+			assert.Equal(t, tt.expected, runGit(t, worktreePath, "rev-parse", "HEAD"))
+			assert.Equal(t, tt.expectedBranch, worktree.BaseBranch(), "the base branch is recorded")
+		})
+	}
+}
+
+func TestDivergenceFromBase(t *testing.T) {
+	t.Run("counts commits after fetching the base", func(t *testing.T) {
+		origin := t.TempDir()
+		runGit(t, origin, "init", "-b", "main")
+		runGit(t, origin, "config", "user.email", "test@example.com")
+		runGit(t, origin, "config", "user.name", "test")
+		commitFile(t, origin, "file.txt", "base\n")
+
+		repo := filepath.Join(t.TempDir(), "repo")
+		runGit(t, origin, "clone", origin, repo)
+		runGit(t, repo, "config", "user.email", "test@example.com")
+		runGit(t, repo, "config", "user.name", "test")
+		worktreePath := filepath.Join(t.TempDir(), "wt")
+		runGit(t, repo, "worktree", "add", "-b", "feature", worktreePath)
+		worktree := NewGitWorktreeFromStorage(repo, worktreePath, "feature", "feature", runGit(t, repo, "rev-parse", "HEAD"))
+
+		commitFile(t, worktreePath, "feature.txt", "feature\n")
+		commitFile(t, origin, "a.txt", "a\n")
+		commitFile(t, origin, "b.txt", "b\n")
+
+		upstream, err := worktree.BaseUpstream()
+		require.NoError(t, err)
+		assert.Equal(t, "origin/main", upstream)
+
+		// The recorded base branch counts, not the branch the repository has checked out.
+		worktree.SetBaseBranch("main")
+		runGit(t, repo, "checkout", "-q", "-b", "local-only")
+		upstream, err = worktree.BaseUpstream()
+		require.NoError(t, err)
+		assert.Equal(t, "origin/main", upstream)
+
+		// The remote commits aren't known until the base is fetched.
+		ahead, behind, err := worktree.DivergenceFromBase()
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{1, 0}, [2]int{ahead, behind})
+
+		require.NoError(t, worktree.FetchBase())
+		ahead, behind, err = worktree.DivergenceFromBase()
+		require.NoError(t, err)
+		assert.Equal(t, [2]int{1, 2}, [2]int{ahead, behind})
+	})
+
+	t.Run("fails without an upstream", func(t *testing.T) {
+		_, worktree := setupRebaseTest(t)
+		_, _, err := worktree.DivergenceFromBase()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "has no upstream")
+	})
+}
+
+func TestCommitsSinceBaseAndPushed(t *testing.T) {
+	repo, worktree := setupRebaseTest(t)
+	commitFile(t, worktree.GetWorktreePath(), "a.txt", "a\n")
+	commitFile(t, worktree.GetWorktreePath(), "b.txt", "b\n")
+	commitFile(t, repo, "main.txt", "main\n")
+
+	count, err := worktree.CommitsSinceBase()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "commits on the base branch don't count")
+
+	pushed, err := worktree.Pushed()
+	require.NoError(t, err)
+	assert.False(t, pushed, "the branch has no upstream")
+
+	remote := t.TempDir()
+	runGit(t, remote, "init", "--bare")
+	runGit(t, repo, "remote", "add", "origin", remote)
+	runGit(t, worktree.GetWorktreePath(), "push", "-u", "origin", "feature")
+	pushed, err = worktree.Pushed()
+	require.NoError(t, err)
+	assert.True(t, pushed)
+
+	commitFile(t, worktree.GetWorktreePath(), "c.txt", "c\n")
+	pushed, err = worktree.Pushed()
+	require.NoError(t, err)
+	assert.False(t, pushed, "the last commit isn't pushed")
+}
+
+func TestHasChangedSince(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, worktree *GitWorktree)
+	}{
+		{
+			name: "edit to a tracked file",
+			change: func(t *testing.T, worktree *GitWorktree) {
+				path := filepath.Join(worktree.GetWorktreePath(), "file.txt")
+				require.NoError(t, os.WriteFile(path, []byte("edited content\n"), 0644))
+			},
+		},
+		{
+			name: "new untracked file",
+			change: func(t *testing.T, worktree *GitWorktree) {
+				path := filepath.Join(worktree.GetWorktreePath(), "new.txt")
+				require.NoError(t, os.WriteFile(path, []byte("new\n"), 0644))
+			},
+		},
+		{
+			name: "second edit to a changed file",
+			change: func(t *testing.T, worktree *GitWorktree) {
+				path := filepath.Join(worktree.GetWorktreePath(), "file.txt")
+				require.NoError(t, os.WriteFile(path, []byte("edited again, longer\n"), 0644))
+			},
+		},
+		{
+			name: "new commit",
+			change: func(t *testing.T, worktree *GitWorktree) {
+				commitFile(t, worktree.GetWorktreePath(), "feature.txt", "feature\n")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, worktree := setupRebaseTest(t)
+			path := filepath.Join(worktree.GetWorktreePath(), "file.txt")
+			require.NoError(t, os.WriteFile(path, []byte("edited\n"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(worktree.GetWorktreePath(), "untracked.txt"), []byte("u\n"), 0644))
+
+			changed, token, err := worktree.HasChangedSince("")
+			require.NoError(t, err)
+			assert.True(t, changed)
+			require.NotEmpty(t, token)
+
+			// Computing the diff must not invalidate the token on its own.
+			require.NoError(t, worktree.Diff(config.DiffOptions{}).Error)
+			changed, _, err = worktree.HasChangedSince(token)
+			require.NoError(t, err)
+			assert.False(t, changed)
+
+			tt.change(t, worktree)
+			changed, _, err = worktree.HasChangedSince(token)
+			require.NoError(t, err)
+			assert.True(t, changed)
+		})
+	}
+
+	t.Run("ignored files don't count", func(t *testing.T) {
+		_, worktree := setupRebaseTest(t)
+		worktreePath := worktree.GetWorktreePath()
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, ".gitignore"), []byte("node_modules/\n"), 0644))
+		_, token, err := worktree.HasChangedSince("")
+		require.NoError(t, err)
+
+		require.NoError(t, os.MkdirAll(filepath.Join(worktreePath, "node_modules", "dep"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "node_modules", "dep", "index.js"), []byte("x\n"), 0644))
+		changed, _, err := worktree.HasChangedSince(token)
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("fails without a worktree", func(t *testing.T) {
+		worktree := NewGitWorktreeFromStorage(t.TempDir(), t.TempDir(), "feature", "feature", "")
+		changed, _, err := worktree.HasChangedSince("")
+		require.Error(t, err)
+		assert.True(t, changed)
+	})
+}
+
+func TestDiffOptions(t *testing.T) {
+	repo, worktree := setupRebaseTest(t)
+	worktreePath := worktree.GetWorktreePath()
+	commitFile(t, worktreePath, "code.txt", "a\nb\nc\nd\ne\nf\ng\n")
+	worktree = NewGitWorktreeFromStorage(repo, worktreePath, "feature", "feature", runGit(t, worktreePath, "rev-parse", "HEAD"))
+	// Reindent one line and change another: only the second change is real.
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "code.txt"), []byte("  a\nb\nc\nd\ne\nf\nchanged\n"), 0644))
+
+	stats := worktree.Diff(config.DiffOptions{})
+	require.NoError(t, stats.Error)
+	assert.Equal(t, 2, stats.Added)
+	assert.Equal(t, 2, stats.Removed)
+
+	ignoreWhitespace := worktree.Diff(config.DiffOptions{IgnoreWhitespace: true})
+	require.NoError(t, ignoreWhitespace.Error)
+	assert.Equal(t, 1, ignoreWhitespace.Added)
+	assert.Equal(t, 1, ignoreWhitespace.Removed)
+	assert.Contains(t, ignoreWhitespace.Content, "\n f\n")
+
+	contextLines := 0
+	noContext := worktree.Diff(config.DiffOptions{ContextLines: &contextLines, IgnoreWhitespace: true})
+	require.NoError(t, noContext.Error)
+	assert.NotContains(t, noContext.Content, "\n f\n")
+	assert.Contains(t, noContext.Content, "+changed")
+}
+
+func TestDiffFiles(t *testing.T) {
+	_, worktree := setupRebaseTest(t)
+	worktreePath := worktree.GetWorktreePath()
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "file.txt"), []byte("base\nmore\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(worktreePath, "src"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "src", "new file.go"), []byte("package src\n"), 0644))
+	// git quotes non-ASCII paths in the diff headers.
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "src", "naïve.go"), []byte("a\nb\n"), 0644))
+
+	stats := worktree.Diff(config.DiffOptions{})
+	require.NoError(t, stats.Error)
+	assert.Equal(t, []string{"file.txt", "src/naïve.go", "src/new file.go"}, stats.Files)
+
+	content, err := worktree.FileDiff(config.DiffOptions{}, "src/new file.go")
+	require.NoError(t, err)
+	assert.Contains(t, content, "+package src")
+	assert.NotContains(t, content, "+more")
+	content, err = worktree.FileDiff(config.DiffOptions{}, "src/naïve.go")
+	require.NoError(t, err)
+	assert.Contains(t, content, "+b")
+	added, removed := stats.FileStats("file.txt")
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 0, removed)
+	added, _ = stats.FileStats("src/naïve.go")
+	assert.Equal(t, 2, added)
+	content, err = worktree.FileDiff(config.DiffOptions{}, "missing.txt")
+	require.NoError(t, err)
+	assert.Empty(t, content)
+}
+
+func TestParseNumstat(t *testing.T) {
+	files, lines := parseNumstat("3\t1\ta.go\x00-\t-\timage.png\x005\t0\t\x00old name.go\x00new name.go\x00")
+	assert.Equal(t, []string{"a.go", "image.png", "new name.go"}, files)
+	assert.Equal(t, [2]int{3, 1}, lines["a.go"])
+	assert.Equal(t, [2]int{0, 0}, lines["image.png"], "binary files count no lines")
+	assert.Equal(t, [2]int{5, 0}, lines["new name.go"])
+}
+
+func TestRepair(t *testing.T) {
+	repo, worktree := setupRebaseTest(t)
+	worktreePath := worktree.GetWorktreePath()
+
+	repaired, err := worktree.Repair()
+	require.NoError(t, err)
+	assert.Empty(t, repaired, "a healthy worktree needs no repair")
+
+	// Deleted outside of git, so git still lists it.
+	require.NoError(t, os.RemoveAll(worktreePath))
+	repaired, err = worktree.Repair()
+	require.NoError(t, err)
+	require.Len(t, repaired, 2)
+	assert.Contains(t, repaired[0], "Removing worktrees/")
+	assert.Contains(t, repaired[1], "recreated missing worktree")
+	assert.FileExists(t, filepath.Join(worktreePath, "file.txt"))
+	assert.Equal(t, "feature", runGit(t, worktreePath, "rev-parse", "--abbrev-ref", "HEAD"))
+
+	// The repository's link to the worktree is broken, e.g. after the worktree was moved back.
+	dotGit, err := os.ReadFile(filepath.Join(worktreePath, ".git"))
+	require.NoError(t, err)
+	adminDir := strings.TrimSpace(strings.TrimPrefix(string(dotGit), "gitdir:"))
+	require.NoError(t, os.WriteFile(filepath.Join(adminDir, "gitdir"), []byte("/nonexistent/.git\n"), 0644))
+	repaired, err = worktree.Repair()
+	require.NoError(t, err)
+	require.NotEmpty(t, repaired)
+	assert.Contains(t, runGit(t, repo, "worktree", "list"), worktreePath)
+	assert.Equal(t, "feature", runGit(t, worktreePath, "rev-parse", "--abbrev-ref", "HEAD"))
+}
+
+func TestInPlaceWorktree(t *testing.T) {
+	repo, _ := setupRebaseTest(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "sub"), 0755))
+
+	worktree, branch, err := NewInPlaceWorktree(filepath.Join(repo, "sub"), "session")
+	require.NoError(t, err)
+	assert.Equal(t, "main", branch)
+	assert.True(t, worktree.InPlace())
+	assert.Equal(t, worktree.GetRepoPath(), worktree.GetWorktreePath(), "the session runs in the repo root")
+	require.NoError(t, worktree.Setup())
+	assert.Equal(t, runGit(t, repo, "rev-parse", "HEAD"), worktree.GetBaseCommitSHA())
+
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "file.txt"), []byte("base\nchanged\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "untracked.txt"), []byte("new\n"), 0644))
+	stats := worktree.Diff(config.DiffOptions{})
+	require.NoError(t, stats.Error)
+	assert.Equal(t, []string{"file.txt"}, stats.Files)
+	assert.Equal(t, "?? untracked.txt", runGit(t, repo, "status", "--porcelain", "untracked.txt"),
+		"untracked files of the user's checkout aren't staged")
+
+	checkedOut, err := worktree.IsBranchCheckedOut()
+	require.NoError(t, err)
+	assert.False(t, checkedOut)
+	assert.Error(t, worktree.ResetToBase())
+	assert.ErrorContains(t, worktree.PushChanges("update", false), "repository's checkout")
+	assert.Equal(t, "?? untracked.txt", runGit(t, repo, "status", "--porcelain", "untracked.txt"), "nothing is committed")
+	require.NoError(t, worktree.Cleanup())
+	assert.FileExists(t, filepath.Join(repo, "file.txt"), "the checkout is kept")
+	assert.Equal(t, "main", runGit(t, repo, "rev-parse", "--abbrev-ref", "HEAD"))
+}
@@ -3,6 +3,7 @@ package git
 import (
 	"claude-squad/config"
 	"claude-squad/log"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,8 +14,28 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 )
 
+// HasCommits returns false if the repository has no commits yet, so there is no HEAD to branch a
+// worktree off of
+func (g *GitWorktree) HasCommits() (bool, error) {
+	repo, err := git.PlainOpen(g.repoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open repository: %w", err)
+	}
+	if _, err := repo.Head(); err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return true, nil
+}
+
 // Setup creates a new worktree for the session
 func (g *GitWorktree) Setup() error {
+	if g.inPlace {
+		return g.setupInPlace()
+	}
+
 	// Ensure worktrees directory exists early (can be done in parallel with branch check)
 	worktreesDir, err := getWorktreeDirectory(g.repoPath)
 	if err != nil {
@@ -52,12 +73,29 @@ func (g *GitWorktree) Setup() error {
 		}
 	}
 
+	if err := checkWorktreeDirectory(worktreesDir); err != nil {
+		return err
+	}
+	if same, err := sameFilesystem(g.repoPath, worktreesDir); err == nil && !same {
+		log.InfoLog.Printf("worktree directory %s is on a different filesystem than repository %s", worktreesDir, g.repoPath)
+	}
+
 	if branchExists {
 		return g.setupFromExistingBranch()
 	}
 	return g.setupNewWorktree()
 }
 
+// setupInPlace records HEAD of the repository's checkout as the base commit.
+func (g *GitWorktree) setupInPlace() error {
+	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD commit hash: %w", err)
+	}
+	g.baseCommitSHA = strings.TrimSpace(output)
+	return nil
+}
+
 // setupFromExistingBranch creates a worktree from an existing branch
 func (g *GitWorktree) setupFromExistingBranch() error {
 	// Directory already created in Setup(), skip duplicate creation
@@ -67,7 +105,7 @@ func (g *GitWorktree) setupFromExistingBranch() error {
 
 	// Create a new worktree from the existing branch
 	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", g.worktreePath, g.branchName); err != nil {
-		return fmt.Errorf("failed to create worktree from branch %s: %w", g.branchName, err)
+		return g.describeWorktreeAddError(fmt.Errorf("failed to create worktree from branch %s: %w", g.branchName, err))
 	}
 
 	// Copy settings and env files from main repo to worktree
@@ -78,7 +116,7 @@ func (g *GitWorktree) setupFromExistingBranch() error {
 	return nil
 }
 
-// setupNewWorktree creates a new worktree from HEAD
+// setupNewWorktree creates a new worktree from HEAD, or from the base branch if one is set
 func (g *GitWorktree) setupNewWorktree() error {
 	// Ensure worktrees directory exists
 	worktreesDir := filepath.Join(g.repoPath, "worktrees")
@@ -100,6 +138,14 @@ func (g *GitWorktree) setupNewWorktree() error {
 		return fmt.Errorf("failed to cleanup existing branch: %w", err)
 	}
 
+	if g.baseBranch != "" {
+		output, err := g.runGitCommand(g.repoPath, "rev-parse", "--verify", g.baseBranch+"^{commit}")
+		if err != nil {
+			return fmt.Errorf("failed to resolve base branch %s: %w", g.baseBranch, err)
+		}
+		return g.addWorktreeFromCommit(strings.TrimSpace(output))
+	}
+
 	output, err := g.runGitCommand(g.repoPath, "rev-parse", "HEAD")
 	if err != nil {
 		if strings.Contains(err.Error(), "fatal: ambiguous argument 'HEAD'") ||
@@ -109,15 +155,24 @@ func (g *GitWorktree) setupNewWorktree() error {
 		}
 		return fmt.Errorf("failed to get HEAD commit hash: %w", err)
 	}
-	headCommit := strings.TrimSpace(string(output))
-	g.baseCommitSHA = headCommit
+	// Remember the branch HEAD is on, so the worktree is rebased onto it later even if the
+	// repository's checkout moved to another branch in the meantime.
+	if branch, err := g.runGitCommand(g.repoPath, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		g.baseBranch = strings.TrimSpace(branch)
+	}
+	return g.addWorktreeFromCommit(strings.TrimSpace(string(output)))
+}
+
+// addWorktreeFromCommit creates the worktree on a new branch starting at commit, which becomes the
+// base commit.
+func (g *GitWorktree) addWorktreeFromCommit(commit string) error {
+	g.baseCommitSHA = commit
 
-	// Create a new worktree from the HEAD commit
+	// Create a new worktree from the commit
 	// Otherwise, we'll inherit uncommitted changes from the previous worktree.
 	// This way, we can start the worktree with a clean slate.
-	// TODO: we might want to give an option to use main/master instead of the current branch.
-	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, headCommit); err != nil {
-		return fmt.Errorf("failed to create worktree from commit %s: %w", headCommit, err)
+	if _, err := g.runGitCommand(g.repoPath, "worktree", "add", "-b", g.branchName, g.worktreePath, commit); err != nil {
+		return g.describeWorktreeAddError(fmt.Errorf("failed to create worktree from commit %s: %w", commit, err))
 	}
 
 	// Copy settings and env files from main repo to worktree
@@ -128,8 +183,11 @@ func (g *GitWorktree) setupNewWorktree() error {
 	return nil
 }
 
-// Cleanup removes the worktree and associated branch
+// Cleanup removes the worktree and associated branch. The checkout of an in-place session is kept.
 func (g *GitWorktree) Cleanup() error {
+	if g.inPlace {
+		return nil
+	}
 	var errs []error
 
 	// Check if worktree path exists before attempting removal
@@ -173,8 +231,11 @@ func (g *GitWorktree) Cleanup() error {
 	return nil
 }
 
-// Remove removes the worktree but keeps the branch
+// Remove removes the worktree but keeps the branch. The checkout of an in-place session is kept.
 func (g *GitWorktree) Remove() error {
+	if g.inPlace {
+		return nil
+	}
 	// Remove the worktree using git command
 	if _, err := g.runGitCommand(g.repoPath, "worktree", "remove", "-f", g.worktreePath); err != nil {
 		return fmt.Errorf("failed to remove worktree: %w", err)
@@ -191,6 +252,57 @@ func (g *GitWorktree) Prune() error {
 	return nil
 }
 
+// Repair brings the worktree and git's metadata about it back in sync. It repairs the links between
+// the worktree and the repository, e.g. after either was moved, or recreates the worktree from its
+// branch if its directory is missing, and prunes stale worktree entries of the repository, e.g. of
+// directories deleted outside of git. It returns a description of each fix, which is empty if
+// nothing needed repair.
+func (g *GitWorktree) Repair() ([]string, error) {
+	if g.inPlace {
+		return nil, nil
+	}
+	var repaired []string
+
+	_, err := os.Stat(g.worktreePath)
+	missing := os.IsNotExist(err)
+	if err != nil && !missing {
+		return nil, fmt.Errorf("failed to check if worktree exists: %w", err)
+	}
+	if !missing {
+		// Repaired before pruning, which would drop the entry of a worktree whose link is broken.
+		output, err := g.runGitCommand(g.repoPath, "worktree", "repair", g.worktreePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to repair worktree: %w", err)
+		}
+		repaired = append(repaired, outputLines(output)...)
+	}
+
+	output, err := g.runGitCommand(g.repoPath, "worktree", "prune", "--verbose")
+	if err != nil {
+		return repaired, fmt.Errorf("failed to prune worktrees: %w", err)
+	}
+	repaired = append(repaired, outputLines(output)...)
+
+	if missing {
+		if err := g.Setup(); err != nil {
+			return repaired, fmt.Errorf("failed to recreate worktree: %w", err)
+		}
+		repaired = append(repaired, fmt.Sprintf("recreated missing worktree %s", g.worktreePath))
+	}
+	return repaired, nil
+}
+
+// outputLines returns the non-empty lines of a command's output.
+func outputLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 // CleanupWorktrees removes all worktrees and their associated branches
 func CleanupWorktrees() error {
 	configDir, err := config.GetConfigDir()
@@ -1,15 +1,20 @@
 package session
 
 import (
+	"claude-squad/config"
 	"claude-squad/log"
 	"claude-squad/session/git"
 	"claude-squad/session/tmux"
+	"crypto/sha256"
+	"errors"
 	"path/filepath"
+	"regexp"
+	"slices"
 
 	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -46,13 +51,53 @@ const (
 	DevServerCrashed
 )
 
+// String returns the status as shown to the user, e.g. "running".
+func (s DevServerStatus) String() string {
+	switch s {
+	case DevServerStopped:
+		return "stopped"
+	case DevServerBuilding:
+		return "building"
+	case DevServerStarting:
+		return "starting"
+	case DevServerRunning:
+		return "running"
+	case DevServerCrashed:
+		return "crashed"
+	default:
+		return "unknown"
+	}
+}
+
 const devServerGracePeriod = 3 * time.Second // Grace period before checking health of newly started server
 
+// devServerQuickExit is how soon after starting a dev command that exits successfully it's taken for a
+// command that doesn't keep running, such as a build, rather than a crashed server.
+const devServerQuickExit = 3 * time.Second
+
 // DevServerConfig holds configuration for a dev server
 type DevServerConfig struct {
 	BuildCommand string            `json:"build_command"`
 	DevCommand   string            `json:"dev_command"`
 	Env          map[string]string `json:"env,omitempty"`
+	// WorkingDir is the directory, relative to the worktree, the build and dev commands run in.
+	// Empty means the worktree root.
+	WorkingDir string `json:"working_dir,omitempty"`
+	// MaxOutputLines is the size of the in-memory output buffer. Zero means
+	// config.DefaultDevServerOutputLines; values above config.MaxDevServerOutputLines are capped.
+	MaxOutputLines int `json:"max_output_lines,omitempty"`
+}
+
+// maxOutputLines returns the output buffer size, applying the default and upper bound.
+func (c DevServerConfig) maxOutputLines() int {
+	switch {
+	case c.MaxOutputLines <= 0:
+		return config.DefaultDevServerOutputLines
+	case c.MaxOutputLines > config.MaxDevServerOutputLines:
+		return config.MaxDevServerOutputLines
+	default:
+		return c.MaxOutputLines
+	}
 }
 
 // DevServer manages the dev server process for an instance
@@ -68,6 +113,72 @@ type DevServer struct {
 	instance   string
 	startMu    sync.Mutex // Prevent concurrent starts
 	startedAt  time.Time  // Track when server was started for grace period
+
+	// dedupeOutput collapses consecutive identical output lines (config.Config.DedupeServerOutput)
+	dedupeOutput bool
+	// sessionPrefix is the tmux prefix the server's session was created under, see migrateSession
+	sessionPrefix string
+	// lastLine and lastLineCount track the most recently appended line for deduplication
+	lastLine      string
+	lastLineCount int
+	// lastCapture and lastCaptureHash hold the previous pane capture so only new lines are appended
+	lastCapture     []string
+	lastCaptureHash [sha256.Size]byte
+	// runCount is the number of times the dev server was started, used to number run markers
+	runCount int
+	// width and height are the size of the server pane the output is shown in. Zero until Resize is
+	// called, in which case the configured default size is used.
+	width, height int
+	sizeMu        sync.Mutex
+	// url is the first URL the current run printed, e.g. http://localhost:3000. Protected by outputMu.
+	url string
+	// buildDuration is how long the current run's build command took, and startDuration how long
+	// its dev command took to print a URL. Zero until known. readyAt is when the dev command started
+	// while waiting for its URL, otherwise zero. Protected by outputMu.
+	buildDuration time.Duration
+	startDuration time.Duration
+	readyAt       time.Time
+	// restore is true if the dev server was running when the app quit, so it should run again
+	// after a restart. Protected by statusMu.
+	restore bool
+}
+
+// devServerURLRegexp matches the URLs dev servers print once they are listening.
+var devServerURLRegexp = regexp.MustCompile(`https?://[A-Za-z0-9.\-_\[\]:]+(?:/[^\s'"<>]*)?`)
+
+// detectURL returns the first URL in line, or an empty string. Wildcard hosts are replaced with
+// localhost so the URL can be opened in a browser.
+func detectURL(line string) string {
+	url := devServerURLRegexp.FindString(line)
+	url = strings.TrimRight(url, ".,;)")
+	for _, wildcard := range []string{"//0.0.0.0", "//[::]"} {
+		url = strings.Replace(url, wildcard, "//localhost", 1)
+	}
+	return url
+}
+
+// runMarkerPrefix starts the divider line inserted into the output each time the dev server starts.
+const runMarkerPrefix = "──── run #"
+
+// IsRunMarker returns true if line is a divider inserted into the dev server output at the start of
+// a run.
+func IsRunMarker(line string) bool {
+	return strings.HasPrefix(line, runMarkerPrefix)
+}
+
+// markNewRunLocked appends a run marker to the output and forgets the previous pane capture, which
+// belonged to the previous run's session. Callers must hold outputMu.
+func (d *DevServer) markNewRunLocked() {
+	d.runCount++
+	d.lastLine = ""
+	d.lastLineCount = 0
+	d.lastCapture = nil
+	d.lastCaptureHash = [sha256.Size]byte{}
+	d.url = ""
+	d.buildDuration = 0
+	d.startDuration = 0
+	d.readyAt = time.Time{}
+	d.appendOutputLocked(fmt.Sprintf("%s%d · started %s ────", runMarkerPrefix, d.runCount, time.Now().Format("15:04:05")))
 }
 
 // Instance is a running instance of claude code.
@@ -82,6 +193,8 @@ type Instance struct {
 	Status Status
 	// Program is the program to run in the instance.
 	Program string
+	// ExtraArgs are appended to Program when the instance starts, e.g. "--model opus".
+	ExtraArgs string
 	// Height is the height of the instance.
 	Height int
 	// Width is the width of the instance.
@@ -94,9 +207,54 @@ type Instance struct {
 	AutoYes bool
 	// Prompt is the initial prompt to pass to the instance on startup
 	Prompt string
-
+	// Labels are free-form tags attached to the instance, e.g. from a template.
+	Labels []string
+	// Pinned is true if the instance is kept at the top of the list.
+	Pinned bool
+	// Container runs the program in a container with the worktree mounted. Nil runs it on the host.
+	Container *config.ContainerConfig
+
+	// baseBranch is the branch the worktree is created from on first setup. Empty means HEAD.
+	baseBranch string
+	// noWorktree is true if the instance runs in the repository's checkout instead of a worktree.
+	noWorktree bool
+	// tmuxStartupCommands are run against the session once it is first created.
+	tmuxStartupCommands []string
+	// resuming is true from BeginResume until Resume returns. The status is Loading meanwhile.
+	resuming bool
+	// branchName is the branch the worktree is created on at first setup. Empty derives it from
+	// the title.
+	branchName string
 	// DiffStats stores the current git diff statistics
 	diffStats *git.DiffStats
+	// diffToken identifies the worktree state diffStats was computed from
+	diffToken string
+	// diffArgs are the git diff flags diffStats was computed with
+	diffArgs string
+	// fileDiff caches the FileDiff of fileDiffPath, computed along with fileDiffOf
+	fileDiff     string
+	fileDiffPath string
+	fileDiffOf   *git.DiffStats
+	// hasConflicts is true if the worktree had unmerged paths at the last diff stats update
+	hasConflicts bool
+	// baseAhead and baseBehind are the commits the branch is ahead of and behind its base's upstream
+	baseAhead, baseBehind int
+	// recorder writes the session output to an asciinema cast file while recording is active
+	recorder *castRecorder
+	// pausedCapture is the pane content captured right before the instance was paused
+	pausedCapture string
+	// color is the manually assigned color. If empty, Color derives one from the title.
+	color string
+	// autoYesBlockedBy is the deny pattern that stopped auto-yes from confirming the current prompt
+	autoYesBlockedBy string
+	// worktreeCheck, if set, rejects the worktree path chosen on first setup, e.g. when another
+	// instance already uses it
+	worktreeCheck func(worktreePath string) error
+	// archived is true if the instance is put away: it is paused and hidden from the active instances
+	archived bool
+	// sessionPrefix is the tmux prefix the instance's sessions were created under, see
+	// migrateSessionName
+	sessionPrefix string
 
 	// DevServer holds the dev server (from devserver package)
 	DevServer interface {
@@ -110,14 +268,21 @@ type Instance struct {
 		Start() error
 		Stop() error
 		CheckHealth()
+		Resize(width, height int) error
+		URL() string
+		LastBuildDuration() time.Duration
+		LastStartDuration() time.Duration
 		GetDevServerSession() *tmux.TmuxSession
+		Suspend() error
+		ShouldRestore() bool
+		Restore() (reattached bool, err error)
 	}
 
 	// The below fields are initialized upon calling Start().
 
 	started bool
-	// tmuxSession is the tmux session for the instance.
-	tmuxSession *tmux.TmuxSession
+	// backend is the terminal session for the instance (tmux by default).
+	backend Backend
 	// gitWorktree is the git worktree for the instance.
 	gitWorktree *git.GitWorktree
 }
@@ -134,7 +299,21 @@ func (i *Instance) ToInstanceData() InstanceData {
 		CreatedAt: i.CreatedAt,
 		UpdatedAt: time.Now(),
 		Program:   i.Program,
+		ExtraArgs: i.ExtraArgs,
 		AutoYes:   i.AutoYes,
+		Labels:    i.Labels,
+		Pinned:    i.Pinned,
+		Container: i.Container,
+		Prompt:    i.Prompt,
+		Archived:  i.archived,
+
+		PausedCapture: i.pausedCapture,
+		Color:         i.color,
+		TmuxPrefix:    i.sessionPrefix,
+	}
+	if i.resuming {
+		// The session may not be back yet, so load the instance as paused.
+		data.Status = Paused
 	}
 
 	// Only include worktree data if gitWorktree is initialized
@@ -145,6 +324,8 @@ func (i *Instance) ToInstanceData() InstanceData {
 			SessionName:   i.Title,
 			BranchName:    i.gitWorktree.GetBranchName(),
 			BaseCommitSHA: i.gitWorktree.GetBaseCommitSHA(),
+			BaseBranch:    i.gitWorktree.BaseBranch(),
+			InPlace:       i.gitWorktree.InPlace(),
 		}
 	}
 
@@ -154,6 +335,9 @@ func (i *Instance) ToInstanceData() InstanceData {
 			Added:   i.diffStats.Added,
 			Removed: i.diffStats.Removed,
 			Content: i.diffStats.Content,
+			Files:   i.diffStats.Files,
+
+			ComputedAt: i.diffStats.ComputedAt,
 		}
 	}
 
@@ -163,6 +347,7 @@ func (i *Instance) ToInstanceData() InstanceData {
 			Config:     i.DevServer.Config(),
 			Status:     i.DevServer.Status(),
 			CrashCount: i.DevServer.CrashCount(),
+			Restore:    i.DevServer.ShouldRestore(),
 		}
 	}
 
@@ -181,6 +366,17 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 		CreatedAt: data.CreatedAt,
 		UpdatedAt: data.UpdatedAt,
 		Program:   data.Program,
+		ExtraArgs: data.ExtraArgs,
+		AutoYes:   data.AutoYes,
+		Labels:    data.Labels,
+		Pinned:    data.Pinned,
+		Container: data.Container,
+		Prompt:    data.Prompt,
+
+		pausedCapture: data.PausedCapture,
+		color:         data.Color,
+		archived:      data.Archived,
+		sessionPrefix: data.TmuxPrefix,
 		gitWorktree: git.NewGitWorktreeFromStorage(
 			data.Worktree.RepoPath,
 			data.Worktree.WorktreePath,
@@ -192,24 +388,41 @@ func FromInstanceData(data InstanceData) (*Instance, error) {
 			Added:   data.DiffStats.Added,
 			Removed: data.DiffStats.Removed,
 			Content: data.DiffStats.Content,
+			Files:   data.DiffStats.Files,
+
+			ComputedAt: data.DiffStats.ComputedAt,
 		},
 	}
+	instance.gitWorktree.SetBaseBranch(data.Worktree.BaseBranch)
+	if instance.sessionPrefix == "" {
+		// Sessions created before the prefix was recorded use the default one.
+		instance.sessionPrefix = config.DefaultTmuxPrefix
+	}
+	if data.Worktree.InPlace {
+		instance.gitWorktree.SetInPlace()
+		instance.noWorktree = true
+	}
 
-	// Restore dev server data if it exists
+	// Restore dev server data if it exists. Its session isn't known yet, so it's stopped until it
+	// is restored. A status other than stopped or crashed means the app quit without stopping it.
 	if data.DevServer != nil {
+		status := data.DevServer.Status
 		instance.DevServer = &DevServer{
-			config:     data.DevServer.Config,
-			status:     data.DevServer.Status,
-			crashCount: data.DevServer.CrashCount,
-			output:     make([]string, 0),
-			worktree:   instance.gitWorktree.GetWorktreePath(),
-			instance:   instance.Title,
+			config:        data.DevServer.Config,
+			status:        DevServerStopped,
+			restore:       data.DevServer.Restore || (status != DevServerStopped && status != DevServerCrashed),
+			crashCount:    data.DevServer.CrashCount,
+			output:        make([]string, 0),
+			worktree:      instance.gitWorktree.GetWorktreePath(),
+			instance:      instance.Title,
+			dedupeOutput:  dedupeServerOutput,
+			sessionPrefix: instance.sessionPrefix,
 		}
 	}
 
 	if instance.Paused() {
 		instance.started = true
-		instance.tmuxSession = tmux.NewTmuxSession(instance.Title, instance.Program)
+		instance.backend = NewBackend(instance.Title, instance.Command())
 	} else {
 		if err := instance.Start(false); err != nil {
 			return nil, err
@@ -227,8 +440,26 @@ type InstanceOptions struct {
 	Path string
 	// Program is the program to run in the instance (e.g. "claude", "aider --model ollama_chat/gemma3:1b")
 	Program string
+	// ExtraArgs are appended to Program when the instance starts.
+	ExtraArgs string
 	// If AutoYes is true, then
 	AutoYes bool
+	// BaseBranch is the branch the worktree is created from. Defaults to HEAD.
+	BaseBranch string
+	// BranchName is the branch the worktree is created on. Defaults to one derived from the title.
+	BranchName string
+	// NoWorktree runs the instance in the repository's checkout at Path, on its current branch,
+	// instead of creating a worktree and a branch. Its diff is against HEAD.
+	NoWorktree bool
+	// Labels are attached to the instance.
+	Labels []string
+	// Prompt is sent to the instance once its program is ready.
+	Prompt string
+	// Container runs the program in a container instead of on the host.
+	Container *config.ContainerConfig
+	// TmuxStartupCommands are tmux commands run against the session once it is first created, e.g.
+	// "split-window -h" to open a shell next to the program. See config.Config.TmuxStartupCommands.
+	TmuxStartupCommands []string
 }
 
 func NewInstance(opts InstanceOptions) (*Instance, error) {
@@ -239,20 +470,66 @@ func NewInstance(opts InstanceOptions) (*Instance, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
+	if opts.Container != nil {
+		if err := opts.Container.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if opts.BranchName != "" {
+		if err := git.ValidateBranchName(opts.BranchName); err != nil {
+			return nil, err
+		}
+	}
 
 	return &Instance{
 		Title:     opts.Title,
 		Status:    Ready,
 		Path:      absPath,
 		Program:   opts.Program,
+		ExtraArgs: strings.TrimSpace(opts.ExtraArgs),
 		Height:    0,
 		Width:     0,
 		CreatedAt: t,
 		UpdatedAt: t,
 		AutoYes:   false,
+		Labels:    opts.Labels,
+		Prompt:    opts.Prompt,
+		Container: opts.Container,
+
+		baseBranch:          opts.BaseBranch,
+		branchName:          opts.BranchName,
+		noWorktree:          opts.NoWorktree,
+		tmuxStartupCommands: opts.TmuxStartupCommands,
+		sessionPrefix:       tmux.TmuxPrefix,
 	}, nil
 }
 
+// Options returns the options the instance was created with, e.g. to create it again.
+func (i *Instance) Options() InstanceOptions {
+	return InstanceOptions{
+		Title:               i.Title,
+		Path:                i.Path,
+		Program:             i.Program,
+		ExtraArgs:           i.ExtraArgs,
+		AutoYes:             i.AutoYes,
+		BaseBranch:          i.baseBranch,
+		BranchName:          i.branchName,
+		NoWorktree:          i.noWorktree,
+		Labels:              i.Labels,
+		Prompt:              i.Prompt,
+		Container:           i.Container,
+		TmuxStartupCommands: i.tmuxStartupCommands,
+	}
+}
+
+// Command returns the command the instance runs: the program followed by the extra arguments.
+func (i *Instance) Command() string {
+	if i.ExtraArgs == "" {
+		return i.Program
+	}
+	return i.Program + " " + i.ExtraArgs
+}
+
 func (i *Instance) RepoName() (string, error) {
 	if !i.started {
 		return "", fmt.Errorf("cannot get repo name for instance that has not been started")
@@ -270,23 +547,49 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 		return fmt.Errorf("instance title cannot be empty")
 	}
 
-	var tmuxSession *tmux.TmuxSession
-	if i.tmuxSession != nil {
-		// Use existing tmux session (useful for testing)
-		tmuxSession = i.tmuxSession
-	} else {
-		// Create new tmux session
-		tmuxSession = tmux.NewTmuxSession(i.Title, i.Program)
+	if i.backend == nil {
+		// Create new session. An existing backend is reused (useful for testing).
+		i.backend = NewBackend(i.Title, i.Command())
 	}
-	i.tmuxSession = tmuxSession
 
 	if firstTimeSetup {
-		gitWorktree, branchName, err := git.NewGitWorktree(i.Path, i.Title)
+		var gitWorktree *git.GitWorktree
+		var branchName string
+		var err error
+		switch {
+		case i.noWorktree:
+			gitWorktree, branchName, err = git.NewInPlaceWorktree(i.Path, i.Title)
+		case i.branchName != "":
+			// Checked again in case the branch was created since SetBranchName.
+			if err := checkNewBranch(i.Path, i.branchName); err != nil {
+				return err
+			}
+			gitWorktree, branchName, err = git.NewGitWorktreeWithBranch(i.Path, i.Title, i.branchName)
+		default:
+			gitWorktree, branchName, err = git.NewGitWorktree(i.Path, i.Title)
+		}
 		if err != nil {
 			return fmt.Errorf("failed to create git worktree: %w", err)
 		}
+		// Checked before the worktree is assigned, so the cleanup of a failed start can't remove a
+		// directory that belongs to another instance.
+		if i.worktreeCheck != nil {
+			if err := i.worktreeCheck(gitWorktree.GetWorktreePath()); err != nil {
+				return err
+			}
+		}
+		gitWorktree.SetBaseBranch(i.baseBranch)
 		i.gitWorktree = gitWorktree
 		i.Branch = branchName
+
+		// Worktrees branch off HEAD, which doesn't exist until the first commit.
+		hasCommits, err := gitWorktree.HasCommits()
+		if err != nil {
+			return fmt.Errorf("failed to check repository for commits: %w", err)
+		}
+		if !hasCommits {
+			return fmt.Errorf("repository %s has no commits; make an initial commit first", gitWorktree.GetRepoPath())
+		}
 	}
 
 	// Setup error handler to cleanup resources on any error
@@ -302,8 +605,14 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 	}()
 
 	if !firstTimeSetup {
-		// Reuse existing session
-		if err := tmuxSession.Restore(); err != nil {
+		// Reuse existing session, picking up sessions created under the default prefix
+		i.migrateSessionName()
+		if !i.backend.DoesSessionExist() {
+			// The session is gone, e.g. after a reboot. Start a new one instead of failing to load.
+			i.recreateSession()
+			return nil
+		}
+		if err := i.backend.Restore(); err != nil {
 			setupErr = fmt.Errorf("failed to restore existing session: %w", err)
 			return setupErr
 		}
@@ -314,8 +623,13 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 			return setupErr
 		}
 
+		if err := i.setContainerCommand(i.backend); err != nil {
+			setupErr = err
+			return setupErr
+		}
+
 		// Create new session
-		if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+		if err := i.backend.Start(i.gitWorktree.GetWorktreePath()); err != nil {
 			// Cleanup git worktree if tmux session creation fails
 			if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
 				err = fmt.Errorf("%v (cleanup error: %v)", err, cleanupErr)
@@ -323,6 +637,7 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 			setupErr = fmt.Errorf("failed to start new session: %w", err)
 			return setupErr
 		}
+		i.runTmuxStartupCommands()
 	}
 
 	i.SetStatus(Running)
@@ -334,8 +649,33 @@ func (i *Instance) Start(firstTimeSetup bool) error {
 // This method always attempts cleanup regardless of the started status,
 // since resources may exist even if started is false.
 func (i *Instance) Kill() error {
+	errs := i.closeSession()
+	errs = append(errs, i.removeWorktree()...)
+	return i.combineErrors(errs)
+}
+
+// KillSession stops the dev server and closes the tmux session but leaves the git worktree in place,
+// so the kill can still be undone. Call CleanupWorktree to finish killing the instance.
+func (i *Instance) KillSession() error {
+	return i.combineErrors(i.closeSession())
+}
+
+// CleanupWorktree removes the git worktree of an instance whose session was closed by KillSession.
+func (i *Instance) CleanupWorktree() error {
+	return i.combineErrors(i.removeWorktree())
+}
+
+// closeSession stops everything running for the instance: the recording, the dev server and the
+// tmux session.
+func (i *Instance) closeSession() []error {
 	var errs []error
 
+	if i.recorder != nil {
+		if err := i.StopRecording(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	// Stop dev server first if it's running
 	if i.DevServer != nil {
 		if err := i.DevServer.Stop(); err != nil {
@@ -344,20 +684,24 @@ func (i *Instance) Kill() error {
 	}
 
 	// Clean up tmux session if it exists (check regardless of started status)
-	if i.tmuxSession != nil {
-		if err := i.tmuxSession.Close(); err != nil {
+	if i.backend != nil {
+		if err := i.backend.Close(); err != nil {
 			errs = append(errs, fmt.Errorf("failed to close tmux session: %w", err))
 		}
 	}
 
+	return errs
+}
+
+// removeWorktree cleans up the git worktree.
+func (i *Instance) removeWorktree() []error {
 	// Clean up git worktree if it exists (check regardless of started status)
 	if i.gitWorktree != nil {
 		if err := i.gitWorktree.Cleanup(); err != nil {
-			errs = append(errs, fmt.Errorf("failed to cleanup git worktree: %w", err))
+			return []error{fmt.Errorf("failed to cleanup git worktree: %w", err)}
 		}
 	}
-
-	return i.combineErrors(errs)
+	return nil
 }
 
 // combineErrors combines multiple errors into a single error
@@ -380,31 +724,134 @@ func (i *Instance) Preview() (string, error) {
 	if !i.started || i.Status == Paused {
 		return "", nil
 	}
-	return i.tmuxSession.CapturePaneContent()
+	return i.backend.CapturePaneContent()
 }
 
 func (i *Instance) HasUpdated() (updated bool, hasPrompt bool) {
 	if !i.started {
 		return false, false
 	}
-	return i.tmuxSession.HasUpdated()
+	updated, hasPrompt = i.backend.HasUpdated()
+	if !hasPrompt {
+		// The prompt that blocked auto-yes is gone.
+		i.autoYesBlockedBy = ""
+	}
+	return updated, hasPrompt
+}
+
+// diffOptions are the options used to compute the diff stats of every instance.
+var diffOptions config.DiffOptions
+
+// SetDiffOptions sets the options used to compute diff stats (config.Config.DiffOptions). Instances
+// pick them up on their next UpdateDiffStats, even if their worktree didn't change.
+func SetDiffOptions(opts config.DiffOptions) {
+	diffOptions = opts
+}
+
+// GetDiffOptions returns the options set by SetDiffOptions.
+func GetDiffOptions() config.DiffOptions {
+	return diffOptions
+}
+
+// diffStaleAfter is the age after which diff stats count as stale. Zero only marks the stats of
+// paused instances stale.
+var diffStaleAfter time.Duration
+
+// SetDiffStaleAfter sets the age after which diff stats count as stale
+// (config.Config.GetDiffStaleAfter).
+func SetDiffStaleAfter(d time.Duration) {
+	diffStaleAfter = d
+}
+
+// dedupeServerOutput is whether dev servers collapse consecutive identical output lines.
+var dedupeServerOutput bool
+
+// SetDedupeServerOutput sets whether dev servers created from now on collapse consecutive identical
+// output lines (config.Config.DedupeServerOutput).
+func SetDedupeServerOutput(enabled bool) {
+	dedupeServerOutput = enabled
+}
+
+// devServerWidth and devServerHeight are the size dev server sessions start with until the size of
+// the server pane is known.
+var devServerWidth, devServerHeight = config.DefaultDevServerWidth, config.DefaultDevServerHeight
+
+// SetDevServerSize sets the size dev server sessions start with until the size of the server pane is
+// known (config.Config.GetDevServerSize).
+func SetDevServerSize(width, height int) {
+	devServerWidth, devServerHeight = width, height
+}
+
+// autoYesDenyPatterns are the lowercased patterns that stop auto-yes from confirming a prompt.
+var autoYesDenyPatterns []string
+
+// SetAutoYesDenyPatterns sets the patterns that suppress auto-yes (config.Config.AutoYesDenyPatterns).
+// Patterns are matched case-insensitively as substrings of the pane content.
+func SetAutoYesDenyPatterns(patterns []string) {
+	autoYesDenyPatterns = nil
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			autoYesDenyPatterns = append(autoYesDenyPatterns, strings.ToLower(pattern))
+		}
+	}
+}
+
+// matchAutoYesDenyPattern returns the first deny pattern found in content, or an empty string.
+func matchAutoYesDenyPattern(content string) string {
+	content = strings.ToLower(content)
+	for _, pattern := range autoYesDenyPatterns {
+		if strings.Contains(content, pattern) {
+			return pattern
+		}
+	}
+	return ""
 }
 
-// TapEnter sends an enter key press to the tmux session if AutoYes is enabled.
+// TapEnter sends an enter key press to the tmux session if AutoYes is enabled. The prompt is left for
+// manual review if the pane matches one of the auto-yes deny patterns.
 func (i *Instance) TapEnter() {
 	if !i.started || !i.AutoYes {
 		return
 	}
-	if err := i.tmuxSession.TapEnter(); err != nil {
+	if len(autoYesDenyPatterns) > 0 {
+		content, err := i.backend.CapturePaneContent()
+		if err != nil {
+			log.ErrorLog.Printf("error capturing pane for auto-yes: %v", err)
+			return
+		}
+		if pattern := matchAutoYesDenyPattern(content); pattern != "" {
+			if i.autoYesBlockedBy == "" {
+				log.WarningLog.Printf("auto-yes suppressed for %s: prompt matches %q", i.Title, pattern)
+			}
+			i.autoYesBlockedBy = pattern
+			return
+		}
+	}
+	i.autoYesBlockedBy = ""
+	if err := i.backend.TapEnter(); err != nil {
 		log.ErrorLog.Printf("error tapping enter: %v", err)
 	}
 }
 
+// AutoYesBlockedBy returns the deny pattern that stopped auto-yes from confirming the current prompt, or
+// an empty string if auto-yes isn't blocked.
+func (i *Instance) AutoYesBlockedBy() string {
+	return i.autoYesBlockedBy
+}
+
 func (i *Instance) Attach() (chan struct{}, error) {
 	if !i.started {
 		return nil, fmt.Errorf("cannot attach instance that has not been started")
 	}
-	return i.tmuxSession.Attach()
+	return i.backend.Attach()
+}
+
+// SwitchClient switches the surrounding tmux client to the instance's session without blocking.
+func (i *Instance) SwitchClient() error {
+	if !i.started {
+		return fmt.Errorf("cannot attach instance that has not been started")
+	}
+	return i.backend.SwitchClient()
 }
 
 func (i *Instance) SetPreviewSize(width, height int) error {
@@ -412,7 +859,16 @@ func (i *Instance) SetPreviewSize(width, height int) error {
 		return fmt.Errorf("cannot set preview size for instance that has not been started or " +
 			"is paused")
 	}
-	return i.tmuxSession.SetDetachedSize(width, height)
+	return i.backend.SetDetachedSize(width, height)
+}
+
+// Resize resizes the live session to the preview size and reflows its content. Use it when the
+// terminal size changed, e.g. on window-size events or after detaching.
+func (i *Instance) Resize(width, height int) error {
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("cannot resize instance that has not been started or is paused")
+	}
+	return i.backend.Resize(width, height)
 }
 
 // GetGitWorktree returns the git worktree for the instance
@@ -437,43 +893,161 @@ func (i *Instance) SetTitle(title string) error {
 	return nil
 }
 
+// SetWorktreeCheck sets a check that rejects the worktree path chosen when the instance is started
+// for the first time, e.g. WorktreeInUse against the other loaded instances.
+func (i *Instance) SetWorktreeCheck(check func(worktreePath string) error) {
+	i.worktreeCheck = check
+}
+
+// ErrWorktreeInUse is returned when a worktree path is already used by another instance. Two
+// instances sharing a worktree would remove each other's files when one of them is killed.
+var ErrWorktreeInUse = errors.New("worktree is already in use")
+
+// WorktreeInUse returns ErrWorktreeInUse if one of instances uses the worktree at worktreePath.
+func WorktreeInUse(worktreePath string, instances []*Instance) error {
+	for _, instance := range instances {
+		worktree, err := instance.GetGitWorktree()
+		if err != nil || worktree == nil || worktree.GetWorktreePath() == "" {
+			continue
+		}
+		if filepath.Clean(worktree.GetWorktreePath()) == filepath.Clean(worktreePath) {
+			return fmt.Errorf("%w: %s belongs to instance '%s'", ErrWorktreeInUse, worktreePath, instance.Title)
+		}
+	}
+	return nil
+}
+
+// BaseBranch returns the branch the worktree is created from. Empty means HEAD.
+func (i *Instance) BaseBranch() string {
+	return i.baseBranch
+}
+
+// SetProgramAndBase sets the program and the base branch of an instance that wasn't started yet.
+func (i *Instance) SetProgramAndBase(program, baseBranch string) error {
+	if i.started {
+		return fmt.Errorf("cannot change program of a started instance")
+	}
+	i.Program = program
+	i.baseBranch = baseBranch
+	return nil
+}
+
+// NoWorktree returns true if the instance runs in the repository's checkout instead of a worktree.
+func (i *Instance) NoWorktree() bool {
+	return i.noWorktree
+}
+
+// BranchName returns the branch the worktree is created on. Empty means one derived from the title.
+func (i *Instance) BranchName() string {
+	return i.branchName
+}
+
+// SetBranchName sets the branch the worktree of an instance that wasn't started yet is created on.
+// Empty derives the branch from the title.
+func (i *Instance) SetBranchName(name string) error {
+	if i.started {
+		return fmt.Errorf("cannot change branch of a started instance")
+	}
+	if name != "" {
+		if err := git.ValidateBranchName(name); err != nil {
+			return err
+		}
+		// Start reports a path outside of a repository.
+		if git.IsGitRepo(i.Path) {
+			if err := checkNewBranch(i.Path, name); err != nil {
+				return err
+			}
+		}
+	}
+	i.branchName = name
+	return nil
+}
+
+// checkNewBranch returns an error if the repository at path already has a branch named name. The
+// branch of a worktree is deleted when its instance is killed, so it must never be one of the user's.
+func checkNewBranch(path string, name string) error {
+	exists, err := git.BranchExists(path, name)
+	if err != nil {
+		return fmt.Errorf("failed to check branch %s: %w", name, err)
+	}
+	if exists {
+		return fmt.Errorf("branch '%s' already exists; choose a new branch name", name)
+	}
+	return nil
+}
+
+// Paused returns true if the instance is paused. A resuming instance still counts as paused until
+// its session is back, so it isn't used in the meantime.
 func (i *Instance) Paused() bool {
-	return i.Status == Paused
+	return i.Status == Paused || i.resuming
+}
+
+// Resuming returns true while a resume started with BeginResume runs.
+func (i *Instance) Resuming() bool {
+	return i.resuming
 }
 
 // TmuxAlive returns true if the tmux session is alive. This is a sanity check before attaching.
 func (i *Instance) TmuxAlive() bool {
-	return i.tmuxSession.DoesSessionExist()
+	return i.backend.DoesSessionExist()
 }
 
 // Pause stops the tmux session and removes the worktree, preserving the branch
 func (i *Instance) Pause() error {
+	if err := i.pause(); err != nil {
+		return err
+	}
+	_ = clipboard.WriteAll(i.gitWorktree.GetWorktreePath())
+	return nil
+}
+
+// pause commits the changes in the worktree and detaches from the tmux session.
+func (i *Instance) pause() error {
 	if !i.started {
 		return fmt.Errorf("cannot pause instance that has not been started")
 	}
 	if i.Status == Paused {
 		return fmt.Errorf("instance is already paused")
 	}
+	if i.resuming {
+		return fmt.Errorf("cannot pause an instance while it is resuming")
+	}
 
 	var errs []error
 
-	// Check if there are any changes to commit
-	if dirty, err := i.gitWorktree.IsDirty(); err != nil {
-		errs = append(errs, fmt.Errorf("failed to check if worktree is dirty: %w", err))
-		log.ErrorLog.Print(err)
-	} else if dirty {
-		// Commit changes locally (without pushing to GitHub)
-		commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822))
-		if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
-			errs = append(errs, fmt.Errorf("failed to commit changes: %w", err))
+	// The tmux session is going away, so finish the recording.
+	if i.recorder != nil {
+		if err := i.StopRecording(); err != nil {
+			log.ErrorLog.Print(err)
+		}
+	}
+
+	// Commit the changes locally (without pushing to GitHub). The changes of an in-place instance
+	// are left uncommitted in the user's checkout.
+	if !i.noWorktree {
+		if dirty, err := i.gitWorktree.IsDirty(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to check if worktree is dirty: %w", err))
 			log.ErrorLog.Print(err)
-			// Return early if we can't commit changes to avoid corrupted state
-			return i.combineErrors(errs)
+		} else if dirty {
+			commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (paused)", i.Title, time.Now().Format(time.RFC822))
+			if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+				errs = append(errs, fmt.Errorf("failed to commit changes: %w", err))
+				log.ErrorLog.Print(err)
+				// Return early if we can't commit changes to avoid corrupted state
+				return i.combineErrors(errs)
+			}
 		}
 	}
 
+	// Keep the final screen so the preview can show it while paused
+	if content, err := i.backend.CapturePaneContent(); err != nil {
+		log.WarningLog.Printf("could not capture pane before pausing: %v", err)
+	} else {
+		i.pausedCapture = content
+	}
+
 	// Detach from tmux session instead of closing to preserve session output
-	if err := i.tmuxSession.DetachSafely(); err != nil {
+	if err := i.backend.DetachSafely(); err != nil {
 		errs = append(errs, fmt.Errorf("failed to detach tmux session: %w", err))
 		log.ErrorLog.Print(err)
 		// Continue with pause process even if detach fails
@@ -491,19 +1065,111 @@ func (i *Instance) Pause() error {
 	}
 
 	i.SetStatus(Paused)
-	_ = clipboard.WriteAll(i.gitWorktree.GetWorktreePath())
 	return nil
 }
 
-// Resume recreates the worktree and restarts the tmux session
-func (i *Instance) Resume() error {
+// Archive puts the instance away without losing anything: it is paused, its dev server is stopped,
+// and it is hidden from the active instances, but its worktree and branch are kept. Archived
+// instances don't count against the instance limit.
+func (i *Instance) Archive() error {
+	if i.archived {
+		return fmt.Errorf("instance is already archived")
+	}
+	if i.resuming {
+		return fmt.Errorf("cannot archive an instance while it is resuming")
+	}
+	if i.Status != Paused {
+		if err := i.pause(); err != nil {
+			return err
+		}
+	}
+	if i.DevServer != nil && i.DevServer.IsRunning() {
+		if err := i.DevServer.Stop(); err != nil {
+			log.WarningLog.Printf("failed to stop dev server of archived instance %s: %v", i.Title, err)
+		}
+	}
+	i.archived = true
+	return nil
+}
+
+// Unarchive returns the instance to the active instances. It stays paused until it is resumed.
+func (i *Instance) Unarchive() error {
+	if !i.archived {
+		return fmt.Errorf("instance is not archived")
+	}
+	i.archived = false
+	return nil
+}
+
+// Archived returns true if the instance is archived.
+func (i *Instance) Archived() bool {
+	return i.archived
+}
+
+// checkResumable returns an error if the instance can't be resumed.
+func (i *Instance) checkResumable() error {
 	if !i.started {
 		return fmt.Errorf("cannot resume instance that has not been started")
 	}
+	if i.resuming {
+		return fmt.Errorf("instance is already resuming")
+	}
 	if i.Status != Paused {
 		return fmt.Errorf("can only resume paused instances")
 	}
+	if i.archived {
+		return fmt.Errorf("cannot resume an archived instance, unarchive it first")
+	}
+	return nil
+}
+
+// BeginResume checks that the instance can be resumed and shows it as Loading until FinishResume,
+// so ResumeSession can run in the background in between.
+func (i *Instance) BeginResume() error {
+	if err := i.checkResumable(); err != nil {
+		return err
+	}
+	i.resuming = true
+	i.SetStatus(Loading)
+	return nil
+}
 
+// ResumeSession recreates the worktree and restarts the tmux session of an instance BeginResume was
+// called for. It doesn't change the instance's state, so it can run in the background while the UI
+// reads it; pass its result to FinishResume.
+func (i *Instance) ResumeSession() error {
+	return i.resume()
+}
+
+// FinishResume ends a resume started with BeginResume with the result of ResumeSession. If it
+// failed, the instance is paused again and err is returned.
+func (i *Instance) FinishResume(err error) error {
+	i.resuming = false
+	if err != nil {
+		i.SetStatus(Paused)
+		return err
+	}
+	i.pausedCapture = ""
+	i.SetStatus(Running)
+	return nil
+}
+
+// Resume recreates the worktree and restarts the tmux session
+func (i *Instance) Resume() error {
+	if err := i.checkResumable(); err != nil {
+		return err
+	}
+	if err := i.resume(); err != nil {
+		return err
+	}
+	i.pausedCapture = ""
+	i.SetStatus(Running)
+	return nil
+}
+
+// resume does the work of Resume once the instance was checked. It only touches the worktree and
+// the session backend, see ResumeSession.
+func (i *Instance) resume() error {
 	// Check if branch is checked out
 	if checked, err := i.gitWorktree.IsBranchCheckedOut(); err != nil {
 		log.ErrorLog.Print(err)
@@ -512,26 +1178,26 @@ func (i *Instance) Resume() error {
 		return fmt.Errorf("cannot resume: branch is checked out, please switch to a different branch")
 	}
 
-	// Check if worktree directory exists (it should exist after pause since we don't remove it)
-	if _, err := os.Stat(i.gitWorktree.GetWorktreePath()); os.IsNotExist(err) {
-		// Worktree was removed externally, need to recreate it
-		if err := i.gitWorktree.Setup(); err != nil {
-			log.ErrorLog.Print(err)
-			return fmt.Errorf("failed to setup git worktree: %w", err)
-		}
-	} else if err != nil {
-		// Error checking if worktree exists
-		return fmt.Errorf("failed to check if worktree exists: %w", err)
+	// The worktree should exist after pause since we don't remove it, but it may have been removed
+	// externally, or git's metadata about it may be stale. An existing worktree is kept as is to
+	// preserve the tmux session's working directory.
+	if err := i.ensureWorktree(); err != nil {
+		log.ErrorLog.Print(err)
+		return err
+	}
+
+	if err := i.setContainerCommand(i.backend); err != nil {
+		return err
 	}
-	// Note: If worktree exists, we don't call Setup() to preserve tmux session's working directory
 
 	// Check if tmux session still exists from pause, otherwise create new one
-	if i.tmuxSession.DoesSessionExist() {
+	i.migrateSessionName()
+	if i.backend.DoesSessionExist() {
 		// Session exists, just restore PTY connection to it
-		if err := i.tmuxSession.Restore(); err != nil {
+		if err := i.backend.Restore(); err != nil {
 			log.ErrorLog.Print(err)
 			// If restore fails, fall back to creating new session
-			if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+			if err := i.backend.Start(i.gitWorktree.GetWorktreePath()); err != nil {
 				log.ErrorLog.Print(err)
 				// Cleanup git worktree if tmux session creation fails
 				if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
@@ -543,7 +1209,7 @@ func (i *Instance) Resume() error {
 		}
 	} else {
 		// Create new tmux session
-		if err := i.tmuxSession.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+		if err := i.backend.Start(i.gitWorktree.GetWorktreePath()); err != nil {
 			log.ErrorLog.Print(err)
 			// Cleanup git worktree if tmux session creation fails
 			if cleanupErr := i.gitWorktree.Cleanup(); cleanupErr != nil {
@@ -553,11 +1219,15 @@ func (i *Instance) Resume() error {
 			return fmt.Errorf("failed to start new session: %w", err)
 		}
 	}
-
-	i.SetStatus(Running)
 	return nil
 }
 
+// PausedCapture returns the pane content captured when the instance was paused, or an empty string
+// if there is none.
+func (i *Instance) PausedCapture() string {
+	return i.pausedCapture
+}
+
 // UpdateDiffStats updates the git diff statistics for this instance
 func (i *Instance) UpdateDiffStats() error {
 	if !i.started {
@@ -570,8 +1240,21 @@ func (i *Instance) UpdateDiffStats() error {
 		return nil
 	}
 
-	stats := i.gitWorktree.Diff()
+	// Skip the git invocations entirely when nothing changed since the last computation.
+	changed, token, err := i.gitWorktree.HasChangedSince(i.diffToken)
+	if err != nil {
+		log.WarningLog.Printf("could not check worktree changes for %s: %v", i.Title, err)
+	}
+	diffArgs := strings.Join(diffOptions.GitArgs(), " ")
+	if !changed && i.diffStats != nil && diffArgs == i.diffArgs {
+		// The stats still match the worktree as far as the change check can tell. ComputedAt is
+		// kept, so stats the check wrongly keeps still turn stale.
+		return nil
+	}
+
+	stats := i.gitWorktree.Diff(diffOptions)
 	if stats.Error != nil {
+		i.diffToken = ""
 		if strings.Contains(stats.Error.Error(), "base commit SHA not set") {
 			// Worktree is not fully set up yet, not an error
 			i.diffStats = nil
@@ -581,46 +1264,347 @@ func (i *Instance) UpdateDiffStats() error {
 	}
 
 	i.diffStats = stats
+	i.diffToken = token
+	i.diffArgs = diffArgs
+
+	hasConflicts, err := i.gitWorktree.HasConflicts()
+	if err != nil {
+		return err
+	}
+	i.hasConflicts = hasConflicts
 	return nil
 }
 
+// FileDiff returns the diff of path, one of the Files of the current diff stats. It is computed
+// with git, so paths git quotes in the full diff are found too, and cached until the diff stats
+// change.
+func (i *Instance) FileDiff(path string) (string, error) {
+	if !i.started || i.Paused() || i.gitWorktree == nil {
+		return "", fmt.Errorf("cannot show the diff of a file of an instance that isn't running")
+	}
+	if i.diffStats != nil && i.fileDiffOf == i.diffStats && i.fileDiffPath == path {
+		return i.fileDiff, nil
+	}
+	content, err := i.gitWorktree.FileDiff(diffOptions, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the diff of %s: %w", path, err)
+	}
+	i.fileDiff, i.fileDiffPath, i.fileDiffOf = content, path, i.diffStats
+	return content, nil
+}
+
+// RefreshDiffStats recomputes the diff stats even if the worktree looks unchanged, e.g. after git
+// operations the change check missed. Paused instances have no worktree to compute them from.
+func (i *Instance) RefreshDiffStats() error {
+	if i.Paused() {
+		return fmt.Errorf("cannot refresh the diff of paused instance '%s'", i.Title)
+	}
+	i.diffToken = ""
+	return i.UpdateDiffStats()
+}
+
+// DiffStatsAge returns how long ago the diff stats were computed, and whether they're stale: paused, saved without a timestamp or older than the SetDiffStaleAfter
+// threshold.
+func (i *Instance) DiffStatsAge() (age time.Duration, stale bool) {
+	if i.diffStats == nil {
+		return 0, false
+	}
+	if i.diffStats.ComputedAt.IsZero() {
+		return 0, true
+	}
+	age = time.Since(i.diffStats.ComputedAt)
+	return age, i.Paused() || (diffStaleAfter > 0 && age > diffStaleAfter)
+}
+
 // GetDiffStats returns the current git diff statistics
 func (i *Instance) GetDiffStats() *git.DiffStats {
 	return i.diffStats
 }
 
+// HasConflicts returns true if the worktree had unmerged paths at the last diff stats update
+func (i *Instance) HasConflicts() bool {
+	return i.hasConflicts
+}
+
+// SetBaseDivergence records how far the instance branch is ahead of and behind its base branch's
+// upstream, as computed by git.GitWorktree.DivergenceFromBase.
+func (i *Instance) SetBaseDivergence(ahead, behind int) {
+	i.baseAhead, i.baseBehind = ahead, behind
+}
+
+// BaseDivergence returns the divergence from the base branch's upstream recorded by SetBaseDivergence.
+func (i *Instance) BaseDivergence() (ahead, behind int) {
+	return i.baseAhead, i.baseBehind
+}
+
+// StartRecording starts recording the instance's terminal output to an asciinema v2 cast file at
+// path. Frames are appended by RecordFrame whenever the pane content changes.
+func (i *Instance) StartRecording(path string) error {
+	if !i.started || i.Status == Paused {
+		return fmt.Errorf("cannot record instance that has not been started or is paused")
+	}
+	if i.recorder != nil {
+		return fmt.Errorf("instance is already recording to %s", i.recorder.path)
+	}
+
+	width, height, err := i.backend.PaneSize()
+	if err != nil {
+		log.WarningLog.Printf("could not get pane size for recording, using default: %v", err)
+		width, height = 80, 24
+	}
+
+	recorder, err := newCastRecorder(path, width, height, i.Title)
+	if err != nil {
+		return err
+	}
+	i.recorder = recorder
+	return i.RecordFrame()
+}
+
+// StopRecording stops an active recording and closes the cast file.
+func (i *Instance) StopRecording() error {
+	if i.recorder == nil {
+		return fmt.Errorf("instance is not recording")
+	}
+	recorder := i.recorder
+	i.recorder = nil
+	if err := recorder.close(); err != nil {
+		return fmt.Errorf("failed to close recording: %w", err)
+	}
+	return nil
+}
+
+// IsRecording returns true if the instance is recording its terminal output.
+func (i *Instance) IsRecording() bool {
+	return i.recorder != nil
+}
+
+// RecordingPath returns the path of the active recording, or an empty string if not recording.
+func (i *Instance) RecordingPath() string {
+	if i.recorder == nil {
+		return ""
+	}
+	return i.recorder.path
+}
+
+// RecordFrame captures the pane and appends it to the active recording if it changed. It does
+// nothing when the instance isn't recording.
+func (i *Instance) RecordFrame() error {
+	if i.recorder == nil || !i.started || i.Status == Paused {
+		return nil
+	}
+	content, err := i.backend.CapturePaneContent()
+	if err != nil {
+		return fmt.Errorf("failed to capture pane for recording: %w", err)
+	}
+	return i.recorder.writeFrame(content)
+}
+
+// ResetToBase discards all file changes and commits made by the instance since its base commit. The
+// tmux session is left running so the agent keeps its context.
+func (i *Instance) ResetToBase() error {
+	if !i.started {
+		return fmt.Errorf("cannot reset instance that has not been started")
+	}
+	if i.Status == Paused {
+		return fmt.Errorf("cannot reset a paused instance")
+	}
+
+	if err := i.gitWorktree.ResetToBase(); err != nil {
+		return err
+	}
+	return i.UpdateDiffStats()
+}
+
+// restartSuffix is appended to the title of a session that is started to replace the instance's
+// session, until the old one is closed.
+const restartSuffix = "-restarting"
+
+// RestartSession kills the instance's session and starts the program again in the same worktree,
+// e.g. when the agent hangs. Git state and file changes are left untouched; the agent's context is lost.
+func (i *Instance) RestartSession() error {
+	if !i.started {
+		return fmt.Errorf("cannot restart instance that has not been started")
+	}
+	if i.Paused() {
+		return fmt.Errorf("cannot restart a paused instance, resume it instead")
+	}
+
+	// The new session starts under a temporary name next to the old one, so the agent keeps
+	// running if it fails to start. Backends that can't rename start it under the title.
+	backend := NewBackend(i.Title+restartSuffix, i.Command())
+	renamer, canRename := backend.(sessionRenamer)
+	if !canRename {
+		backend = NewBackend(i.Title, i.Command())
+	}
+	if err := i.setContainerCommand(backend); err != nil {
+		return err
+	}
+	if err := backend.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+		return fmt.Errorf("failed to restart session: %w", err)
+	}
+	if err := i.backend.Close(); err != nil {
+		// The session may already be gone, which is fine since it's replaced anyway.
+		log.WarningLog.Printf("failed to close session of %s on restart: %v", i.Title, err)
+	}
+	if canRename {
+		if err := renamer.Rename(i.Title); err != nil {
+			// The new session still works under its temporary name.
+			log.WarningLog.Printf("failed to rename restarted session of %s: %v", i.Title, err)
+		}
+	}
+	i.backend = backend
+	i.autoYesBlockedBy = ""
+	i.SetStatus(Running)
+	return nil
+}
+
+// RebaseOntoBase commits any pending changes and rebases the instance branch onto the current HEAD
+// of the repository. A failed rebase is aborted, leaving the branch as it was.
+func (i *Instance) RebaseOntoBase() error {
+	if !i.started {
+		return fmt.Errorf("cannot rebase instance that has not been started")
+	}
+	if i.Status == Paused {
+		return fmt.Errorf("cannot rebase a paused instance")
+	}
+
+	commitMsg := fmt.Sprintf("[claudesquad] update from '%s' on %s (before rebase)", i.Title, time.Now().Format(time.RFC822))
+	if err := i.gitWorktree.CommitChanges(commitMsg); err != nil {
+		return fmt.Errorf("failed to commit changes: %w", err)
+	}
+
+	if err := i.gitWorktree.RebaseOntoBase(); err != nil {
+		return err
+	}
+	return i.UpdateDiffStats()
+}
+
 // SendPrompt sends a prompt to the tmux session
 func (i *Instance) SendPrompt(prompt string) error {
 	if !i.started {
 		return fmt.Errorf("instance not started")
 	}
-	if i.tmuxSession == nil {
+	if i.backend == nil {
 		return fmt.Errorf("tmux session not initialized")
 	}
-	if err := i.tmuxSession.SendKeys(prompt); err != nil {
+	if err := i.backend.SendKeys(prompt); err != nil {
 		return fmt.Errorf("error sending keys to tmux session: %w", err)
 	}
 
 	// Brief pause to prevent carriage return from being interpreted as newline
 	time.Sleep(100 * time.Millisecond)
-	if err := i.tmuxSession.TapEnter(); err != nil {
+	if err := i.backend.TapEnter(); err != nil {
 		return fmt.Errorf("error tapping enter: %w", err)
 	}
 
 	return nil
 }
 
+// SendInitialPrompt sends Prompt to the instance and clears it, so it is attempted only once. It
+// is a no-op when there is no initial prompt.
+func (i *Instance) SendInitialPrompt() error {
+	prompt := i.Prompt
+	if prompt == "" {
+		return nil
+	}
+	i.Prompt = ""
+	if err := i.SendPrompt(prompt); err != nil {
+		return fmt.Errorf("failed to send initial prompt to %s: %w", i.Title, err)
+	}
+	return nil
+}
+
 // PreviewFullHistory captures the entire tmux pane output including full scrollback history
 func (i *Instance) PreviewFullHistory() (string, error) {
 	if !i.started || i.Status == Paused {
 		return "", nil
 	}
-	return i.tmuxSession.CapturePaneContentWithOptions("-", "-")
+	return i.backend.CapturePaneContentWithOptions("-", "-")
 }
 
 // SetTmuxSession sets the tmux session for testing purposes
 func (i *Instance) SetTmuxSession(session *tmux.TmuxSession) {
-	i.tmuxSession = session
+	if session == nil {
+		// Avoid storing a typed nil, which would compare non-nil as a Backend.
+		i.SetBackend(nil)
+		return
+	}
+	i.SetBackend(session)
+}
+
+// recreateSession starts a new session in the instance's worktree for a loaded instance whose
+// session no longer exists, recreating the worktree if it was removed too. The agent starts over but
+// the changes in the worktree are kept. If the session can't be started, the instance is paused
+// instead so that it still loads and can be resumed later.
+func (i *Instance) recreateSession() {
+	log.WarningLog.Printf("session for instance '%s' no longer exists, starting a new one", i.Title)
+	err := i.startInWorktree()
+	if err != nil {
+		log.ErrorLog.Printf("failed to recreate session for instance '%s', pausing it: %v", i.Title, err)
+		i.SetStatus(Paused)
+		return
+	}
+	i.SetStatus(Running)
+}
+
+// startInWorktree starts the backend in the existing worktree, setting the worktree up again if its
+// directory is missing.
+func (i *Instance) startInWorktree() error {
+	if err := i.ensureWorktree(); err != nil {
+		return err
+	}
+	if err := i.setContainerCommand(i.backend); err != nil {
+		return err
+	}
+	if err := i.backend.Start(i.gitWorktree.GetWorktreePath()); err != nil {
+		return fmt.Errorf("failed to start new session: %w", err)
+	}
+	return nil
+}
+
+// RepairWorktree recreates the worktree of a started instance if its directory is missing and fixes
+// git's metadata about it, see git.GitWorktree.Repair. It returns a description of each fix.
+func (i *Instance) RepairWorktree() ([]string, error) {
+	if !i.started {
+		return nil, fmt.Errorf("cannot repair the worktree of an instance that has not been started")
+	}
+	if i.resuming {
+		return nil, fmt.Errorf("cannot repair the worktree of an instance while it is resuming")
+	}
+	return i.repairWorktree()
+}
+
+// repairWorktree repairs the worktree and logs each fix.
+func (i *Instance) repairWorktree() ([]string, error) {
+	repaired, err := i.gitWorktree.Repair()
+	for _, fix := range repaired {
+		log.InfoLog.Printf("repaired worktree of %s: %s", i.Title, fix)
+	}
+	if err != nil {
+		return repaired, fmt.Errorf("failed to repair git worktree: %w", err)
+	}
+	return repaired, nil
+}
+
+// ensureWorktree recreates the worktree if its directory is missing and repairs git's metadata
+// about it. Failing to repair an existing worktree is only logged, since the session can still run
+// in it.
+func (i *Instance) ensureWorktree() error {
+	_, statErr := os.Stat(i.gitWorktree.GetWorktreePath())
+	if _, err := i.repairWorktree(); err != nil {
+		if statErr == nil {
+			log.WarningLog.Printf("could not repair worktree of %s: %v", i.Title, err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// SetBackend sets the session backend. It must be called before Start to take effect.
+func (i *Instance) SetBackend(backend Backend) {
+	i.backend = backend
 }
 
 // SendKeys sends keys to the tmux session
@@ -628,20 +1612,42 @@ func (i *Instance) SendKeys(keys string) error {
 	if !i.started || i.Status == Paused {
 		return fmt.Errorf("cannot send keys to instance that has not been started or is paused")
 	}
-	return i.tmuxSession.SendKeys(keys)
+	return i.backend.SendKeys(keys)
 }
 
 // NewDevServer creates a new DevServer with the given configuration
 func NewDevServer(config DevServerConfig, worktree string, instance string) *DevServer {
 	return &DevServer{
-		config:   config,
-		status:   DevServerStopped,
-		output:   make([]string, 0),
-		worktree: worktree,
-		instance: instance,
+		config:        config,
+		status:        DevServerStopped,
+		output:        make([]string, 0),
+		worktree:      worktree,
+		instance:      instance,
+		dedupeOutput:  dedupeServerOutput,
+		sessionPrefix: tmux.TmuxPrefix,
 	}
 }
 
+// DevServerLog returns the dev server output with a header naming the instance, the commands, the
+// status and the crash count, so the exported text can be shared on its own.
+func (i *Instance) DevServerLog() (string, error) {
+	if i.DevServer == nil {
+		return "", fmt.Errorf("no dev server configured for '%s'", i.Title)
+	}
+	server := i.DevServer
+	header := []string{fmt.Sprintf("Dev server log for '%s'", i.Title)}
+	if command := server.Config().BuildCommand; command != "" {
+		header = append(header, "Build command: "+command)
+	}
+	header = append(header,
+		"Command: "+server.Config().DevCommand,
+		"Status: "+server.Status().String(),
+		fmt.Sprintf("Crash count: %d", server.CrashCount()),
+		"Exported: "+time.Now().Format("2006-01-02 15:04:05"),
+	)
+	return strings.Join(header, "\n") + "\n\n" + server.Output() + "\n", nil
+}
+
 // SetDevServerSession sets the tmux session for the dev server
 func (d *DevServer) SetDevServerSession(session *tmux.TmuxSession) {
 	d.session = session
@@ -659,30 +1665,121 @@ func (d *DevServer) GetOutput() string {
 	return strings.Join(d.output, "\n")
 }
 
-// appendOutput adds a line to the output buffer (max 100 lines)
+// URL returns the URL detected in the output of the current run, or an empty string if none was printed.
+func (d *DevServer) URL() string {
+	d.outputMu.RLock()
+	defer d.outputMu.RUnlock()
+	return d.url
+}
+
+// LastBuildDuration returns how long the build command of the current run took. Zero if there is no
+// build command or it hasn't finished.
+func (d *DevServer) LastBuildDuration() time.Duration {
+	d.outputMu.RLock()
+	defer d.outputMu.RUnlock()
+	return d.buildDuration
+}
+
+// LastStartDuration returns how long the dev command of the current run took to be ready, i.e. to
+// print the URL it listens on. Zero until then, or if it never prints one.
+func (d *DevServer) LastStartDuration() time.Duration {
+	d.outputMu.RLock()
+	defer d.outputMu.RUnlock()
+	return d.startDuration
+}
+
+// appendOutput adds a line to the output buffer (DevServerConfig.MaxOutputLines). With dedupeOutput enabled, a line
+// identical to the previous one is collapsed into it as "line ×N".
 func (d *DevServer) appendOutput(line string) {
 	d.outputMu.Lock()
 	defer d.outputMu.Unlock()
+	d.appendOutputLocked(line)
+}
+
+// appendOutputLocked is appendOutput for callers already holding outputMu
+func (d *DevServer) appendOutputLocked(line string) {
+	if d.url == "" || !d.readyAt.IsZero() {
+		url := detectURL(line)
+		if d.url == "" {
+			d.url = url
+		}
+		if url != "" && !d.readyAt.IsZero() {
+			d.startDuration = time.Since(d.readyAt)
+			d.readyAt = time.Time{}
+		}
+	}
+	if d.dedupeOutput && len(d.output) > 0 && d.lastLineCount > 0 && line == d.lastLine {
+		d.lastLineCount++
+		d.output[len(d.output)-1] = formatRepeatedLine(line, d.lastLineCount)
+		return
+	}
+	d.lastLine = line
+	d.lastLineCount = 1
 	d.output = append(d.output, line)
-	if len(d.output) > 100 {
-		d.output = d.output[len(d.output)-100:]
+	if limit := d.config.maxOutputLines(); len(d.output) > limit {
+		d.output = d.output[len(d.output)-limit:]
 	}
 }
 
-// UpdateOutputFromSession captures output from the tmux session
+// resetOutput clears the output buffer along with the deduplication and capture tracking
+func (d *DevServer) resetOutput() {
+	d.outputMu.Lock()
+	defer d.outputMu.Unlock()
+	d.output = make([]string, 0)
+	d.url = ""
+	d.buildDuration = 0
+	d.startDuration = 0
+	d.readyAt = time.Time{}
+	d.lastLine = ""
+	d.lastLineCount = 0
+	d.lastCapture = nil
+	d.lastCaptureHash = [sha256.Size]byte{}
+}
+
+// formatRepeatedLine renders a line that was repeated count times in a row
+func formatRepeatedLine(line string, count int) string {
+	return fmt.Sprintf("%s ×%d", line, count)
+}
+
+// newCaptureOffset returns the index of the first line in cur that wasn't already in prev. The pane
+// scrolls as output arrives, so the longest suffix of prev that is also a prefix of cur is treated as
+// already seen. If there is no such overlap, lines rewritten in place (e.g. progress bars) are
+// detected by the common prefix of both captures.
+func newCaptureOffset(prev, cur []string) int {
+	for k := min(len(prev), len(cur)); k > 0; k-- {
+		if slices.Equal(prev[len(prev)-k:], cur[:k]) {
+			return k
+		}
+	}
+
+	common := 0
+	for common < len(prev) && common < len(cur) && prev[common] == cur[common] {
+		common++
+	}
+	return common
+}
+
+// UpdateOutputFromSession captures output from the tmux session and appends only the lines that
+// weren't seen in the previous capture. Unchanged captures are skipped.
 func (d *DevServer) UpdateOutputFromSession() error {
 	if d.session == nil {
 		return nil
 	}
 	content, err := d.session.CapturePaneContent()
 	if err != nil {
-		log.InfoLog.Printf("UpdateOutputFromSession: capture error: %v", err)
+		log.DebugLog.Printf("UpdateOutputFromSession: capture error: %v", err)
 		return err
 	}
 
 	d.outputMu.Lock()
 	defer d.outputMu.Unlock()
 
+	hash := sha256.Sum256([]byte(content))
+	if hash == d.lastCaptureHash {
+		return nil
+	}
+	d.lastCaptureHash = hash
+
 	lines := strings.Split(content, "\n")
 
 	// Filter out empty lines
@@ -693,16 +1790,12 @@ func (d *DevServer) UpdateOutputFromSession() error {
 		}
 	}
 
-	// Only keep last 100 lines (matches tmux scrollback limit)
-	if len(nonEmptyLines) > 100 {
-		nonEmptyLines = nonEmptyLines[len(nonEmptyLines)-100:]
+	newLines := nonEmptyLines[newCaptureOffset(d.lastCapture, nonEmptyLines):]
+	d.lastCapture = nonEmptyLines
+	for _, line := range newLines {
+		d.appendOutputLocked(line)
 	}
 
-	// Update output buffer
-	d.output = nonEmptyLines
-
-	log.InfoLog.Printf("UpdateOutputFromSession: captured %d total lines (empty filtered)", len(nonEmptyLines))
-
 	return nil
 }
 
@@ -715,31 +1808,31 @@ func (d *DevServer) IsRunning() bool {
 // SessionExists returns true if the tmux session exists and is responsive
 func (d *DevServer) SessionExists() bool {
 	if d.session == nil {
-		log.InfoLog.Printf("DevServer.SessionExists: session is nil")
+		log.DebugLog.Printf("DevServer.SessionExists: session is nil")
 		return false
 	}
 	doesExist := d.session.DoesSessionExist()
-	log.InfoLog.Printf("DevServer.SessionExists: tmux DoesSessionExist = %v", doesExist)
+	log.DebugLog.Printf("DevServer.SessionExists: tmux DoesSessionExist = %v", doesExist)
 	if !doesExist {
 		return false
 	}
 	// Try to capture content to verify the session is responsive
 	content, err := d.session.CapturePaneContent()
 	if err != nil {
-		log.InfoLog.Printf("DevServer.SessionExists: CapturePaneContent error = %v", err)
+		log.DebugLog.Printf("DevServer.SessionExists: CapturePaneContent error = %v", err)
 		return false
 	}
-	log.InfoLog.Printf("DevServer.SessionExists: captured %d bytes", len(content))
+	log.DebugLog.Printf("DevServer.SessionExists: captured %d bytes", len(content))
 	// If we can capture content, session is alive
 	return content != ""
 }
 
 // CheckHealth checks if the dev server is still running
 func (d *DevServer) CheckHealth() {
-	log.InfoLog.Printf("CheckHealth: called - status=%v, startedAt=%v, session=%v", d.status, d.startedAt, d.session != nil)
+	log.DebugLog.Printf("CheckHealth: called - status=%v, startedAt=%v, session=%v", d.status, d.startedAt, d.session != nil)
 
 	if d.status != DevServerRunning {
-		log.InfoLog.Printf("CheckHealth: early return - status not Running")
+		log.DebugLog.Printf("CheckHealth: early return - status not Running")
 		return
 	}
 
@@ -756,20 +1849,27 @@ func (d *DevServer) CheckHealth() {
 
 	sessionExists := d.session.DoesSessionExist()
 	timeSinceStart := time.Since(d.startedAt)
-	log.InfoLog.Printf("CheckHealth: sessionExists=%v, timeSinceStart=%v, startedAt=%v", sessionExists, timeSinceStart, d.startedAt)
+	log.DebugLog.Printf("CheckHealth: sessionExists=%v, timeSinceStart=%v, startedAt=%v", sessionExists, timeSinceStart, d.startedAt)
 
 	// Safety check: if startedAt is zero time, use current time as fallback
 	if d.startedAt.IsZero() {
 		log.WarningLog.Printf("CheckHealth: startedAt is zero, using current time for safety")
 		d.startedAt = time.Now()
 		timeSinceStart = time.Since(d.startedAt)
-		log.InfoLog.Printf("CheckHealth: updated startedAt to %v, timeSinceStart=%v", d.startedAt, timeSinceStart)
+		log.DebugLog.Printf("CheckHealth: updated startedAt to %v, timeSinceStart=%v", d.startedAt, timeSinceStart)
 	}
 
 	if !sessionExists {
-		log.InfoLog.Printf("CheckHealth: checking grace period - timeSinceStart=%v < devServerGracePeriod=%v = %v", timeSinceStart, devServerGracePeriod, timeSinceStart < devServerGracePeriod)
+		log.DebugLog.Printf("CheckHealth: checking grace period - timeSinceStart=%v < devServerGracePeriod=%v = %v", timeSinceStart, devServerGracePeriod, timeSinceStart < devServerGracePeriod)
 		if timeSinceStart < devServerGracePeriod {
-			log.InfoLog.Printf("CheckHealth: within grace period, skipping crash detection")
+			log.DebugLog.Printf("CheckHealth: within grace period, skipping crash detection")
+			return
+		}
+		if d.exitedQuickly() {
+			log.InfoLog.Printf("CheckHealth: dev command exited successfully right after starting, marking as stopped")
+			d.SetStatus(DevServerStopped)
+			d.appendOutput(fmt.Sprintf("[%s] Dev command exited quickly — did you mean to run a long-lived server? "+
+				"'%s' finished without errors, like a build does.", time.Now().Format("15:04:05"), d.config.DevCommand))
 			return
 		}
 		log.InfoLog.Printf("CheckHealth: grace period exceeded, marking as crashed")
@@ -802,9 +1902,15 @@ func (d *DevServer) HasUpdated() bool {
 	if d.session == nil {
 		return false
 	}
-	prevLen := len(d.output)
+	d.outputMu.RLock()
+	prevHash := d.lastCaptureHash
+	d.outputMu.RUnlock()
+
 	d.UpdateOutputFromSession()
-	return len(d.output) > prevLen
+
+	d.outputMu.RLock()
+	defer d.outputMu.RUnlock()
+	return d.lastCaptureHash != prevHash
 }
 
 // Status returns the current dev server status
@@ -843,7 +1949,7 @@ func (d *DevServer) IncrementCrashCount() {
 
 // UpdateOutput updates the output from the tmux session
 func (d *DevServer) UpdateOutput() {
-	log.InfoLog.Printf("UpdateOutput: called, calling UpdateOutputFromSession")
+	log.DebugLog.Printf("UpdateOutput: called, calling UpdateOutputFromSession")
 	d.UpdateOutputFromSession()
 }
 
@@ -859,7 +1965,7 @@ func (d *DevServer) Start() error {
 	d.startMu.Lock()
 	defer d.startMu.Unlock()
 
-	log.InfoLog.Printf("DevServer.Start: called")
+	log.DebugLog.Printf("DevServer.Start: called")
 
 	if d.IsRunning() {
 		log.InfoLog.Printf("DevServer.Start: already running")
@@ -869,22 +1975,37 @@ func (d *DevServer) Start() error {
 	if d.config.DevCommand == "" {
 		return fmt.Errorf("dev command not configured")
 	}
+	if _, err := d.workDir(); err != nil {
+		return err
+	}
+
+	// Separate this run's output from the previous ones.
+	d.outputMu.Lock()
+	d.markNewRunLocked()
+	d.outputMu.Unlock()
 
 	d.SetStatus(DevServerBuilding)
-	log.InfoLog.Printf("DevServer.Start: status = Building")
+	log.DebugLog.Printf("DevServer.Start: status = Building")
 
 	if d.config.BuildCommand != "" {
 		log.InfoLog.Printf("DevServer.Start: running build command: %s", d.config.BuildCommand)
+		buildStart := time.Now()
 		if err := d.runBuild(); err != nil {
 			log.ErrorLog.Printf("DevServer.Start: build failed: %v", err)
 			d.SetStatus(DevServerStopped)
 			return fmt.Errorf("build failed: %w", err)
 		}
-		log.InfoLog.Printf("DevServer.Start: build completed")
+		d.outputMu.Lock()
+		d.buildDuration = time.Since(buildStart)
+		d.outputMu.Unlock()
+		log.InfoLog.Printf("DevServer.Start: build completed in %v", d.LastBuildDuration())
 	}
 
 	d.SetStatus(DevServerStarting)
-	log.InfoLog.Printf("DevServer.Start: status = Starting")
+	log.DebugLog.Printf("DevServer.Start: status = Starting")
+	d.outputMu.Lock()
+	d.readyAt = time.Now()
+	d.outputMu.Unlock()
 
 	if err := d.startDevServer(); err != nil {
 		log.ErrorLog.Printf("DevServer.Start: startDevServer failed: %v", err)
@@ -893,8 +2014,11 @@ func (d *DevServer) Start() error {
 	}
 
 	d.startedAt = time.Now()
-	log.InfoLog.Printf("DevServer.Start: startedAt set to %v", d.startedAt)
-	d.SetStatus(DevServerRunning)
+	log.DebugLog.Printf("DevServer.Start: startedAt set to %v", d.startedAt)
+	d.statusMu.Lock()
+	d.restore = false
+	d.status = DevServerRunning
+	d.statusMu.Unlock()
 	log.InfoLog.Printf("DevServer.Start: status = Running, dev server started successfully")
 
 	return nil
@@ -902,11 +2026,14 @@ func (d *DevServer) Start() error {
 
 // Stop stops the dev server
 func (d *DevServer) Stop() error {
+	d.statusMu.Lock()
+	d.restore = false
+	d.statusMu.Unlock()
+	defer d.removeExitStatus()
+
 	if d.session == nil {
 		d.SetStatus(DevServerStopped)
-		d.outputMu.Lock()
-		d.output = make([]string, 0)
-		d.outputMu.Unlock()
+		d.resetOutput()
 		return nil
 	}
 
@@ -919,16 +2046,121 @@ func (d *DevServer) Stop() error {
 
 	d.session = nil
 	d.SetStatus(DevServerStopped)
+	d.resetOutput()
+	return nil
+}
+
+// Suspend stops the dev server like Stop but remembers whether it was running, so ShouldRestore
+// reports it after a restart.
+func (d *DevServer) Suspend() error {
+	restore := d.ShouldRestore()
+	err := d.Stop()
+	d.statusMu.Lock()
+	d.restore = restore
+	d.statusMu.Unlock()
+	return err
+}
+
+// ShouldRestore returns true if the dev server is running, or was running when the app quit and
+// hasn't been started or stopped since.
+func (d *DevServer) ShouldRestore() bool {
+	d.statusMu.RLock()
+	defer d.statusMu.RUnlock()
+	return d.restore || d.status == DevServerRunning || d.status == DevServerStarting || d.status == DevServerBuilding
+}
+
+// Restore runs the dev server again after a restart. If its tmux session is still alive, e.g.
+// because the app quit without stopping it, it reattaches to the session instead of starting a new
+// one, and returns true.
+func (d *DevServer) Restore() (reattached bool, err error) {
+	d.migrateSession()
+	sessionName := tmux.TmuxPrefix + devServerSessionName(d.instance)
+	session := tmux.NewTmuxSession(sessionName, d.config.DevCommand)
+	if !session.DoesSessionExist() {
+		return false, d.Start()
+	}
+
+	d.startMu.Lock()
+	defer d.startMu.Unlock()
 	d.outputMu.Lock()
-	d.output = make([]string, 0)
+	d.markNewRunLocked()
 	d.outputMu.Unlock()
+	d.appendOutput(fmt.Sprintf("[%s] Reattached to the running dev server: %s", time.Now().Format("15:04:05"), d.config.DevCommand))
+
+	d.session = session
+	d.startedAt = time.Now()
+	d.statusMu.Lock()
+	d.restore = false
+	d.status = DevServerRunning
+	d.statusMu.Unlock()
+	log.InfoLog.Printf("DevServer.Restore: reattached to session %s", sessionName)
+	return true, nil
+}
+
+// workDir returns the directory the dev server runs in: the configured working directory resolved
+// against the worktree. It must exist and stay inside the worktree.
+func (d *DevServer) workDir() (string, error) {
+	if d.config.WorkingDir == "" {
+		return d.worktree, nil
+	}
+	if filepath.IsAbs(d.config.WorkingDir) {
+		return "", fmt.Errorf("dev server working directory %s must be relative to the worktree", d.config.WorkingDir)
+	}
+	dir := filepath.Join(d.worktree, d.config.WorkingDir)
+	if rel, err := filepath.Rel(d.worktree, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("dev server working directory %s is outside the worktree", d.config.WorkingDir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("dev server working directory %s: %w", d.config.WorkingDir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("dev server working directory %s is not a directory", d.config.WorkingDir)
+	}
+	return dir, nil
+}
+
+// Resize sets the size of the dev server's tmux session so tools that format their output to the
+// terminal width (progress bars, tables) fit the server pane. A running session is resized right away,
+// otherwise the size is used on the next start.
+func (d *DevServer) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid dev server size %dx%d", width, height)
+	}
+	d.sizeMu.Lock()
+	changed := d.width != width || d.height != height
+	d.width, d.height = width, height
+	d.sizeMu.Unlock()
+
+	if !changed || d.session == nil || !d.IsRunning() {
+		return nil
+	}
+	if err := d.session.Resize(width, height); err != nil {
+		return fmt.Errorf("failed to resize dev server session: %w", err)
+	}
 	return nil
 }
 
+// size returns the size to start the dev server's tmux session with: the server pane size if known,
+// otherwise the configured default.
+func (d *DevServer) size() (width, height int) {
+	d.sizeMu.Lock()
+	width, height = d.width, d.height
+	d.sizeMu.Unlock()
+	if width > 0 && height > 0 {
+		return width, height
+	}
+	return devServerWidth, devServerHeight
+}
+
 // runBuild runs the build command
 func (d *DevServer) runBuild() error {
-	cmd := exec.Command("sh", "-c", d.config.BuildCommand)
-	cmd.Dir = d.worktree
+	dir, err := d.workDir()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", withEnvExports(d.config.BuildCommand, d.config.Env))
+	cmd.Dir = dir
 	output, err := cmd.Output()
 	if err != nil {
 		d.appendOutput(string(output))
@@ -938,61 +2170,146 @@ func (d *DevServer) runBuild() error {
 	return nil
 }
 
+// withEnvExports prefixes command with exports of env, sorted by name. The variables are set in the
+// shell that runs command before it is expanded, so e.g. `next dev -p $PORT` sees PORT from env. A
+// `VAR=value command` prefix wouldn't work for that: the shell expands $PORT before assigning it.
+// Values are quoted, so they are used literally.
+func withEnvExports(command string, env map[string]string) string {
+	if len(env) == 0 {
+		return command
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	exports := make([]string, 0, len(names))
+	for _, name := range names {
+		exports = append(exports, name+"='"+strings.ReplaceAll(env[name], "'", `'\''`)+"'")
+	}
+	return "export " + strings.Join(exports, " ") + "; " + command
+}
+
+// withExitStatus makes command write its exit status to path when it exits, so it can be told apart
+// once its session is gone. The status isn't written if the session is killed.
+func withExitStatus(command, path string) string {
+	return command + "\nstatus=$?; echo $status > '" + strings.ReplaceAll(path, "'", `'\''`) + "'; exit $status"
+}
+
+// exitStatusPath returns the file the dev command writes its exit status to, see withExitStatus. It
+// is in the config directory rather than a shared temp directory, so nobody else can place a
+// symlink for the shell to write through, and it is named after the worktree too, since instances
+// of different repos can have the same title.
+func (d *DevServer) exitStatusPath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s.exit", devServerSessionName(d.instance), git.RepoIdentity(d.worktree)[:12])
+	return filepath.Join(configDir, "devservers", name), nil
+}
+
+// removeExitStatus removes the file written by withExitStatus, if any.
+func (d *DevServer) removeExitStatus() {
+	if path, err := d.exitStatusPath(); err == nil {
+		os.Remove(path)
+	}
+}
+
+// exitedQuickly returns true if the dev command exited successfully within devServerQuickExit of
+// being started.
+func (d *DevServer) exitedQuickly() bool {
+	path, err := d.exitStatusPath()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil || d.startedAt.IsZero() {
+		return false
+	}
+	status, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(status)) != "0" {
+		return false
+	}
+	// startedAt is set once the session has settled, so a command can exit before it.
+	return info.ModTime().Sub(d.startedAt) < devServerQuickExit
+}
+
+// migrateSession renames the server's session if it was created under a tmux prefix other than
+// the current one, so a server left running when the configured prefix changed isn't orphaned.
+func (d *DevServer) migrateSession() {
+	if d.sessionPrefix == "" || d.sessionPrefix == tmux.TmuxPrefix {
+		return
+	}
+	session := tmux.NewTmuxSession(tmux.TmuxPrefix+devServerSessionName(d.instance), d.config.DevCommand)
+	if err := session.MigrateFromPrefix(d.sessionPrefix); err != nil {
+		log.WarningLog.Printf("failed to migrate dev server session of %s from prefix %s: %v", d.instance, d.sessionPrefix, err)
+		return
+	}
+	d.sessionPrefix = tmux.TmuxPrefix
+}
+
 // startDevServer starts the dev server in a tmux session
 func (d *DevServer) startDevServer() error {
-	log.InfoLog.Printf("startDevServer: d.worktree = '%s'", d.worktree)
-	log.InfoLog.Printf("startDevServer: d.instance = '%s'", d.instance)
-	log.InfoLog.Printf("startDevServer: d.config.DevCommand = '%s'", d.config.DevCommand)
+	dir, err := d.workDir()
+	if err != nil {
+		return err
+	}
 
+	log.DebugLog.Printf("startDevServer: d.worktree = '%s'", d.worktree)
+	log.DebugLog.Printf("startDevServer: d.instance = '%s'", d.instance)
+	log.DebugLog.Printf("startDevServer: d.config.DevCommand = '%s'", d.config.DevCommand)
+
+	// A session left running under the previous prefix is replaced like one under the current.
+	d.migrateSession()
 	sessionName := devServerSessionName(d.instance)
 	fullSessionName := fmt.Sprintf("%s%s", tmux.TmuxPrefix, sessionName)
 
 	log.InfoLog.Printf("Starting dev server session: %s (sessionName=%s)", fullSessionName, sessionName)
-	log.InfoLog.Printf("Dev command: %s", d.config.DevCommand)
-	log.InfoLog.Printf("Worktree: %s", d.worktree)
+	log.DebugLog.Printf("Dev command: %s", d.config.DevCommand)
+	log.DebugLog.Printf("Worktree: %s", d.worktree)
 
 	if exec.Command("tmux", "has-session", "-t", fullSessionName).Run() == nil {
 		log.InfoLog.Printf("Killing existing session: %s", fullSessionName)
 		exec.Command("tmux", "kill-session", "-t", fullSessionName).Run()
 	}
 
-	// Build the dev command with optional environment variables
-	var devCmd string
-	if len(d.config.Env) > 0 {
-		envParts := make([]string, 0, len(d.config.Env))
-		for k, v := range d.config.Env {
-			envParts = append(envParts, fmt.Sprintf("%s=%s", k, v))
-		}
-		envPrefix := strings.Join(envParts, " ")
-		devCmd = fmt.Sprintf("%s %s", envPrefix, d.config.DevCommand)
-	} else {
-		devCmd = d.config.DevCommand
+	exitStatusPath, err := d.exitStatusPath()
+	if err != nil {
+		return fmt.Errorf("failed to get the exit status path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(exitStatusPath), 0700); err != nil {
+		return fmt.Errorf("failed to create the exit status directory: %w", err)
 	}
+	os.Remove(exitStatusPath)
+	devCmd := withExitStatus(withEnvExports(d.config.DevCommand, d.config.Env), exitStatusPath)
 
-	log.InfoLog.Printf("Full command: %s (in dir: %s)", devCmd, d.worktree)
+	log.InfoLog.Printf("Full command: %s (in dir: %s)", devCmd, dir)
 
 	// Use -c to set working directory instead of cd && pattern
-	tmuxCmd := exec.Command("tmux", "new-session", "-d", "-s", fullSessionName, "-c", d.worktree, "-x", "200", "-y", "50", "sh", "-c", devCmd)
+	width, height := d.size()
+	tmuxCmd := exec.Command("tmux", "new-session", "-d", "-s", fullSessionName, "-c", dir,
+		"-x", strconv.Itoa(width), "-y", strconv.Itoa(height), "sh", "-c", devCmd)
 
-	log.InfoLog.Printf("Executing tmux command: %v", tmuxCmd.Args)
+	log.DebugLog.Printf("Executing tmux command: %v", tmuxCmd.Args)
 	ptmx, err := tmux.MakePtyFactory().Start(tmuxCmd)
 	if err != nil {
 		log.ErrorLog.Printf("Failed to start tmux session: %v", err)
 		return fmt.Errorf("failed to start tmux session: %w", err)
 	}
-	log.InfoLog.Printf("Tmux session command started successfully")
+	log.DebugLog.Printf("Tmux session command started successfully")
 
 	// Create TmuxSession object first so we can use DoesSessionExist
 	d.session = tmux.NewTmuxSession(fullSessionName, d.config.DevCommand)
 
 	// Poll for session existence with exponential backoff (matching TmuxSession.Start pattern)
-	log.InfoLog.Printf("Waiting for tmux session to be created...")
+	log.DebugLog.Printf("Waiting for tmux session to be created...")
 	timeout := time.After(2 * time.Second)
 	sleepDuration := 5 * time.Millisecond
 	attempt := 0
 	for !d.session.DoesSessionExist() {
 		attempt++
-		log.InfoLog.Printf("Polling session existence, attempt %d, sleepDuration=%v", attempt, sleepDuration)
+		log.DebugLog.Printf("Polling session existence, attempt %d, sleepDuration=%v", attempt, sleepDuration)
 		select {
 		case <-timeout:
 			ptmx.Close()
@@ -1007,11 +2324,11 @@ func (d *DevServer) startDevServer() error {
 	}
 	ptmx.Close()
 
-	log.InfoLog.Printf("Waiting 500ms for session to stabilize...")
+	log.DebugLog.Printf("Waiting 500ms for session to stabilize...")
 	time.Sleep(500 * time.Millisecond)
 
 	finalExists := d.session.DoesSessionExist()
-	log.InfoLog.Printf("Session exists check after PTY close + 500ms delay: %v (after %d attempts)", finalExists, attempt)
+	log.DebugLog.Printf("Session exists check after PTY close + 500ms delay: %v (after %d attempts)", finalExists, attempt)
 
 	if !finalExists {
 		log.WarningLog.Printf("Session disappeared during stabilization period! The dev command may have failed to start.")
@@ -1019,7 +2336,7 @@ func (d *DevServer) startDevServer() error {
 		log.WarningLog.Printf("Dev server will continue, but will be marked as crashed if it doesn't stay running")
 	}
 
-	log.InfoLog.Printf("Marking server as running")
+	log.DebugLog.Printf("Marking server as running")
 	d.appendOutput(fmt.Sprintf("[%s] Starting dev server: %s", time.Now().Format("15:04:05"), d.config.DevCommand))
 
 	return nil
@@ -1029,8 +2346,5 @@ var devCmd string
 
 // devServerSessionName returns just the session name for tmux (without the prefix)
 func devServerSessionName(instanceName string) string {
-	whitespaceRegex := regexp.MustCompile(`\s+`)
-	name := whitespaceRegex.ReplaceAllString(instanceName, "")
-	name = strings.ReplaceAll(name, ".", "_")
-	return fmt.Sprintf("%s_dev", name)
+	return tmux.DevServerSessionName(instanceName)
 }
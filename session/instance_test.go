@@ -1,7 +1,18 @@
 package session
 
 import (
+	"claude-squad/config"
+	"claude-squad/log"
+	"claude-squad/session/git"
+	"claude-squad/session/tmux"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,7 +31,7 @@ func TestInstanceKill_WhenNotStarted_CleansUpResources(t *testing.T) {
 	t.Run("kill does not panic when all resources are nil", func(t *testing.T) {
 		instance := createTestInstance()
 		instance.started = false
-		instance.tmuxSession = nil
+		instance.backend = nil
 		instance.gitWorktree = nil
 		instance.DevServer = nil
 
@@ -32,7 +43,7 @@ func TestInstanceKill_WhenNotStarted_CleansUpResources(t *testing.T) {
 	t.Run("kill returns nil when nothing to clean up", func(t *testing.T) {
 		instance := createTestInstance()
 		instance.started = false
-		instance.tmuxSession = nil
+		instance.backend = nil
 		instance.gitWorktree = nil
 		instance.DevServer = nil
 
@@ -106,7 +117,7 @@ func TestInstanceKill_StartedFlagBehavior(t *testing.T) {
 	t.Run("instance can be killed multiple times without error", func(t *testing.T) {
 		instance := createTestInstance()
 		instance.started = false
-		instance.tmuxSession = nil
+		instance.backend = nil
 		instance.gitWorktree = nil
 		instance.DevServer = nil
 
@@ -119,3 +130,906 @@ func TestInstanceKill_StartedFlagBehavior(t *testing.T) {
 		assert.NoError(t, err, "Kill() should be idempotent")
 	})
 }
+
+func TestInstanceAutoYes_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		autoYes bool
+	}{
+		{name: "enabled", autoYes: true},
+		{name: "disabled", autoYes: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := createTestInstance()
+			instance.Status = Paused
+			instance.AutoYes = tt.autoYes
+
+			restored, err := FromInstanceData(instance.ToInstanceData())
+			require.NoError(t, err)
+			assert.Equal(t, tt.autoYes, restored.AutoYes)
+		})
+	}
+}
+
+func TestInstancePrompt_RoundTrip(t *testing.T) {
+	instance := createTestInstance()
+	instance.Status = Paused
+	instance.Prompt = "fix the flaky test"
+
+	// A prompt that wasn't sent before the app quit is sent after the next launch.
+	restored, err := FromInstanceData(instance.ToInstanceData())
+	require.NoError(t, err)
+	assert.Equal(t, "fix the flaky test", restored.Prompt)
+}
+
+func TestInstanceNoWorktree_RoundTrip(t *testing.T) {
+	instance, err := NewInstance(InstanceOptions{Title: "in-place", Path: t.TempDir(), Program: "claude", NoWorktree: true})
+	require.NoError(t, err)
+	assert.True(t, instance.NoWorktree())
+	assert.True(t, instance.Options().NoWorktree)
+
+	instance.Status = Paused
+	instance.gitWorktree = git.NewGitWorktreeFromStorage(instance.Path, instance.Path, "in-place", "main", "")
+	instance.gitWorktree.SetInPlace()
+	restored, err := FromInstanceData(instance.ToInstanceData())
+	require.NoError(t, err)
+	assert.True(t, restored.NoWorktree())
+	worktree, err := restored.GetGitWorktree()
+	require.NoError(t, err)
+	assert.True(t, worktree.InPlace())
+}
+
+func TestInstanceBaseBranch_RoundTrip(t *testing.T) {
+	instance := createTestInstance()
+	instance.started = true
+	instance.Status = Paused
+	instance.gitWorktree = git.NewGitWorktreeFromStorage(instance.Path, t.TempDir(), "test", "test", "abc")
+	instance.gitWorktree.SetBaseBranch("release")
+
+	restored, err := FromInstanceData(instance.ToInstanceData())
+	require.NoError(t, err)
+	worktree, err := restored.GetGitWorktree()
+	require.NoError(t, err)
+	assert.Equal(t, "release", worktree.BaseBranch(), "rebases use the base branch after a restart")
+}
+
+func TestInstanceArchive(t *testing.T) {
+	instance := createTestInstance()
+	assert.Error(t, instance.Archive(), "instances that haven't started can't be paused")
+	assert.False(t, instance.Archived())
+
+	instance.started = true
+	instance.Status = Paused
+	require.NoError(t, instance.Archive())
+	assert.True(t, instance.Archived())
+	assert.Error(t, instance.Archive())
+	assert.ErrorContains(t, instance.Resume(), "archived")
+
+	restored, err := FromInstanceData(instance.ToInstanceData())
+	require.NoError(t, err)
+	assert.True(t, restored.Archived())
+	assert.Equal(t, Paused, restored.Status)
+
+	require.NoError(t, restored.Unarchive())
+	assert.False(t, restored.Archived())
+	assert.Equal(t, Paused, restored.Status, "unarchived instances stay paused")
+	assert.Error(t, restored.Unarchive())
+}
+
+func TestInstanceBeginResume(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	instance := createTestInstance()
+	instance.started = true
+	instance.Status = Paused
+	// Not a git repository, so the resume fails once it runs.
+	instance.gitWorktree = git.NewGitWorktreeFromStorage(t.TempDir(), t.TempDir(), "test-instance", "test-instance", "")
+
+	require.NoError(t, instance.BeginResume())
+	assert.Equal(t, Loading, instance.Status)
+	assert.True(t, instance.Resuming())
+	assert.True(t, instance.Paused(), "a resuming instance counts as paused until its session is back")
+	assert.Equal(t, Paused, instance.ToInstanceData().Status)
+	assert.ErrorContains(t, instance.BeginResume(), "already resuming")
+
+	assert.ErrorContains(t, instance.Resume(), "already resuming")
+	assert.ErrorContains(t, instance.RestartSession(), "paused")
+	assert.ErrorContains(t, instance.Archive(), "resuming")
+
+	require.Error(t, instance.FinishResume(instance.ResumeSession()))
+	assert.False(t, instance.Resuming())
+	assert.Equal(t, Paused, instance.Status, "a failed resume pauses the instance again")
+}
+
+func TestInstanceColor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cycles int
+	}{
+		{name: "derived from title", cycles: 0},
+		{name: "cycled once", cycles: 1},
+		{name: "cycled through the whole palette", cycles: len(InstancePalette)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := createTestInstance()
+			instance.Status = Paused
+			derived := instance.Color()
+			assert.Contains(t, InstancePalette, derived)
+			assert.Equal(t, derived, createTestInstance().Color(), "color should be stable for a title")
+
+			for i := 0; i < tt.cycles; i++ {
+				instance.CycleColor()
+			}
+			idx := slices.Index(InstancePalette, derived)
+			expected := InstancePalette[(idx+tt.cycles)%len(InstancePalette)]
+			assert.Equal(t, expected, instance.Color())
+
+			restored, err := FromInstanceData(instance.ToInstanceData())
+			require.NoError(t, err)
+			assert.Equal(t, expected, restored.Color())
+		})
+	}
+}
+
+func TestInstanceCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		program   string
+		extraArgs string
+		expected  string
+	}{
+		{name: "program only", program: "claude", expected: "claude"},
+		{name: "appends extra args", program: "claude", extraArgs: " --model opus ", expected: "claude --model opus"},
+		{name: "keeps program args", program: "aider --no-git", extraArgs: "--model sonnet", expected: "aider --no-git --model sonnet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := NewInstance(InstanceOptions{Title: "test", Path: t.TempDir(), Program: tt.program, ExtraArgs: tt.extraArgs})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, instance.Command())
+
+			instance.Status = Paused
+			restored, err := FromInstanceData(instance.ToInstanceData())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, restored.Command())
+		})
+	}
+}
+
+func TestInstanceRestartSession_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		started bool
+		status  Status
+	}{
+		{name: "not started", started: false, status: Ready},
+		{name: "paused", started: true, status: Paused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := NewInstance(InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude"})
+			require.NoError(t, err)
+			instance.started = tt.started
+			instance.Status = tt.status
+
+			assert.Error(t, instance.RestartSession())
+			assert.Equal(t, tt.status, instance.Status)
+		})
+	}
+}
+
+// restartBackend is a Backend that records the calls made on the sessions of a restart.
+type restartBackend struct {
+	Backend
+	name  string
+	calls *[]string
+}
+
+func (b *restartBackend) Start(workDir string) error {
+	*b.calls = append(*b.calls, "start "+b.name)
+	return nil
+}
+
+func (b *restartBackend) Close() error {
+	*b.calls = append(*b.calls, "close "+b.name)
+	return nil
+}
+
+func (b *restartBackend) Rename(name string) error {
+	*b.calls = append(*b.calls, "rename "+b.name+" "+name)
+	b.name = name
+	return nil
+}
+
+func TestInstanceRestartSession_StartsBeforeClosing(t *testing.T) {
+	var calls []string
+	defer func(newBackend func(name, program string) Backend) { NewBackend = newBackend }(NewBackend)
+	NewBackend = func(name, program string) Backend {
+		return &restartBackend{name: name, calls: &calls}
+	}
+
+	instance := &Instance{
+		Title:       "test",
+		Path:        t.TempDir(),
+		Status:      Ready,
+		started:     true,
+		backend:     &restartBackend{name: "test", calls: &calls},
+		gitWorktree: git.NewGitWorktreeFromStorage(t.TempDir(), t.TempDir(), "test", "test-branch", ""),
+	}
+
+	require.NoError(t, instance.RestartSession())
+	assert.Equal(t, []string{"start test-restarting", "close test", "rename test-restarting test"}, calls)
+	assert.Equal(t, Running, instance.Status)
+}
+
+// migratingBackend is a Backend that records the prefixes its session is migrated from.
+type migratingBackend struct {
+	Backend
+	migratedFrom []string
+	migrateErr   error
+}
+
+func (b *migratingBackend) MigrateLegacyName() error { return nil }
+
+func (b *migratingBackend) MigrateFromPrefix(oldPrefix string) error {
+	b.migratedFrom = append(b.migratedFrom, oldPrefix)
+	return b.migrateErr
+}
+
+func TestMigrateSessionName(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	defer tmux.SetTmuxPrefix(config.DefaultTmuxPrefix)
+	tmux.SetTmuxPrefix("second_")
+
+	tests := []struct {
+		name           string
+		recorded       string
+		migrateErr     error
+		expectedFrom   []string
+		expectedPrefix string
+	}{
+		{name: "migrates from the recorded prefix", recorded: "first_", expectedFrom: []string{"first_"}, expectedPrefix: "second_"},
+		{name: "keeps the recorded prefix when migrating fails", recorded: "first_", migrateErr: fmt.Errorf("rename failed"),
+			expectedFrom: []string{"first_"}, expectedPrefix: "first_"},
+		{name: "skips sessions under the current prefix", recorded: "second_", expectedPrefix: "second_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &migratingBackend{migrateErr: tt.migrateErr}
+			instance := &Instance{Title: "test", backend: backend, sessionPrefix: tt.recorded}
+			instance.migrateSessionName()
+			assert.Equal(t, tt.expectedFrom, backend.migratedFrom)
+			assert.Equal(t, tt.expectedPrefix, instance.ToInstanceData().TmuxPrefix)
+		})
+	}
+
+	// Data saved before the prefix was recorded was created under the default prefix.
+	restored, err := FromInstanceData(InstanceData{Title: "test", Status: Paused})
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultTmuxPrefix, restored.sessionPrefix)
+}
+
+// missingSessionBackend is a Backend whose session doesn't exist, as after a reboot. It records the
+// directory it is started in.
+type missingSessionBackend struct {
+	Backend
+	startErr error
+	startDir string
+}
+
+func (b *missingSessionBackend) DoesSessionExist() bool { return false }
+
+func (b *missingSessionBackend) Restore() error {
+	return fmt.Errorf("session doesn't exist")
+}
+
+func (b *missingSessionBackend) Start(workDir string) error {
+	b.startDir = workDir
+	return b.startErr
+}
+
+func TestInstanceStart_RecreatesMissingSession(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	tests := []struct {
+		name     string
+		startErr error
+		status   Status
+	}{
+		{name: "recreated in the worktree", status: Running},
+		{name: "paused when it can't be recreated", startErr: fmt.Errorf("tmux not found"), status: Paused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			worktree := t.TempDir()
+			backend := &missingSessionBackend{startErr: tt.startErr}
+			instance := &Instance{
+				Title:       "test-instance",
+				Path:        t.TempDir(),
+				Status:      Ready,
+				backend:     backend,
+				gitWorktree: git.NewGitWorktreeFromStorage(t.TempDir(), worktree, "test-instance", "test-branch", ""),
+			}
+
+			require.NoError(t, instance.Start(false))
+			assert.True(t, instance.Started())
+			assert.Equal(t, worktree, backend.startDir)
+			assert.Equal(t, tt.status, instance.Status)
+			// The worktree is kept either way.
+			assert.DirExists(t, worktree)
+		})
+	}
+}
+
+func TestInstanceSendInitialPrompt(t *testing.T) {
+	tests := []struct {
+		name      string
+		prompt    string
+		expectErr bool
+	}{
+		{name: "no-op without a prompt", prompt: ""},
+		{name: "attempted only once", prompt: "run the tests", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := NewInstance(InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude", Prompt: tt.prompt})
+			require.NoError(t, err)
+			assert.Equal(t, tt.prompt, instance.Prompt)
+
+			// The instance isn't started, so sending fails, but the prompt isn't retried.
+			err = instance.SendInitialPrompt()
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Empty(t, instance.Prompt)
+			assert.NoError(t, instance.SendInitialPrompt())
+		})
+	}
+}
+
+func TestMatchAutoYesDenyPattern(t *testing.T) {
+	defer SetAutoYesDenyPatterns(nil)
+
+	tests := []struct {
+		name     string
+		patterns []string
+		content  string
+		expected string
+	}{
+		{
+			name:     "no patterns",
+			patterns: nil,
+			content:  "Run rm -rf build? (y/n)",
+			expected: "",
+		},
+		{
+			name:     "matches case-insensitively",
+			patterns: []string{"Force Push"},
+			content:  "Do you want to FORCE PUSH to main?",
+			expected: "force push",
+		},
+		{
+			name:     "returns the first matching pattern",
+			patterns: []string{"delete", "rm -rf"},
+			content:  "Run rm -rf build? (y/n)",
+			expected: "rm -rf",
+		},
+		{
+			name:     "ignores blank patterns",
+			patterns: []string{"  ", ""},
+			content:  "Proceed? (y/n)",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetAutoYesDenyPatterns(tt.patterns)
+			assert.Equal(t, tt.expected, matchAutoYesDenyPattern(tt.content))
+		})
+	}
+}
+
+func TestDevServerAppendOutput_Dedupe(t *testing.T) {
+	tests := []struct {
+		name     string
+		dedupe   bool
+		lines    []string
+		expected []string
+	}{
+		{
+			name:     "keeps duplicates when disabled",
+			dedupe:   false,
+			lines:    []string{"compiling", "compiling", "done"},
+			expected: []string{"compiling", "compiling", "done"},
+		},
+		{
+			name:     "collapses consecutive duplicates",
+			dedupe:   true,
+			lines:    []string{"compiling", "compiling", "compiling", "done"},
+			expected: []string{"compiling ×3", "done"},
+		},
+		{
+			name:     "resets the count when a different line arrives",
+			dedupe:   true,
+			lines:    []string{"a", "a", "b", "a", "a"},
+			expected: []string{"a ×2", "b", "a ×2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devServer := &DevServer{dedupeOutput: tt.dedupe}
+			for _, line := range tt.lines {
+				devServer.appendOutput(line)
+			}
+			assert.Equal(t, tt.expected, devServer.output)
+		})
+	}
+}
+
+func TestDevServerAppendOutput_MaxLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxLines int
+		appended int
+		expected int
+	}{
+		{name: "defaults when unset", maxLines: 0, appended: 150, expected: config.DefaultDevServerOutputLines},
+		{name: "uses the configured size", maxLines: 500, appended: 600, expected: 500},
+		{name: "keeps everything below the size", maxLines: 500, appended: 20, expected: 20},
+		{name: "caps oversized buffers", maxLines: 1000000, appended: config.MaxDevServerOutputLines + 10, expected: config.MaxDevServerOutputLines},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devServer := &DevServer{config: DevServerConfig{MaxOutputLines: tt.maxLines}}
+			for i := 0; i < tt.appended; i++ {
+				devServer.appendOutput(fmt.Sprintf("line %d", i))
+			}
+			require.Len(t, devServer.output, tt.expected)
+			assert.Equal(t, fmt.Sprintf("line %d", tt.appended-1), devServer.output[len(devServer.output)-1])
+		})
+	}
+}
+
+func TestDevServerWorkDir(t *testing.T) {
+	worktree := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(worktree, "packages", "web"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(worktree, "README.md"), []byte("readme"), 0644))
+
+	tests := []struct {
+		name       string
+		workingDir string
+		expected   string
+		expectErr  bool
+	}{
+		{name: "defaults to the worktree root", workingDir: "", expected: worktree},
+		{name: "resolves a subdirectory", workingDir: "packages/web", expected: filepath.Join(worktree, "packages", "web")},
+		{name: "rejects a missing directory", workingDir: "packages/api", expectErr: true},
+		{name: "rejects a file", workingDir: "README.md", expectErr: true},
+		{name: "rejects paths outside the worktree", workingDir: "../other", expectErr: true},
+		{name: "rejects absolute paths", workingDir: "/tmp", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			devServer := &DevServer{
+				config:   DevServerConfig{DevCommand: "npm run dev", WorkingDir: tt.workingDir},
+				worktree: worktree,
+			}
+			dir, err := devServer.workDir()
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, dir)
+		})
+	}
+}
+
+func TestWithEnvExports(t *testing.T) {
+	assert.Equal(t, "next dev", withEnvExports("next dev", nil))
+
+	env := map[string]string{"PORT": "3000", "GREETING": "it's $HOME"}
+	command := withEnvExports(`echo "$PORT ${GREETING}"`, env)
+	assert.Equal(t, `export GREETING='it'\''s $HOME' PORT='3000'; echo "$PORT ${GREETING}"`, command)
+
+	output, err := exec.Command("sh", "-c", command).Output()
+	require.NoError(t, err)
+	assert.Equal(t, "3000 it's $HOME\n", string(output))
+}
+
+func TestDevServerBuildSeesEnv(t *testing.T) {
+	devServer := &DevServer{
+		config: DevServerConfig{
+			BuildCommand:   "echo building on $PORT",
+			Env:            map[string]string{"PORT": "4000"},
+			MaxOutputLines: 10,
+		},
+		worktree: t.TempDir(),
+	}
+	require.NoError(t, devServer.runBuild())
+	assert.Contains(t, devServer.Output(), "building on 4000")
+}
+
+func TestDevServerResize(t *testing.T) {
+	tests := []struct {
+		name      string
+		width     int
+		height    int
+		expectErr bool
+	}{
+		{name: "stores the size for the next start", width: 120, height: 40},
+		{name: "rejects an empty size", width: 0, height: 40, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A stopped dev server has no session to resize, so only the size is recorded.
+			devServer := &DevServer{status: DevServerStopped}
+			err := devServer.Resize(tt.width, tt.height)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			width, height := devServer.size()
+			assert.Equal(t, tt.width, width)
+			assert.Equal(t, tt.height, height)
+		})
+	}
+
+	t.Run("uses the set default until the pane size is known", func(t *testing.T) {
+		SetDevServerSize(90, 30)
+		defer SetDevServerSize(config.DefaultDevServerWidth, config.DefaultDevServerHeight)
+		width, height := (&DevServer{}).size()
+		assert.Equal(t, 90, width)
+		assert.Equal(t, 30, height)
+	})
+}
+
+func TestDetectURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "vite", line: "  ➜  Local:   http://localhost:5173/", expected: "http://localhost:5173/"},
+		{name: "next", line: "- Local:        http://localhost:3000", expected: "http://localhost:3000"},
+		{name: "trailing punctuation", line: "Listening on http://127.0.0.1:8080.", expected: "http://127.0.0.1:8080"},
+		{name: "wildcard host", line: "Serving at http://0.0.0.0:8000/app", expected: "http://localhost:8000/app"},
+		{name: "no url", line: "compiled successfully", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, detectURL(tt.line))
+		})
+	}
+
+	t.Run("keeps the first url of a run", func(t *testing.T) {
+		devServer := &DevServer{}
+		devServer.appendOutput("Local:   http://localhost:5173/")
+		devServer.appendOutput("Network: http://192.168.1.5:5173/")
+		assert.Equal(t, "http://localhost:5173/", devServer.URL())
+
+		devServer.markNewRunLocked()
+		assert.Empty(t, devServer.URL())
+	})
+}
+
+func TestDevServerRunMarkers(t *testing.T) {
+	devServer := &DevServer{dedupeOutput: true}
+	devServer.lastCapture = []string{"listening on :3000"}
+
+	devServer.markNewRunLocked()
+	devServer.appendOutput("listening on :3000")
+	devServer.markNewRunLocked()
+	devServer.appendOutput("listening on :3000")
+
+	require.Len(t, devServer.output, 4)
+	assert.True(t, IsRunMarker(devServer.output[0]))
+	assert.Contains(t, devServer.output[0], "run #1")
+	assert.Equal(t, "listening on :3000", devServer.output[1])
+	assert.True(t, IsRunMarker(devServer.output[2]))
+	assert.Contains(t, devServer.output[2], "run #2")
+	// The same line in the next run isn't collapsed into the previous run's output.
+	assert.Equal(t, "listening on :3000", devServer.output[3])
+	assert.Nil(t, devServer.lastCapture)
+	assert.False(t, IsRunMarker("listening on :3000"))
+}
+
+func TestInstanceDevServerLog(t *testing.T) {
+	instance := &Instance{Title: "web"}
+	_, err := instance.DevServerLog()
+	assert.Error(t, err)
+
+	devServer := &DevServer{
+		config:     DevServerConfig{BuildCommand: "npm run build", DevCommand: "npm run dev"},
+		status:     DevServerCrashed,
+		crashCount: 2,
+	}
+	devServer.appendOutput("error: build failed")
+	instance.DevServer = devServer
+
+	text, err := instance.DevServerLog()
+	require.NoError(t, err)
+	assert.Contains(t, text, "Dev server log for 'web'")
+	assert.Contains(t, text, "Build command: npm run build")
+	assert.Contains(t, text, "Command: npm run dev")
+	assert.Contains(t, text, "Status: crashed")
+	assert.Contains(t, text, "Crash count: 2")
+	assert.True(t, strings.HasSuffix(text, "\n\nerror: build failed\n"))
+}
+
+func TestNewCaptureOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		prev     []string
+		cur      []string
+		expected int
+	}{
+		{
+			name:     "first capture is all new",
+			prev:     nil,
+			cur:      []string{"a", "b"},
+			expected: 0,
+		},
+		{
+			name:     "unchanged capture has nothing new",
+			prev:     []string{"a", "b"},
+			cur:      []string{"a", "b"},
+			expected: 2,
+		},
+		{
+			name:     "lines added below",
+			prev:     []string{"a", "b"},
+			cur:      []string{"a", "b", "c", "d"},
+			expected: 2,
+		},
+		{
+			name:     "pane scrolled",
+			prev:     []string{"a", "b", "c"},
+			cur:      []string{"b", "c", "d"},
+			expected: 2,
+		},
+		{
+			name:     "last line rewritten in place",
+			prev:     []string{"a", "b", "50%"},
+			cur:      []string{"a", "b", "60%"},
+			expected: 2,
+		},
+		{
+			name:     "pane cleared",
+			prev:     []string{"a", "b"},
+			cur:      []string{"x", "y"},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, newCaptureOffset(tt.prev, tt.cur))
+		})
+	}
+}
+
+func TestDevServerTimings(t *testing.T) {
+	devServer := &DevServer{config: DevServerConfig{MaxOutputLines: 10}}
+	devServer.appendOutput("ready on http://localhost:3000")
+	assert.Zero(t, devServer.LastStartDuration(), "the URL of a run that isn't starting isn't timed")
+
+	devServer.outputMu.Lock()
+	devServer.markNewRunLocked()
+	devServer.buildDuration = 4200 * time.Millisecond
+	devServer.readyAt = time.Now().Add(-time.Second)
+	devServer.outputMu.Unlock()
+	devServer.appendOutput("compiling...")
+	assert.Zero(t, devServer.LastStartDuration())
+	devServer.appendOutput("ready on http://localhost:3001")
+	ready := devServer.LastStartDuration()
+	assert.GreaterOrEqual(t, ready, time.Second)
+	devServer.appendOutput("also on http://192.168.1.2:3001")
+	assert.Equal(t, ready, devServer.LastStartDuration(), "only the first URL counts")
+	assert.Equal(t, 4200*time.Millisecond, devServer.LastBuildDuration())
+
+	devServer.resetOutput()
+	assert.Zero(t, devServer.LastBuildDuration())
+	assert.Zero(t, devServer.LastStartDuration())
+}
+
+func TestDevServerRestoreState(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	load := func(server DevServerData) *DevServer {
+		t.Helper()
+		instance, err := FromInstanceData(InstanceData{Title: "test", Path: t.TempDir(), Status: Paused, Program: "claude", DevServer: &server})
+		require.NoError(t, err)
+		return instance.DevServer.(*DevServer)
+	}
+	config := DevServerConfig{DevCommand: "npm run dev"}
+
+	// A dev server saved while running has no session after loading, so it is stopped until restored.
+	devServer := load(DevServerData{Config: config, Status: DevServerRunning})
+	assert.Equal(t, DevServerStopped, devServer.Status())
+	assert.True(t, devServer.ShouldRestore())
+	assert.False(t, load(DevServerData{Config: config, Status: DevServerCrashed}).ShouldRestore())
+
+	// Suspending a running server keeps the intent, stopping it drops it.
+	devServer = load(DevServerData{Config: config})
+	devServer.SetStatus(DevServerRunning)
+	require.NoError(t, devServer.Suspend())
+	assert.Equal(t, DevServerStopped, devServer.Status())
+	assert.True(t, devServer.ShouldRestore())
+	instance := &Instance{Title: "test", DevServer: devServer}
+	assert.True(t, instance.ToInstanceData().DevServer.Restore)
+	require.NoError(t, devServer.Stop())
+	assert.False(t, devServer.ShouldRestore())
+	assert.False(t, instance.ToInstanceData().DevServer.Restore)
+}
+
+// startupRecordingBackend is a session backend that records the startup commands it runs.
+type startupRecordingBackend struct {
+	Backend
+	commands []string
+}
+
+func (b *startupRecordingBackend) RunStartupCommands(commands []string) error {
+	b.commands = append(b.commands, commands...)
+	return nil
+}
+
+func TestInstanceTmuxStartupCommands(t *testing.T) {
+	commands := []string{"split-window -h", "send-keys 'git status' Enter"}
+	instance, err := NewInstance(InstanceOptions{Title: "test", Path: t.TempDir(), Program: "claude", TmuxStartupCommands: commands})
+	require.NoError(t, err)
+	assert.Equal(t, commands, instance.Options().TmuxStartupCommands, "retries run them too")
+
+	backend := &startupRecordingBackend{}
+	instance.backend = backend
+	instance.runTmuxStartupCommands()
+	assert.Equal(t, commands, backend.commands)
+}
+
+func TestDiffStatsStaleness(t *testing.T) {
+	run := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+	repo := t.TempDir()
+	run(repo, "init", "-b", "main")
+	run(repo, "commit", "--allow-empty", "-m", "base")
+	base := run(repo, "rev-parse", "HEAD")
+	worktreePath := filepath.Join(t.TempDir(), "wt")
+	run(repo, "worktree", "add", "-b", "feature", worktreePath)
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "a.txt"), []byte("a\n"), 0644))
+
+	SetDiffStaleAfter(10 * time.Second)
+	defer SetDiffStaleAfter(0)
+	instance := &Instance{
+		Title:       "stale",
+		Status:      Ready,
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage(repo, worktreePath, "stale", "feature", base),
+	}
+	require.NoError(t, instance.UpdateDiffStats())
+	_, stale := instance.DiffStatsAge()
+	assert.False(t, stale)
+	assert.Equal(t, 1, instance.GetDiffStats().Added)
+
+	// Skipping the computation because the worktree looks unchanged doesn't make old stats fresh.
+	instance.diffStats.ComputedAt = time.Now().Add(-time.Minute)
+	age, stale := instance.DiffStatsAge()
+	assert.True(t, stale)
+	assert.GreaterOrEqual(t, age, time.Minute)
+	require.NoError(t, instance.UpdateDiffStats())
+	_, stale = instance.DiffStatsAge()
+	assert.True(t, stale)
+
+	// A refresh recomputes the stats even if the change check says nothing changed.
+	instance.diffStats.Added = 42
+	require.NoError(t, instance.RefreshDiffStats())
+	assert.Equal(t, 1, instance.GetDiffStats().Added)
+	_, stale = instance.DiffStatsAge()
+	assert.False(t, stale)
+
+	// The timestamp is saved, and the stats of a paused instance are always stale.
+	loaded, err := FromInstanceData(InstanceData{Title: "stale", Path: repo, Status: Paused, Program: "claude",
+		DiffStats: instance.ToInstanceData().DiffStats})
+	require.NoError(t, err)
+	assert.WithinDuration(t, instance.GetDiffStats().ComputedAt, loaded.GetDiffStats().ComputedAt, 0)
+	_, stale = loaded.DiffStatsAge()
+	assert.True(t, stale)
+	assert.ErrorContains(t, loaded.RefreshDiffStats(), "paused")
+}
+
+func TestDevServerQuickExit(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	t.Setenv("HOME", t.TempDir())
+
+	// A session that doesn't exist, like the one of a dev command that exited.
+	newServer := func(t *testing.T, status string) *DevServer {
+		t.Helper()
+		devServer := NewDevServer(DevServerConfig{DevCommand: "npm run build"}, t.TempDir(),
+			fmt.Sprintf("quick-exit-%d", time.Now().UnixNano()))
+		devServer.session = tmux.NewTmuxSession(tmux.TmuxPrefix+devServerSessionName(devServer.instance), "npm run build")
+		devServer.SetStatus(DevServerRunning)
+		devServer.startedAt = time.Now().Add(-devServerGracePeriod - time.Second)
+		path, err := devServer.exitStatusPath()
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, os.WriteFile(path, []byte(status+"\n"), 0644))
+		exitedAt := devServer.startedAt.Add(time.Second)
+		require.NoError(t, os.Chtimes(path, exitedAt, exitedAt))
+		return devServer
+	}
+
+	devServer := newServer(t, "0")
+	devServer.CheckHealth()
+	assert.Equal(t, DevServerStopped, devServer.Status(), "a successful quick exit isn't a crash")
+	assert.Zero(t, devServer.CrashCount())
+	assert.Contains(t, devServer.Output(), "did you mean to run a long-lived server?")
+
+	devServer = newServer(t, "1")
+	devServer.CheckHealth()
+	assert.Equal(t, DevServerCrashed, devServer.Status())
+	assert.Equal(t, 1, devServer.CrashCount())
+
+	// Stopping the dev server removes the file.
+	path, err := devServer.exitStatusPath()
+	require.NoError(t, err)
+	devServer.session = nil
+	require.NoError(t, devServer.Stop())
+	assert.NoFileExists(t, path)
+
+	// The status is written once the command exits.
+	path = filepath.Join(t.TempDir(), "exit")
+	output, err := exec.Command("sh", "-c", withExitStatus("true", path)).CombinedOutput()
+	require.NoError(t, err, string(output))
+	status, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "0\n", string(status))
+	err = exec.Command("sh", "-c", withExitStatus("exit 3", path)).Run()
+	assert.Error(t, err, "the exit status of the command is kept")
+}
+
+func TestSetBranchNameRejectsExistingBranch(t *testing.T) {
+	repo := t.TempDir()
+	for _, args := range [][]string{{"init", "-b", "main"}, {"commit", "--allow-empty", "-m", "base"}, {"branch", "mine"}} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	instance, err := NewInstance(InstanceOptions{Title: "branch", Path: repo, Program: "claude"})
+	require.NoError(t, err)
+	assert.ErrorContains(t, instance.SetBranchName("mine"), "already exists")
+	assert.Empty(t, instance.BranchName())
+	require.NoError(t, instance.SetBranchName("new/branch"))
+	assert.Equal(t, "new/branch", instance.BranchName())
+}
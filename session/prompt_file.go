@@ -0,0 +1,66 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// bracketedPasteStart and bracketedPasteEnd wrap text sent as a paste, so programs don't treat
+	// the newlines in it as submitting the prompt line by line.
+	bracketedPasteStart = "\x1b[200~"
+	bracketedPasteEnd   = "\x1b[201~"
+)
+
+// ReadPromptFile reads a text file to send as a prompt. Files larger than maxBytes are truncated
+// at a character boundary and truncated is set. Binary files are rejected.
+func ReadPromptFile(path string, maxBytes int) (content string, truncated bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	if bytes.IndexByte(data, 0) != -1 {
+		return "", false, fmt.Errorf("%s looks like a binary file", path)
+	}
+	if maxBytes > 0 && len(data) > maxBytes {
+		data = data[:maxBytes]
+		// Don't cut a multi-byte character in half.
+		start := len(data) - 1
+		for start > 0 && start > len(data)-utf8.UTFMax && !utf8.RuneStart(data[start]) {
+			start--
+		}
+		if !utf8.FullRune(data[start:]) {
+			data = data[:start]
+		}
+		truncated = true
+	}
+	return string(data), truncated, nil
+}
+
+// sanitizePrompt normalizes line endings and drops control characters other than newlines and
+// tabs. Escape sequences in the text would otherwise be interpreted by the program as key presses.
+func sanitizePrompt(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// SendPastedPrompt sends multi-line text to the instance as a single pasted prompt and submits it.
+func (i *Instance) SendPastedPrompt(text string) error {
+	text = strings.TrimRight(sanitizePrompt(text), "\n")
+	if text == "" {
+		return fmt.Errorf("prompt is empty")
+	}
+	return i.SendPrompt(bracketedPasteStart + text + bracketedPasteEnd)
+}
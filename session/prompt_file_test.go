@@ -0,0 +1,95 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPromptFile(t *testing.T) {
+	tests := []struct {
+		name              string
+		content           string
+		maxBytes          int
+		expected          string
+		expectedTruncated bool
+		expectErr         bool
+	}{
+		{
+			name:     "reads the whole file",
+			content:  "line 1\nline 2\n",
+			maxBytes: 100,
+			expected: "line 1\nline 2\n",
+		},
+		{
+			name:              "truncates to the limit",
+			content:           "abcdef",
+			maxBytes:          4,
+			expected:          "abcd",
+			expectedTruncated: true,
+		},
+		{
+			name:              "doesn't split multi-byte characters",
+			content:           "ab€cd",
+			maxBytes:          4,
+			expected:          "ab",
+			expectedTruncated: true,
+		},
+		{
+			name:     "no limit",
+			content:  "abcdef",
+			maxBytes: 0,
+			expected: "abcdef",
+		},
+		{
+			name:      "rejects binary files",
+			content:   "abc\x00def",
+			maxBytes:  100,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "prompt.txt")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			content, truncated, err := ReadPromptFile(path, tt.maxBytes)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, content)
+			assert.Equal(t, tt.expectedTruncated, truncated)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, _, err := ReadPromptFile(filepath.Join(t.TempDir(), "missing.txt"), 100)
+		require.Error(t, err)
+	})
+}
+
+func TestSanitizePrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "keeps newlines and tabs", input: "a\n\tb", expected: "a\n\tb"},
+		{name: "normalizes CRLF", input: "a\r\nb", expected: "a\nb"},
+		{name: "drops escape sequences", input: "a\x1b[201~b", expected: "a[201~b"},
+		{name: "drops other control characters", input: "a\x03\rb\x7f", expected: "ab"},
+		{name: "keeps unicode", input: "héllo €", expected: "héllo €"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sanitizePrompt(tt.input))
+		})
+	}
+}
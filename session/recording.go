@@ -0,0 +1,85 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// castHeader is the first line of an asciinema v2 cast file.
+type castHeader struct {
+	Version   int    `json:"version"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Timestamp int64  `json:"timestamp"`
+	Title     string `json:"title,omitempty"`
+}
+
+// castRecorder writes pane captures to an asciinema v2 cast file. Each changed capture is written as
+// an output event that redraws the whole screen, so the cast replays with `asciinema play`.
+type castRecorder struct {
+	path     string
+	file     *os.File
+	start    time.Time
+	lastHash [sha256.Size]byte
+}
+
+// newCastRecorder creates the cast file at path and writes its header.
+func newCastRecorder(path string, width, height int, title string) (*castRecorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording file: %w", err)
+	}
+
+	r := &castRecorder{path: path, file: file, start: time.Now()}
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+	})
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to marshal recording header: %w", err)
+	}
+	if _, err := file.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write recording header: %w", err)
+	}
+
+	return r, nil
+}
+
+// writeFrame appends content as a screen redraw if it changed since the last frame.
+func (r *castRecorder) writeFrame(content string) error {
+	hash := sha256.Sum256([]byte(content))
+	if hash == r.lastHash {
+		return nil
+	}
+	r.lastHash = hash
+
+	// Clear the screen and move the cursor home, then draw the capture. The terminal needs \r\n.
+	data := "\x1b[H\x1b[2J" + strings.ReplaceAll(strings.TrimRight(content, "\n"), "\n", "\r\n")
+	event, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), "o", data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal recording event: %w", err)
+	}
+	if _, err := r.file.Write(append(event, '\n')); err != nil {
+		return fmt.Errorf("failed to write recording event: %w", err)
+	}
+	return nil
+}
+
+// close closes the cast file.
+func (r *castRecorder) close() error {
+	return r.file.Close()
+}
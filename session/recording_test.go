@@ -0,0 +1,69 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCastRecorder(t *testing.T) {
+	tests := []struct {
+		name       string
+		frames     []string
+		wantEvents []string
+	}{
+		{
+			name:       "no frames writes only the header",
+			frames:     nil,
+			wantEvents: nil,
+		},
+		{
+			name:       "unchanged frames are skipped",
+			frames:     []string{"hello", "hello", "world"},
+			wantEvents: []string{"\x1b[H\x1b[2Jhello", "\x1b[H\x1b[2Jworld"},
+		},
+		{
+			name:       "newlines are written as carriage return line feeds",
+			frames:     []string{"one\ntwo\n"},
+			wantEvents: []string{"\x1b[H\x1b[2Jone\r\ntwo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "recordings", "test.cast")
+			recorder, err := newCastRecorder(path, 120, 40, "test")
+			require.NoError(t, err)
+			for _, frame := range tt.frames {
+				require.NoError(t, recorder.writeFrame(frame))
+			}
+			require.NoError(t, recorder.close())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+			var header castHeader
+			require.NoError(t, json.Unmarshal([]byte(lines[0]), &header))
+			assert.Equal(t, 2, header.Version)
+			assert.Equal(t, 120, header.Width)
+			assert.Equal(t, 40, header.Height)
+			assert.Equal(t, "test", header.Title)
+
+			var events []string
+			for _, line := range lines[1:] {
+				var event []interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &event))
+				require.Len(t, event, 3)
+				assert.Equal(t, "o", event[1])
+				events = append(events, event[2].(string))
+			}
+			assert.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// GenerateReport renders a table of what the started instances accomplished: their title, branch,
+// commits since the base commit, diff stats and whether their branch is pushed. The diff stats are
+// the last ones computed, see UpdateDiffStats. Values that can't be determined are shown as "?"
+// and the errors are returned along with the report.
+func GenerateReport(instances []*Instance) (string, error) {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tBRANCH\tCOMMITS\tDIFF\tPUSHED")
+
+	var errs []error
+	for _, instance := range instances {
+		if !instance.Started() {
+			continue
+		}
+		commits, pushed := "?", "?"
+		if count, err := instance.gitWorktree.CommitsSinceBase(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", instance.Title, err))
+		} else {
+			commits = strconv.Itoa(count)
+		}
+		if ok, err := instance.gitWorktree.Pushed(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", instance.Title, err))
+		} else if ok {
+			pushed = "yes"
+		} else {
+			pushed = "no"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", instance.Title, instance.Branch, commits, reportDiff(instance), pushed)
+	}
+
+	if err := w.Flush(); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return strings.TrimRight(sb.String(), "\n"), errors.Join(errs...)
+}
+
+// reportDiff formats the last diff stats of instance for GenerateReport.
+func reportDiff(instance *Instance) string {
+	stats := instance.GetDiffStats()
+	if stats == nil || stats.Error != nil {
+		return "?"
+	}
+	return fmt.Sprintf("+%d -%d, %d files", stats.Added, stats.Removed, len(stats.Files))
+}
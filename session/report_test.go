@@ -0,0 +1,57 @@
+package session
+
+import (
+	"claude-squad/session/git"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateReport(t *testing.T) {
+	run := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+	repo := t.TempDir()
+	run(repo, "init", "-b", "main")
+	run(repo, "commit", "--allow-empty", "-m", "base")
+	base := run(repo, "rev-parse", "HEAD")
+	worktreePath := filepath.Join(t.TempDir(), "wt")
+	run(repo, "worktree", "add", "-b", "feature", worktreePath)
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "a.txt"), []byte("a\n"), 0644))
+	run(worktreePath, "add", ".")
+	run(worktreePath, "commit", "-m", "a")
+
+	done := &Instance{
+		Title:       "done",
+		Branch:      "feature",
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage(repo, worktreePath, "done", "feature", base),
+		diffStats:   &git.DiffStats{Added: 12, Removed: 3, Files: []string{"a.txt", "b.txt"}},
+	}
+	broken := &Instance{
+		Title:       "broken",
+		Branch:      "missing",
+		started:     true,
+		gitWorktree: git.NewGitWorktreeFromStorage(repo, worktreePath, "broken", "missing", base),
+	}
+	notStarted := createTestInstance()
+
+	report, err := GenerateReport([]*Instance{done, broken, notStarted})
+	assert.ErrorContains(t, err, "broken: ")
+	lines := strings.Split(report, "\n")
+	require.Len(t, lines, 3, "instances that haven't started are left out")
+	assert.Equal(t, []string{"TITLE", "BRANCH", "COMMITS", "DIFF", "PUSHED"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"done", "feature", "1", "+12", "-3,", "2", "files", "no"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"broken", "missing", "?", "?", "no"}, strings.Fields(lines[2]))
+}
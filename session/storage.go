@@ -2,10 +2,13 @@ package session
 
 import (
 	"claude-squad/config"
+	"claude-squad/log"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -20,11 +23,28 @@ type InstanceData struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	AutoYes   bool      `json:"auto_yes"`
+	Labels    []string  `json:"labels,omitempty"`
+	Pinned    bool      `json:"pinned,omitempty"`
 
 	Program   string          `json:"program"`
+	ExtraArgs string          `json:"extra_args,omitempty"`
 	Worktree  GitWorktreeData `json:"worktree"`
 	DiffStats DiffStatsData   `json:"diff_stats"`
 	DevServer *DevServerData  `json:"dev_server,omitempty"`
+	// PausedCapture is the last pane content captured when the instance was paused.
+	PausedCapture string `json:"paused_capture,omitempty"`
+	// Color is the manually assigned list color. Empty means it is derived from the title.
+	Color string `json:"color,omitempty"`
+	// Container is the container the program runs in, if any.
+	Container *config.ContainerConfig `json:"container,omitempty"`
+	// Prompt is the initial prompt that hasn't been sent yet, e.g. because the app quit before the
+	// program was ready.
+	Prompt string `json:"prompt,omitempty"`
+	// Archived is true if the instance is hidden from the active instances.
+	Archived bool `json:"archived,omitempty"`
+	// TmuxPrefix is the tmux prefix the instance's sessions were created under, so they are found
+	// again after the configured prefix changes. Empty for data saved before it was recorded.
+	TmuxPrefix string `json:"tmux_prefix,omitempty"`
 }
 
 // DevServerData represents the serializable data of a DevServer
@@ -32,6 +52,8 @@ type DevServerData struct {
 	Config     DevServerConfig `json:"config"`
 	Status     DevServerStatus `json:"status"`
 	CrashCount int             `json:"crash_count"`
+	// Restore is true if the dev server was running when the app quit and should run again.
+	Restore bool `json:"restore,omitempty"`
 }
 
 // GitWorktreeData represents the serializable data of a GitWorktree
@@ -41,18 +63,28 @@ type GitWorktreeData struct {
 	SessionName   string `json:"session_name"`
 	BranchName    string `json:"branch_name"`
 	BaseCommitSHA string `json:"base_commit_sha"`
+	// BaseBranch is the branch the worktree was created from. Empty for worktrees saved by older
+	// versions.
+	BaseBranch string `json:"base_branch,omitempty"`
+	// InPlace is true if the session runs in the repository's checkout instead of a worktree.
+	InPlace bool `json:"in_place,omitempty"`
 }
 
 // DiffStatsData represents the serializable data of a DiffStats
 type DiffStatsData struct {
-	Added   int    `json:"added"`
-	Removed int    `json:"removed"`
-	Content string `json:"content"`
+	Added   int      `json:"added"`
+	Removed int      `json:"removed"`
+	Content string   `json:"content"`
+	Files   []string `json:"files,omitempty"`
+	// ComputedAt is zero for stats saved by older versions.
+	ComputedAt time.Time `json:"computed_at,omitempty"`
 }
 
 // Storage handles saving and loading instances using the state interface
 type Storage struct {
 	state config.InstanceStorage
+	// lastSaved fingerprints the instances written by the last save, see SaveInstancesIfChanged
+	lastSaved [sha256.Size]byte
 }
 
 // NewStorage creates a new storage instance
@@ -64,44 +96,152 @@ func NewStorage(state config.InstanceStorage) (*Storage, error) {
 
 // SaveInstances saves the list of instances to disk
 func (s *Storage) SaveInstances(instances []*Instance) error {
-	// Convert instances to InstanceData
+	return s.saveInstances(instancesData(instances))
+}
+
+// SaveInstancesIfChanged saves the list of instances unless nothing but their UpdatedAt changed
+// since the last save. Returns true if the instances were written.
+func (s *Storage) SaveInstancesIfChanged(instances []*Instance) (bool, error) {
+	data := instancesData(instances)
+	fingerprint, err := fingerprintInstances(data)
+	if err != nil {
+		return false, err
+	}
+	if fingerprint == s.lastSaved {
+		return false, nil
+	}
+	return true, s.saveInstances(data)
+}
+
+// instancesData converts instances to their serializable form.
+func instancesData(instances []*Instance) []InstanceData {
 	data := make([]InstanceData, 0)
 	for _, instance := range instances {
-		// Save all instances, not just started ones, to ensure paused
-		// instances are properly persisted and can be resumed on restart
+		// Paused instances count as started, so they are kept and can be resumed on restart. An
+		// unstarted instance is a placeholder still being named, which has no title or worktree
+		// yet and would keep the saved instances from loading.
+		if !instance.Started() {
+			continue
+		}
 		data = append(data, instance.ToInstanceData())
 	}
+	return data
+}
 
-	// Marshal to JSON
+// saveInstances writes data and remembers its fingerprint.
+func (s *Storage) saveInstances(data []InstanceData) error {
 	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Errorf("failed to marshal instances: %w", err)
 	}
+	if err := s.state.SaveInstances(jsonData); err != nil {
+		return err
+	}
+	fingerprint, err := fingerprintInstances(data)
+	if err != nil {
+		return err
+	}
+	s.lastSaved = fingerprint
+	return nil
+}
 
-	return s.state.SaveInstances(jsonData)
+// fingerprintInstances hashes data without UpdatedAt, which ToInstanceData sets to the current time.
+func fingerprintInstances(data []InstanceData) ([sha256.Size]byte, error) {
+	stripped := make([]InstanceData, len(data))
+	for i, instance := range data {
+		instance.UpdatedAt = time.Time{}
+		stripped[i] = instance
+	}
+	jsonData, err := json.Marshal(stripped)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to marshal instances: %w", err)
+	}
+	return sha256.Sum256(jsonData), nil
 }
 
 // LoadInstances loads the list of instances from disk
 func (s *Storage) LoadInstances() ([]*Instance, error) {
 	jsonData := s.state.GetInstances()
 
-	var instancesData []InstanceData
-	if err := json.Unmarshal(jsonData, &instancesData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal instances: %w", err)
+	if len(jsonData) == 0 {
+		return []*Instance{}, nil
+	}
+
+	// Decode each entry on its own so that a single corrupt entry doesn't prevent the rest
+	// from loading.
+	var rawInstances []json.RawMessage
+	if err := json.Unmarshal(jsonData, &rawInstances); err != nil {
+		log.ErrorLog.Printf("failed to unmarshal instances, starting with no instances: %v", err)
+		backupCorruptInstances(jsonData)
+		return []*Instance{}, nil
 	}
 
-	instances := make([]*Instance, len(instancesData))
-	for i, data := range instancesData {
+	corrupt := false
+	instances := make([]*Instance, 0, len(rawInstances))
+	// worktreeOwners maps the worktree paths of the loaded instances to their titles
+	worktreeOwners := make(map[string]string)
+	for i, raw := range rawInstances {
+		var data InstanceData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			log.ErrorLog.Printf("skipping corrupt instance entry %d: %v", i, err)
+			corrupt = true
+			continue
+		}
+		// Killing either of two instances sharing a worktree would remove the other's files, so
+		// only the first one is loaded.
+		if path := data.Worktree.WorktreePath; path != "" {
+			path = filepath.Clean(path)
+			if owner, ok := worktreeOwners[path]; ok {
+				log.ErrorLog.Printf("skipping instance '%s': %v", data.Title,
+					fmt.Errorf("%w: %s belongs to instance '%s'", ErrWorktreeInUse, path, owner))
+				corrupt = true
+				continue
+			}
+			worktreeOwners[path] = data.Title
+		}
+
 		instance, err := FromInstanceData(data)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create instance %s: %w", data.Title, err)
+			log.ErrorLog.Printf("skipping instance '%s': failed to create it: %v", data.Title, err)
+			corrupt = true
+			continue
 		}
-		instances[i] = instance
+		instances = append(instances, instance)
+	}
+
+	if corrupt {
+		backupCorruptInstances(jsonData)
 	}
 
 	return instances, nil
 }
 
+// backedUpInstances holds the hashes of the instance data backed up by backupCorruptInstances, since
+// every load of the same corrupt data, e.g. by DeleteInstance, would write another backup.
+var (
+	backedUpInstancesMu sync.Mutex
+	backedUpInstances   = make(map[[sha256.Size]byte]bool)
+)
+
+// backupCorruptInstances saves a copy of the raw instance data before the corrupt entries get
+// dropped by the next save. The same data is only backed up once per process.
+func backupCorruptInstances(data json.RawMessage) {
+	hash := sha256.Sum256(data)
+	backedUpInstancesMu.Lock()
+	defer backedUpInstancesMu.Unlock()
+	if backedUpInstances[hash] {
+		return
+	}
+
+	backupPath, err := config.BackupInstancesData(data)
+	if err != nil {
+		log.ErrorLog.Printf("failed to back up corrupt instance data: %v", err)
+		return
+	}
+	backedUpInstances[hash] = true
+	log.WarningLog.Printf("backed up corrupt instance data to %s", backupPath)
+}
+
 // DeleteInstance removes an instance from storage
 func (s *Storage) DeleteInstance(title string) error {
 	instances, err := s.LoadInstances()
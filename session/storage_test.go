@@ -0,0 +1,205 @@
+package session
+
+import (
+	"claude-squad/log"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryInstanceStorage is an in-memory config.InstanceStorage used for testing
+type memoryInstanceStorage struct {
+	data  json.RawMessage
+	saves int
+}
+
+func (m *memoryInstanceStorage) SaveInstances(instancesJSON json.RawMessage) error {
+	m.data = instancesJSON
+	m.saves++
+	return nil
+}
+
+func (m *memoryInstanceStorage) GetInstances() json.RawMessage {
+	return m.data
+}
+
+func (m *memoryInstanceStorage) DeleteAllInstances() error {
+	m.data = json.RawMessage("[]")
+	return nil
+}
+
+// findBackups returns the instance backup files written to the config dir under home
+func findBackups(t *testing.T, home string) []string {
+	entries, err := os.ReadDir(filepath.Join(home, ".claude-squad"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var backups []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".bak") {
+			backups = append(backups, filepath.Join(home, ".claude-squad", entry.Name()))
+		}
+	}
+	return backups
+}
+
+func TestLoadInstances_CorruptData(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	tests := []struct {
+		name           string
+		data           string
+		expectedTitles []string
+		expectBackup   bool
+	}{
+		{
+			name:           "valid entries load without backup",
+			data:           `[{"title":"one","status":3},{"title":"two","status":3}]`,
+			expectedTitles: []string{"one", "two"},
+			expectBackup:   false,
+		},
+		{
+			name:           "corrupt entry is skipped and the rest load",
+			data:           `[{"title":"one","status":3},{"title":123},{"title":"two","status":3}]`,
+			expectedTitles: []string{"one", "two"},
+			expectBackup:   true,
+		},
+		{
+			name:           "instance sharing a worktree with an earlier one is skipped",
+			data:           `[{"title":"one","status":3,"worktree":{"worktree_path":"/wt/a"}},{"title":"two","status":3,"worktree":{"worktree_path":"/wt/a/"}},{"title":"three","status":3,"worktree":{"worktree_path":"/wt/b"}}]`,
+			expectedTitles: []string{"one", "three"},
+			expectBackup:   true,
+		},
+		{
+			name:           "instance that fails to start is skipped",
+			data:           `[{"title":"one","status":3},{"title":"","status":0},{"title":"two","status":3}]`,
+			expectedTitles: []string{"one", "two"},
+			expectBackup:   true,
+		},
+		{
+			name:           "unreadable array loads nothing",
+			data:           `{"title":"one"`,
+			expectedTitles: []string{},
+			expectBackup:   true,
+		},
+		{
+			name:           "empty data loads nothing",
+			data:           ``,
+			expectedTitles: []string{},
+			expectBackup:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+
+			storage, err := NewStorage(&memoryInstanceStorage{data: json.RawMessage(tt.data)})
+			require.NoError(t, err)
+
+			instances, err := storage.LoadInstances()
+			require.NoError(t, err)
+
+			titles := make([]string, 0, len(instances))
+			for _, instance := range instances {
+				titles = append(titles, instance.Title)
+			}
+			assert.Equal(t, tt.expectedTitles, titles)
+
+			backups := findBackups(t, home)
+			if tt.expectBackup {
+				require.Len(t, backups, 1)
+				content, err := os.ReadFile(backups[0])
+				require.NoError(t, err)
+				assert.Equal(t, tt.data, string(content))
+			} else {
+				assert.Empty(t, backups)
+			}
+
+			// Loading the same data again, as DeleteInstance does, doesn't back it up again.
+			_, err = storage.LoadInstances()
+			require.NoError(t, err)
+			assert.Len(t, findBackups(t, home), len(backups))
+		})
+	}
+}
+
+func TestSaveInstancesIfChanged(t *testing.T) {
+	state := &memoryInstanceStorage{}
+	storage, err := NewStorage(state)
+	require.NoError(t, err)
+
+	instance := createTestInstance()
+	instance.started = true
+	instances := []*Instance{instance}
+
+	saved, err := storage.SaveInstancesIfChanged(instances)
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	// Only UpdatedAt differs, so nothing is written.
+	saved, err = storage.SaveInstancesIfChanged(instances)
+	require.NoError(t, err)
+	assert.False(t, saved)
+
+	devServer := &DevServer{config: DevServerConfig{DevCommand: "npm run dev"}}
+	instance.DevServer = devServer
+	saved, err = storage.SaveInstancesIfChanged(instances)
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	devServer.IncrementCrashCount()
+	saved, err = storage.SaveInstancesIfChanged(instances)
+	require.NoError(t, err)
+	assert.True(t, saved)
+
+	// A regular save counts as the last save too.
+	instance.Pinned = true
+	require.NoError(t, storage.SaveInstances(instances))
+	saved, err = storage.SaveInstancesIfChanged(instances)
+	require.NoError(t, err)
+	assert.False(t, saved)
+	assert.Equal(t, 4, state.saves)
+
+	// The placeholder of an instance being named isn't saved.
+	placeholder := &Instance{Path: "/mock/path"}
+	saved, err = storage.SaveInstancesIfChanged(append(instances, placeholder))
+	require.NoError(t, err)
+	assert.False(t, saved)
+}
+
+func TestWorktreeInUse(t *testing.T) {
+	instance, err := FromInstanceData(InstanceData{
+		Title:    "one",
+		Status:   Paused,
+		Worktree: GitWorktreeData{RepoPath: "/repo", WorktreePath: "/wt/one"},
+	})
+	require.NoError(t, err)
+	instances := []*Instance{createTestInstance(), instance}
+
+	assert.NoError(t, WorktreeInUse("/wt/two", instances))
+	err = WorktreeInUse("/wt/one/", instances)
+	require.ErrorIs(t, err, ErrWorktreeInUse)
+	assert.Contains(t, err.Error(), "'one'")
+
+	// A new instance is refused before its worktree is set, so a failed start can't remove the
+	// other instance's files.
+	t.Setenv("HOME", t.TempDir())
+	repoPath := t.TempDir()
+	require.NoError(t, exec.Command("git", "init", "-q", repoPath).Run())
+	newInstance, err := NewInstance(InstanceOptions{Title: "two", Path: repoPath, Program: "claude"})
+	require.NoError(t, err)
+	newInstance.SetWorktreeCheck(func(string) error { return ErrWorktreeInUse })
+	require.ErrorIs(t, newInstance.Start(true), ErrWorktreeInUse)
+	assert.Nil(t, newInstance.gitWorktree)
+}
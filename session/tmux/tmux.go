@@ -3,6 +3,7 @@ package tmux
 import (
 	"bytes"
 	"claude-squad/cmd"
+	"claude-squad/config"
 	"claude-squad/log"
 	"context"
 	"crypto/sha256"
@@ -11,10 +12,15 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/creack/pty"
 )
@@ -24,13 +30,29 @@ const ProgramClaude = "claude"
 const ProgramAider = "aider"
 const ProgramGemini = "gemini"
 
+// isClaude returns true if the program command runs claude, with or without arguments.
+func (t *TmuxSession) isClaude() bool {
+	return programName(t.program) == ProgramClaude
+}
+
+// isAiderOrGemini returns true if the program command runs aider or gemini, with or without
+// arguments.
+func (t *TmuxSession) isAiderOrGemini() bool {
+	name := programName(t.program)
+	return name == ProgramAider || name == ProgramGemini
+}
+
 // TmuxSession represents a managed tmux session
 type TmuxSession struct {
 	// Initialized by NewTmuxSession
 	//
 	// The name of the tmux session and the sanitized name used for tmux commands.
+	name          string
 	sanitizedName string
 	program       string
+	// launchCommand is the command Start runs instead of program, e.g. program inside a container.
+	// program is still used to recognize the program's prompts.
+	launchCommand string
 	// ptyFactory is used to create a PTY for the tmux session.
 	ptyFactory PtyFactory
 	// cmdExec is used to execute commands in the tmux session.
@@ -57,18 +79,59 @@ type TmuxSession struct {
 	wg     *sync.WaitGroup
 }
 
-const TmuxPrefix = "claudesquad_"
+// TmuxPrefix is the prefix used for all tmux sessions created by claude-squad. It is set once at
+// startup via SetTmuxPrefix.
+var TmuxPrefix = config.DefaultTmuxPrefix
 
 var whiteSpaceRegex = regexp.MustCompile(`\s+`)
 
+// SetTmuxPrefix sets the prefix used for tmux session names. An empty prefix restores the default.
+// It must be called before any sessions are created.
+func SetTmuxPrefix(prefix string) {
+	prefix = whiteSpaceRegex.ReplaceAllString(prefix, "")
+	prefix = strings.ReplaceAll(prefix, ".", "_") // tmux replaces all . with _
+	if prefix == "" {
+		prefix = config.DefaultTmuxPrefix
+	}
+	TmuxPrefix = prefix
+}
+
+// DevServerSuffix is appended to the session name of an instance's dev server. SanitizeSessionName
+// never returns a name ending in it, so dev server sessions can't collide with agent sessions.
+const DevServerSuffix = "_dev"
+
+// SanitizeSessionName returns a tmux-safe session name, without the prefix, for an instance title.
+// Whitespace is removed and the characters tmux doesn't allow (. and :) are replaced with _. Titles
+// that have to be changed get a short hash of the original title appended, so different titles never
+// map to the same name (e.g. "my app" and "myapp").
+func SanitizeSessionName(title string) string {
+	name := strings.ReplaceAll(legacySessionName(title), ":", "_")
+	if name != "" && name == title && !strings.HasSuffix(name, DevServerSuffix) {
+		return name
+	}
+	sum := sha256.Sum256([]byte(title))
+	return fmt.Sprintf("%s_%x", name, sum[:3])
+}
+
+// DevServerSessionName returns the session name, without the prefix, of the dev server for an
+// instance title.
+func DevServerSessionName(title string) string {
+	return SanitizeSessionName(title) + DevServerSuffix
+}
+
+// legacySessionName is how session names were derived before SanitizeSessionName. Sessions created
+// that way are renamed by MigrateLegacyName.
+func legacySessionName(title string) string {
+	name := whiteSpaceRegex.ReplaceAllString(title, "")
+	return strings.ReplaceAll(name, ".", "_")
+}
+
 func toClaudeSquadTmuxName(str string) string {
-	str = whiteSpaceRegex.ReplaceAllString(str, "")
-	str = strings.ReplaceAll(str, ".", "_") // tmux replaces all . with _
 	// Don't add prefix if it's already there
 	if strings.HasPrefix(str, TmuxPrefix) {
 		return str
 	}
-	return fmt.Sprintf("%s%s", TmuxPrefix, str)
+	return fmt.Sprintf("%s%s", TmuxPrefix, SanitizeSessionName(str))
 }
 
 // NewTmuxSession creates a new TmuxSession with the given name and program.
@@ -83,6 +146,7 @@ func NewTmuxSessionWithDeps(name string, program string, ptyFactory PtyFactory,
 
 func newTmuxSession(name string, program string, ptyFactory PtyFactory, cmdExec cmd.Executor) *TmuxSession {
 	return &TmuxSession{
+		name:          name,
 		sanitizedName: toClaudeSquadTmuxName(name),
 		program:       program,
 		ptyFactory:    ptyFactory,
@@ -90,6 +154,12 @@ func newTmuxSession(name string, program string, ptyFactory PtyFactory, cmdExec
 	}
 }
 
+// SetLaunchCommand makes Start run command instead of the program, e.g. to run the program in a
+// container. It has no effect on a session that was already started.
+func (t *TmuxSession) SetLaunchCommand(command string) {
+	t.launchCommand = command
+}
+
 // Start creates and starts a new tmux session, then attaches to it. Program is the command to run in
 // the session (ex. claude). workdir is the git worktree directory.
 func (t *TmuxSession) Start(workDir string) error {
@@ -98,8 +168,13 @@ func (t *TmuxSession) Start(workDir string) error {
 		return fmt.Errorf("tmux session already exists: %s", t.sanitizedName)
 	}
 
+	command := t.program
+	if t.launchCommand != "" {
+		command = t.launchCommand
+	}
+
 	// Create a new detached tmux session and start claude in it
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir, t.program)
+	cmd := exec.Command("tmux", "new-session", "-d", "-s", t.sanitizedName, "-c", workDir, command)
 
 	ptmx, err := t.ptyFactory.Start(cmd)
 	if err != nil {
@@ -153,11 +228,11 @@ func (t *TmuxSession) Start(workDir string) error {
 		return fmt.Errorf("error restoring tmux session: %w", err)
 	}
 
-	if strings.HasSuffix(t.program, ProgramClaude) || strings.HasSuffix(t.program, ProgramAider) || strings.HasSuffix(t.program, ProgramGemini) {
+	if t.isClaude() || t.isAiderOrGemini() {
 		searchString := "Do you trust the files in this folder?"
 		tapFunc := t.TapEnter
 		maxWaitTime := 30 * time.Second // Much longer timeout for slower systems
-		if !strings.HasSuffix(t.program, ProgramClaude) {
+		if !t.isClaude() {
 			searchString = "Open documentation url for more info"
 			tapFunc = t.TapDAndEnter
 			maxWaitTime = 45 * time.Second // Aider/Gemini take longer to start
@@ -240,13 +315,45 @@ func (t *TmuxSession) TapDAndEnter() error {
 	return nil
 }
 
+// sendKeysChunkSize bounds the text sent by one send-keys command, since tmux limits the size of the
+// commands a client sends to the server.
+const sendKeysChunkSize = 4096
+
+// SendKeys types keys into the session verbatim. It uses send-keys in literal mode, so key names like
+// Enter aren't looked up and text is never taken as tmux syntax or key bindings like the prefix key.
 func (t *TmuxSession) SendKeys(keys string) error {
-	_, err := t.ptmx.Write([]byte(keys))
-	return err
+	for len(keys) > 0 {
+		chunk := keys
+		if len(chunk) > sendKeysChunkSize {
+			// Split on a rune boundary so multi-byte characters stay whole.
+			end := sendKeysChunkSize
+			for end > 0 && !utf8.RuneStart(keys[end]) {
+				end--
+			}
+			chunk = keys[:end]
+		}
+		keys = keys[len(chunk):]
+
+		sendCmd := exec.Command("tmux", "send-keys", "-t", t.sanitizedName, "-l", "--", escapeTrailingSemicolon(chunk))
+		if err := t.cmdExec.Run(sendCmd); err != nil {
+			return fmt.Errorf("error sending keys to session %s: %w", t.sanitizedName, err)
+		}
+	}
+	return nil
+}
+
+// escapeTrailingSemicolon escapes a semicolon at the end of a tmux command argument, which tmux
+// otherwise takes as the end of the command and drops. tmux turns a trailing \; back into ;.
+func escapeTrailingSemicolon(arg string) string {
+	if strings.HasSuffix(arg, ";") {
+		return arg[:len(arg)-1] + `\;`
+	}
+	return arg
 }
 
 // HasUpdated checks if the tmux pane content has changed since the last tick. It also returns true if
-// the tmux pane has a prompt for aider or claude code.
+// the tmux pane has a prompt for aider or claude code. Patterns configured for the program with
+// SetProgramPatterns take precedence over both checks.
 func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
 	content, err := t.CapturePaneContent()
 	if err != nil {
@@ -255,7 +362,7 @@ func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
 	}
 
 	// Only set hasPrompt for claude and aider. Use these strings to check for a prompt.
-	if t.program == ProgramClaude {
+	if t.isClaude() {
 		hasPrompt = strings.Contains(content, "No, and tell Claude what to do differently")
 	} else if strings.HasPrefix(t.program, ProgramAider) {
 		hasPrompt = strings.Contains(content, "(Y)es/(N)o/(D)on't ask again")
@@ -265,9 +372,74 @@ func (t *TmuxSession) HasUpdated() (updated bool, hasPrompt bool) {
 
 	if !bytes.Equal(t.monitor.hash(content), t.monitor.prevOutputHash) {
 		t.monitor.prevOutputHash = t.monitor.hash(content)
-		return true, hasPrompt
+		updated = true
+	}
+
+	if patterns, ok := programPatterns[programName(t.program)]; ok {
+		updated, hasPrompt = patterns.apply(content, updated, hasPrompt)
 	}
-	return false, hasPrompt
+	return updated, hasPrompt
+}
+
+// ProgramPatterns detect the state of a program from its pane content. A nil pattern keeps the
+// built-in detection for that state.
+type ProgramPatterns struct {
+	// Prompt matches while the program waits for the user to confirm something.
+	Prompt *regexp.Regexp
+	// Working matches while the program is working. It replaces checking whether the pane changed,
+	// which misreads spinners that stall and cursors that blink.
+	Working *regexp.Regexp
+}
+
+// apply returns the updated and hasPrompt results for content, given the built-in results.
+func (p ProgramPatterns) apply(content string, updated, hasPrompt bool) (bool, bool) {
+	if p.Prompt != nil {
+		hasPrompt = p.Prompt.MatchString(content)
+	}
+	if p.Working != nil {
+		updated = p.Working.MatchString(content)
+	}
+	return updated, hasPrompt
+}
+
+// CompileProgramPatterns compiles the configured patterns (config.Config.ProgramPatterns) for
+// SetProgramPatterns. Invalid patterns are logged and left out, so the built-in detection is used
+// for them.
+func CompileProgramPatterns(patterns map[string]config.ProgramPatterns) map[string]ProgramPatterns {
+	compiled := make(map[string]ProgramPatterns, len(patterns))
+	compile := func(program, field, pattern string) *regexp.Regexp {
+		if pattern == "" {
+			return nil
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.WarningLog.Printf("program_patterns: ignoring %s pattern of %q: %v", field, program, err)
+			return nil
+		}
+		return re
+	}
+	for program, configured := range patterns {
+		compiled[program] = ProgramPatterns{
+			Prompt:  compile(program, "prompt", configured.Prompt),
+			Working: compile(program, "working", configured.Working),
+		}
+	}
+	return compiled
+}
+
+// programPatterns are the detection patterns keyed by program name, set by SetProgramPatterns.
+var programPatterns map[string]ProgramPatterns
+
+// SetProgramPatterns sets the detection patterns used by HasUpdated, keyed by program name: the
+// base name of the program's executable, e.g. "claude" or "aider".
+func SetProgramPatterns(patterns map[string]ProgramPatterns) {
+	programPatterns = patterns
+}
+
+// programName returns the base name of the executable the program command runs.
+func programName(program string) string {
+	executable, _, _ := strings.Cut(strings.TrimSpace(program), " ")
+	return filepath.Base(executable)
 }
 
 func (t *TmuxSession) Attach() (chan struct{}, error) {
@@ -355,6 +527,23 @@ func (t *TmuxSession) Attach() (chan struct{}, error) {
 	return t.attachCh, nil
 }
 
+// AttachReadOnly attaches like Attach, but with a read-only tmux client, so input such as a stray
+// ctrl+c doesn't reach the session. Ctrl+q still detaches, after which the usual client is restored.
+func (t *TmuxSession) AttachReadOnly() (chan struct{}, error) {
+	if t.ptmx != nil {
+		if err := t.ptmx.Close(); err != nil {
+			return nil, fmt.Errorf("error closing PTY: %w", err)
+		}
+		t.ptmx = nil
+	}
+	ptmx, err := t.ptyFactory.Start(exec.Command("tmux", "attach-session", "-r", "-t", t.sanitizedName))
+	if err != nil {
+		return nil, fmt.Errorf("error opening read-only PTY: %w", err)
+	}
+	t.ptmx = ptmx
+	return t.Attach()
+}
+
 // DetachSafely disconnects from the current tmux session without panicking
 func (t *TmuxSession) DetachSafely() error {
 	// Only detach if we're actually attached
@@ -478,12 +667,223 @@ func (t *TmuxSession) updateWindowSize(cols, rows int) error {
 	})
 }
 
+// Resize resizes the live session to width x height. Updating only the PTY leaves tmux to reflow the
+// pane lazily, which can leave the content wrapped for the old size, so the tmux window is resized
+// directly. tmux then goes back to sizing the window from its clients.
+func (t *TmuxSession) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid session size %dx%d", width, height)
+	}
+
+	if t.ptmx != nil {
+		if err := t.updateWindowSize(width, height); err != nil {
+			return fmt.Errorf("error resizing pty: %w", err)
+		}
+	}
+
+	resizeCmd := exec.Command("tmux", "resize-window", "-t", t.sanitizedName,
+		"-x", strconv.Itoa(width), "-y", strconv.Itoa(height))
+	if err := t.cmdExec.Run(resizeCmd); err != nil {
+		return fmt.Errorf("error resizing session: %w", err)
+	}
+
+	// resize-window switches the window to manual sizing. Restore automatic sizing so attaching
+	// still follows the terminal size.
+	unsetCmd := exec.Command("tmux", "set-option", "-w", "-u", "-t", t.sanitizedName, "window-size")
+	if err := t.cmdExec.Run(unsetCmd); err != nil {
+		return fmt.Errorf("error restoring window sizing: %w", err)
+	}
+	return nil
+}
+
+// RunStartupCommands runs tmux commands against the session, e.g. "split-window -h" to open a shell
+// next to the program. Commands are split into arguments like a shell would, and target the session
+// unless they have a -t option of their own. Afterwards the program's pane is selected again, since
+// the preview and the status detection read the active pane.
+func (t *TmuxSession) RunStartupCommands(commands []string) error {
+	output, err := t.cmdExec.Output(exec.Command("tmux", "display-message", "-p", "-t", t.sanitizedName, "#{pane_id}"))
+	if err != nil {
+		return fmt.Errorf("error getting the program's pane: %v", err)
+	}
+	programPane := strings.TrimSpace(string(output))
+
+	var errs []error
+	for _, command := range commands {
+		args, err := splitCommandLine(command)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid tmux startup command %q: %w", command, err))
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if !slices.Contains(args[1:], "-t") {
+			args = append([]string{args[0], "-t", t.sanitizedName}, args[1:]...)
+		}
+		if err := t.cmdExec.Run(exec.Command("tmux", args...)); err != nil {
+			errs = append(errs, fmt.Errorf("tmux startup command %q failed: %w", command, err))
+		}
+	}
+
+	for _, selectCmd := range []string{"select-window", "select-pane"} {
+		if err := t.cmdExec.Run(exec.Command("tmux", selectCmd, "-t", programPane)); err != nil {
+			errs = append(errs, fmt.Errorf("error selecting the program's pane: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitCommandLine splits command into arguments at unquoted whitespace. Single quotes keep their
+// contents as is, double quotes keep them except for backslash escapes, and a backslash outside
+// quotes escapes the next character.
+func splitCommandLine(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// PaneSize returns the width and height of the session's pane.
+func (t *TmuxSession) PaneSize() (int, int, error) {
+	cmd := exec.Command("tmux", "display-message", "-p", "-t", t.sanitizedName, "#{pane_width} #{pane_height}")
+	output, err := t.cmdExec.Output(cmd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error getting pane size: %v", err)
+	}
+	var width, height int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(output)), "%d %d", &width, &height); err != nil {
+		return 0, 0, fmt.Errorf("error parsing pane size %q: %v", output, err)
+	}
+	return width, height, nil
+}
+
 func (t *TmuxSession) DoesSessionExist() bool {
 	// Using "-t name" does a prefix match, which is wrong. `-t=` does an exact match.
 	existsCmd := exec.Command("tmux", "has-session", fmt.Sprintf("-t=%s", t.sanitizedName))
 	return t.cmdExec.Run(existsCmd) == nil
 }
 
+// InsideTmux returns true if claude-squad itself is running inside a tmux client.
+func InsideTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// SwitchClient switches the surrounding tmux client to this session. Unlike Attach, it doesn't
+// block, so the caller keeps running in its own session. Only works when InsideTmux is true.
+func (t *TmuxSession) SwitchClient() error {
+	if !InsideTmux() {
+		return fmt.Errorf("not running inside tmux")
+	}
+	switchCmd := exec.Command("tmux", "switch-client", fmt.Sprintf("-t=%s", t.sanitizedName))
+	if err := t.cmdExec.Run(switchCmd); err != nil {
+		return fmt.Errorf("failed to switch tmux client to %s: %w", t.sanitizedName, err)
+	}
+	return nil
+}
+
+// MigrateFromPrefix renames a session that was created under oldPrefix so that it matches the
+// current prefix. This keeps sessions from being orphaned when the configured prefix changes. It
+// does nothing if the session already exists under the current prefix or there is no session
+// under the old one.
+func (t *TmuxSession) MigrateFromPrefix(oldPrefix string) error {
+	if oldPrefix == TmuxPrefix || t.DoesSessionExist() {
+		return nil
+	}
+
+	oldName := oldPrefix + strings.TrimPrefix(t.sanitizedName, TmuxPrefix)
+	existsCmd := exec.Command("tmux", "has-session", fmt.Sprintf("-t=%s", oldName))
+	if t.cmdExec.Run(existsCmd) != nil {
+		return nil
+	}
+
+	renameCmd := exec.Command("tmux", "rename-session", "-t", oldName, t.sanitizedName)
+	if err := t.cmdExec.Run(renameCmd); err != nil {
+		return fmt.Errorf("failed to rename tmux session %s to %s: %w", oldName, t.sanitizedName, err)
+	}
+	log.InfoLog.Printf("migrated tmux session %s to %s", oldName, t.sanitizedName)
+	return nil
+}
+
+// MigrateLegacyName renames a session that was named before SanitizeSessionName was introduced, e.g.
+// "claudesquad_myapp" for the title "my app". It does nothing if the session already exists under its
+// current name or there is no legacy session.
+func (t *TmuxSession) MigrateLegacyName() error {
+	legacyName := TmuxPrefix + legacySessionName(t.name)
+	if legacyName == t.sanitizedName || t.DoesSessionExist() {
+		return nil
+	}
+
+	existsCmd := exec.Command("tmux", "has-session", fmt.Sprintf("-t=%s", legacyName))
+	if t.cmdExec.Run(existsCmd) != nil {
+		return nil
+	}
+
+	renameCmd := exec.Command("tmux", "rename-session", "-t", legacyName, t.sanitizedName)
+	if err := t.cmdExec.Run(renameCmd); err != nil {
+		return fmt.Errorf("failed to rename tmux session %s to %s: %w", legacyName, t.sanitizedName, err)
+	}
+	log.InfoLog.Printf("migrated tmux session %s to %s", legacyName, t.sanitizedName)
+	return nil
+}
+
+// Rename renames the session so that it is named after name, e.g. to move a replacement session
+// in place of the one it replaced.
+func (t *TmuxSession) Rename(name string) error {
+	sanitizedName := toClaudeSquadTmuxName(name)
+	renameCmd := exec.Command("tmux", "rename-session", "-t", t.sanitizedName, sanitizedName)
+	if err := t.cmdExec.Run(renameCmd); err != nil {
+		return fmt.Errorf("failed to rename tmux session %s to %s: %w", t.sanitizedName, sanitizedName, err)
+	}
+	t.name = name
+	t.sanitizedName = sanitizedName
+	return nil
+}
+
 // CapturePaneContent captures the content of the tmux pane
 func (t *TmuxSession) CapturePaneContent() (string, error) {
 	// Add -e flag to preserve escape sequences (ANSI color codes)
@@ -522,7 +922,7 @@ func CleanupSessions(cmdExec cmd.Executor) error {
 		return fmt.Errorf("failed to list tmux sessions: %v", err)
 	}
 
-	re := regexp.MustCompile(fmt.Sprintf(`%s.*:`, TmuxPrefix))
+	re := regexp.MustCompile(fmt.Sprintf(`%s.*:`, regexp.QuoteMeta(TmuxPrefix)))
 	matches := re.FindAllString(string(output), -1)
 	for i, match := range matches {
 		matches[i] = match[:strings.Index(match, ":")]
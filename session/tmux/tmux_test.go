@@ -2,13 +2,17 @@ package tmux
 
 import (
 	cmd2 "claude-squad/cmd"
+	"claude-squad/config"
+	"claude-squad/log"
 	"fmt"
 	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"claude-squad/cmd/cmd_test"
 
@@ -46,7 +50,272 @@ func TestSanitizeName(t *testing.T) {
 	require.Equal(t, TmuxPrefix+"asdf", session.sanitizedName)
 
 	session = NewTmuxSession("a sd f . . asdf", "program")
-	require.Equal(t, TmuxPrefix+"asdf__asdf", session.sanitizedName)
+	require.Equal(t, TmuxPrefix+SanitizeSessionName("a sd f . . asdf"), session.sanitizedName)
+	require.Regexp(t, `^asdf__asdf_[0-9a-f]{6}$`, SanitizeSessionName("a sd f . . asdf"))
+}
+
+func TestIsAiderOrGemini(t *testing.T) {
+	tests := []struct {
+		program  string
+		expected bool
+	}{
+		{program: "aider", expected: true},
+		{program: "aider --model sonnet", expected: true},
+		{program: "/usr/local/bin/gemini", expected: true},
+		{program: "gemini --yolo", expected: true},
+		{program: "claude --model gemini", expected: false},
+		{program: "claude --append-system-prompt aider", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.program, func(t *testing.T) {
+			require.Equal(t, tt.expected, NewTmuxSession("test", tt.program).isAiderOrGemini())
+		})
+	}
+}
+
+func TestIsClaude(t *testing.T) {
+	tests := []struct {
+		program  string
+		expected bool
+	}{
+		{program: "claude", expected: true},
+		{program: "claude --model opus", expected: true},
+		{program: "/usr/local/bin/claude --dangerously-skip-permissions", expected: true},
+		{program: "aider --model claude", expected: false},
+		{program: "gemini", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.program, func(t *testing.T) {
+			require.Equal(t, tt.expected, NewTmuxSession("test", tt.program).isClaude())
+		})
+	}
+}
+
+func TestSanitizeSessionName(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		expected string
+	}{
+		{name: "plain title is unchanged", title: "feature-1", expected: "feature-1"},
+		{name: "whitespace is removed", title: "my app", expected: `^myapp_[0-9a-f]{6}$`},
+		{name: "dots and colons are replaced", title: "v1.2:fix", expected: `^v1_2_fix_[0-9a-f]{6}$`},
+		{name: "dev suffix is reserved for dev servers", title: "web_dev", expected: `^web_dev_[0-9a-f]{6}$`},
+		{name: "empty title", title: "", expected: `^_[0-9a-f]{6}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := SanitizeSessionName(tt.title)
+			require.Regexp(t, tt.expected, name)
+			require.NotContains(t, name, ".")
+			require.NotContains(t, name, ":")
+		})
+	}
+
+	t.Run("titles that sanitize to the same string stay unique", func(t *testing.T) {
+		titles := []string{"myapp", "my app", "my  app", "my\tapp", "m y a p p"}
+		seen := make(map[string]string)
+		for _, title := range titles {
+			name := SanitizeSessionName(title)
+			require.NotContains(t, seen, name, "%q collides with %q", title, seen[name])
+			seen[name] = title
+		}
+	})
+
+	t.Run("dev server sessions don't collide with agent sessions", func(t *testing.T) {
+		require.NotEqual(t, DevServerSessionName("web"), SanitizeSessionName("web_dev"))
+		require.NotEqual(t, DevServerSessionName("web"), SanitizeSessionName(DevServerSessionName("web")))
+	})
+}
+
+func TestMigrateLegacyName(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	tests := []struct {
+		name           string
+		title          string
+		sessions       map[string]bool
+		expectedRename string
+	}{
+		{
+			name:           "renames session with the legacy name",
+			title:          "my app",
+			sessions:       map[string]bool{"claudesquad_myapp": true},
+			expectedRename: "tmux rename-session -t claudesquad_myapp " + TmuxPrefix + SanitizeSessionName("my app"),
+		},
+		{
+			name:     "skips titles whose name didn't change",
+			title:    "myapp",
+			sessions: map[string]bool{"claudesquad_myapp": true},
+		},
+		{
+			name:     "skips when there is no legacy session",
+			title:    "my app",
+			sessions: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranCmds []string
+			cmdExec := cmd_test.MockCmdExec{
+				RunFunc: func(cmd *exec.Cmd) error {
+					ranCmds = append(ranCmds, cmd2.ToString(cmd))
+					if strings.Contains(cmd.String(), "has-session") {
+						name := strings.TrimPrefix(cmd.Args[len(cmd.Args)-1], "-t=")
+						if !tt.sessions[name] {
+							return fmt.Errorf("session not found")
+						}
+					}
+					return nil
+				},
+				OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+					return nil, nil
+				},
+			}
+
+			session := NewTmuxSessionWithDeps(tt.title, "program", NewMockPtyFactory(t), cmdExec)
+			require.NoError(t, session.MigrateLegacyName())
+
+			var renames []string
+			for _, c := range ranCmds {
+				if strings.Contains(c, "rename-session") {
+					renames = append(renames, c)
+				}
+			}
+			if tt.expectedRename == "" {
+				require.Empty(t, renames)
+			} else {
+				require.Equal(t, []string{tt.expectedRename}, renames)
+			}
+		})
+	}
+}
+
+func TestSetTmuxPrefix(t *testing.T) {
+	defer SetTmuxPrefix(config.DefaultTmuxPrefix)
+
+	SetTmuxPrefix("my proj.")
+	require.Equal(t, "myproj_", TmuxPrefix)
+	require.Equal(t, "myproj_asdf", NewTmuxSession("asdf", "program").sanitizedName)
+
+	SetTmuxPrefix("")
+	require.Equal(t, config.DefaultTmuxPrefix, TmuxPrefix)
+}
+
+func TestMigrateFromPrefix(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+	defer SetTmuxPrefix(config.DefaultTmuxPrefix)
+	SetTmuxPrefix("custom_")
+
+	tests := []struct {
+		name           string
+		sessions       map[string]bool
+		expectedRename bool
+	}{
+		{
+			name:           "renames session under old prefix",
+			sessions:       map[string]bool{"claudesquad_asdf": true},
+			expectedRename: true,
+		},
+		{
+			name:           "skips when session exists under new prefix",
+			sessions:       map[string]bool{"claudesquad_asdf": true, "custom_asdf": true},
+			expectedRename: false,
+		},
+		{
+			name:           "skips when there is no old session",
+			sessions:       map[string]bool{},
+			expectedRename: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranCmds []string
+			cmdExec := cmd_test.MockCmdExec{
+				RunFunc: func(cmd *exec.Cmd) error {
+					ranCmds = append(ranCmds, cmd2.ToString(cmd))
+					if strings.Contains(cmd.String(), "has-session") {
+						name := strings.TrimPrefix(cmd.Args[len(cmd.Args)-1], "-t=")
+						if !tt.sessions[name] {
+							return fmt.Errorf("session not found")
+						}
+					}
+					return nil
+				},
+				OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+					return nil, nil
+				},
+			}
+
+			session := NewTmuxSessionWithDeps("asdf", "program", NewMockPtyFactory(t), cmdExec)
+			require.NoError(t, session.MigrateFromPrefix(config.DefaultTmuxPrefix))
+
+			renameCmd := "tmux rename-session -t claudesquad_asdf custom_asdf"
+			if tt.expectedRename {
+				require.Contains(t, ranCmds, renameCmd)
+			} else {
+				require.NotContains(t, ranCmds, renameCmd)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name         string
+		width        int
+		height       int
+		expectErr    bool
+		expectedCmds []string
+	}{
+		{
+			name:   "resizes the window and restores automatic sizing",
+			width:  120,
+			height: 40,
+			expectedCmds: []string{
+				"tmux resize-window -t claudesquad_asdf -x 120 -y 40",
+				"tmux set-option -w -u -t claudesquad_asdf window-size",
+			},
+		},
+		{
+			name:      "rejects an empty size",
+			width:     0,
+			height:    40,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranCmds []string
+			cmdExec := cmd_test.MockCmdExec{
+				RunFunc: func(cmd *exec.Cmd) error {
+					ranCmds = append(ranCmds, cmd2.ToString(cmd))
+					return nil
+				},
+				OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+					return nil, nil
+				},
+			}
+
+			session := NewTmuxSessionWithDeps("asdf", "program", NewMockPtyFactory(t), cmdExec)
+			err := session.Resize(tt.width, tt.height)
+			if tt.expectErr {
+				require.Error(t, err)
+				require.Empty(t, ranCmds)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedCmds, ranCmds)
+		})
+	}
 }
 
 func TestStartTmuxSession(t *testing.T) {
@@ -86,3 +355,215 @@ func TestStartTmuxSession(t *testing.T) {
 	_, err = ptyFactory.files[1].Stat()
 	require.NoError(t, err)
 }
+
+func TestStartTmuxSessionWithLaunchCommand(t *testing.T) {
+	ptyFactory := NewMockPtyFactory(t)
+
+	created := false
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			if strings.Contains(cmd.String(), "has-session") && !created {
+				created = true
+				return fmt.Errorf("session already exists")
+			}
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte("output"), nil
+		},
+	}
+
+	workdir := t.TempDir()
+	session := newTmuxSession("test-session", "claude", ptyFactory, cmdExec)
+	session.SetLaunchCommand("docker run --rm -it agent claude")
+
+	require.NoError(t, session.Start(workdir))
+	require.Equal(t, fmt.Sprintf("tmux new-session -d -s claudesquad_test-session -c %s docker run --rm -it agent claude", workdir),
+		cmd2.ToString(ptyFactory.cmds[0]))
+	// The program, not the launch command, identifies the agent.
+	require.True(t, session.isClaude())
+}
+
+func TestProgramPatterns(t *testing.T) {
+	patterns := ProgramPatterns{
+		Prompt:  regexp.MustCompile(`Apply edit\? \(y/n\)`),
+		Working: regexp.MustCompile(`Thinking…`),
+	}
+	tests := []struct {
+		name            string
+		patterns        ProgramPatterns
+		content         string
+		changed         bool
+		expectedUpdated bool
+		expectedPrompt  bool
+	}{
+		{name: "working while the screen is unchanged", patterns: patterns, content: "Thinking…", expectedUpdated: true},
+		{name: "ready while the screen changes", patterns: patterns, content: "> ", changed: true},
+		{name: "waiting for confirmation", patterns: patterns, content: "Apply edit? (y/n)", expectedPrompt: true},
+		{name: "no patterns keep the built-in results", content: "Thinking…", changed: true, expectedUpdated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, hasPrompt := tt.patterns.apply(tt.content, tt.changed, false)
+			require.Equal(t, tt.expectedUpdated, updated)
+			require.Equal(t, tt.expectedPrompt, hasPrompt)
+		})
+	}
+}
+
+func TestCompileProgramPatterns(t *testing.T) {
+	patterns := CompileProgramPatterns(map[string]config.ProgramPatterns{
+		"aider": {Prompt: `\(Y\)es/\(N\)o`, Working: `Waiting for`},
+		"codex": {Prompt: `(unclosed`, Working: `Working`},
+	})
+	require.Len(t, patterns, 2)
+	require.True(t, patterns["aider"].Prompt.MatchString("Edit file? (Y)es/(N)o"))
+	require.True(t, patterns["aider"].Working.MatchString("Waiting for model"))
+	require.Nil(t, patterns["codex"].Prompt, "invalid patterns fall back to the built-in detection")
+	require.NotNil(t, patterns["codex"].Working)
+}
+
+func TestProgramName(t *testing.T) {
+	require.Equal(t, "claude", programName("claude"))
+	require.Equal(t, "aider", programName(" /usr/local/bin/aider --model sonnet"))
+}
+
+func TestSendKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		keys         string
+		expectedArgs []string
+	}{
+		{
+			name:         "semicolons and quotes",
+			keys:         `echo "it's"; ls -a; printf '%s' x`,
+			expectedArgs: []string{`echo "it's"; ls -a; printf '%s' x`},
+		},
+		{
+			name:         "key names are typed as words",
+			keys:         "Enter C-c Escape",
+			expectedArgs: []string{"Enter C-c Escape"},
+		},
+		{
+			name:         "trailing semicolon is escaped",
+			keys:         "run the tests;",
+			expectedArgs: []string{`run the tests\;`},
+		},
+		{
+			name:         "options are not parsed",
+			keys:         "-t other",
+			expectedArgs: []string{"-t other"},
+		},
+		{
+			name: "nothing to send",
+			keys: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []string
+			cmdExec := cmd_test.MockCmdExec{
+				RunFunc: func(cmd *exec.Cmd) error {
+					require.Equal(t, []string{"tmux", "send-keys", "-t", "claudesquad_asdf", "-l", "--"}, cmd.Args[:6])
+					sent = append(sent, cmd.Args[6])
+					return nil
+				},
+				OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+					return nil, nil
+				},
+			}
+
+			session := NewTmuxSessionWithDeps("asdf", "program", NewMockPtyFactory(t), cmdExec)
+			require.NoError(t, session.SendKeys(tt.keys))
+			require.Equal(t, tt.expectedArgs, sent)
+		})
+	}
+}
+
+func TestSendKeysChunksLongText(t *testing.T) {
+	var sent []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			sent = append(sent, cmd.Args[len(cmd.Args)-1])
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return nil, nil
+		},
+	}
+
+	// Multi-byte characters straddle the chunk boundary.
+	keys := "a" + strings.Repeat("é", sendKeysChunkSize)
+	session := NewTmuxSessionWithDeps("asdf", "program", NewMockPtyFactory(t), cmdExec)
+	require.NoError(t, session.SendKeys(keys))
+	require.Greater(t, len(sent), 1)
+	for _, chunk := range sent {
+		require.LessOrEqual(t, len(chunk), sendKeysChunkSize)
+		require.True(t, utf8.ValidString(chunk))
+	}
+	require.Equal(t, keys, strings.Join(sent, ""))
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	args, err := splitCommandLine(`send-keys 'npm run "dev"' "it's \"here\"" a\ b  Enter`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"send-keys", `npm run "dev"`, `it's "here"`, "a b", "Enter"}, args)
+
+	args, err = splitCommandLine(`  split-window -h ''  `)
+	require.NoError(t, err)
+	require.Equal(t, []string{"split-window", "-h", ""}, args)
+
+	_, err = splitCommandLine(`send-keys 'oops`)
+	require.Error(t, err)
+}
+
+func TestRunStartupCommands(t *testing.T) {
+	var ran []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			ran = append(ran, cmd2.ToString(cmd))
+			if strings.Contains(cmd.String(), "bogus") {
+				return fmt.Errorf("unknown command")
+			}
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return []byte("%3\n"), nil
+		},
+	}
+	session := newTmuxSession("test-session", "claude", NewMockPtyFactory(t), cmdExec)
+
+	err := session.RunStartupCommands([]string{
+		"split-window -h",
+		"send-keys -t claudesquad_test-session:0.1 'git status' Enter",
+		"bogus",
+	})
+	require.ErrorContains(t, err, `"bogus" failed`)
+	require.Equal(t, []string{
+		"tmux split-window -t claudesquad_test-session -h",
+		"tmux send-keys -t claudesquad_test-session:0.1 git status Enter",
+		"tmux bogus -t claudesquad_test-session",
+		"tmux select-window -t %3",
+		"tmux select-pane -t %3",
+	}, ran)
+}
+
+func TestRename(t *testing.T) {
+	var ranCmds []string
+	cmdExec := cmd_test.MockCmdExec{
+		RunFunc: func(cmd *exec.Cmd) error {
+			ranCmds = append(ranCmds, cmd2.ToString(cmd))
+			return nil
+		},
+		OutputFunc: func(cmd *exec.Cmd) ([]byte, error) {
+			return nil, nil
+		},
+	}
+
+	session := NewTmuxSessionWithDeps("my app-restarting", "program", NewMockPtyFactory(t), cmdExec)
+	require.NoError(t, session.Rename("my app"))
+	require.Equal(t, []string{"tmux rename-session -t " + TmuxPrefix + SanitizeSessionName("my app-restarting") + " " + TmuxPrefix + SanitizeSessionName("my app")}, ranCmds)
+	require.Equal(t, TmuxPrefix+SanitizeSessionName("my app"), session.sanitizedName)
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"claude-squad/keys"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// cheatSheetKeys are the key bindings listed in the cheat sheet, in order.
+var cheatSheetKeys = []keys.KeyName{
+	keys.KeyNew, keys.KeyPrompt, keys.KeyEnter, keys.KeySubmit, keys.KeyCheckout, keys.KeyResume,
+	keys.KeyKill, keys.KeyUndo, keys.KeyQuickSwitch, keys.KeyTab, keys.KeyDevServerStart,
+	keys.KeyDevServerStop, keys.KeyDetails, keys.KeyCompactList, keys.KeyCheatSheet, keys.KeyHelp,
+	keys.KeyQuit,
+}
+
+// cheatSheetKeyText returns all the keys bound to name in keys.GlobalKeyStringsMap, e.g. "enter/o".
+func cheatSheetKeyText(name keys.KeyName) string {
+	var bound []string
+	for k, n := range keys.GlobalKeyStringsMap {
+		if n == name {
+			bound = append(bound, k)
+		}
+	}
+	sort.Strings(bound)
+	return strings.Join(bound, "/")
+}
+
+// cheatSheetLines renders the cheat sheet wrapped to width. The binding of keyDown is underlined.
+func cheatSheetLines(width int, keyDown keys.KeyName) []string {
+	var lines []string
+	var line strings.Builder
+	lineWidth := 0
+	for _, name := range cheatSheetKeys {
+		text := cheatSheetKeyText(name)
+		if text == "" {
+			continue
+		}
+		desc := keys.GlobalkeyBindings[name].Help().Desc
+		localKeyStyle, localDescStyle := keyStyle, descStyle
+		if keyDown == name {
+			localKeyStyle = localKeyStyle.Underline(true)
+			localDescStyle = localDescStyle.Underline(true)
+		}
+		item := localKeyStyle.Render(text) + " " + localDescStyle.Render(desc)
+		itemWidth := lipgloss.Width(item)
+
+		if lineWidth > 0 && lineWidth+lipgloss.Width(separator)+itemWidth > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth = 0
+		}
+		if lineWidth > 0 {
+			line.WriteString(sepStyle.Render(separator))
+			lineWidth += lipgloss.Width(separator)
+		}
+		line.WriteString(item)
+		lineWidth += itemWidth
+	}
+	if lineWidth > 0 {
+		lines = append(lines, line.String())
+	}
+	return lines
+}
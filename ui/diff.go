@@ -1,9 +1,11 @@
 package ui
 
 import (
+	"claude-squad/keys"
 	"claude-squad/session"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
@@ -13,14 +15,17 @@ var (
 	AdditionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#22c55e"))
 	DeletionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ef4444"))
 	HunkStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#0ea5e9"))
+	StaleStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#f59e0b"))
 )
 
 type DiffPane struct {
 	viewport viewport.Model
 	diff     string
 	stats    string
-	width    int
-	height   int
+	// file narrows the diff to one changed file. Empty shows all files.
+	file   string
+	width  int
+	height int
 }
 
 func NewDiffPane() *DiffPane {
@@ -40,6 +45,17 @@ func (d *DiffPane) SetSize(width, height int) {
 	}
 }
 
+// SetFile narrows the diff to the changes of path. An empty path shows all files. It takes effect on
+// the next SetDiff.
+func (d *DiffPane) SetFile(path string) {
+	d.file = path
+}
+
+// File returns the file the diff is narrowed to, or an empty string if it shows all files.
+func (d *DiffPane) File() string {
+	return d.file
+}
+
 func (d *DiffPane) SetDiff(instance *session.Instance) {
 	centeredFallbackMessage := lipgloss.Place(
 		d.width,
@@ -81,19 +97,57 @@ func (d *DiffPane) SetDiff(instance *session.Instance) {
 		return
 	}
 
+	note := staleNote(instance)
 	if stats.IsEmpty() {
 		d.stats = ""
 		d.diff = ""
+		if note != "" {
+			centeredFallbackMessage = lipgloss.Place(d.width, d.height, lipgloss.Center, lipgloss.Center,
+				lipgloss.JoinVertical(lipgloss.Center, "No changes", StaleStyle.Render(note)))
+		}
 		d.viewport.SetContent(centeredFallbackMessage)
 	} else {
-		additions := AdditionStyle.Render(fmt.Sprintf("%d additions(+)", stats.Added))
-		deletions := DeletionStyle.Render(fmt.Sprintf("%d deletions(-)", stats.Removed))
+		added, removed, content := stats.Added, stats.Removed, stats.Content
+		if d.file != "" {
+			if fileContent, err := instance.FileDiff(d.file); err == nil && fileContent != "" {
+				added, removed = stats.FileStats(d.file)
+				content = fileContent
+			} else {
+				// The file has no changes anymore, e.g. after a reset.
+				d.file = ""
+			}
+		}
+		additions := AdditionStyle.Render(fmt.Sprintf("%d additions(+)", added))
+		deletions := DeletionStyle.Render(fmt.Sprintf("%d deletions(-)", removed))
 		d.stats = lipgloss.JoinHorizontal(lipgloss.Center, additions, " ", deletions)
-		d.diff = colorizeDiff(stats.Content)
+		if d.file != "" {
+			d.stats = lipgloss.JoinHorizontal(lipgloss.Center, d.file, "  ", d.stats)
+		}
+		if note != "" {
+			d.stats = lipgloss.JoinHorizontal(lipgloss.Center, d.stats, "  ", StaleStyle.Render(note))
+		}
+		d.diff = colorizeDiff(content)
 		d.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, d.stats, d.diff))
 	}
 }
 
+// staleNote returns a warning for the diff stats of instance if they may be outdated, or an empty
+// string if they're current.
+func staleNote(instance *session.Instance) string {
+	age, stale := instance.DiffStatsAge()
+	if !stale {
+		return ""
+	}
+	if instance.Paused() {
+		return "paused, the diff may be outdated"
+	}
+	refresh := keys.GlobalkeyBindings[keys.KeyRefreshDiff].Help().Key
+	if age == 0 {
+		return fmt.Sprintf("the diff may be outdated, press %s to refresh", refresh)
+	}
+	return fmt.Sprintf("computed %s ago, press %s to refresh", age.Round(time.Second), refresh)
+}
+
 func (d *DiffPane) String() string {
 	return d.viewport.View()
 }
@@ -108,6 +162,34 @@ func (d *DiffPane) ScrollDown() {
 	d.viewport.LineDown(1)
 }
 
+// ScrollPageUp scrolls the viewport up by a page, or half a page when half is true
+func (d *DiffPane) ScrollPageUp(half bool) {
+	if half {
+		d.viewport.HalfViewUp()
+	} else {
+		d.viewport.ViewUp()
+	}
+}
+
+// ScrollPageDown scrolls the viewport down by a page, or half a page when half is true
+func (d *DiffPane) ScrollPageDown(half bool) {
+	if half {
+		d.viewport.HalfViewDown()
+	} else {
+		d.viewport.ViewDown()
+	}
+}
+
+// ScrollToTop jumps to the start of the diff
+func (d *DiffPane) ScrollToTop() {
+	d.viewport.GotoTop()
+}
+
+// ScrollToBottom jumps to the end of the diff
+func (d *DiffPane) ScrollToBottom() {
+	d.viewport.GotoBottom()
+}
+
 func colorizeDiff(diff string) string {
 	var coloredOutput strings.Builder
 
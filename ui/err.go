@@ -10,6 +10,10 @@ import (
 type ErrBox struct {
 	height, width int
 	err           error
+	// info is a non-error message shown when there is no error
+	info string
+	// pinned is true if the error stays until a key is pressed, which the error box points out.
+	pinned bool
 }
 
 var errStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
@@ -17,16 +21,47 @@ var errStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
 	Dark:  "#FF0000",
 })
 
+var infoStyle = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{
+	Light: "#B8860B",
+	Dark:  "#FFD700",
+})
+
+// pinnedErrorHint follows errors that stay until a key is pressed.
+const pinnedErrorHint = " (press any key)"
+
 func NewErrBox() *ErrBox {
 	return &ErrBox{}
 }
 
 func (e *ErrBox) SetError(err error) {
 	e.err = err
+	e.pinned = false
+}
+
+// SetPinnedError shows an error that stays until a key is pressed.
+func (e *ErrBox) SetPinnedError(err error) {
+	e.err = err
+	e.pinned = true
+}
+
+// Pinned returns true if the error shown stays until a key is pressed.
+func (e *ErrBox) Pinned() bool {
+	return e.err != nil && e.pinned
+}
+
+// SetInfo shows a non-error message, such as a toast, until it is cleared. Errors take precedence.
+func (e *ErrBox) SetInfo(info string) {
+	e.info = info
+}
+
+// ClearInfo removes the info message.
+func (e *ErrBox) ClearInfo() {
+	e.info = ""
 }
 
 func (e *ErrBox) Clear() {
 	e.err = nil
+	e.pinned = false
 }
 
 func (e *ErrBox) SetSize(width, height int) {
@@ -35,14 +70,26 @@ func (e *ErrBox) SetSize(width, height int) {
 }
 
 func (e *ErrBox) String() string {
-	var err string
+	var err, hint string
+	style := errStyle
 	if e.err != nil {
 		err = e.err.Error()
+		if e.pinned {
+			hint = pinnedErrorHint
+		}
+	} else if e.info != "" {
+		err = e.info
+		style = infoStyle
+	}
+	if err != "" {
 		lines := strings.Split(err, "\n")
 		err = strings.Join(lines, "//")
-		if runewidth.StringWidth(err) > e.width-3 && e.width-3 >= 0 {
-			err = runewidth.Truncate(err, e.width-3, "...")
+		// Keep the hint of a pinned error visible when the error is cut.
+		avail := e.width - 3 - runewidth.StringWidth(hint)
+		if runewidth.StringWidth(err) > avail && avail >= 0 {
+			err = runewidth.Truncate(err, avail, "...")
 		}
+		err += hint
 	}
-	return lipgloss.Place(e.width, e.height, lipgloss.Center, lipgloss.Center, errStyle.Render(err))
+	return lipgloss.Place(e.width, e.height, lipgloss.Center, lipgloss.Center, style.Render(err))
 }
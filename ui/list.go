@@ -5,6 +5,7 @@ import (
 	"claude-squad/session"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -14,6 +15,8 @@ import (
 
 const readyIcon = "● "
 const pausedIcon = "⏸ "
+const markedIcon = "✓"
+const pinnedIcon = "⚑ "
 
 var readyStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#51bd73", Dark: "#51bd73"})
@@ -49,6 +52,19 @@ var mainTitle = lipgloss.NewStyle().
 	Background(lipgloss.Color("62")).
 	Foreground(lipgloss.Color("230"))
 
+// unfocusedMainTitle, unfocusedSelectedTitleStyle and unfocusedSelectedDescStyle replace their
+// highlighted counterparts while the list doesn't have the focus.
+var unfocusedMainTitle = mainTitle.
+	Background(lipgloss.AdaptiveColor{Light: "#C9C5C5", Dark: "#4A4A4A"})
+
+var unfocusedSelectedTitleStyle = selectedTitleStyle.
+	Background(lipgloss.AdaptiveColor{Light: "#ECECEC", Dark: "#3A3A3A"}).
+	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
+
+var unfocusedSelectedDescStyle = selectedDescStyle.
+	Background(lipgloss.AdaptiveColor{Light: "#ECECEC", Dark: "#3A3A3A"}).
+	Foreground(lipgloss.AdaptiveColor{Light: "#7A7474", Dark: "#9C9494"})
+
 var autoYesStyle = lipgloss.NewStyle().
 	Background(lipgloss.Color("#dde4f0")).
 	Foreground(lipgloss.Color("#1a1a1a"))
@@ -62,12 +78,50 @@ var devServerStoppedStyle = lipgloss.NewStyle().
 var devServerCrashedStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#de613e", Dark: "#de613e"})
 
+const conflictText = "[CONFLICT]"
+
+var conflictStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.AdaptiveColor{Light: "#de613e", Dark: "#de613e"})
+
+const instanceAutoYesText = "[AUTO-YES]"
+
+const autoYesBlockedText = "[REVIEW]"
+
+var instanceAutoYesStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.AdaptiveColor{Light: "#d18b00", Dark: "#ffb000"})
+
+var divergenceStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.AdaptiveColor{Light: "#0087af", Dark: "#5fafd7"})
+
+const recordingText = "[REC]"
+
+var recordingStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.AdaptiveColor{Light: "#e0245e", Dark: "#ff5f87"})
+
 type List struct {
 	items         []*session.Instance
 	selectedIdx   int
 	height, width int
 	renderer      *InstanceRenderer
 	autoyes       bool
+	// pausedOnly hides all instances that aren't paused
+	pausedOnly bool
+	// archiveView shows the archived instances instead of the active ones
+	archiveView bool
+	// collapsed holds the repo groups whose instances are hidden
+	collapsed map[string]bool
+	// marked holds the instances marked for batch actions. It is independent of the cursor.
+	marked map[*session.Instance]bool
+	// scrollOffset is the first row rendered when the list is taller than its height.
+	scrollOffset int
+	// compact renders each instance on a single line with only its title and status.
+	compact bool
+	// focused is true if the list gets the navigation keys, which is the default. The title and the
+	// selection are dimmed otherwise.
+	focused bool
 
 	// map of repo name to number of instances using it. Used to display the repo name only if there are
 	// multiple repos in play.
@@ -76,10 +130,13 @@ type List struct {
 
 func NewList(spinner *spinner.Model, autoYes bool) *List {
 	return &List{
-		items:    []*session.Instance{},
-		renderer: &InstanceRenderer{spinner: spinner},
-		repos:    make(map[string]int),
-		autoyes:  autoYes,
+		items:     []*session.Instance{},
+		renderer:  &InstanceRenderer{spinner: spinner},
+		repos:     make(map[string]int),
+		collapsed: make(map[string]bool),
+		marked:    make(map[*session.Instance]bool),
+		autoyes:   autoYes,
+		focused:   true,
 	}
 }
 
@@ -90,15 +147,15 @@ func (l *List) SetSize(width, height int) {
 	l.renderer.setWidth(width)
 }
 
-// SetSessionPreviewSize sets the height and width for the tmux sessions. This makes the stdout line have the correct
-// width and height.
+// SetSessionPreviewSize resizes the tmux sessions to the given height and width. This makes the stdout line have the
+// correct width and height.
 func (l *List) SetSessionPreviewSize(width, height int) (err error) {
 	for i, item := range l.items {
 		if !item.Started() || item.Paused() {
 			continue
 		}
 
-		if innerErr := item.SetPreviewSize(width, height); innerErr != nil {
+		if innerErr := item.Resize(width, height); innerErr != nil {
 			err = errors.Join(
 				err, fmt.Errorf("could not set preview size for instance %d: %v", i, innerErr))
 		}
@@ -110,10 +167,44 @@ func (l *List) NumInstances() int {
 	return len(l.items)
 }
 
+// NumActiveInstances returns the number of instances that aren't archived.
+func (l *List) NumActiveInstances() int {
+	n := 0
+	for _, item := range l.items {
+		if !item.Archived() {
+			n++
+		}
+	}
+	return n
+}
+
+// SetCompact switches between rendering each instance on a single line with only its title and
+// status, and the expanded rows with the branch line and all the other metadata.
+func (l *List) SetCompact(compact bool) {
+	l.compact = compact
+}
+
+// Compact returns true if instances are rendered on a single line.
+func (l *List) Compact() bool {
+	return l.compact
+}
+
+// SetFocused sets whether the list has the focus. An unfocused list dims its title and selection.
+func (l *List) SetFocused(focused bool) {
+	l.focused = focused
+}
+
+// Focused returns true if the list has the focus.
+func (l *List) Focused() bool {
+	return l.focused
+}
+
 // InstanceRenderer handles rendering of session.Instance objects
 type InstanceRenderer struct {
 	spinner *spinner.Model
 	width   int
+	// unfocused dims the selected instance because the list doesn't have the focus.
+	unfocused bool
 }
 
 func (r *InstanceRenderer) setWidth(width int) {
@@ -157,21 +248,39 @@ func getDevServerStatusText(instance *session.Instance) string {
 }
 
 func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, hasMultipleRepos bool) string {
+	return r.render(i, idx, selected, false, false, hasMultipleRepos)
+}
+
+// render renders an instance. Marked instances get a check mark in front of their number. Compact
+// instances only get their title line, without padding.
+func (r *InstanceRenderer) render(i *session.Instance, idx int, selected, marked, compact bool, hasMultipleRepos bool) string {
 	prefix := fmt.Sprintf(" %d. ", idx)
 	if idx >= 10 {
 		prefix = prefix[:len(prefix)-1]
 	}
+	if marked {
+		prefix = markedIcon + prefix[1:]
+	}
 	titleS := selectedTitleStyle
 	descS := selectedDescStyle
+	if r.unfocused {
+		titleS = unfocusedSelectedTitleStyle
+		descS = unfocusedSelectedDescStyle
+	}
 	if !selected {
-		titleS = titleStyle
+		// Tint the title so each instance is recognizable by color. The selected row keeps its
+		// highlight colors for contrast.
+		titleS = titleStyle.Foreground(lipgloss.Color(i.Color()))
 		descS = listDescStyle
 	}
+	if compact {
+		titleS = titleS.PaddingTop(0)
+	}
 
 	// add spinner next to title if it's running
 	var join string
 	switch i.Status {
-	case session.Running:
+	case session.Running, session.Loading:
 		join = fmt.Sprintf("%s ", r.spinner.View())
 	case session.Ready:
 		join = readyStyle.Render(readyIcon)
@@ -182,6 +291,9 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 
 	// Cut the title if it's too long
 	titleText := i.Title
+	if i.Pinned {
+		titleText = pinnedIcon + titleText
+	}
 	widthAvail := r.width - 3 - runewidth.StringWidth(prefix) - 1
 	if widthAvail > 0 && runewidth.StringWidth(titleText) > widthAvail {
 		titleText = runewidth.Truncate(titleText, widthAvail-3, "...")
@@ -192,6 +304,9 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 		" ",
 		join,
 	))
+	if compact {
+		return title
+	}
 
 	stat := i.GetDiffStats()
 
@@ -225,6 +340,39 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	// Use fixed width for diff stats to avoid layout issues
 	remainingWidth -= diffWidth
 
+	// Flag worktrees with unmerged paths so they stand out.
+	conflict := ""
+	if i.HasConflicts() {
+		conflict = conflictStyle.Background(descS.GetBackground()).Render(conflictText)
+		remainingWidth -= runewidth.StringWidth(conflictText)
+	}
+
+	// Make it obvious which instances will auto-confirm prompts.
+	autoYes := ""
+	if i.AutoYes {
+		text := instanceAutoYesText
+		if i.AutoYesBlockedBy() != "" {
+			// A deny pattern matched, so the prompt is waiting for manual review.
+			text = autoYesBlockedText
+		}
+		autoYes = instanceAutoYesStyle.Background(descS.GetBackground()).Render(text)
+		remainingWidth -= runewidth.StringWidth(text)
+	}
+
+	// Show how far the branch diverged once its base has moved on.
+	divergence := ""
+	if ahead, behind := i.BaseDivergence(); behind > 0 {
+		text := fmt.Sprintf("↑%d ↓%d ", ahead, behind)
+		divergence = divergenceStyle.Background(descS.GetBackground()).Render(text)
+		remainingWidth -= runewidth.StringWidth(text)
+	}
+
+	recording := ""
+	if i.IsRecording() {
+		recording = recordingStyle.Background(descS.GetBackground()).Render(recordingText)
+		remainingWidth -= runewidth.StringWidth(recordingText)
+	}
+
 	branch := i.Branch
 	if i.Started() && hasMultipleRepos {
 		repoName, err := i.RepoName()
@@ -256,7 +404,7 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 	}
 
 	devServerStatus := getDevServerStatusText(i)
-	branchLine := fmt.Sprintf("%s %s-%s%s%s%s", strings.Repeat(" ", len(prefix)), branchIcon, branch, spaces, diff, devServerStatus)
+	branchLine := fmt.Sprintf("%s %s-%s%s%s%s%s%s%s%s", strings.Repeat(" ", len(prefix)), branchIcon, branch, spaces, autoYes, recording, conflict, divergence, diff, devServerStatus)
 
 	// join title and subtitle
 	text := lipgloss.JoinVertical(
@@ -269,7 +417,16 @@ func (r *InstanceRenderer) Render(i *session.Instance, idx int, selected bool, h
 }
 
 func (l *List) String() string {
-	const titleText = " Instances "
+	titleText := " Instances "
+	if l.archiveView {
+		titleText = " Archived instances "
+	}
+	if l.pausedOnly {
+		titleText += "(paused only) "
+	}
+	if len(l.marked) > 0 {
+		titleText += fmt.Sprintf("(%d marked) ", len(l.marked))
+	}
 	const autoYesText = " auto-yes "
 
 	// Write the title.
@@ -280,12 +437,16 @@ func (l *List) String() string {
 	// Write title line
 	// add padding of 2 because the border on list items adds some extra characters
 	titleWidth := AdjustPreviewWidth(l.width) + 2
+	titleS := mainTitle
+	if !l.focused {
+		titleS = unfocusedMainTitle
+	}
 	if !l.autoyes {
 		b.WriteString(lipgloss.Place(
-			titleWidth, 1, lipgloss.Left, lipgloss.Bottom, mainTitle.Render(titleText)))
+			titleWidth, 1, lipgloss.Left, lipgloss.Bottom, titleS.Render(titleText)))
 	} else {
 		title := lipgloss.Place(
-			titleWidth/2, 1, lipgloss.Left, lipgloss.Bottom, mainTitle.Render(titleText))
+			titleWidth/2, 1, lipgloss.Left, lipgloss.Bottom, titleS.Render(titleText))
 		autoYes := lipgloss.Place(
 			titleWidth-(titleWidth/2), 1, lipgloss.Right, lipgloss.Bottom, autoYesStyle.Render(autoYesText))
 		b.WriteString(lipgloss.JoinHorizontal(
@@ -295,23 +456,324 @@ func (l *List) String() string {
 	b.WriteString("\n")
 	b.WriteString("\n")
 
-	// Render the list.
-	for i, item := range l.items {
-		b.WriteString(l.renderer.Render(item, i+1, i == l.selectedIdx, len(l.repos) > 1))
-		if i != len(l.items)-1 {
-			b.WriteString("\n\n")
+	// Render the list. The visible instances are numbered in the order they are shown, which is the
+	// order the number keys select them in, see SelectVisible.
+	l.ensureVisibleSelection()
+	l.renderer.unfocused = !l.focused
+	grouped := l.grouped()
+	if grouped {
+		// Leave room for the indentation under the group headers.
+		l.renderer.width -= len(groupIndent)
+		defer func() { l.renderer.width += len(groupIndent) }()
+	}
+	var rows []listRow
+	group, groupStarted := "", false
+	number := 0
+	for _, i := range l.displayOrder() {
+		item := l.items[i]
+		if grouped && (!groupStarted || repoGroup(item) != group) {
+			group, groupStarted = repoGroup(item), true
+			if header := l.renderGroupHeader(group); header != "" {
+				rows = append(rows, listRow{header: header, item: -1})
+			}
+		}
+		if l.isVisible(i) {
+			number++
+			rows = append(rows, listRow{item: i, number: number})
 		}
 	}
+
+	// Only render the rows that fit below the title, so large lists stay cheap to draw.
+	start, end := l.scrollWindow(rows, l.height-listTitleHeight)
+	for n, row := range rows[start:end] {
+		if n > 0 {
+			b.WriteString("\n" + strings.Repeat("\n", l.rowGap()))
+		}
+		if row.item < 0 {
+			b.WriteString(row.header)
+			continue
+		}
+		item := l.items[row.item]
+		// When grouped, the repo name is shown in the group header instead of on each instance.
+		rendered := l.renderer.render(item, row.number, row.item == l.selectedIdx, l.marked[item], l.compact, false)
+		if grouped {
+			rendered = groupIndent + strings.ReplaceAll(rendered, "\n", "\n"+groupIndent)
+		}
+		b.WriteString(rendered)
+	}
 	return lipgloss.Place(l.width, l.height, lipgloss.Left, lipgloss.Top, b.String())
 }
 
-// Down selects the next item in the list.
-func (l *List) Down() {
+// listTitleHeight is the number of lines above the first row: two blank lines, the title and
+// another blank line.
+const listTitleHeight = 4
+
+// listRow is a row of the rendered list: either a repo group header or the instance at index item.
+type listRow struct {
+	header string
+	// item is the index of the instance in List.items, or -1 for group headers.
+	item int
+	// number is the position of the instance among the visible instances, counting from 1.
+	number int
+}
+
+// rowHeight returns the number of lines row takes up when rendered.
+func (l *List) rowHeight(row listRow) int {
+	if row.item < 0 || l.compact {
+		return 1
+	}
+	// The title and branch lines, plus the padding of their styles.
+	return 2 + titleStyle.GetVerticalPadding() + listDescStyle.GetVerticalPadding()
+}
+
+// rowGap returns the number of blank lines between rows. Compact rows aren't separated.
+func (l *List) rowGap() int {
+	if l.compact {
+		return 0
+	}
+	return 1
+}
+
+// rowsHeight returns the number of lines rows take up, including the blank lines between rows.
+func (l *List) rowsHeight(rows []listRow) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	height := (len(rows) - 1) * l.rowGap()
+	for _, row := range rows {
+		height += l.rowHeight(row)
+	}
+	return height
+}
+
+// scrollWindow returns the range of rows to render in avail lines. The window scrolls just far
+// enough to keep the selected instance, and its group header when possible, in view. Everything is
+// rendered when the list has no size yet.
+func (l *List) scrollWindow(rows []listRow, avail int) (start, end int) {
+	if avail <= 0 || l.rowsHeight(rows) <= avail {
+		l.scrollOffset = 0
+		return 0, len(rows)
+	}
+
+	selected := slices.IndexFunc(rows, func(r listRow) bool { return r.item == l.selectedIdx })
+	if selected < 0 {
+		selected = 0
+	}
+	offset := min(l.scrollOffset, len(rows)-1)
+	if selected < offset {
+		offset = selected
+		if offset > 0 && rows[offset-1].item < 0 {
+			offset--
+		}
+	}
+	for offset < selected && l.rowsHeight(rows[offset:selected+1]) > avail {
+		offset++
+	}
+	// Don't leave empty space at the bottom, e.g. after instances were removed.
+	for offset > 0 && l.rowsHeight(rows[offset-1:]) <= avail {
+		offset--
+	}
+	l.scrollOffset = offset
+
+	end = offset + 1
+	for end < len(rows) && l.rowsHeight(rows[offset:end+1]) <= avail {
+		end++
+	}
+	return offset, end
+}
+
+// groupIndent indents instances under their repo group header.
+const groupIndent = "  "
+
+var groupHeaderStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.AdaptiveColor{Light: "#7D56F4", Dark: "#a68cff"})
+
+// repoGroup returns the repo group of an instance. Instances that haven't been started yet have no repo.
+func repoGroup(instance *session.Instance) string {
+	repoName, err := instance.RepoName()
+	if err != nil {
+		return ""
+	}
+	return repoName
+}
+
+// grouped returns true if instances are rendered under repo group headers, which is the case when they
+// span multiple repos.
+func (l *List) grouped() bool {
+	return len(l.repos) > 1
+}
+
+// displayOrder returns the item indexes in the order they are rendered. When grouped, instances are
+// ordered by repo, in order of each repo's first appearance, keeping their relative order.
+func (l *List) displayOrder() []int {
+	order := make([]int, 0, len(l.items))
+	if !l.grouped() {
+		for i := range l.items {
+			order = append(order, i)
+		}
+		return order
+	}
+
+	var groups []string
+	byGroup := make(map[string][]int)
+	for i, item := range l.items {
+		group := repoGroup(item)
+		if _, ok := byGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], i)
+	}
+	for _, group := range groups {
+		order = append(order, byGroup[group]...)
+	}
+	return order
+}
+
+// renderGroupHeader renders the header of a repo group, or an empty string if the view filter hides
+// all of its instances.
+func (l *List) renderGroupHeader(group string) string {
+	count := 0
+	for _, item := range l.items {
+		if repoGroup(item) == group && l.matchesFilter(item) {
+			count++
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+
+	name := group
+	if name == "" {
+		name = "(new)"
+	}
+	if l.collapsed[group] {
+		return groupHeaderStyle.Render(fmt.Sprintf(" ▸ %s (%d hidden)", name, count))
+	}
+	return groupHeaderStyle.Render(fmt.Sprintf(" ▾ %s (%d)", name, count))
+}
+
+// ToggleGroupCollapsed collapses or expands the repo group of the selected instance. The selection
+// moves to the nearest visible instance when its group is collapsed.
+func (l *List) ToggleGroupCollapsed() {
+	if len(l.items) == 0 || !l.grouped() {
+		return
+	}
+	group := repoGroup(l.items[l.selectedIdx])
+	if l.collapsed[group] {
+		delete(l.collapsed, group)
+	} else {
+		l.collapsed[group] = true
+	}
+	l.ensureVisibleSelection()
+}
+
+// ExpandAllGroups expands every collapsed repo group.
+func (l *List) ExpandAllGroups() {
+	l.collapsed = make(map[string]bool)
+	l.ensureVisibleSelection()
+}
+
+// visibleOrder returns the indexes of the visible items in the order they are rendered.
+func (l *List) visibleOrder() []int {
+	var order []int
+	for _, i := range l.displayOrder() {
+		if l.isVisible(i) {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// SelectVisible selects the visible instance shown with the number n, counting from 1. Noop if
+// fewer instances are visible.
+func (l *List) SelectVisible(n int) {
+	order := l.visibleOrder()
+	if n < 1 || n > len(order) {
+		return
+	}
+	l.selectedIdx = order[n-1]
+}
+
+// isVisible returns true if the item at idx passes the current view filter and isn't in a collapsed
+// repo group.
+func (l *List) isVisible(idx int) bool {
+	item := l.items[idx]
+	if !l.matchesFilter(item) {
+		return false
+	}
+	return !l.grouped() || !l.collapsed[repoGroup(item)]
+}
+
+// matchesFilter returns true if item is shown by the current view: the archive view or the active
+// instances, optionally only the paused ones.
+func (l *List) matchesFilter(item *session.Instance) bool {
+	if item.Archived() != l.archiveView {
+		return false
+	}
+	return !l.pausedOnly || item.Paused()
+}
+
+// ensureVisibleSelection moves the selection to the nearest visible item if the selected one is hidden
+// by the view filter, e.g. because it was resumed while only paused instances are shown.
+func (l *List) ensureVisibleSelection() {
 	if len(l.items) == 0 {
 		return
 	}
-	if l.selectedIdx < len(l.items)-1 {
-		l.selectedIdx++
+	if l.selectedIdx >= len(l.items) {
+		l.selectedIdx = len(l.items) - 1
+	}
+	if l.isVisible(l.selectedIdx) {
+		return
+	}
+	order := l.displayOrder()
+	pos := slices.Index(order, l.selectedIdx)
+	for _, i := range order[pos+1:] {
+		if l.isVisible(i) {
+			l.selectedIdx = i
+			return
+		}
+	}
+	for j := pos - 1; j >= 0; j-- {
+		if l.isVisible(order[j]) {
+			l.selectedIdx = order[j]
+			return
+		}
+	}
+}
+
+// TogglePausedOnly toggles the view filter that only shows paused instances. The filter doesn't
+// modify the instances in the list.
+func (l *List) TogglePausedOnly() {
+	l.pausedOnly = !l.pausedOnly
+	l.ensureVisibleSelection()
+}
+
+// PausedOnly returns true if only paused instances are shown.
+func (l *List) PausedOnly() bool {
+	return l.pausedOnly
+}
+
+// SetArchiveView switches between showing the active instances and showing the archived ones.
+func (l *List) SetArchiveView(archiveView bool) {
+	l.archiveView = archiveView
+	l.ensureVisibleSelection()
+}
+
+// ArchiveView returns true if the archived instances are shown instead of the active ones.
+func (l *List) ArchiveView() bool {
+	return l.archiveView
+}
+
+// Down selects the next visible item in the list.
+func (l *List) Down() {
+	order := l.displayOrder()
+	pos := slices.Index(order, l.selectedIdx)
+	for _, i := range order[pos+1:] {
+		if l.isVisible(i) {
+			l.selectedIdx = i
+			return
+		}
 	}
 }
 
@@ -327,10 +789,43 @@ func (l *List) Kill() {
 		log.ErrorLog.Printf("could not kill instance: %v", err)
 	}
 
-	// If you delete the last one in the list, select the previous one.
-	if l.selectedIdx == len(l.items)-1 {
-		defer l.Up()
+	l.RemoveSelected()
+}
+
+// RemoveSelected removes the selected instance from the list without killing it and returns it,
+// or nil if the list is empty.
+func (l *List) RemoveSelected() *session.Instance {
+	if len(l.items) == 0 {
+		return nil
 	}
+	return l.removeAt(l.selectedIdx)
+}
+
+// RemoveInstance removes instance from the list without killing it, keeping the selection on the
+// same instance if it's another one. It returns false if instance isn't in the list.
+func (l *List) RemoveInstance(instance *session.Instance) bool {
+	idx := slices.Index(l.items, instance)
+	if idx < 0 {
+		return false
+	}
+	if idx < l.selectedIdx {
+		l.selectedIdx--
+	}
+	l.removeAt(idx)
+	return true
+}
+
+// removeAt removes the instance at idx and returns it.
+func (l *List) removeAt(idx int) *session.Instance {
+	targetInstance := l.items[idx]
+
+	// If you delete the last one in the list, select the previous one.
+	defer func() {
+		if l.selectedIdx >= len(l.items) && l.selectedIdx > 0 {
+			l.selectedIdx = len(l.items) - 1
+		}
+		l.ensureVisibleSelection()
+	}()
 
 	// Unregister the reponame.
 	repoName, err := targetInstance.RepoName()
@@ -340,8 +835,11 @@ func (l *List) Kill() {
 		l.rmRepo(repoName)
 	}
 
+	delete(l.marked, targetInstance)
+
 	// Since there's items after this, the selectedIdx can stay the same.
-	l.items = append(l.items[:l.selectedIdx], l.items[l.selectedIdx+1:]...)
+	l.items = append(l.items[:idx], l.items[idx+1:]...)
+	return targetInstance
 }
 
 func (l *List) Attach() (chan struct{}, error) {
@@ -349,13 +847,15 @@ func (l *List) Attach() (chan struct{}, error) {
 	return targetInstance.Attach()
 }
 
-// Up selects the prev item in the list.
+// Up selects the prev visible item in the list.
 func (l *List) Up() {
-	if len(l.items) == 0 {
-		return
-	}
-	if l.selectedIdx > 0 {
-		l.selectedIdx--
+	order := l.displayOrder()
+	pos := slices.Index(order, l.selectedIdx)
+	for j := pos - 1; j >= 0; j-- {
+		if l.isVisible(order[j]) {
+			l.selectedIdx = order[j]
+			return
+		}
 	}
 }
 
@@ -380,10 +880,20 @@ func (l *List) rmRepo(repo string) {
 // AddInstance adds a new instance to the list. It returns a finalizer function that should be called when the instance
 // is started. If the instance was restored from storage or is paused, you can call the finalizer immediately.
 // When creating a new one and entering the name, you want to call the finalizer once the name is done.
+// The finalizer is idempotent: only the first successful call registers the instance's repo.
 func (l *List) AddInstance(instance *session.Instance) (finalize func()) {
-	l.items = append(l.items, instance)
+	if instance.Pinned {
+		// Keep pinned instances at the top, e.g. when they are restored from storage.
+		l.items = slices.Insert(l.items, l.numPinned(), instance)
+	} else {
+		l.items = append(l.items, instance)
+	}
+	registered := false
 	// The finalizer registers the repo name once the instance is started.
 	return func() {
+		if registered {
+			return
+		}
 		repoName, err := instance.RepoName()
 		if err != nil {
 			log.ErrorLog.Printf("could not get repo name: %v", err)
@@ -391,25 +901,97 @@ func (l *List) AddInstance(instance *session.Instance) (finalize func()) {
 		}
 
 		l.addRepo(repoName)
+		registered = true
 	}
 }
 
-// GetSelectedInstance returns the currently selected instance
+// GetSelectedInstance returns the currently selected instance, or nil if no instance is visible
 func (l *List) GetSelectedInstance() *session.Instance {
 	if len(l.items) == 0 {
 		return nil
 	}
+	l.ensureVisibleSelection()
+	if !l.isVisible(l.selectedIdx) {
+		return nil
+	}
 	return l.items[l.selectedIdx]
 }
 
-// SetSelectedInstance sets the selected index. Noop if the index is out of bounds.
+// SetSelectedInstance sets the selected index. Noop if the index is out of bounds or hidden by the
+// view filter.
 func (l *List) SetSelectedInstance(idx int) {
-	if idx >= len(l.items) {
+	if idx >= len(l.items) || !l.isVisible(idx) {
 		return
 	}
 	l.selectedIdx = idx
 }
 
+// SelectInstance selects instance, e.g. right after adding it, wherever AddInstance placed it. Noop
+// if the instance isn't in the list or hidden by the view filter.
+func (l *List) SelectInstance(instance *session.Instance) {
+	if idx := slices.Index(l.items, instance); idx >= 0 {
+		l.SetSelectedInstance(idx)
+	}
+}
+
+// numPinned returns the number of pinned instances, which are always the first items.
+func (l *List) numPinned() int {
+	n := 0
+	for n < len(l.items) && l.items[n].Pinned {
+		n++
+	}
+	return n
+}
+
+// TogglePinned pins or unpins the selected instance and keeps it selected. Newly pinned instances
+// go below the already pinned ones at the top of the list. Unpinned instances go back among the
+// others in creation order.
+func (l *List) TogglePinned() {
+	selected := l.GetSelectedInstance()
+	if selected == nil {
+		return
+	}
+	l.items = slices.Delete(l.items, l.selectedIdx, l.selectedIdx+1)
+	selected.Pinned = !selected.Pinned
+	pos := l.numPinned()
+	if !selected.Pinned {
+		for pos < len(l.items) && !l.items[pos].CreatedAt.After(selected.CreatedAt) {
+			pos++
+		}
+	}
+	l.items = slices.Insert(l.items, pos, selected)
+	l.selectedIdx = pos
+}
+
+// ToggleMarked marks or unmarks the selected instance for batch actions. The cursor doesn't move.
+func (l *List) ToggleMarked() {
+	selected := l.GetSelectedInstance()
+	if selected == nil {
+		return
+	}
+	if l.marked[selected] {
+		delete(l.marked, selected)
+	} else {
+		l.marked[selected] = true
+	}
+}
+
+// MarkedInstances returns the marked instances in list order.
+func (l *List) MarkedInstances() []*session.Instance {
+	var marked []*session.Instance
+	for _, item := range l.items {
+		if l.marked[item] {
+			marked = append(marked, item)
+		}
+	}
+	return marked
+}
+
+// ClearMarked unmarks all instances.
+func (l *List) ClearMarked() {
+	l.marked = make(map[*session.Instance]bool)
+}
+
 // GetInstances returns all instances in the list
 func (l *List) GetInstances() []*session.Instance {
 	return l.items
@@ -0,0 +1,424 @@
+package ui
+
+import (
+	"claude-squad/log"
+	"claude-squad/session"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestList(statuses ...session.Status) *List {
+	s := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	list := NewList(&s, false)
+	for i, status := range statuses {
+		list.items = append(list.items, &session.Instance{Title: string(rune('a' + i)), Status: status})
+	}
+	return list
+}
+
+func TestListAddInstanceFinalizer(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	s := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	list := NewList(&s, false)
+
+	// A paused instance restored from storage is started without touching tmux or git.
+	instance, err := session.FromInstanceData(session.InstanceData{
+		Title:    "test",
+		Status:   session.Paused,
+		Worktree: session.GitWorktreeData{RepoPath: "/tmp/repo"},
+	})
+	require.NoError(t, err)
+
+	finalize := list.AddInstance(instance)
+	finalize()
+	finalize()
+
+	assert.Equal(t, 1, list.NumInstances())
+	assert.Equal(t, map[string]int{"repo": 1}, list.repos)
+}
+
+func TestListRemoveSelected(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	tests := []struct {
+		name         string
+		selected     int
+		wantRemoved  string
+		wantSelected string
+	}{
+		{name: "middle item keeps the index", selected: 1, wantRemoved: "b", wantSelected: "c"},
+		{name: "last item selects the previous one", selected: 2, wantRemoved: "c", wantSelected: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := newTestList(session.Paused, session.Paused, session.Paused)
+			list.SetSelectedInstance(tt.selected)
+
+			removed := list.RemoveSelected()
+			require.NotNil(t, removed)
+			assert.Equal(t, tt.wantRemoved, removed.Title)
+			assert.Equal(t, 2, list.NumInstances())
+			assert.Equal(t, tt.wantSelected, list.GetSelectedInstance().Title)
+		})
+	}
+
+	t.Run("empty list", func(t *testing.T) {
+		assert.Nil(t, newTestList().RemoveSelected())
+	})
+}
+
+// newGroupedTestList creates a list of paused instances, one per repo path, with titles a, b, c, ...
+func newGroupedTestList(t *testing.T, repoPaths ...string) *List {
+	s := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	list := NewList(&s, false)
+	for i, repoPath := range repoPaths {
+		instance, err := session.FromInstanceData(session.InstanceData{
+			Title:    string(rune('a' + i)),
+			Status:   session.Paused,
+			Worktree: session.GitWorktreeData{RepoPath: repoPath},
+		})
+		require.NoError(t, err)
+		list.AddInstance(instance)()
+	}
+	return list
+}
+
+func TestListGroupByRepo(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	t.Run("navigation follows the grouped order", func(t *testing.T) {
+		list := newGroupedTestList(t, "/tmp/one", "/tmp/two", "/tmp/one")
+		assert.Equal(t, []int{0, 2, 1}, list.displayOrder())
+
+		var titles []string
+		for range 3 {
+			titles = append(titles, list.GetSelectedInstance().Title)
+			list.Down()
+		}
+		assert.Equal(t, []string{"a", "c", "b"}, titles)
+		list.Up()
+		assert.Equal(t, "c", list.GetSelectedInstance().Title)
+	})
+
+	t.Run("a single repo isn't grouped", func(t *testing.T) {
+		list := newGroupedTestList(t, "/tmp/one", "/tmp/one")
+		assert.False(t, list.grouped())
+		list.ToggleGroupCollapsed()
+		assert.Equal(t, "a", list.GetSelectedInstance().Title)
+	})
+
+	t.Run("collapsing hides the group and moves the selection", func(t *testing.T) {
+		list := newGroupedTestList(t, "/tmp/one", "/tmp/two", "/tmp/one")
+		list.SetSize(80, 40)
+		list.ToggleGroupCollapsed()
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+		assert.Contains(t, list.String(), "one (2 hidden)")
+
+		list.Up()
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+
+		list.ExpandAllGroups()
+		list.Up()
+		assert.Equal(t, "c", list.GetSelectedInstance().Title)
+	})
+}
+
+func TestListPausedOnlyFilter(t *testing.T) {
+	t.Run("navigation skips instances that aren't paused", func(t *testing.T) {
+		list := newTestList(session.Paused, session.Running, session.Paused, session.Ready)
+		list.TogglePausedOnly()
+		assert.True(t, list.PausedOnly())
+
+		assert.Equal(t, "a", list.GetSelectedInstance().Title)
+		list.Down()
+		assert.Equal(t, "c", list.GetSelectedInstance().Title)
+		list.Down()
+		assert.Equal(t, "c", list.GetSelectedInstance().Title)
+		list.Up()
+		assert.Equal(t, "a", list.GetSelectedInstance().Title)
+	})
+
+	t.Run("selection moves off an instance hidden by the filter", func(t *testing.T) {
+		list := newTestList(session.Running, session.Paused)
+		list.TogglePausedOnly()
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+
+		// Resuming the selected instance removes it from the filtered view.
+		list.items[1].Status = session.Running
+		assert.Nil(t, list.GetSelectedInstance())
+
+		list.TogglePausedOnly()
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+		assert.Len(t, list.GetInstances(), 2)
+	})
+
+	t.Run("hidden instances can't be selected by index", func(t *testing.T) {
+		list := newTestList(session.Paused, session.Running)
+		list.TogglePausedOnly()
+		list.SetSelectedInstance(1)
+		assert.Equal(t, "a", list.GetSelectedInstance().Title)
+	})
+}
+
+func TestListMarked(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	titles := func(instances []*session.Instance) []string {
+		var result []string
+		for _, instance := range instances {
+			result = append(result, instance.Title)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		toggle []int
+		want   []string
+	}{
+		{name: "nothing marked", toggle: nil, want: nil},
+		{name: "marked in list order", toggle: []int{2, 0}, want: []string{"a", "c"}},
+		{name: "toggling twice unmarks", toggle: []int{1, 2, 1}, want: []string{"c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := newTestList(session.Paused, session.Paused, session.Paused)
+			for _, idx := range tt.toggle {
+				list.SetSelectedInstance(idx)
+				list.ToggleMarked()
+				// Marking doesn't move the cursor.
+				assert.Equal(t, list.items[idx], list.GetSelectedInstance())
+			}
+			assert.Equal(t, tt.want, titles(list.MarkedInstances()))
+		})
+	}
+
+	t.Run("removed instances are unmarked", func(t *testing.T) {
+		list := newTestList(session.Paused, session.Paused)
+		list.ToggleMarked()
+		removed := list.RemoveSelected()
+		assert.Empty(t, list.MarkedInstances())
+
+		list.AddInstance(removed)
+		assert.Empty(t, list.MarkedInstances())
+	})
+
+	t.Run("clear unmarks everything", func(t *testing.T) {
+		list := newTestList(session.Paused, session.Paused)
+		list.ToggleMarked()
+		list.Down()
+		list.ToggleMarked()
+		require.Len(t, list.MarkedInstances(), 2)
+
+		list.ClearMarked()
+		assert.Empty(t, list.MarkedInstances())
+	})
+}
+
+func TestListTogglePinned(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	list := newTestList(session.Paused, session.Paused, session.Paused, session.Paused)
+	for i, item := range list.items {
+		item.CreatedAt = time.Unix(int64(i), 0)
+	}
+	titles := func() string {
+		var result string
+		for _, item := range list.items {
+			result += item.Title
+		}
+		return result
+	}
+
+	// Pinned instances move to the top in the order they were pinned and stay selected.
+	list.SetSelectedInstance(2)
+	list.TogglePinned()
+	assert.Equal(t, "cabd", titles())
+	assert.Equal(t, "c", list.GetSelectedInstance().Title)
+	list.SetSelectedInstance(3)
+	list.TogglePinned()
+	assert.Equal(t, "cdab", titles())
+	assert.True(t, list.GetSelectedInstance().Pinned)
+
+	// Unpinning puts the instance back in creation order below the pinned ones.
+	list.SetSelectedInstance(0)
+	list.TogglePinned()
+	assert.Equal(t, "dabc", titles())
+	assert.False(t, list.GetSelectedInstance().Pinned)
+	assert.Equal(t, 3, list.selectedIdx)
+
+	// Pinned instances added later, e.g. restored from storage, join the pinned ones.
+	list.AddInstance(&session.Instance{Title: "e", Status: session.Paused, Pinned: true})
+	assert.Equal(t, "deabc", titles())
+}
+
+func TestListScrollWindow(t *testing.T) {
+	// Room for exactly three instances of four lines each, with a blank line between them.
+	const height = listTitleHeight + 3*4 + 2
+
+	tests := []struct {
+		name           string
+		selections     []int
+		expectedOffset int
+	}{
+		{name: "starts at the top", selections: []int{0}, expectedOffset: 0},
+		{name: "scrolls down to the selection", selections: []int{9}, expectedOffset: 7},
+		{name: "doesn't scroll while the selection is visible", selections: []int{9, 8, 7}, expectedOffset: 7},
+		{name: "scrolls up to the selection", selections: []int{9, 3}, expectedOffset: 3},
+		{name: "scrolls one row at a time", selections: []int{2, 3}, expectedOffset: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+			list := NewList(&s, false)
+			for i := 0; i < 10; i++ {
+				list.items = append(list.items, &session.Instance{Title: fmt.Sprintf("instance-%02d", i), Status: session.Paused})
+			}
+			list.SetSize(60, height)
+
+			var rendered string
+			for _, idx := range tt.selections {
+				list.SetSelectedInstance(idx)
+				rendered = list.String()
+			}
+
+			assert.Equal(t, tt.expectedOffset, list.scrollOffset)
+			assert.Equal(t, height, lipgloss.Height(rendered))
+			for i := range list.items {
+				visible := i >= tt.expectedOffset && i < tt.expectedOffset+3
+				assert.Equal(t, visible, strings.Contains(rendered, fmt.Sprintf("instance-%02d", i)), "instance %d", i)
+			}
+		})
+	}
+
+	t.Run("renders everything when it fits", func(t *testing.T) {
+		list := newTestList(session.Paused, session.Paused)
+		list.SetSize(60, height)
+		list.SetSelectedInstance(1)
+		rendered := list.String()
+		assert.Equal(t, 0, list.scrollOffset)
+		assert.Contains(t, rendered, " 1. ")
+		assert.Contains(t, rendered, " 2. ")
+	})
+}
+
+func TestListCompact(t *testing.T) {
+	s := spinner.New(spinner.WithSpinner(spinner.MiniDot))
+	list := NewList(&s, false)
+	for i := 0; i < 10; i++ {
+		list.items = append(list.items, &session.Instance{Title: fmt.Sprintf("instance-%02d", i), Status: session.Paused})
+	}
+	// Room for five compact rows, or a single expanded one.
+	list.SetSize(60, listTitleHeight+5)
+	list.SetCompact(true)
+	require.True(t, list.Compact())
+
+	list.SetSelectedInstance(9)
+	rendered := list.String()
+	assert.Equal(t, 5, list.scrollOffset)
+	for i := range list.items {
+		assert.Equal(t, i >= 5, strings.Contains(rendered, fmt.Sprintf("instance-%02d", i)), "instance %d", i)
+	}
+	assert.NotContains(t, rendered, branchIcon, "compact rows only show the title and status")
+
+	// Expanded rows are taller, so only the selected one fits.
+	list.SetCompact(false)
+	rendered = list.String()
+	assert.Equal(t, 9, list.scrollOffset)
+	assert.Contains(t, rendered, "instance-09")
+	assert.NotContains(t, rendered, "instance-08")
+	assert.Contains(t, rendered, branchIcon)
+}
+
+func TestListArchiveView(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	list := newTestList(session.Paused, session.Ready, session.Paused)
+	require.NoError(t, list.items[0].Archive())
+	assert.Equal(t, 2, list.NumActiveInstances())
+
+	// Archived instances are hidden from the active instances.
+	list.SetSelectedInstance(0)
+	assert.Equal(t, "b", list.GetSelectedInstance().Title)
+	list.SetSelectedInstance(0)
+	assert.Equal(t, "b", list.GetSelectedInstance().Title)
+
+	// The archive view only shows the archived instances.
+	list.SetArchiveView(true)
+	assert.Equal(t, "a", list.GetSelectedInstance().Title)
+	list.Down()
+	assert.Equal(t, "a", list.GetSelectedInstance().Title)
+	assert.Contains(t, list.String(), "Archived instances")
+
+	require.NoError(t, list.items[0].Unarchive())
+	assert.Nil(t, list.GetSelectedInstance())
+	assert.Equal(t, 3, list.NumActiveInstances())
+}
+
+func TestListSelectVisible(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	t.Run("numbers follow the grouped order", func(t *testing.T) {
+		list := newGroupedTestList(t, "/tmp/one", "/tmp/two", "/tmp/one")
+		list.SetSize(80, 40)
+		assert.Equal(t, []int{0, 2, 1}, list.visibleOrder())
+
+		rendered := list.String()
+		assert.Contains(t, rendered, " 3.  b")
+		assert.Less(t, strings.Index(rendered, " 1.  a"), strings.Index(rendered, " 2.  c"))
+		assert.Less(t, strings.Index(rendered, " 2.  c"), strings.Index(rendered, " 3.  b"))
+
+		list.SelectVisible(2)
+		assert.Equal(t, "c", list.GetSelectedInstance().Title)
+		list.SelectVisible(3)
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+		list.SelectVisible(4)
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+	})
+
+	t.Run("hidden instances aren't numbered", func(t *testing.T) {
+		list := newTestList(session.Running, session.Paused, session.Ready, session.Paused)
+		list.SetSize(80, 40)
+		list.TogglePausedOnly()
+
+		rendered := list.String()
+		assert.Contains(t, rendered, " 1.  b")
+		assert.Contains(t, rendered, " 2.  d")
+
+		list.SelectVisible(2)
+		assert.Equal(t, "d", list.GetSelectedInstance().Title)
+		list.SelectVisible(1)
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+		list.SelectVisible(3)
+		assert.Equal(t, "b", list.GetSelectedInstance().Title)
+	})
+
+	t.Run("the archive view is numbered from 1", func(t *testing.T) {
+		list := newTestList(session.Paused, session.Paused, session.Paused)
+		require.NoError(t, list.items[2].Archive())
+		list.SetSize(80, 40)
+		list.SetArchiveView(true)
+
+		assert.Contains(t, list.String(), " 1.  c")
+		list.SelectVisible(1)
+		assert.Equal(t, "c", list.GetSelectedInstance().Title)
+	})
+}
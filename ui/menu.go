@@ -32,6 +32,12 @@ var verticalSeparator = " │ "
 var menuStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.Color("205"))
 
+const autoYesIndicatorText = "AUTO-YES"
+
+var autoYesIndicatorStyle = lipgloss.NewStyle().
+	Bold(true).
+	Foreground(lipgloss.AdaptiveColor{Light: "#d18b00", Dark: "#ffb000"})
+
 // MenuState represents different states the menu can be in
 type MenuState int
 
@@ -48,6 +54,10 @@ type Menu struct {
 	state         MenuState
 	instance      *session.Instance
 	isInDiffTab   bool
+	// autoYes shows the auto-yes indicator in front of the options.
+	autoYes bool
+	// cheatSheet shows a strip of the most common key bindings above the options.
+	cheatSheet bool
 
 	// keyDown is the key which is pressed. The default is -1.
 	keyDown keys.KeyName
@@ -100,6 +110,31 @@ func (m *Menu) SetInDiffTab(inDiffTab bool) {
 	m.updateOptions()
 }
 
+// SetAutoYes sets whether the auto-yes indicator is shown, i.e. whether any prompt may be
+// confirmed automatically.
+func (m *Menu) SetAutoYes(autoYes bool) {
+	m.autoYes = autoYes
+}
+
+// SetCheatSheet sets whether the cheat sheet of common key bindings is shown above the options.
+func (m *Menu) SetCheatSheet(show bool) {
+	m.cheatSheet = show
+}
+
+// CheatSheet returns true if the cheat sheet is shown.
+func (m *Menu) CheatSheet() bool {
+	return m.cheatSheet
+}
+
+// CheatSheetHeight returns the number of lines the cheat sheet takes up at width, or zero if it
+// is hidden. The menu's height must include them.
+func (m *Menu) CheatSheetHeight(width int) int {
+	if !m.cheatSheet {
+		return 0
+	}
+	return len(cheatSheetLines(width, m.keyDown))
+}
+
 // updateOptions updates the menu options based on current state and instance
 func (m *Menu) updateOptions() {
 	switch m.state {
@@ -222,6 +257,16 @@ func (m *Menu) String() string {
 		}
 	}
 
-	centeredMenuText := menuStyle.Render(s.String())
-	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, centeredMenuText)
+	menuText := menuStyle.Render(s.String())
+	if m.autoYes {
+		menuText = autoYesIndicatorStyle.Render(autoYesIndicatorText) + sepStyle.Render(verticalSeparator) + menuText
+	}
+	if !m.cheatSheet {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, menuText)
+	}
+	cheatSheet := lipgloss.JoinVertical(lipgloss.Center, cheatSheetLines(m.width, m.keyDown)...)
+	cheatSheetHeight := lipgloss.Height(cheatSheet)
+	return lipgloss.JoinVertical(lipgloss.Center,
+		lipgloss.PlaceHorizontal(m.width, lipgloss.Center, cheatSheet),
+		lipgloss.Place(m.width, max(m.height-cheatSheetHeight, 1), lipgloss.Center, lipgloss.Center, menuText))
 }
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"claude-squad/keys"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMenuCheatSheet(t *testing.T) {
+	menu := NewMenu()
+	menu.SetSize(200, 3)
+	assert.Zero(t, menu.CheatSheetHeight(200), "the cheat sheet is hidden by default")
+
+	menu.SetCheatSheet(true)
+	wide := menu.CheatSheetHeight(200)
+	narrow := menu.CheatSheetHeight(60)
+	require.Positive(t, wide)
+	assert.Greater(t, narrow, wide, "the cheat sheet wraps to fit the width")
+	for _, line := range cheatSheetLines(60, -1) {
+		assert.LessOrEqual(t, lipgloss.Width(line), 60)
+	}
+
+	rendered := menu.String()
+	assert.Equal(t, 3, lipgloss.Height(rendered))
+	assert.Contains(t, rendered, "enter/o open")
+	assert.Contains(t, rendered, "key hints")
+
+	// Keys come from the key map, so a rebound quit key shows up.
+	require.NoError(t, keys.SetQuitKey("Q"))
+	defer keys.SetQuitKey("q")
+	assert.Contains(t, strings.Join(cheatSheetLines(200, -1), "\n"), "Q quit")
+}
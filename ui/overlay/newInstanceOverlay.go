@@ -0,0 +1,189 @@
+package overlay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// MaxTitleLength is the maximum number of characters in an instance title.
+const MaxTitleLength = 32
+
+var (
+	newInstanceLabelStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#7F7A7A"))
+	newInstanceFocusedLabelStyle = lipgloss.NewStyle().
+					Foreground(lipgloss.Color("62")).
+					Bold(true)
+	newInstanceErrorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF0000"))
+)
+
+// NewInstanceValues are the values entered in a NewInstanceOverlay.
+type NewInstanceValues struct {
+	Title      string
+	Program    string
+	BaseBranch string
+	// Branch is the branch the worktree is created on. Empty derives it from the title.
+	Branch string
+	// DevServerFrom is the title of the instance whose dev server config is copied. Empty uses
+	// the repo's dev server settings.
+	DevServerFrom string
+}
+
+// newInstanceField indexes the inputs of a NewInstanceOverlay.
+type newInstanceField int
+
+const (
+	newInstanceTitle newInstanceField = iota
+	newInstanceProgram
+	newInstanceBaseBranch
+	newInstanceBranch
+	newInstanceDevServerFrom
+)
+
+// newInstanceLabels are the labels of the inputs, by newInstanceField.
+var newInstanceLabels = []string{"Title", "Program", "Base branch", "Branch", "Dev server"}
+
+// newInstanceLabelWidth is the width of the label column, which fits the longest label.
+const newInstanceLabelWidth = 13
+
+// NewInstanceOverlay is the form for naming a new instance and, optionally, changing its program,
+// base branch and branch. Tab and shift+tab move between fields, enter submits and esc cancels.
+type NewInstanceOverlay struct {
+	inputs  []textinput.Model
+	focused newInstanceField
+	// Submitted is true if the form was submitted and accepted by OnSubmit.
+	Submitted bool
+	// Canceled is true if the overlay was closed with esc.
+	Canceled bool
+	// OnSubmit is called with the values when the form is submitted. If it returns an error, the
+	// error is shown and the overlay stays open so the values can be fixed.
+	OnSubmit func(values NewInstanceValues) error
+	err      error
+}
+
+// NewNewInstanceOverlay creates the form with the given initial values, focused on the title.
+func NewNewInstanceOverlay(values NewInstanceValues) *NewInstanceOverlay {
+	initial := []string{values.Title, values.Program, values.BaseBranch, values.Branch, values.DevServerFrom}
+	inputs := make([]textinput.Model, len(initial))
+	for i, value := range initial {
+		ti := textinput.New()
+		ti.Prompt = ""
+		ti.CursorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("62"))
+		ti.SetValue(value)
+		inputs[i] = ti
+	}
+	inputs[newInstanceTitle].CharLimit = MaxTitleLength
+	inputs[newInstanceBaseBranch].Placeholder = "HEAD"
+	inputs[newInstanceBranch].Placeholder = "from title"
+	inputs[newInstanceDevServerFrom].Placeholder = "repo settings"
+	// Tab switches fields, so suggestions are completed with right instead.
+	inputs[newInstanceDevServerFrom].KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
+	inputs[newInstanceTitle].Focus()
+
+	return &NewInstanceOverlay{inputs: inputs}
+}
+
+// SetSize sets the width of the overlay, including its border and padding.
+func (n *NewInstanceOverlay) SetSize(width, height int) {
+	for i := range n.inputs {
+		n.inputs[i].Width = max(width-6-newInstanceLabelWidth, 10)
+	}
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly.
+// Returns true if the overlay should be closed.
+func (n *NewInstanceOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "esc":
+		n.Canceled = true
+		return true
+	case "enter":
+		if n.OnSubmit != nil {
+			if err := n.OnSubmit(n.Values()); err != nil {
+				n.err = err
+				return false
+			}
+		}
+		n.Submitted = true
+		return true
+	case "tab", "down":
+		n.focus((n.focused + 1) % newInstanceField(len(n.inputs)))
+	case "shift+tab", "up":
+		n.focus((n.focused + newInstanceField(len(n.inputs)) - 1) % newInstanceField(len(n.inputs)))
+	default:
+		n.inputs[n.focused], _ = n.inputs[n.focused].Update(msg)
+		n.err = nil
+	}
+	return false
+}
+
+// SetDevServerSources sets the titles of the instances whose dev server config can be copied. They
+// are suggested while typing in the dev server field.
+func (n *NewInstanceOverlay) SetDevServerSources(titles []string) {
+	input := &n.inputs[newInstanceDevServerFrom]
+	input.ShowSuggestions = len(titles) > 0
+	input.SetSuggestions(titles)
+	if len(titles) > 0 {
+		input.Placeholder = fmt.Sprintf("repo settings, or copy from e.g. %s", titles[0])
+	}
+}
+
+// focus moves the cursor to field.
+func (n *NewInstanceOverlay) focus(field newInstanceField) {
+	n.inputs[n.focused].Blur()
+	n.focused = field
+	n.inputs[n.focused].Focus()
+}
+
+// Values returns the entered values, with surrounding whitespace removed from the program and
+// branches.
+func (n *NewInstanceOverlay) Values() NewInstanceValues {
+	return NewInstanceValues{
+		Title:      n.inputs[newInstanceTitle].Value(),
+		Program:    strings.TrimSpace(n.inputs[newInstanceProgram].Value()),
+		BaseBranch: strings.TrimSpace(n.inputs[newInstanceBaseBranch].Value()),
+		Branch:     strings.TrimSpace(n.inputs[newInstanceBranch].Value()),
+
+		DevServerFrom: strings.TrimSpace(n.inputs[newInstanceDevServerFrom].Value()),
+	}
+}
+
+// Error returns the error returned by the last rejected submit, if any.
+func (n *NewInstanceOverlay) Error() error {
+	return n.err
+}
+
+// Render renders the new instance overlay.
+func (n *NewInstanceOverlay) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("62")).
+		Bold(true).
+		MarginBottom(1)
+
+	content := titleStyle.Render("New session") + "\n"
+	for i, input := range n.inputs {
+		labelStyle := newInstanceLabelStyle
+		if newInstanceField(i) == n.focused {
+			labelStyle = newInstanceFocusedLabelStyle
+		}
+		content += labelStyle.Render(fmt.Sprintf("%-*s", newInstanceLabelWidth, newInstanceLabels[i])) + input.View() + "\n"
+	}
+	if n.err != nil {
+		content += "\n" + newInstanceErrorStyle.Render(n.err.Error()) + "\n"
+	}
+	content += "\n Enter to create • Tab to switch fields • Esc to cancel "
+
+	return style.Render(content)
+}
@@ -0,0 +1,179 @@
+package overlay
+
+import (
+	"claude-squad/util"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// quickSwitchMaxResults limits how many matches are shown at once.
+const quickSwitchMaxResults = 10
+
+var quickSwitchMatchStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#ffb000")).
+	Bold(true)
+
+// quickSwitchResult is an item that matches the query, with the rune positions that matched.
+type quickSwitchResult struct {
+	index     int
+	score     int
+	positions []int
+}
+
+// QuickSwitchOverlay lets the user fuzzy-search items by typing and pick one with enter. Results
+// are ordered best match first.
+type QuickSwitchOverlay struct {
+	// Title is shown above the query.
+	Title   string
+	input   textinput.Model
+	items   []SelectionItem
+	results []quickSwitchResult
+	// selected is the index of the highlighted result.
+	selected int
+	// Submitted is true if a result was chosen with enter.
+	Submitted bool
+	// Canceled is true if the overlay was closed with esc or enter without any results.
+	Canceled bool
+	width    int
+}
+
+// NewQuickSwitchOverlay creates a quick switcher over items, initially showing all of them.
+func NewQuickSwitchOverlay(title string, items []SelectionItem) *QuickSwitchOverlay {
+	ti := textinput.New()
+	ti.Focus()
+	ti.Prompt = "> "
+	ti.CursorStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("62"))
+
+	q := &QuickSwitchOverlay{
+		Title: title,
+		input: ti,
+		items: items,
+		width: 50,
+	}
+	q.filter()
+	return q
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly.
+// Returns true if the overlay should be closed.
+func (q *QuickSwitchOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "esc":
+		q.Canceled = true
+		return true
+	case "enter":
+		if len(q.results) == 0 {
+			q.Canceled = true
+			return true
+		}
+		q.Submitted = true
+		return true
+	case "up", "ctrl+p", "ctrl+k":
+		if q.selected > 0 {
+			q.selected--
+		}
+	case "down", "ctrl+n", "ctrl+j":
+		if q.selected < len(q.results)-1 {
+			q.selected++
+		}
+	default:
+		before := q.input.Value()
+		q.input, _ = q.input.Update(msg)
+		if q.input.Value() != before {
+			q.filter()
+		}
+	}
+	return false
+}
+
+// filter matches the items against the query and selects the best match.
+func (q *QuickSwitchOverlay) filter() {
+	q.results = q.results[:0]
+	query := strings.TrimSpace(q.input.Value())
+	for i, item := range q.items {
+		if score, positions, ok := util.FuzzyMatch(query, item.Label); ok {
+			q.results = append(q.results, quickSwitchResult{index: i, score: score, positions: positions})
+		}
+	}
+	// Keep the original order between equally good matches.
+	sort.SliceStable(q.results, func(a, b int) bool {
+		return q.results[a].score > q.results[b].score
+	})
+	q.selected = 0
+}
+
+// Selected returns the index in items of the highlighted result, or -1 if nothing matches.
+func (q *QuickSwitchOverlay) Selected() int {
+	if len(q.results) == 0 {
+		return -1
+	}
+	return q.results[q.selected].index
+}
+
+// SetWidth sets the width of the overlay.
+func (q *QuickSwitchOverlay) SetWidth(width int) {
+	q.width = width
+}
+
+// Render renders the quick switcher with the matched characters of each result highlighted.
+func (q *QuickSwitchOverlay) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2).
+		Width(q.width)
+
+	var b strings.Builder
+	b.WriteString(selectionTitleStyle.Render(q.Title))
+	b.WriteString("\n")
+	b.WriteString(q.input.View())
+	b.WriteString("\n\n")
+
+	if len(q.results) == 0 {
+		b.WriteString(selectionDescStyle.Render(" No matches"))
+		b.WriteString("\n")
+	}
+	// Scroll the results so the selected one stays visible.
+	start := max(0, q.selected-quickSwitchMaxResults+1)
+	end := min(len(q.results), start+quickSwitchMaxResults)
+	for i := start; i < end; i++ {
+		result := q.results[i]
+		item := q.items[result.index]
+		base := lipgloss.NewStyle()
+		if i == q.selected {
+			base = selectionSelectedStyle
+		}
+		line := base.Render(" ") + highlightMatches(item.Label, result.positions, base) + base.Render(" ")
+		if item.Description != "" {
+			line += " " + selectionDescStyle.Render(item.Description)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n ↑/↓ to select • Enter to switch • Esc to cancel ")
+
+	return style.Render(b.String())
+}
+
+// highlightMatches renders text in base, with the runes at positions highlighted.
+func highlightMatches(text string, positions []int, base lipgloss.Style) string {
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+	highlight := quickSwitchMatchStyle.Inherit(base)
+
+	var b strings.Builder
+	for i, r := range []rune(text) {
+		if matched[i] {
+			b.WriteString(highlight.Render(string(r)))
+		} else {
+			b.WriteString(base.Render(string(r)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package overlay
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	selectionTitleStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("62")).
+				Bold(true).
+				MarginBottom(1)
+	selectionSelectedStyle = lipgloss.NewStyle().
+				Background(lipgloss.Color("62")).
+				Foreground(lipgloss.Color("#FFFFFF"))
+	selectionDescStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "#A49FA5", Dark: "#777777"})
+)
+
+// SelectionItem is an option shown in a SelectionOverlay.
+type SelectionItem struct {
+	// Label is the text shown for the item.
+	Label string
+	// Description is shown dimmed next to the label.
+	Description string
+}
+
+// SelectionOverlay lets the user pick one item from a list.
+type SelectionOverlay struct {
+	// Title is shown above the items.
+	Title    string
+	items    []SelectionItem
+	selected int
+	// Submitted is true if an item was chosen with enter.
+	Submitted bool
+	// Canceled is true if the overlay was closed with esc.
+	Canceled bool
+	width    int
+	height   int
+}
+
+// NewSelectionOverlay creates a selection overlay with the first item selected.
+func NewSelectionOverlay(title string, items []SelectionItem) *SelectionOverlay {
+	return &SelectionOverlay{
+		Title: title,
+		items: items,
+		width: 50,
+	}
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly.
+// Returns true if the overlay should be closed.
+func (s *SelectionOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "esc", "q":
+		s.Canceled = true
+		return true
+	case "enter":
+		if len(s.items) == 0 {
+			s.Canceled = true
+			return true
+		}
+		s.Submitted = true
+		return true
+	case "up", "k":
+		if s.selected > 0 {
+			s.selected--
+		}
+	case "down", "j":
+		if s.selected < len(s.items)-1 {
+			s.selected++
+		}
+	}
+	return false
+}
+
+// Selected returns the index of the selected item.
+func (s *SelectionOverlay) Selected() int {
+	return s.selected
+}
+
+// SetWidth sets the width of the overlay.
+func (s *SelectionOverlay) SetWidth(width int) {
+	s.width = width
+}
+
+// SetHeight sets the height of the overlay. Items that don't fit are scrolled into view as the
+// selection moves. Zero shows all items.
+func (s *SelectionOverlay) SetHeight(height int) {
+	s.height = height
+}
+
+// visibleItems returns the range of items that fit in the overlay, keeping the selection in view.
+func (s *SelectionOverlay) visibleItems() (start, end int) {
+	// The border, padding, title and hint take 8 rows.
+	rows := s.height - 8
+	if s.height == 0 || rows >= len(s.items) {
+		return 0, len(s.items)
+	}
+	rows = max(rows, 1)
+	start = min(max(s.selected-rows/2, 0), len(s.items)-rows)
+	return start, start + rows
+}
+
+// Render renders the selection overlay.
+func (s *SelectionOverlay) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2).
+		Width(s.width)
+
+	var b strings.Builder
+	b.WriteString(selectionTitleStyle.Render(s.Title))
+	b.WriteString("\n")
+	start, end := s.visibleItems()
+	for i := start; i < end; i++ {
+		item := s.items[i]
+		line := " " + item.Label + " "
+		if i == s.selected {
+			line = selectionSelectedStyle.Render(line)
+		}
+		if item.Description != "" {
+			line += " " + selectionDescStyle.Render(item.Description)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n ↑/↓ to select • Enter to confirm • Esc to cancel ")
+
+	return style.Render(b.String())
+}
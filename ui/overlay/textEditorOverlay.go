@@ -0,0 +1,102 @@
+package overlay
+
+import (
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var textEditorErrorStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#FF0000"))
+
+// TextEditorOverlay is a multi-line text editor, e.g. for editing a config file. Enter inserts a
+// newline, ctrl+s submits and esc cancels.
+type TextEditorOverlay struct {
+	// Title is shown above the text.
+	Title    string
+	textarea textarea.Model
+	// Submitted is true if the text was submitted and accepted by OnSubmit.
+	Submitted bool
+	// Canceled is true if the overlay was closed with esc.
+	Canceled bool
+	// OnSubmit is called with the text when it is submitted. If it returns an error, the error is
+	// shown and the overlay stays open so the text can be fixed.
+	OnSubmit func(value string) error
+	err      error
+}
+
+// NewTextEditorOverlay creates a text editor with the given title and initial value.
+func NewTextEditorOverlay(title string, initialValue string) *TextEditorOverlay {
+	ta := textarea.New()
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	ta.ShowLineNumbers = true
+	ta.SetValue(initialValue)
+	ta.Focus()
+
+	return &TextEditorOverlay{
+		Title:    title,
+		textarea: ta,
+	}
+}
+
+// SetSize sets the size of the overlay, including its border and padding.
+func (t *TextEditorOverlay) SetSize(width, height int) {
+	t.textarea.SetWidth(max(width-6, 10))
+	// Leave room for the title, the error, the hint and the border.
+	t.textarea.SetHeight(max(height-10, 3))
+}
+
+// HandleKeyPress processes a key press and updates the state accordingly.
+// Returns true if the overlay should be closed.
+func (t *TextEditorOverlay) HandleKeyPress(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "esc":
+		t.Canceled = true
+		return true
+	case "ctrl+s":
+		if t.OnSubmit != nil {
+			if err := t.OnSubmit(t.textarea.Value()); err != nil {
+				t.err = err
+				return false
+			}
+		}
+		t.Submitted = true
+		return true
+	default:
+		t.textarea, _ = t.textarea.Update(msg)
+		return false
+	}
+}
+
+// GetValue returns the current text.
+func (t *TextEditorOverlay) GetValue() string {
+	return t.textarea.Value()
+}
+
+// Error returns the error returned by the last rejected submit, if any.
+func (t *TextEditorOverlay) Error() error {
+	return t.err
+}
+
+// Render renders the text editor overlay.
+func (t *TextEditorOverlay) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(1, 2)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("62")).
+		Bold(true).
+		MarginBottom(1)
+
+	content := titleStyle.Render(t.Title) + "\n"
+	content += t.textarea.View() + "\n"
+	if t.err != nil {
+		content += textEditorErrorStyle.Width(t.textarea.Width()).Render(t.err.Error()) + "\n"
+	}
+	content += "\n ctrl+s to save • Esc to cancel "
+
+	return style.Render(content)
+}
@@ -19,8 +19,16 @@ type PreviewPane struct {
 	previewState previewState
 	isScrolling  bool
 	viewport     viewport.Model
+
+	// pausedSnapshot shows the content captured at pause time for paused instances
+	pausedSnapshot bool
 }
 
+var pausedBannerStyle = lipgloss.NewStyle().
+	Bold(true).
+	Background(lipgloss.AdaptiveColor{Light: "#FFD700", Dark: "#FFD700"}).
+	Foreground(lipgloss.Color("#1a1a1a"))
+
 type previewState struct {
 	// fallback is true if the preview pane is displaying fallback text
 	fallback bool
@@ -49,12 +57,26 @@ func (p *PreviewPane) setFallbackState(message string) {
 	}
 }
 
+// SetPausedSnapshot sets whether paused instances show the screen captured when they were paused
+// instead of the resume message.
+func (p *PreviewPane) SetPausedSnapshot(enabled bool) {
+	p.pausedSnapshot = enabled
+}
+
 // Updates the preview pane content with the tmux pane content
 func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
 	switch {
 	case instance == nil:
 		p.setFallbackState("No agents running yet. Spin up a new instance with 'n' to get started!")
 		return nil
+	case instance.Status == session.Paused && p.pausedSnapshot && instance.PausedCapture() != "":
+		banner := pausedBannerStyle.Width(p.width).Render(fmt.Sprintf(
+			" PAUSED - press 'r' to resume. Branch '%s' can be checked out.", instance.Branch))
+		p.previewState = previewState{
+			fallback: false,
+			text:     lipgloss.JoinVertical(lipgloss.Left, banner, instance.PausedCapture()),
+		}
+		return nil
 	case instance.Status == session.Paused:
 		p.setFallbackState(lipgloss.JoinVertical(lipgloss.Center,
 			"Session is paused. Press 'r' to resume.",
@@ -70,6 +92,9 @@ func (p *PreviewPane) UpdateContent(instance *session.Instance) error {
 				)),
 		))
 		return nil
+	case instance.Resuming():
+		p.setFallbackState("Resuming...")
+		return nil
 	}
 
 	var content string
@@ -186,60 +211,104 @@ func (p *PreviewPane) ScrollUp(instance *session.Instance) error {
 	}
 
 	if !p.isScrolling {
-		// Entering scroll mode - capture entire pane content including scrollback history
-		content, err := instance.PreviewFullHistory()
-		if err != nil {
-			return err
-		}
+		return p.enterScrollMode(instance)
+	}
 
-		// Set content in the viewport
-		footer := lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
-			Render("ESC to exit scroll mode")
+	// Already in scroll mode, just scroll the viewport
+	p.viewport.LineUp(1)
+	return nil
+}
 
-		contentWithFooter := lipgloss.JoinVertical(lipgloss.Left, content, footer)
-		p.viewport.SetContent(contentWithFooter)
+// enterScrollMode captures the entire pane content including scrollback history and shows it in
+// the viewport, positioned at the bottom.
+func (p *PreviewPane) enterScrollMode(instance *session.Instance) error {
+	content, err := instance.PreviewFullHistory()
+	if err != nil {
+		return err
+	}
 
-		// Position the viewport at the bottom initially
-		p.viewport.GotoBottom()
+	// Set content in the viewport
+	footer := lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
+		Render("ESC to exit scroll mode")
 
-		p.isScrolling = true
-		return nil
-	}
+	contentWithFooter := lipgloss.JoinVertical(lipgloss.Left, content, footer)
+	p.viewport.SetContent(contentWithFooter)
 
-	// Already in scroll mode, just scroll the viewport
-	p.viewport.LineUp(1)
+	// Position the viewport at the bottom initially
+	p.viewport.GotoBottom()
+
+	p.isScrolling = true
 	return nil
 }
 
-// ScrollDown scrolls down in the viewport
-func (p *PreviewPane) ScrollDown(instance *session.Instance) error {
+// ScrollPageUp scrolls up by a page, or half a page when half is true, entering scroll mode first
+// if needed.
+func (p *PreviewPane) ScrollPageUp(instance *session.Instance, half bool) error {
+	return p.scrollPage(instance, func() {
+		if half {
+			p.viewport.HalfViewUp()
+		} else {
+			p.viewport.ViewUp()
+		}
+	})
+}
+
+// ScrollPageDown scrolls down by a page, or half a page when half is true, entering scroll mode
+// first if needed.
+func (p *PreviewPane) ScrollPageDown(instance *session.Instance, half bool) error {
+	return p.scrollPage(instance, func() {
+		if half {
+			p.viewport.HalfViewDown()
+		} else {
+			p.viewport.ViewDown()
+		}
+	})
+}
+
+func (p *PreviewPane) scrollPage(instance *session.Instance, scroll func()) error {
 	if instance == nil || instance.Status == session.Paused {
 		return nil
 	}
-
 	if !p.isScrolling {
-		// Entering scroll mode - capture entire pane content including scrollback history
-		content, err := instance.PreviewFullHistory()
-		if err != nil {
+		if err := p.enterScrollMode(instance); err != nil {
 			return err
 		}
+	}
+	scroll()
+	return nil
+}
 
-		// Set content in the viewport
-		footer := lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
-			Render("ESC to exit scroll mode")
-
-		contentWithFooter := lipgloss.JoinVertical(lipgloss.Left, content, footer)
-		p.viewport.SetContent(contentWithFooter)
+// ScrollToTop enters scroll mode and jumps to the start of the scrollback history.
+func (p *PreviewPane) ScrollToTop(instance *session.Instance) error {
+	if instance == nil || instance.Status == session.Paused {
+		return nil
+	}
+	if !p.isScrolling {
+		if err := p.enterScrollMode(instance); err != nil {
+			return err
+		}
+	}
+	p.viewport.GotoTop()
+	return nil
+}
 
-		// Position the viewport at the bottom initially
-		p.viewport.GotoBottom()
+// ScrollToBottom jumps to the latest output by leaving scroll mode, so the preview follows the
+// session again.
+func (p *PreviewPane) ScrollToBottom(instance *session.Instance) error {
+	return p.ResetToNormalMode(instance)
+}
 
-		p.isScrolling = true
+// ScrollDown scrolls down in the viewport
+func (p *PreviewPane) ScrollDown(instance *session.Instance) error {
+	if instance == nil || instance.Status == session.Paused {
 		return nil
 	}
 
+	if !p.isScrolling {
+		return p.enterScrollMode(instance)
+	}
+
 	// Already in copy mode, just scroll the viewport
 	p.viewport.LineDown(1)
 	return nil
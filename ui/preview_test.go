@@ -380,6 +380,195 @@ func TestPreviewContentWithoutScrolling(t *testing.T) {
 	require.Contains(t, renderedString, "test", "Rendered preview should contain the test content")
 }
 
+func TestPreviewPausedInstance(t *testing.T) {
+	log.Initialize(false)
+	defer log.Close()
+
+	tests := []struct {
+		name           string
+		pausedSnapshot bool
+		capture        string
+		contains       []string
+	}{
+		{
+			name:           "snapshot shows the capture under a banner",
+			pausedSnapshot: true,
+			capture:        "final agent output",
+			contains:       []string{"PAUSED", "final agent output"},
+		},
+		{
+			name:           "snapshot without a capture shows the resume message",
+			pausedSnapshot: true,
+			capture:        "",
+			contains:       []string{"Session is paused"},
+		},
+		{
+			name:           "message mode ignores the capture",
+			pausedSnapshot: false,
+			capture:        "final agent output",
+			contains:       []string{"Session is paused"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := session.FromInstanceData(session.InstanceData{
+				Title:         "paused",
+				Branch:        "test-branch",
+				Status:        session.Paused,
+				PausedCapture: tt.capture,
+			})
+			require.NoError(t, err)
+
+			previewPane := NewPreviewPane()
+			previewPane.SetSize(100, 30)
+			previewPane.SetPausedSnapshot(tt.pausedSnapshot)
+			require.NoError(t, previewPane.UpdateContent(instance))
+
+			rendered := previewPane.String()
+			for _, s := range tt.contains {
+				require.Contains(t, rendered, s)
+			}
+		})
+	}
+}
+
+func TestServerPaneScrollToTopAndBottom(t *testing.T) {
+	tests := []struct {
+		name              string
+		scroll            func(s *ServerPane)
+		expectedScrolling bool
+		expectedAtTop     bool
+	}{
+		{
+			name:              "top enters scroll mode at the oldest output",
+			scroll:            func(s *ServerPane) { s.ScrollToTop() },
+			expectedScrolling: true,
+			expectedAtTop:     true,
+		},
+		{
+			name: "bottom follows new output again",
+			scroll: func(s *ServerPane) {
+				s.ScrollToTop()
+				s.ScrollToBottom()
+			},
+			expectedScrolling: false,
+		},
+		{
+			name: "pages up into scroll mode",
+			scroll: func(s *ServerPane) {
+				for i := 0; i < 5; i++ {
+					s.ScrollPageUp(false)
+				}
+			},
+			expectedScrolling: true,
+			expectedAtTop:     true,
+		},
+		{
+			name: "half pages stay in scroll mode",
+			scroll: func(s *ServerPane) {
+				s.ScrollToTop()
+				for i := 0; i < 20; i++ {
+					s.ScrollPageDown(true)
+				}
+			},
+			expectedScrolling: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]string, 50)
+			for i := range lines {
+				lines[i] = fmt.Sprintf("line %d", i)
+			}
+			serverPane := NewServerPane()
+			serverPane.SetSize(40, 10)
+			serverPane.text = strings.Join(lines, "\n")
+			serverPane.viewport.SetContent(serverPane.text)
+			serverPane.viewport.GotoBottom()
+
+			tt.scroll(serverPane)
+
+			require.Equal(t, tt.expectedScrolling, serverPane.IsScrolling())
+			require.Equal(t, tt.expectedAtTop, serverPane.viewport.AtTop())
+			require.Equal(t, !tt.expectedAtTop, serverPane.viewport.AtBottom())
+		})
+	}
+}
+
+func TestServerPaneFollow(t *testing.T) {
+	tests := []struct {
+		name             string
+		follow           bool
+		expectedAtBottom bool
+	}{
+		{
+			name:             "follow pins the view to new output while scrolled up",
+			follow:           true,
+			expectedAtBottom: true,
+		},
+		{
+			name:             "without follow scroll mode keeps its position",
+			follow:           false,
+			expectedAtBottom: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]string, 50)
+			for i := range lines {
+				lines[i] = fmt.Sprintf("line %d", i)
+			}
+			serverPane := NewServerPane()
+			serverPane.SetSize(40, 10)
+			serverPane.text = strings.Join(lines, "\n")
+			serverPane.refreshViewport()
+
+			if tt.follow {
+				require.True(t, serverPane.ToggleFollow())
+			}
+			serverPane.ScrollToTop()
+
+			serverPane.text += "\nnew output"
+			serverPane.refreshViewport()
+
+			require.Equal(t, tt.follow, serverPane.FollowEnabled())
+			require.Equal(t, !tt.follow, serverPane.IsScrolling())
+			require.Equal(t, tt.expectedAtBottom, serverPane.viewport.AtBottom())
+		})
+	}
+}
+
+func TestTabbedWindowKeepsServerTab(t *testing.T) {
+	withServer, err := session.NewInstance(session.InstanceOptions{Title: "with-server", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+	withServer.DevServer = session.NewDevServer(session.DevServerConfig{DevCommand: "npm run dev"}, t.TempDir(), withServer.Title)
+	withoutServer, err := session.NewInstance(session.InstanceOptions{Title: "without-server", Path: t.TempDir(), Program: "claude"})
+	require.NoError(t, err)
+
+	window := NewTabbedWindow(NewPreviewPane(), NewDiffPane())
+	window.SetSize(100, 30)
+	window.Toggle()
+	require.True(t, window.IsInServerTab())
+
+	window.SetInstance(withServer)
+	require.NoError(t, window.UpdateServer(withServer))
+	require.Contains(t, window.String(), "npm run dev")
+	window.ScrollUp()
+	require.True(t, window.IsServerInScrollMode())
+
+	// Selecting an instance without a dev server stays on the server tab and says so.
+	window.SetInstance(withoutServer)
+	require.NoError(t, window.UpdateServer(withoutServer))
+	require.True(t, window.IsInServerTab())
+	require.False(t, window.IsServerInScrollMode())
+	rendered := window.String()
+	require.Contains(t, rendered, "No dev server configured.")
+	require.NotContains(t, rendered, "npm run dev")
+}
+
 // Helper function for max
 func max(a, b int) int {
 	if a > b {
@@ -4,6 +4,7 @@ import (
 	"claude-squad/session"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
@@ -12,6 +13,10 @@ import (
 var serverPaneStyle = lipgloss.NewStyle().
 	Foreground(lipgloss.AdaptiveColor{Light: "#1a1a1a", Dark: "#dddddd"})
 
+var runMarkerStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("#7D56F4")).
+	Bold(true)
+
 type ServerPane struct {
 	width        int
 	height       int
@@ -19,6 +24,10 @@ type ServerPane struct {
 	viewport     viewport.Model
 	isScrolling  bool
 	userScrolled bool // Track if user manually scrolled
+	// followEnabled pins the view to the latest output on every update, regardless of scroll mode
+	followEnabled bool
+	// runLines are the line numbers in text of the dividers between dev server runs
+	runLines []int
 }
 
 func NewServerPane() *ServerPane {
@@ -85,6 +94,9 @@ func (s *ServerPane) UpdateContent(instance *session.Instance) error {
 		} else {
 			s.text = output
 		}
+		if timings := serverTimings(instance); timings != "" {
+			s.text = timings + "\n\n" + s.text
+		}
 	case session.DevServerCrashed:
 		output := server.Output()
 		if output != "" {
@@ -110,19 +122,69 @@ func (s *ServerPane) UpdateContent(instance *session.Instance) error {
 		}
 	}
 
-	// Update viewport and auto-scroll (only when not in scroll mode)
-	if s.viewport.Width > 0 && s.viewport.Height > 0 && !s.isScrolling {
-		wasAtBottom := s.viewport.AtBottom()
+	s.highlightRunMarkers()
+	s.refreshViewport()
+
+	return nil
+}
 
+// serverTimings describes how long the build and the start of the current run of the dev server of
+// instance took, e.g. "Built in 4.2s · Ready in 1.1s", or returns an empty string if neither is known.
+func serverTimings(instance *session.Instance) string {
+	server := instance.DevServer
+	var parts []string
+	if d := server.LastBuildDuration(); d > 0 {
+		parts = append(parts, "Built in "+d.Round(100*time.Millisecond).String())
+	}
+	if d := server.LastStartDuration(); d > 0 {
+		parts = append(parts, "Ready in "+d.Round(100*time.Millisecond).String())
+	}
+	return strings.Join(parts, " · ")
+}
+
+// refreshViewport sets the viewport content to the latest text. With follow enabled the view is
+// pinned to the bottom, even if the user scrolled away. Otherwise it only auto-scrolls when not in
+// scroll mode and already at the bottom.
+func (s *ServerPane) refreshViewport() {
+	if s.viewport.Width == 0 || s.viewport.Height == 0 {
+		return
+	}
+
+	if s.followEnabled {
+		s.isScrolling = false
+		s.userScrolled = false
 		s.viewport.SetContent(s.text)
+		s.viewport.GotoBottom()
+		return
+	}
 
-		if wasAtBottom {
-			s.viewport.GotoBottom()
-			s.userScrolled = false
-		}
+	if s.isScrolling {
+		return
 	}
 
-	return nil
+	wasAtBottom := s.viewport.AtBottom()
+
+	s.viewport.SetContent(s.text)
+
+	if wasAtBottom {
+		s.viewport.GotoBottom()
+		s.userScrolled = false
+	}
+}
+
+// ToggleFollow turns follow mode on or off and returns whether it is now enabled. Enabling it jumps
+// to the latest output.
+func (s *ServerPane) ToggleFollow() bool {
+	s.followEnabled = !s.followEnabled
+	if s.followEnabled {
+		s.refreshViewport()
+	}
+	return s.followEnabled
+}
+
+// FollowEnabled returns true if the pane is pinned to the latest output.
+func (s *ServerPane) FollowEnabled() bool {
+	return s.followEnabled
 }
 
 func (s *ServerPane) String() string {
@@ -137,7 +199,7 @@ func (s *ServerPane) String() string {
 	wasAtBottom := s.viewport.AtBottom()
 	s.viewport.SetContent(s.text)
 
-	if wasAtBottom {
+	if wasAtBottom || s.followEnabled {
 		s.viewport.GotoBottom()
 	}
 
@@ -145,40 +207,50 @@ func (s *ServerPane) String() string {
 }
 
 func (s *ServerPane) ScrollUp() {
-	if !s.isScrolling {
-		// Entering scroll mode
-		s.isScrolling = true
-		s.userScrolled = true
-
-		// Add footer for scroll mode
-		footer := lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
-			Render("ESC to exit scroll mode | ↑↓ to scroll")
-
-		s.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, s.text, footer))
-	} else {
-		s.userScrolled = true
-	}
-
+	s.enterScrollMode()
 	s.viewport.LineUp(1)
 }
 
 func (s *ServerPane) ScrollDown() {
-	if !s.isScrolling {
-		// Entering scroll mode
-		s.isScrolling = true
-		s.userScrolled = true
+	s.enterScrollMode()
+	s.viewport.LineDown(1)
+}
 
-		footer := lipgloss.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
-			Render("ESC to exit scroll mode | ↑↓ to scroll")
+// ScrollPageUp scrolls up by a page, or half a page when half is true.
+func (s *ServerPane) ScrollPageUp(half bool) {
+	s.enterScrollMode()
+	if half {
+		s.viewport.HalfViewUp()
+	} else {
+		s.viewport.ViewUp()
+	}
+}
 
-		s.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, s.text, footer))
+// ScrollPageDown scrolls down by a page, or half a page when half is true.
+func (s *ServerPane) ScrollPageDown(half bool) {
+	s.enterScrollMode()
+	if half {
+		s.viewport.HalfViewDown()
 	} else {
-		s.userScrolled = true
+		s.viewport.ViewDown()
 	}
+}
 
-	s.viewport.LineDown(1)
+// enterScrollMode stops following new output and adds the scroll mode footer, if not already
+// scrolling.
+func (s *ServerPane) enterScrollMode() {
+	s.userScrolled = true
+	if s.isScrolling {
+		return
+	}
+	s.isScrolling = true
+
+	// Add footer for scroll mode
+	footer := lipgloss.NewStyle().
+		Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
+		Render("ESC to exit scroll mode | ↑↓ to scroll")
+
+	s.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, s.text, footer))
 }
 
 func (s *ServerPane) ResetToNormalMode() {
@@ -194,6 +266,66 @@ func (s *ServerPane) ResetToNormalMode() {
 	}
 }
 
+// ScrollToTop enters scroll mode at the oldest buffered output.
+func (s *ServerPane) ScrollToTop() {
+	s.jumpToLine(0)
+}
+
+// ScrollToBottom jumps to the latest output and leaves scroll mode, so the pane follows new output
+// again.
+func (s *ServerPane) ScrollToBottom() {
+	s.ResetToNormalMode()
+	s.viewport.GotoBottom()
+}
+
 func (s *ServerPane) IsScrolling() bool {
 	return s.isScrolling
 }
+
+// highlightRunMarkers styles the dividers between dev server runs in text and records their lines.
+func (s *ServerPane) highlightRunMarkers() {
+	s.runLines = s.runLines[:0]
+	lines := strings.Split(s.text, "\n")
+	for i, line := range lines {
+		if session.IsRunMarker(line) {
+			s.runLines = append(s.runLines, i)
+			lines[i] = runMarkerStyle.Render(line)
+		}
+	}
+	if len(s.runLines) > 0 {
+		s.text = strings.Join(lines, "\n")
+	}
+}
+
+// JumpToPrevRun scrolls to the start of the dev server run above the top of the view.
+func (s *ServerPane) JumpToPrevRun() {
+	for i := len(s.runLines) - 1; i >= 0; i-- {
+		if s.runLines[i] < s.viewport.YOffset {
+			s.jumpToLine(s.runLines[i])
+			return
+		}
+	}
+}
+
+// JumpToNextRun scrolls to the start of the dev server run below the top of the view.
+func (s *ServerPane) JumpToNextRun() {
+	for _, line := range s.runLines {
+		if line > s.viewport.YOffset {
+			s.jumpToLine(line)
+			return
+		}
+	}
+}
+
+// jumpToLine enters scroll mode with line at the top of the view.
+func (s *ServerPane) jumpToLine(line int) {
+	if !s.isScrolling {
+		s.isScrolling = true
+		footer := lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Light: "#808080", Dark: "#808080"}).
+			Render("ESC to exit scroll mode | ↑↓ to scroll | [ ] to jump between runs")
+		s.viewport.SetContent(lipgloss.JoinVertical(lipgloss.Left, s.text, footer))
+	}
+	s.userScrolled = true
+	s.viewport.SetYOffset(line)
+}
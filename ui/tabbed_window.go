@@ -28,6 +28,10 @@ var (
 	windowStyle = lipgloss.NewStyle().
 			BorderForeground(highlightColor).
 			Border(lipgloss.NormalBorder(), false, true, true, true)
+	// unfocusedColor replaces highlightColor on the borders of a pane that doesn't have the focus.
+	unfocusedColor = lipgloss.AdaptiveColor{Light: "#C9C5C5", Dark: "#4A4A4A"}
+	// unfocusedContentStyle dims the content of a pane that doesn't have the focus.
+	unfocusedContentStyle = lipgloss.NewStyle().Faint(true)
 )
 
 const (
@@ -49,6 +53,9 @@ type TabbedWindow struct {
 	activeTab int
 	height    int
 	width     int
+	// focused is true if the window, rather than the list, gets the navigation and scroll keys. The
+	// window is dimmed otherwise.
+	focused bool
 
 	preview  *PreviewPane
 	server   *ServerPane
@@ -69,7 +76,15 @@ func NewTabbedWindow(preview *PreviewPane, diff *DiffPane) *TabbedWindow {
 	}
 }
 
+// SetInstance sets the instance shown in the tabs. The active tab is kept, so e.g. the server tab
+// stays selected and shows that the instance has no dev server rather than switching to another tab.
+// The server pane leaves scroll mode when the instance changes, since the position belonged to the
+// previous instance's output, and the diff shows all files again.
 func (w *TabbedWindow) SetInstance(instance *session.Instance) {
+	if instance != w.instance {
+		w.server.ResetToNormalMode()
+		w.diff.SetFile("")
+	}
 	w.instance = instance
 }
 
@@ -107,6 +122,14 @@ func (w *TabbedWindow) ToggleBackward() {
 	w.activeTab = (w.activeTab - 1 + len(w.tabs)) % len(w.tabs)
 }
 
+// SetTab selects the tab at index, in the order the tabs are shown. Indexes out of range are
+// ignored.
+func (w *TabbedWindow) SetTab(index int) {
+	if index >= 0 && index < len(w.tabs) {
+		w.activeTab = index
+	}
+}
+
 // ToggleWithReset toggles the tab and resets preview pane to normal mode
 func (w *TabbedWindow) ToggleWithReset(instance *session.Instance) error {
 	// Reset preview pane to normal mode before switching
@@ -132,6 +155,20 @@ func (w *TabbedWindow) UpdateDiff(instance *session.Instance) {
 	w.diff.SetDiff(instance)
 }
 
+// ShowDiffFile switches to the diff tab narrowed to the changes of path. An empty path shows all
+// files.
+func (w *TabbedWindow) ShowDiffFile(instance *session.Instance, path string) {
+	w.activeTab = DiffTab
+	w.diff.SetFile(path)
+	w.diff.SetDiff(instance)
+	w.diff.ScrollToTop()
+}
+
+// DiffFile returns the file the diff tab is narrowed to, or an empty string if it shows all files.
+func (w *TabbedWindow) DiffFile() string {
+	return w.diff.File()
+}
+
 func (w *TabbedWindow) UpdateServer(instance *session.Instance) error {
 	if w.activeTab != ServerTab {
 		return nil
@@ -154,6 +191,22 @@ func (w *TabbedWindow) IsServerInScrollMode() bool {
 	return w.server.IsScrolling()
 }
 
+// ToggleServerFollow toggles pinning the server pane to the latest output and returns whether it
+// is now enabled
+func (w *TabbedWindow) ToggleServerFollow() bool {
+	return w.server.ToggleFollow()
+}
+
+// JumpToPrevServerRun scrolls the server pane to the previous dev server run
+func (w *TabbedWindow) JumpToPrevServerRun() {
+	w.server.JumpToPrevRun()
+}
+
+// JumpToNextServerRun scrolls the server pane to the next dev server run
+func (w *TabbedWindow) JumpToNextServerRun() {
+	w.server.JumpToNextRun()
+}
+
 // Add these new methods for handling scroll events
 func (w *TabbedWindow) ScrollUp() {
 	switch w.activeTab {
@@ -183,14 +236,81 @@ func (w *TabbedWindow) ScrollDown() {
 	}
 }
 
+// ScrollPageUp scrolls the active pane up by a page, or half a page when half is true
+func (w *TabbedWindow) ScrollPageUp(half bool) {
+	switch w.activeTab {
+	case PreviewTab:
+		if err := w.preview.ScrollPageUp(w.instance, half); err != nil {
+			log.InfoLog.Printf("tabbed window failed to scroll page up: %v", err)
+		}
+	case ServerTab:
+		w.server.ScrollPageUp(half)
+	case DiffTab:
+		w.diff.ScrollPageUp(half)
+	}
+}
+
+// ScrollPageDown scrolls the active pane down by a page, or half a page when half is true
+func (w *TabbedWindow) ScrollPageDown(half bool) {
+	switch w.activeTab {
+	case PreviewTab:
+		if err := w.preview.ScrollPageDown(w.instance, half); err != nil {
+			log.InfoLog.Printf("tabbed window failed to scroll page down: %v", err)
+		}
+	case ServerTab:
+		w.server.ScrollPageDown(half)
+	case DiffTab:
+		w.diff.ScrollPageDown(half)
+	}
+}
+
+// ScrollToTop jumps to the top of the active pane
+func (w *TabbedWindow) ScrollToTop() {
+	switch w.activeTab {
+	case PreviewTab:
+		if err := w.preview.ScrollToTop(w.instance); err != nil {
+			log.InfoLog.Printf("tabbed window failed to scroll to top: %v", err)
+		}
+	case ServerTab:
+		w.server.ScrollToTop()
+	case DiffTab:
+		w.diff.ScrollToTop()
+	}
+}
+
+// ScrollToBottom jumps to the bottom of the active pane. The preview and server panes follow new
+// output again.
+func (w *TabbedWindow) ScrollToBottom() {
+	switch w.activeTab {
+	case PreviewTab:
+		if err := w.preview.ScrollToBottom(w.instance); err != nil {
+			log.InfoLog.Printf("tabbed window failed to scroll to bottom: %v", err)
+		}
+	case ServerTab:
+		w.server.ScrollToBottom()
+	case DiffTab:
+		w.diff.ScrollToBottom()
+	}
+}
+
+// SetFocused sets whether the window has the focus. An unfocused window is dimmed.
+func (w *TabbedWindow) SetFocused(focused bool) {
+	w.focused = focused
+}
+
+// Focused returns true if the window has the focus.
+func (w *TabbedWindow) Focused() bool {
+	return w.focused
+}
+
 // IsInDiffTab returns true if the diff tab is currently active
 func (w *TabbedWindow) IsInDiffTab() bool {
-	return w.activeTab == 2
+	return w.activeTab == DiffTab
 }
 
 // IsInServerTab returns true if the server tab is currently active
 func (w *TabbedWindow) IsInServerTab() bool {
-	return w.activeTab == 1
+	return w.activeTab == ServerTab
 }
 
 // IsPreviewInScrollMode returns true if the preview pane is in scroll mode
@@ -234,6 +354,9 @@ func (w *TabbedWindow) String() string {
 		}
 		style = style.Border(border)
 		style = style.Width(width - 1)
+		if !w.focused {
+			style = style.BorderForeground(unfocusedColor).Faint(true)
+		}
 		renderedTabs = append(renderedTabs, style.Render(t))
 	}
 
@@ -249,7 +372,12 @@ func (w *TabbedWindow) String() string {
 	default:
 		content = w.preview.String()
 	}
-	window := windowStyle.Render(
+	windowS := windowStyle
+	if !w.focused {
+		windowS = windowS.BorderForeground(unfocusedColor)
+		content = unfocusedContentStyle.Render(content)
+	}
+	window := windowS.Render(
 		lipgloss.Place(
 			w.width, w.height-2-windowStyle.GetVerticalFrameSize()-tabHeight,
 			lipgloss.Left, lipgloss.Top, content))
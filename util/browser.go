@@ -0,0 +1,31 @@
+package util
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// OpenURL opens url in the default browser.
+func OpenURL(url string) error {
+	cmd := browserCommand(runtime.GOOS, url)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", url, err)
+	}
+	// Don't leave a zombie behind; the browser keeps running on its own.
+	go cmd.Wait()
+	return nil
+}
+
+// browserCommand returns the command that opens url in the default browser on goos.
+func browserCommand(goos, url string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		// The empty argument is the window title; without it start treats a quoted URL as the title.
+		return exec.Command("cmd", "/c", "start", "", url)
+	default:
+		return exec.Command("xdg-open", url)
+	}
+}
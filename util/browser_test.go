@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrowserCommand(t *testing.T) {
+	const url = "http://localhost:3000"
+
+	tests := []struct {
+		name     string
+		goos     string
+		expected []string
+	}{
+		{name: "macOS", goos: "darwin", expected: []string{"open", url}},
+		{name: "windows", goos: "windows", expected: []string{"cmd", "/c", "start", "", url}},
+		{name: "linux", goos: "linux", expected: []string{"xdg-open", url}},
+		{name: "other unix", goos: "freebsd", expected: []string{"xdg-open", url}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, browserCommand(tt.goos, url).Args)
+		})
+	}
+}
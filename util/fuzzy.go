@@ -0,0 +1,63 @@
+package util
+
+import (
+	"unicode"
+)
+
+const (
+	// fuzzyMatchScore is awarded for every matched character.
+	fuzzyMatchScore = 1
+	// fuzzyConsecutiveBonus is added when a match directly follows the previous one.
+	fuzzyConsecutiveBonus = 8
+	// fuzzyBoundaryBonus is added when a match starts the text or a word in it.
+	fuzzyBoundaryBonus = 8
+	// fuzzyGapPenalty is subtracted for every skipped character between two matches.
+	fuzzyGapPenalty = 3
+)
+
+// FuzzyMatch reports whether every rune of pattern appears in text in order, ignoring case. It
+// returns a score that is higher for tighter matches and matches at word starts, and the rune
+// indexes of text that matched. An empty pattern matches everything with a score of zero.
+func FuzzyMatch(pattern, text string) (score int, positions []int, ok bool) {
+	patternRunes := []rune(pattern)
+	if len(patternRunes) == 0 {
+		return 0, nil, true
+	}
+	textRunes := []rune(text)
+
+	p := 0
+	for i, r := range textRunes {
+		if p == len(patternRunes) {
+			break
+		}
+		if unicode.ToLower(r) != unicode.ToLower(patternRunes[p]) {
+			continue
+		}
+		score += fuzzyMatchScore
+		if i == 0 || isWordBoundary(textRunes[i-1]) {
+			score += fuzzyBoundaryBonus
+		}
+		if len(positions) > 0 {
+			if gap := i - positions[len(positions)-1] - 1; gap == 0 {
+				score += fuzzyConsecutiveBonus
+			} else {
+				score -= gap * fuzzyGapPenalty
+			}
+		}
+		positions = append(positions, i)
+		p++
+	}
+	if p < len(patternRunes) {
+		return 0, nil, false
+	}
+	return score, positions, true
+}
+
+// isWordBoundary returns true if a rune after r starts a new word.
+func isWordBoundary(r rune) bool {
+	switch r {
+	case ' ', '-', '_', '/', '.', ':':
+		return true
+	}
+	return false
+}
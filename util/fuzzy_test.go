@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name              string
+		pattern           string
+		text              string
+		expectedOK        bool
+		expectedPositions []int
+	}{
+		{name: "empty pattern matches everything", pattern: "", text: "backend", expectedOK: true},
+		{name: "prefix", pattern: "back", text: "backend", expectedOK: true, expectedPositions: []int{0, 1, 2, 3}},
+		{name: "ignores case", pattern: "BE", text: "backend", expectedOK: true, expectedPositions: []int{0, 4}},
+		{name: "subsequence", pattern: "fe", text: "frontend", expectedOK: true, expectedPositions: []int{0, 5}},
+		{name: "out of order", pattern: "eb", text: "backend", expectedOK: false},
+		{name: "missing rune", pattern: "bx", text: "backend", expectedOK: false},
+		{name: "multibyte runes", pattern: "é", text: "café-api", expectedOK: true, expectedPositions: []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, positions, ok := FuzzyMatch(tt.pattern, tt.text)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedPositions, positions)
+		})
+	}
+}
+
+func TestFuzzyMatchScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		better  string
+		worse   string
+	}{
+		{name: "consecutive beats scattered", pattern: "api", better: "api-server", worse: "a-p-i"},
+		{name: "word start beats mid-word", pattern: "web", better: "fix-web", worse: "cobweb"},
+		{name: "tight beats loose", pattern: "fe", better: "fe-login", worse: "frontend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			betterScore, _, ok := FuzzyMatch(tt.pattern, tt.better)
+			assert.True(t, ok)
+			worseScore, _, ok := FuzzyMatch(tt.pattern, tt.worse)
+			assert.True(t, ok)
+			assert.Greater(t, betterScore, worseScore)
+		})
+	}
+}